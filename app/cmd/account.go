@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"backend-mobile-api/internal/repository/minio"
+	"backend-mobile-api/internal/repository/postgres"
+	accountSvc "backend-mobile-api/service/account-svc"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// purge is meant to run from a scheduler, every run handles one batch of the
+// accounts whose grace period ended and leaves failed ones for the next run.
+var (
+	accountCommand = &cobra.Command{
+		Use:   "account",
+		Short: "Maintain user accounts",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			initPostgres()
+		},
+	}
+	accountPurgeCommand = &cobra.Command{
+		Use:   "purge",
+		Short: "Purge the accounts whose deletion grace period ended",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return accountPurge()
+		},
+	}
+)
+
+func init() {
+	accountCommand.AddCommand(accountPurgeCommand)
+	rootCmd.AddCommand(accountCommand)
+}
+
+func accountPurge() error {
+	ctx := context.Background()
+	minioClient, err := rootConfig.Minio.MinioClientSet()
+	if err != nil {
+		return err
+	}
+	accountService := accountSvc.NewAccountService(
+		postgres.NewAccountRepository(MasterDatabase, CLoger),
+		postgres.NewUserRepository(MasterDatabase, CLoger),
+		minio.NewMinioRepository(minioClient, &rootConfig, rootConfig.Minio.Bucket, CLoger),
+		postgres.NewUnitOfWork(MasterDatabase, CLoger),
+		&rootConfig.AccountDeletion,
+		CLoger,
+	)
+	purged, err := accountService.PurgeDue(ctx, time.Now())
+	fmt.Printf("purged %d accounts\n", purged)
+	return err
+}
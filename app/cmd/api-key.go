@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"backend-mobile-api/internal/middleware"
+	"backend-mobile-api/internal/repository/postgres"
+	"backend-mobile-api/model/entity"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// api keys authenticate services and back-office tools on /api/internal/v1 through X-API-KEY
+var (
+	apiKeyName    string
+	apiKeyScopes  []string
+	apiKeyIPs     []string
+	apiKeyExpires time.Duration
+
+	apiKeyCommand = &cobra.Command{
+		Use:   "api-key",
+		Short: "Manage api keys for internal routes",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			initPostgres()
+		},
+	}
+	apiKeyCreateCommand = &cobra.Command{
+		Use:   "create",
+		Short: "Create an api key, the key is only shown once",
+		Args:  cobra.NoArgs,
+		RunE:  apiKeyCreate,
+	}
+	apiKeyListCommand = &cobra.Command{
+		Use:   "list",
+		Short: "List api keys",
+		Args:  cobra.NoArgs,
+		RunE:  apiKeyList,
+	}
+	apiKeyRevokeCommand = &cobra.Command{
+		Use:   "revoke <prefix>",
+		Short: "Revoke an api key",
+		Args:  cobra.ExactArgs(1),
+		RunE:  apiKeyRevoke,
+	}
+)
+
+func init() {
+	apiKeyCreateCommand.Flags().StringVar(&apiKeyName, "name", "", "owner of the key, e.g. verihubs-callback")
+	apiKeyCreateCommand.Flags().StringSliceVar(&apiKeyScopes, "scope", nil, "permission the key grants, repeatable, e.g. otp:callback")
+	apiKeyCreateCommand.Flags().StringSliceVar(&apiKeyIPs, "ip", nil, "ip or cidr the key may be used from, repeatable, empty allows any")
+	apiKeyCreateCommand.Flags().DurationVar(&apiKeyExpires, "expires", 0, "lifetime of the key, 0 never expires")
+	_ = apiKeyCreateCommand.MarkFlagRequired("name")
+	_ = apiKeyCreateCommand.MarkFlagRequired("scope")
+	apiKeyCommand.AddCommand(apiKeyCreateCommand, apiKeyListCommand, apiKeyRevokeCommand)
+	rootCmd.AddCommand(apiKeyCommand)
+}
+
+func apiKeyCreate(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	repository := postgres.NewApiKeyRepository(MasterDatabase, CLoger)
+
+	key, prefix, hash, err := middleware.GenerateApiKey()
+	if err != nil {
+		return err
+	}
+	apiKey := &entity.ApiKey{
+		Name:        apiKeyName,
+		Prefix:      prefix,
+		KeyHash:     hash,
+		Scopes:      middleware.JoinApiKeyList(apiKeyScopes),
+		IPAllowlist: middleware.JoinApiKeyList(apiKeyIPs),
+	}
+	if apiKeyExpires > 0 {
+		expiredAt := time.Now().Add(apiKeyExpires)
+		apiKey.ExpiredAt = &expiredAt
+	}
+	err = postgres.NewUnitOfWork(MasterDatabase, CLoger).Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		return repository.InsertApiKey(ctx, tx, apiKey)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created api key %s for %s, scopes %s\n", prefix, apiKey.Name, apiKey.Scopes)
+	fmt.Println(key)
+	return nil
+}
+
+func apiKeyList(cmd *cobra.Command, args []string) error {
+	keys, err := postgres.NewApiKeyRepository(MasterDatabase, CLoger).SelectApiKeys(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		status := "active"
+		switch {
+		case key.RevokedAt != nil:
+			status = "revoked " + key.RevokedAt.Format(time.RFC3339)
+		case key.ExpiredAt != nil && time.Now().After(*key.ExpiredAt):
+			status = "expired " + key.ExpiredAt.Format(time.RFC3339)
+		}
+		fmt.Printf("%s\t%s\t%s\t[%s]\t[%s]\n", key.Prefix, key.Name, status, key.Scopes, key.IPAllowlist)
+	}
+	return nil
+}
+
+func apiKeyRevoke(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	repository := postgres.NewApiKeyRepository(MasterDatabase, CLoger)
+	err := postgres.NewUnitOfWork(MasterDatabase, CLoger).Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		affected, err := repository.RevokeApiKey(ctx, tx, args[0])
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return fmt.Errorf("no active api key with prefix %s", args[0])
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("revoked api key %s\n", args[0])
+	return nil
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"backend-mobile-api/internal/middleware"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// rotating a key: generate, wait until every instance and consumer of the JWKS
+// has reloaded (JWT_KEYRING_RELOAD), promote, and retire the old key once the
+// refresh token lifetime has passed.
+var (
+	jwtKeyUse     string
+	jwtKeyPromote bool
+
+	jwtKeyCommand = &cobra.Command{
+		Use:   "jwt-key",
+		Short: "Manage JWT signing keys in JWT_KEYRING_DIR",
+	}
+	jwtKeyGenerateCommand = &cobra.Command{
+		Use:   "generate",
+		Short: "Generate a new signing key",
+		Args:  cobra.NoArgs,
+		RunE:  jwtKeyGenerate,
+	}
+	jwtKeyPromoteCommand = &cobra.Command{
+		Use:   "promote <kid>",
+		Short: "Sign new tokens with the given key",
+		Args:  cobra.ExactArgs(1),
+		RunE:  jwtKeyPromoteRun,
+	}
+	jwtKeyRetireCommand = &cobra.Command{
+		Use:   "retire <kid>",
+		Short: "Remove a key, tokens signed with it stop validating",
+		Args:  cobra.ExactArgs(1),
+		RunE:  jwtKeyRetire,
+	}
+	jwtKeyListCommand = &cobra.Command{
+		Use:   "list",
+		Short: "List signing keys",
+		Args:  cobra.NoArgs,
+		RunE:  jwtKeyList,
+	}
+)
+
+func init() {
+	jwtKeyCommand.PersistentFlags().StringVarP(&jwtKeyUse, "use", "u", middleware.KEY_USE_ACCESS, "key use: access or refresh")
+	jwtKeyGenerateCommand.Flags().BoolVar(&jwtKeyPromote, "promote", false, "promote the key right away, only for a keyring without consumers yet")
+	jwtKeyCommand.AddCommand(jwtKeyGenerateCommand, jwtKeyPromoteCommand, jwtKeyRetireCommand, jwtKeyListCommand)
+	rootCmd.AddCommand(jwtKeyCommand)
+}
+
+func jwtKeyKeyringDir() (string, error) {
+	if jwtKeyUse != middleware.KEY_USE_ACCESS && jwtKeyUse != middleware.KEY_USE_REFRESH {
+		return "", fmt.Errorf("unknown key use %q", jwtKeyUse)
+	}
+	if rootConfig.Jwt.KeyringDir == "" {
+		return "", fmt.Errorf("JWT_KEYRING_DIR is not set")
+	}
+	return rootConfig.Jwt.KeyringDir, nil
+}
+
+func jwtKeyGenerate(cmd *cobra.Command, args []string) error {
+	dir, err := jwtKeyKeyringDir()
+	if err != nil {
+		return err
+	}
+	kid, err := middleware.GenerateKeyringKey(dir, jwtKeyUse)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("generated %s key %s\n", jwtKeyUse, kid)
+	if !jwtKeyPromote {
+		return nil
+	}
+	if err = middleware.PromoteKeyringKey(dir, jwtKeyUse, kid); err != nil {
+		return err
+	}
+	fmt.Printf("promoted %s key %s\n", jwtKeyUse, kid)
+	return nil
+}
+
+func jwtKeyPromoteRun(cmd *cobra.Command, args []string) error {
+	dir, err := jwtKeyKeyringDir()
+	if err != nil {
+		return err
+	}
+	if err = middleware.PromoteKeyringKey(dir, jwtKeyUse, args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("promoted %s key %s\n", jwtKeyUse, args[0])
+	return nil
+}
+
+func jwtKeyRetire(cmd *cobra.Command, args []string) error {
+	dir, err := jwtKeyKeyringDir()
+	if err != nil {
+		return err
+	}
+	if err = middleware.RetireKeyringKey(dir, jwtKeyUse, args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("retired %s key %s\n", jwtKeyUse, args[0])
+	return nil
+}
+
+func jwtKeyList(cmd *cobra.Command, args []string) error {
+	dir, err := jwtKeyKeyringDir()
+	if err != nil {
+		return err
+	}
+	kids, active, err := middleware.ListKeyringKeys(dir, jwtKeyUse)
+	if err != nil {
+		return err
+	}
+	for _, kid := range kids {
+		if kid == active {
+			fmt.Printf("%s (active)\n", kid)
+			continue
+		}
+		fmt.Println(kid)
+	}
+	if active == "" {
+		fmt.Printf("no active %s key in keyring, signing with %s\n", jwtKeyUse, rootConfig.Jwt.LegacyKid)
+	}
+	return nil
+}
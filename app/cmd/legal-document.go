@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"backend-mobile-api/internal/repository/postgres"
+	"backend-mobile-api/model/entity"
+	"backend-mobile-api/model/enum"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// a published mandatory version makes every user accept it again from their next
+// login, --at schedules it so the text can be announced before it applies.
+var (
+	legalDocumentTitle     string
+	legalDocumentOptional  bool
+	legalDocumentPublishAt string
+
+	legalDocumentCommand = &cobra.Command{
+		Use:   "legal-document",
+		Short: "Publish versions of the terms, privacy policy and marketing consent",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			initPostgres()
+		},
+	}
+	legalDocumentPublishCommand = &cobra.Command{
+		Use:   "publish <type> <version> <url>",
+		Short: "Publish a new version of a legal document",
+		Args:  cobra.ExactArgs(3),
+		RunE:  legalDocumentPublish,
+	}
+	legalDocumentListCommand = &cobra.Command{
+		Use:   "list",
+		Short: "List legal document versions",
+		Args:  cobra.NoArgs,
+		RunE:  legalDocumentList,
+	}
+)
+
+func init() {
+	legalDocumentPublishCommand.Flags().StringVar(&legalDocumentTitle, "title", "", "title shown to the user")
+	legalDocumentPublishCommand.Flags().BoolVar(&legalDocumentOptional, "optional", false, "users may decline this version, e.g. marketing")
+	legalDocumentPublishCommand.Flags().StringVar(&legalDocumentPublishAt, "at", "", "publish time in RFC3339, empty publishes now")
+	_ = legalDocumentPublishCommand.MarkFlagRequired("title")
+	legalDocumentCommand.AddCommand(legalDocumentPublishCommand, legalDocumentListCommand)
+	rootCmd.AddCommand(legalDocumentCommand)
+}
+
+func legalDocumentPublish(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	repository := postgres.NewConsentRepository(MasterDatabase, CLoger)
+
+	documentType := enum.LegalDocumentType(strings.ToUpper(args[0]))
+	if !documentType.Valid() {
+		return fmt.Errorf("unknown legal document type %s", args[0])
+	}
+	publishedAt := time.Now()
+	if legalDocumentPublishAt != "" {
+		var err error
+		if publishedAt, err = time.Parse(time.RFC3339, legalDocumentPublishAt); err != nil {
+			return fmt.Errorf("at: %w", err)
+		}
+	}
+	document := &entity.LegalDocument{
+		Type:        documentType,
+		Version:     args[1],
+		Title:       legalDocumentTitle,
+		URL:         args[2],
+		Mandatory:   !legalDocumentOptional,
+		PublishedAt: publishedAt,
+	}
+	err := postgres.NewUnitOfWork(MasterDatabase, CLoger).Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		return repository.InsertLegalDocument(ctx, tx, document)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("published %s %s as document %d from %s\n", document.Type, document.Version, document.ID, document.PublishedAt.Format(time.RFC3339))
+	return nil
+}
+
+func legalDocumentList(cmd *cobra.Command, args []string) error {
+	documents, err := postgres.NewConsentRepository(MasterDatabase, CLoger).SelectLegalDocuments(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, document := range documents {
+		mandatory := "mandatory"
+		if !document.Mandatory {
+			mandatory = "optional"
+		}
+		fmt.Printf("%d\t%s\t%s\t%s\t%s\t%s\n", document.ID, document.Type, document.Version, mandatory, document.PublishedAt.Format(time.RFC3339), document.URL)
+	}
+	return nil
+}
@@ -7,7 +7,9 @@ import (
 	"backend-mobile-api/internal/rest"
 	"backend-mobile-api/model/enum"
 	"context"
+	"net"
 	"os"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -19,7 +21,13 @@ import (
 var restCommand = &cobra.Command{
 	Use:   "rest",
 	Short: "Start REST Server",
-	Run:   restServer,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		initRedisClient()
+		initPostgres()
+		initApp()
+		initSwagger()
+	},
+	Run: restServer,
 }
 
 func init() {
@@ -37,29 +45,23 @@ func restServer(cmd *cobra.Command, args []string) {
 				"/api/v1/users/auth/refresh-token",
 				"/api/v1/users/auth/otp/send",
 				"/api/v1/users/auth/otp/verify",
+				"/api/v1/users/auth/otp/status",
+				"/api/v1/users/auth/device/verify",
+				"/api/v1/users/auth/biometric/challenge",
+				"/api/v1/legal-documents",
+				"/api/v1/dev/otp",
+				"/api/internal/v1/verihubs/otp-invoker",
 
 				"/healthcheck/liveness",
 				"/healthcheck/readiness",
-
-				"/api/internal/v1/verihubs/otp-invoker",
-				"/api/internal/v1/verihubs/verify-ktp",
-				"/api/internal/v1/verihubs/verify-passport",
-				"/api/internal/v1/verihubs/verify-selfie",
-
-				"/api/internal/v1/articles/insert",
-				"/api/internal/v1/articles/update",
-				"/api/internal/v1/articles/delete",
-				"/api/internal/v1/articles/list",
-
-				"/api/internal/v1/verify-ktp",
-				"/api/internal/v1/verify-passport",
-				"/api/internal/v1/verify-selfie",
+				"/.well-known/jwks.json",
 			},
-			AccessByRole: map[string][]enum.RolesEnum{},
 		},
 		MandatoryHeader: []string{
 			"/swagger/*",
 			"/api/internal/v1/verihubs/otp-invoker",
+			"/api/internal/v1/verihubs/otp-metrics",
+			"/api/internal/v1/verihubs/otp-timeline",
 			"/api/internal/v1/verihubs/verify-ktp",
 			"/api/internal/v1/verihubs/verify-passport",
 			"/api/internal/v1/verihubs/verify-selfie",
@@ -69,12 +71,17 @@ func restServer(cmd *cobra.Command, args []string) {
 			"/api/internal/v1/articles/delete",
 			"/api/internal/v1/articles/list",
 
+			"/api/internal/v1/transactions/status-update",
+
 			"/healthcheck/liveness",
 			"/healthcheck/readiness",
+			"/.well-known/jwks.json",
 		},
 		ValidationSignaure: []string{
 			"/swagger/*",
 			"/api/internal/v1/verihubs/otp-invoker",
+			"/api/internal/v1/verihubs/otp-metrics",
+			"/api/internal/v1/verihubs/otp-timeline",
 			"/api/internal/v1/verihubs/verify-ktp",
 			"/api/internal/v1/verihubs/verify-passport",
 			"/api/internal/v1/verihubs/verify-selfie",
@@ -84,12 +91,17 @@ func restServer(cmd *cobra.Command, args []string) {
 			"/api/internal/v1/articles/delete",
 			"/api/internal/v1/articles/list",
 
+			"/api/internal/v1/transactions/status-update",
+
 			"/healthcheck/liveness",
 			"/healthcheck/readiness",
+			"/.well-known/jwks.json",
 		},
 		ValidationXNonce: []string{
 			"/swagger/*",
 			"/api/internal/v1/verihubs/otp-invoker",
+			"/api/internal/v1/verihubs/otp-metrics",
+			"/api/internal/v1/verihubs/otp-timeline",
 			"/api/internal/v1/verihubs/verify-ktp",
 			"/api/internal/v1/verihubs/verify-passport",
 			"/api/internal/v1/verihubs/verify-selfie",
@@ -99,19 +111,24 @@ func restServer(cmd *cobra.Command, args []string) {
 			"/api/internal/v1/articles/delete",
 			"/api/internal/v1/articles/list",
 
+			"/api/internal/v1/transactions/status-update",
+
 			"/healthcheck/liveness",
 			"/healthcheck/readiness",
+			"/.well-known/jwks.json",
 		},
 	}
 
 	props := config.LoadForServer(EnvFilePath)
 	e := echo.New()
+	// the client ip feeds the api key allowlist and rate limits, never take it from a header the client controls
+	e.IPExtractor = ipExtractor(props.TrustedProxies)
 	e.Use(middleware.Recover())
 	//cors
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins:     []string{"*"},
 		AllowHeaders:     []string{"Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Menu-Slug, X-Origin-Path, X-Request-Id,VerificationCode,XMLHttpRequest"},
-		AllowMethods:     []string{"POST, HEAD, PATCH, OPTIONS, GET, PUT"},
+		AllowMethods:     []string{"POST, HEAD, PATCH, OPTIONS, GET, PUT, DELETE"},
 		AllowCredentials: true,
 	}))
 
@@ -126,7 +143,7 @@ func restServer(cmd *cobra.Command, args []string) {
 		},
 	})))
 	r := e.Group("")
-	rest.RouthInit(r, &controller, customMiddlewareService)
+	excludedPaths.Authorization.AccessByRoute = rest.RouthInit(r, &controller, customMiddlewareService)
 	rest.InitHealthcheckHandler(r, healtCheckController)
 
 	routeList := internalMiddleware.ListRouth{}
@@ -143,3 +160,24 @@ func restServer(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 }
+
+// ipExtractor reads X-Forwarded-For only when it was appended by one of the
+// trusted proxies, otherwise the address of the peer is the client ip.
+func ipExtractor(trustedProxies []string) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range trustedProxies {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(proxy))
+		if err != nil {
+			log.Fatalf("invalid SERVER_TRUSTED_PROXIES %q: %v", proxy, err)
+		}
+		options = append(options, echo.TrustIPRange(network))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
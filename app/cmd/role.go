@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"backend-mobile-api/internal/repository/postgres"
+	"backend-mobile-api/model/enum"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// roles are granted here rather than through the api so the first admin can be created,
+// a changed role is in the token from the next login or refresh.
+var (
+	roleCommand = &cobra.Command{
+		Use:   "role",
+		Short: "Grant or revoke user roles",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			initPostgres()
+		},
+	}
+	roleGrantCommand = &cobra.Command{
+		Use:   "grant <email> <role>",
+		Short: "Grant a role to a user",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return roleUpdate(args[0], args[1], true)
+		},
+	}
+	roleRevokeCommand = &cobra.Command{
+		Use:   "revoke <email> <role>",
+		Short: "Revoke a role from a user",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return roleUpdate(args[0], args[1], false)
+		},
+	}
+)
+
+func init() {
+	roleCommand.AddCommand(roleGrantCommand, roleRevokeCommand)
+	rootCmd.AddCommand(roleCommand)
+}
+
+func roleUpdate(email string, roleName string, grant bool) error {
+	ctx := context.Background()
+	userRepository := postgres.NewUserRepository(MasterDatabase, CLoger)
+	roleRepository := postgres.NewRoleRepository(MasterDatabase, CLoger)
+
+	user, err := userRepository.SelectUserByEmailOrPhoneNumber(ctx, email)
+	if err != nil {
+		return fmt.Errorf("user %s: %w", email, err)
+	}
+	role, err := roleRepository.SelectRoleByName(ctx, enum.RolesEnum(strings.ToUpper(roleName)))
+	if err != nil {
+		return fmt.Errorf("role %s: %w", roleName, err)
+	}
+
+	err = postgres.NewUnitOfWork(MasterDatabase, CLoger).Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		if grant {
+			return roleRepository.AssignUserRole(ctx, tx, user.ID, role.ID)
+		}
+		return roleRepository.RemoveUserRole(ctx, tx, user.ID, role.ID)
+	})
+	if err != nil {
+		return err
+	}
+	if grant {
+		fmt.Printf("granted %s to %s\n", role.Name, user.Email)
+		return nil
+	}
+	fmt.Printf("revoked %s from %s\n", role.Name, user.Email)
+	return nil
+}
@@ -5,6 +5,8 @@ import (
 	"backend-mobile-api/docs"
 	"backend-mobile-api/helpers"
 	"backend-mobile-api/internal/middleware"
+	bankInquiry "backend-mobile-api/internal/outbond/bank-inquiry"
+	"backend-mobile-api/internal/outbond/provider"
 	"backend-mobile-api/internal/outbond/smtp"
 	"backend-mobile-api/internal/outbond/verihubs"
 	"backend-mobile-api/internal/repository/minio"
@@ -14,8 +16,14 @@ import (
 	articleController "backend-mobile-api/internal/rest/article-controller"
 	bankListController "backend-mobile-api/internal/rest/bank-list-controller"
 	checkAccountBankController "backend-mobile-api/internal/rest/check-account-bank-controller"
+	consentController "backend-mobile-api/internal/rest/consent-controller"
+	developerController "backend-mobile-api/internal/rest/developer-controller"
+	deviceController "backend-mobile-api/internal/rest/device-controller"
+	loginEventController "backend-mobile-api/internal/rest/login-event-controller"
+	paymentRequestController "backend-mobile-api/internal/rest/payment-request-controller"
 	ppobListController "backend-mobile-api/internal/rest/ppob-list-controller"
 	recipientController "backend-mobile-api/internal/rest/recipient-controller"
+	sessionController "backend-mobile-api/internal/rest/session-controller"
 	transactionController "backend-mobile-api/internal/rest/transactions-controller"
 	userPaymentAccountController "backend-mobile-api/internal/rest/user-account-payments-controller"
 	recipientSvc "backend-mobile-api/service/recipient-svc"
@@ -25,13 +33,23 @@ import (
 	userAuth "backend-mobile-api/internal/rest/user-auth-controller"
 	userProfileController "backend-mobile-api/internal/rest/user-profile-controller"
 	verihubsInvokerController "backend-mobile-api/internal/rest/verihubs-invoker-controller"
+	accountInquirySvc "backend-mobile-api/service/account-inquiry-svc"
+	accountSvc "backend-mobile-api/service/account-svc"
 	articleSvc "backend-mobile-api/service/article-svc"
 	banklistsvc "backend-mobile-api/service/bank-list-svc"
 	"backend-mobile-api/service/biometricSvc"
+	consentSvc "backend-mobile-api/service/consent-svc"
+	developerSvc "backend-mobile-api/service/developer-svc"
+	deviceSvc "backend-mobile-api/service/device-svc"
 	kycservice "backend-mobile-api/service/kyc-service"
+	loginEventSvc "backend-mobile-api/service/login-event-svc"
 	"backend-mobile-api/service/notification"
 	"backend-mobile-api/service/otp"
+	paymentRequestService "backend-mobile-api/service/payment-request-svc"
+	pinAttemptSvc "backend-mobile-api/service/pin-attempt-svc"
 	ppoblistsvc "backend-mobile-api/service/ppob-list-svc"
+	sessionSvc "backend-mobile-api/service/session-svc"
+	tokenFamilySvc "backend-mobile-api/service/token-family-svc"
 	userAccountPaymentSvc "backend-mobile-api/service/user-accounts-payment-svc"
 	user_auth_svc "backend-mobile-api/service/user-auth-svc"
 	userProfileService "backend-mobile-api/service/user-profile-svc"
@@ -67,14 +85,10 @@ var (
 )
 
 func init() {
+	// connections and services are set up in the PreRun of the commands that need them
 	cobra.OnInitialize(func() {
 		initCustomLoger()
 		initConfigReader()
-		initRedisClient()
-		initPostgres()
-		initApp()
-		initSwagger()
-
 	})
 }
 func initConfigReader() {
@@ -131,6 +145,42 @@ func initApp() {
 	bankRepo := postgres.NewBankListRepository(MasterDatabase, CLoger) // kalau pakai *gorm.DB, ambil DB() biar dapat *sql.DB
 	ppobRepo := postgres.NewPpobListRepository(MasterDatabase, CLoger)
 	userPaymentAccountRepo := postgres.NewUserPaymentsAccountRepository(MasterDatabase, CLoger)
+	tokenFamilyRepository := postgres.NewTokenFamilyRepository(MasterDatabase, CLoger)
+	roleRepository := postgres.NewRoleRepository(MasterDatabase, CLoger)
+	apiKeyRepository := postgres.NewApiKeyRepository(MasterDatabase, CLoger)
+	biometricKeyRepository := postgres.NewBiometricKeyRepository(MasterDatabase, CLoger)
+	unitOfWork := postgres.NewUnitOfWork(MasterDatabase, CLoger)
+	accountRepository := postgres.NewAccountRepository(MasterDatabase, CLoger)
+	consentRepository := postgres.NewConsentRepository(MasterDatabase, CLoger)
+	loginEventRepository := postgres.NewLoginEventRepository(MasterDatabase, CLoger)
+	//outbound
+	firebaseNotifier, err := notification.InitFirebaseNotifier(
+		context.Background(),
+		rootConfig.Firebase.CredentialsFile, // pastikan ada di config
+	)
+	if err != nil {
+		panic(err)
+	}
+	smtp := smtp.NewSmtp(&rootConfig, CLoger)
+	outboundVeriHubsSvc := verihubs.NewOutboundVeriHubsService(&rootConfig.Verihubs, &rootConfig, CLoger)
+	otpProvider, err := provider.NewOtpProvider(&rootConfig, outboundVeriHubsSvc, redisRepository, CLoger)
+	if err != nil {
+		panic(err)
+	}
+	kycProvider, err := provider.NewKycProvider(&rootConfig, outboundVeriHubsSvc)
+	if err != nil {
+		panic(err)
+	}
+	pinAttemptService := pinAttemptSvc.NewPinAttemptService(redisRepository, &rootConfig.PinAttempt, CLoger)
+	otpService := otp.NewOtpService(
+		otpRepository,
+		&rootConfig,
+		redisRepository,
+		CLoger,
+		otpProvider,
+		smtp,
+		unitOfWork,
+	)
 	// service
 	ppobListService := ppoblistsvc.NewPpobListService(ppobRepo)
 	bankService := banklistsvc.NewBankListService(bankRepo)
@@ -138,43 +188,147 @@ func initApp() {
 	// === Recipient ===
 	// repository
 	recipientRepo := postgres.NewRecipientRepository(MasterDatabase, CLoger)
+	accountInquiryAdapter, err := bankInquiry.NewAccountInquiry(&rootConfig, CLoger)
+	if err != nil {
+		panic(err)
+	}
 	// service
-	recipientService := recipientSvc.NewRecipientService(recipientRepo)
+	accountInquiryService := accountInquirySvc.NewAccountInquiryService(accountInquiryAdapter, redisRepository, &rootConfig.BankInquiry, CLoger)
+	recipientService := recipientSvc.NewRecipientService(
+		recipientRepo,
+		bankRepo,
+		deviceRepository,
+		accountInquiryService,
+		firebaseNotifier,
+		&rootConfig.RecipientPolicy,
+		CLoger,
+	)
 	// controller
 	controller.RecipientController = recipientController.NewRecipientController(recipientService)
-	controller.CheckAccountBankController = checkAccountBankController.NewCheckAccountBankController()
+	controller.CheckAccountBankController = checkAccountBankController.NewCheckAccountBankController(accountInquiryService)
 	// controller
 	controller.UserAccountPaymentsController = userPaymentAccountController.NewUserAccountPaymentsController(userAccountsPaymentService)
 	controller.PpobListController = ppobListController.NewPpobListController(ppobListService)
 	controller.BankListController = bankListController.NewBankListController(bankService)
 	// === Transaction ===
-	firebaseNotifier, err := notification.InitFirebaseNotifier(
-		context.Background(),
-		rootConfig.Firebase.CredentialsFile, // pastikan ada di config
-	)
-	if err != nil {
-		panic(err)
-	}
-	smtp := smtp.NewSmtp(&rootConfig, CLoger)
 	transactionRepo := postgres.NewTransactionRepository(MasterDatabase, CLoger)
-	transactionService := transactionsvc.NewTransactionService(transactionRepo, firebaseNotifier, smtp, userRepository)
+	paymentRequestRepo := postgres.NewPaymentRequestRepository(MasterDatabase, CLoger)
+	paymentRequestSvc := paymentRequestService.NewPaymentRequestService(
+		paymentRequestRepo,
+		userRepository,
+		transactionRepo,
+		firebaseNotifier,
+		CLoger,
+		unitOfWork,
+	)
+	transactionService := transactionsvc.NewTransactionService(
+		transactionRepo,
+		firebaseNotifier,
+		smtp,
+		userRepository,
+		recipientRepo,
+		otpService,
+		redisRepository,
+		&rootConfig,
+		pinAttemptService,
+		paymentRequestSvc,
+		unitOfWork,
+	)
 	controller.TransactionController = transactionController.NewTransactionController(transactionService)
+	// === Payment Request ===
+	controller.PaymentRequestController = paymentRequestController.NewPaymentRequestController(paymentRequestSvc)
 
 	minioClient, err := rootConfig.Minio.MinioClientSet()
 	if err != nil {
 		panic(err)
 	}
 	minioRepository := minio.NewMinioRepository(minioClient, &rootConfig, rootConfig.Minio.Bucket, CLoger)
+	accountService := accountSvc.NewAccountService(
+		accountRepository,
+		userRepository,
+		minioRepository,
+		unitOfWork,
+		&rootConfig.AccountDeletion,
+		CLoger,
+	)
+	consentService := consentSvc.NewConsentService(consentRepository, userRepository, unitOfWork, CLoger)
+	loginEventService := loginEventSvc.NewLoginEventService(
+		loginEventRepository,
+		deviceRepository,
+		userRepository,
+		unitOfWork,
+		smtp,
+		firebaseNotifier,
+		&rootConfig.LoginAlert,
+		CLoger,
+	)
 	ktpRepository := postgres.NewKycKtpRepository(MasterDatabase, CLoger)
 	passportRepository := postgres.NewKycPassportRepository(MasterDatabase, CLoger)
 
 	//middleware
-	customMiddlewareService = middleware.NewCustomMiddleware(&rootConfig.Jwt, CLoger, *redisRepository, &rootConfig)
+	accessKeyring, err := middleware.NewKeyring(
+		rootConfig.Jwt.KeyringDir,
+		middleware.KEY_USE_ACCESS,
+		rootConfig.Jwt.LegacyKid,
+		rootConfig.Jwt.SecreteKey,
+		rootConfig.Jwt.PublicKey,
+		rootConfig.Jwt.KeyringReload,
+		CLoger,
+	)
+	if err != nil {
+		panic(err)
+	}
+	refreshKeyring, err := middleware.NewKeyring(
+		rootConfig.Jwt.KeyringDir,
+		middleware.KEY_USE_REFRESH,
+		rootConfig.Jwt.LegacyKid,
+		rootConfig.Jwt.RefreshSecreteKey,
+		rootConfig.Jwt.RefreshPublicKey,
+		rootConfig.Jwt.KeyringReload,
+		CLoger,
+	)
+	if err != nil {
+		panic(err)
+	}
+	customMiddlewareService = middleware.NewCustomMiddleware(&rootConfig.Jwt, CLoger, *redisRepository, &rootConfig, accessKeyring, refreshKeyring, apiKeyRepository, tokenBlacklistRepository, userRepository)
 	//xsesionMiddleware = middleware.NewXsesionMiddleware(&rootConfig, CLoger, *redisRepository)
-
-	//outbound
-
-	outboundVeriHubsSvc := verihubs.NewOutboundVeriHubsService(&rootConfig.Verihubs, &rootConfig, CLoger)
+	tokenFamilyService := tokenFamilySvc.NewTokenFamilyService(
+		tokenFamilyRepository,
+		tokenBlacklistRepository,
+		roleRepository,
+		customMiddlewareService,
+		redisRepository,
+		&rootConfig.Jwt,
+		CLoger,
+		unitOfWork,
+	)
+	deviceService := deviceSvc.NewDeviceService(
+		deviceRepository,
+		userRepository,
+		tokenFamilyRepository,
+		biometricKeyRepository,
+		tokenFamilyService,
+		otpService,
+		redisRepository,
+		smtp,
+		firebaseNotifier,
+		&rootConfig,
+		CLoger,
+		unitOfWork,
+	)
+	biometricService := biometricSvc.NewBiometricService(
+		userRepository,
+		userDetilRepository,
+		biometricKeyRepository,
+		tokenFamilyService,
+		deviceService,
+		consentService,
+		loginEventService,
+		redisRepository,
+		&rootConfig,
+		CLoger,
+		unitOfWork,
+	)
 
 	//controller
 	healtCheckController = rest.NewHealtCheckHandler(CLoger, MasterDatabase, RedisClient, minioClient)
@@ -189,20 +343,38 @@ func initApp() {
 			accessStateRepsoitory,
 			otpRepository,
 			tokenBlacklistRepository,
-			outboundVeriHubsSvc,
+			otpProvider,
 			userDetilRepository,
 			deviceRepository,
+			tokenFamilyService,
+			pinAttemptService,
+			deviceService,
+			otpService,
+			unitOfWork,
+			accountService,
+			consentService,
+			loginEventService,
 		),
-		biometricSvc.NewBiometricService(
-			userRepository,
-			userDetilRepository,
-			customMiddlewareService,
-			tokenBlacklistRepository,
+		biometricService,
+	)
+	controller.ConsentController = consentController.NewConsentController(consentService)
+	controller.LoginEventController = loginEventController.NewLoginEventController(loginEventService)
+	controller.SessionController = sessionController.NewSessionController(
+		sessionSvc.NewSessionService(
+			tokenFamilyRepository,
+			tokenFamilyService,
+			CLoger,
 		),
 	)
+	controller.DeviceController = deviceController.NewDeviceController(deviceService)
+	if rootConfig.Provider.DevOtpEndpoint && !rootConfig.App.IsProduction() {
+		controller.DeveloperController = developerController.NewDeveloperController(
+			developerSvc.NewDeveloperService(redisRepository, CLoger),
+		)
+	}
 	controller.VerihubsInvoker = verihubsInvokerController.NewVerihubsInvokerController(
 		verihubsInvokerService.NewVerihubsInvokerService(
-			otpRepository, CLoger,
+			otpRepository, otpService, &rootConfig.Verihubs, CLoger, unitOfWork,
 		),
 	)
 	controller.UserProfileController = userProfileController.NewUserProfileController(
@@ -214,30 +386,32 @@ func initApp() {
 			CLoger,
 			accessStateRepsoitory,
 			otpRepository,
-			outboundVeriHubsSvc,
+			otpProvider,
 			userDetilRepository,
-			otp.NewOtpService(
-				otpRepository,
-				&rootConfig,
-				redisRepository,
-				CLoger,
-				outboundVeriHubsSvc,
-				smtp,
-			),
+			otpService,
 			minioRepository,
+			pinAttemptService,
+			deviceService,
+			biometricService,
+			unitOfWork,
+			accountService,
+			tokenFamilyService,
 		),
+		biometricService,
 	)
 	controller.ArticleController = articleController.NewArticleController(
 		articleSvc.NewArticleService(
 			userRepository,
 			articleRepository,
 			CLoger,
+			deviceService,
+			unitOfWork,
 		),
 	)
 
 	controller.KycController = kyccontroller.NewKycController(
 		kycservice.NewKycService(
-			CLoger, outboundVeriHubsSvc, passportRepository, ktpRepository, userRepository, userDetilRepository, minioRepository),
+			CLoger, kycProvider, passportRepository, ktpRepository, userRepository, userDetilRepository, minioRepository, unitOfWork),
 	)
 
 }
@@ -0,0 +1,10 @@
+package config
+
+import "time"
+
+type AccountDeletion struct {
+	// GracePeriod is how long a deletion request can be cancelled by logging in again
+	GracePeriod time.Duration `envconfig:"ACCOUNT_DELETION_GRACE_PERIOD" default:"720h"`
+	// PurgeBatch caps the accounts one run of the purge job handles
+	PurgeBatch int `envconfig:"ACCOUNT_DELETION_PURGE_BATCH" default:"100"`
+}
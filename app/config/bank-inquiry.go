@@ -0,0 +1,18 @@
+package config
+
+import "time"
+
+type BankInquiry struct {
+	// Provider selects the account inquiry adapter: "snap" calls the bank, "fake" answers locally and is refused in production
+	Provider        string        `envconfig:"BANK_INQUIRY_PROVIDER" default:"snap"`
+	Domain          string        `envconfig:"BANK_INQUIRY_DOMAIN"`
+	ClientKey       string        `envconfig:"BANK_INQUIRY_CLIENT_KEY"`
+	ClientSecret    string        `envconfig:"BANK_INQUIRY_CLIENT_SECRET"`
+	PrivateKey      string        `envconfig:"BANK_INQUIRY_PRIVATE_KEY"`
+	PartnerID       string        `envconfig:"BANK_INQUIRY_PARTNER_ID"`
+	ChannelID       string        `envconfig:"BANK_INQUIRY_CHANNEL_ID" default:"95221"`
+	Origin          string        `envconfig:"BANK_INQUIRY_ORIGIN"`
+	Timeout         time.Duration `envconfig:"BANK_INQUIRY_TIMEOUT" default:"10s"`
+	CacheExpire     time.Duration `envconfig:"BANK_INQUIRY_CACHE_EXPIRE" default:"300s"`
+	ConfirmedExpire time.Duration `envconfig:"BANK_INQUIRY_CONFIRMED_EXPIRE" default:"1800s"`
+}
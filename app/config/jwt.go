@@ -3,10 +3,16 @@ package config
 import "time"
 
 type Jwt struct {
-	SecreteKey        string        `envconfig:"JWT_SECRET_KEY" required:"true"`
-	PublicKey         string        `envconfig:"JWT_PUBLIC_KEY" required:"true"`
-	RefreshSecreteKey string        `envconfig:"JWT_REFRESH_SECRET_KEY" required:"true"`
-	RefreshPublicKey  string        `envconfig:"JWT_REFRESH_PUBLIC_KEY" required:"true"`
+	// the env key pairs are the legacy keyring entry, tokens signed before key rotation carry no kid and are checked against them
+	SecreteKey        string        `envconfig:"JWT_SECRET_KEY"`
+	PublicKey         string        `envconfig:"JWT_PUBLIC_KEY"`
+	RefreshSecreteKey string        `envconfig:"JWT_REFRESH_SECRET_KEY"`
+	RefreshPublicKey  string        `envconfig:"JWT_REFRESH_PUBLIC_KEY"`
 	Expiration        time.Duration `envconfig:"JWT_EXPIRATION" required:"true"`
 	RefreshExpiration time.Duration `envconfig:"JWT_REFRESH_EXPIRATION" required:"true"`
+	// KeyringDir holds the rotated keys as <dir>/<access|refresh>/<kid>.pem, the kid used for signing is in <dir>/<access|refresh>/active
+	KeyringDir string `envconfig:"JWT_KEYRING_DIR"`
+	// KeyringReload is how often the keyring directory is read again, so a promoted key is picked up without restart
+	KeyringReload time.Duration `envconfig:"JWT_KEYRING_RELOAD" default:"1m"`
+	LegacyKid     string        `envconfig:"JWT_LEGACY_KID" default:"legacy"`
 }
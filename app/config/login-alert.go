@@ -0,0 +1,8 @@
+package config
+
+type LoginAlert struct {
+	// FarDistanceKm is how far from the previous successful login a new one has to be for the user to be alerted
+	FarDistanceKm float64 `envconfig:"LOGIN_ALERT_FAR_DISTANCE_KM" default:"500"`
+	// MaxHistoryLimit caps the page size of the login history
+	MaxHistoryLimit int `envconfig:"LOGIN_ALERT_MAX_HISTORY_LIMIT" default:"100"`
+}
@@ -0,0 +1,22 @@
+package config
+
+import "time"
+
+type Otp struct {
+	// Channels is the failover order, an otp that can not be delivered moves to the next channel the user has a destination for
+	Channels []string `envconfig:"OTP_CHANNELS" default:"WHATSAPP,SMS,EMAIL"`
+	// DeliveryTimeout is how long a channel has to report delivery before the next one is tried
+	DeliveryTimeout time.Duration `envconfig:"OTP_DELIVERY_TIMEOUT" default:"30s"`
+	// ResendCooldown is the wait before another otp of the same purpose goes to the same destination
+	ResendCooldown time.Duration `envconfig:"OTP_RESEND_COOLDOWN" default:"60s"`
+	// the send caps count every otp requested in the last hour and day, a zero cap is not enforced
+	DestinationHourlyLimit int64 `envconfig:"OTP_DESTINATION_HOURLY_LIMIT" default:"5"`
+	DestinationDailyLimit  int64 `envconfig:"OTP_DESTINATION_DAILY_LIMIT" default:"10"`
+	DeviceHourlyLimit      int64 `envconfig:"OTP_DEVICE_HOURLY_LIMIT" default:"10"`
+	DeviceDailyLimit       int64 `envconfig:"OTP_DEVICE_DAILY_LIMIT" default:"20"`
+	// the ip caps are looser, users behind the same carrier nat share an address
+	IpHourlyLimit int64 `envconfig:"OTP_IP_HOURLY_LIMIT" default:"30"`
+	IpDailyLimit  int64 `envconfig:"OTP_IP_DAILY_LIMIT" default:"100"`
+	// MaxVerifyAttempts wrong codes on a verify key invalidate every otp sent under it
+	MaxVerifyAttempts int64 `envconfig:"OTP_MAX_VERIFY_ATTEMPTS" default:"5"`
+}
@@ -0,0 +1,21 @@
+package config
+
+import "time"
+
+type PinAttempt struct {
+	// BaseDelay is the wait after the first wrong pin, doubled on every next failure up to MaxDelay
+	BaseDelay time.Duration `envconfig:"PIN_ATTEMPT_BASE_DELAY" default:"1s"`
+	MaxDelay  time.Duration `envconfig:"PIN_ATTEMPT_MAX_DELAY" default:"30s"`
+	// TemporaryLockAfter failures lock the pin for TemporaryLockDuration, repeated every TemporaryLockAfter failures
+	TemporaryLockAfter    int64         `envconfig:"PIN_ATTEMPT_TEMPORARY_LOCK_AFTER" default:"3"`
+	TemporaryLockDuration time.Duration `envconfig:"PIN_ATTEMPT_TEMPORARY_LOCK_DURATION" default:"30m"`
+	// PermanentLockAfter failures lock the pin until it is changed through forgot-pin
+	PermanentLockAfter int64 `envconfig:"PIN_ATTEMPT_PERMANENT_LOCK_AFTER" default:"6"`
+	// DeviceLockAfter failures from one device, whatever the user, lock the device for TemporaryLockDuration
+	DeviceLockAfter int64 `envconfig:"PIN_ATTEMPT_DEVICE_LOCK_AFTER" default:"10"`
+	// Window is how long failures are counted without a successful attempt
+	Window time.Duration `envconfig:"PIN_ATTEMPT_WINDOW" default:"24h"`
+	// RequiredForTransaction rejects POST /transactions without a pin. App builds before the pin field
+	// do not send it, keep this off until those builds are retired; a pin that is sent is always verified
+	RequiredForTransaction bool `envconfig:"PIN_ATTEMPT_REQUIRED_FOR_TRANSACTION" default:"false"`
+}
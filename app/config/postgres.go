@@ -25,7 +25,10 @@ func (pg Postgres) OpenPostgresDatabaseConnection() (*gorm.DB, error) {
 	db, err := gorm.Open(postgres.New(postgres.Config{
 		DSN:                  fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable TimeZone=Asia/Jakarta", pg.Host, pg.Port, pg.User, pg.Password, pg.Dbname),
 		PreferSimpleProtocol: true, // disables implicit prepared statement usage
-	}), &gorm.Config{})
+	}), &gorm.Config{
+		// unique violations come back as gorm.ErrDuplicatedKey
+		TranslateError: true,
+	})
 	if err != nil {
 		log.Errorf("Error opening database connection: %v", err)
 		return nil, err
@@ -0,0 +1,11 @@
+package config
+
+type Provider struct {
+	// Otp and Kyc select the adapter: "verihubs" calls Verihubs, "fake" answers locally and is refused in production
+	Otp string `envconfig:"PROVIDER_OTP" default:"verihubs"`
+	Kyc string `envconfig:"PROVIDER_KYC" default:"verihubs"`
+	// FakeKycResult scripts every answer of the fake kyc adapter: "verified", "rejected" or "error"
+	FakeKycResult string `envconfig:"PROVIDER_FAKE_KYC_RESULT" default:"verified"`
+	// DevOtpEndpoint exposes the last otp sent to a destination on /api/v1/dev/otp, never outside production and only when set
+	DevOtpEndpoint bool `envconfig:"DEV_OTP_ENDPOINT_ENABLED" default:"false"`
+}
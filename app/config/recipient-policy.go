@@ -0,0 +1,15 @@
+package config
+
+import "time"
+
+type RecipientPolicy struct {
+	// CoolingOffPeriod is how long a recipient counts as new after it was added
+	CoolingOffPeriod time.Duration `envconfig:"RECIPIENT_POLICY_COOLING_OFF_PERIOD" default:"24h"`
+	// CoolingOffMaxAmount caps the total transferred to a new recipient during the cooling-off period
+	CoolingOffMaxAmount float64 `envconfig:"RECIPIENT_POLICY_COOLING_OFF_MAX_AMOUNT" default:"1000000"`
+	// StepUpFirstTransfer requires an OTP before the first transfer to an account
+	StepUpFirstTransfer bool   `envconfig:"RECIPIENT_POLICY_STEP_UP_FIRST_TRANSFER" default:"true"`
+	StepUpOtpMethod     string `envconfig:"RECIPIENT_POLICY_STEP_UP_OTP_METHOD" default:"EMAIL"`
+	// NotifyOnAdd sends a push notification every time a recipient is added
+	NotifyOnAdd bool `envconfig:"RECIPIENT_POLICY_NOTIFY_ON_ADD" default:"true"`
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+)
+
+type ResetLink struct {
+	// AllowedHosts are the web hosts a reset link may point to, an empty list allows none
+	AllowedHosts []string `envconfig:"RESET_LINK_ALLOWED_HOSTS"`
+	// AllowedSchemes are http(s) plus the app deep-link schemes, a deep link is trusted on its scheme alone
+	AllowedSchemes []string `envconfig:"RESET_LINK_ALLOWED_SCHEMES" default:"https"`
+	// Secret signs the reset token, reset links are refused while it is empty
+	Secret string `envconfig:"RESET_LINK_SECRET"`
+}
+
+// Allows tells whether a client supplied callback url may be put in an email
+// sent from our domain.
+func (r *ResetLink) Allows(u *url.URL) bool {
+	if u == nil || u.User != nil {
+		return false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if !slices.ContainsFunc(r.AllowedSchemes, func(s string) bool { return strings.EqualFold(s, scheme) }) {
+		return false
+	}
+	if scheme != "http" && scheme != "https" {
+		return true
+	}
+	return slices.ContainsFunc(r.AllowedHosts, func(h string) bool { return strings.EqualFold(h, u.Hostname()) })
+}
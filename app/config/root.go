@@ -16,6 +16,16 @@ type Root struct {
 	Server   Server
 	Verihubs Verihubs
 	Minio    Minio
+
+	BankInquiry     BankInquiry
+	RecipientPolicy RecipientPolicy
+	PinAttempt      PinAttempt
+	Signature       Signature
+	Otp             Otp
+	Provider        Provider
+	ResetLink       ResetLink
+	AccountDeletion AccountDeletion
+	LoginAlert      LoginAlert
 }
 
 func mustLoad(prefix string, spec interface{}) {
@@ -41,6 +51,16 @@ func Load(filenames ...string) Root {
 
 		Verihubs: Verihubs{},
 		Minio:    Minio{},
+
+		BankInquiry:     BankInquiry{},
+		RecipientPolicy: RecipientPolicy{},
+		PinAttempt:      PinAttempt{},
+		Signature:       Signature{},
+		Otp:             Otp{},
+		Provider:        Provider{},
+		ResetLink:       ResetLink{},
+		AccountDeletion: AccountDeletion{},
+		LoginAlert:      LoginAlert{},
 	}
 	mustLoad("FIREBASE", &r.Firebase)
 	mustLoad("SERVER", &r.Server)
@@ -51,6 +71,15 @@ func Load(filenames ...string) Root {
 	mustLoad("SMTP", &r.Smtp)
 	mustLoad("VERIHUBS", &r.Verihubs)
 	mustLoad("MINIO", &r.Minio)
+	mustLoad("BANK_INQUIRY", &r.BankInquiry)
+	mustLoad("RECIPIENT_POLICY", &r.RecipientPolicy)
+	mustLoad("PIN_ATTEMPT", &r.PinAttempt)
+	mustLoad("SIGNATURE", &r.Signature)
+	mustLoad("OTP", &r.Otp)
+	mustLoad("PROVIDER", &r.Provider)
+	mustLoad("RESET_LINK", &r.ResetLink)
+	mustLoad("ACCOUNT_DELETION", &r.AccountDeletion)
+	mustLoad("LOGIN_ALERT", &r.LoginAlert)
 
 	return r
 }
@@ -6,6 +6,9 @@ type Server struct {
 	Port   string `envconfig:"SERVER_PORT" required:"true" default:":9090"`
 	Host   string `envconfig:"SERVER_HOST" required:"true" default:"127.0.0.1"`
 	Domain string `envconfig:"SERVER_DOMAIN" required:"true" default:"127.0.0.1:9090"`
+	// TrustedProxies are the CIDRs of the load balancers in front of the service, X-Forwarded-For
+	// is only read from them. Empty means the service is reached directly and the peer address is used
+	TrustedProxies []string `envconfig:"SERVER_TRUSTED_PROXIES"`
 }
 
 func LoadForServer(filenames ...string) Server {
@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+type Signature struct {
+	// Versions lists the X-SIGNATURE-VERSION values still accepted, drop a version to stop serving the app builds using it.
+	// v1 is the legacy unkeyed sha256 of the body, v2 is hmac-sha256 over the canonical request.
+	// v1 has to be listed explicitly and even then is only taken on routes that need no login
+	Versions []string `envconfig:"SIGNATURE_VERSIONS" default:"v2"`
+	// ClientSecret is built into the app and keys v2 signatures of requests made before login
+	ClientSecret string `envconfig:"SIGNATURE_CLIENT_SECRET"`
+	// TimestampWindow is how far X-TIMESTAMP may be from server time, either way
+	TimestampWindow time.Duration `envconfig:"SIGNATURE_TIMESTAMP_WINDOW" default:"5m"`
+}
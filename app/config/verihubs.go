@@ -9,6 +9,8 @@ type Verihubs struct {
 	OTPCallBackUrl  *string `envconfig:"VERIHUBS_OTP_CALLBACK_URL"`
 	OTPSMSChallenge *string `envconfig:"VERIHUBS_OTP_SMS_CHALLENGE"`
 	OTPSMSTemplate  *string `envconfig:"VERIHUBS_OTP_SMS_TEMPLATE"`
+	// OTPCallbackSecret signs the token added to the callback url of every otp, callbacks are refused while it is empty
+	OTPCallbackSecret string `envconfig:"VERIHUBS_OTP_CALLBACK_SECRET"`
 
 	OTPWhatsappChallenge    *string `envconfig:"VERIHUBS_OTP_WHATSAPP_CHALLENGE"`
 	OTPWhatsappLangCode     string  `envconfig:"VERIHUBS_OTP_WHATSAPP_LANG_CODE" required:"true"`
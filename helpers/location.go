@@ -0,0 +1,38 @@
+package helpers
+
+import (
+	"math"
+	"strconv"
+)
+
+const earthRadiusKm = 6371.0
+
+// CoarseLatitude and CoarseLongitude parse a coordinate and round it to two
+// decimals (~1 km), locations are kept only to recognise a session or a login,
+// not to track. Anything that is not a finite coordinate in range is dropped.
+func CoarseLatitude(value string) *float64 {
+	return coarseCoordinate(value, 90)
+}
+
+func CoarseLongitude(value string) *float64 {
+	return coarseCoordinate(value, 180)
+}
+
+func coarseCoordinate(value string, limit float64) *float64 {
+	coordinate, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(coordinate) || math.IsInf(coordinate, 0) || math.Abs(coordinate) > limit {
+		return nil
+	}
+	coordinate = math.Round(coordinate*100) / 100
+	return &coordinate
+}
+
+// DistanceKm is the great-circle distance between two points.
+func DistanceKm(latitude1, longitude1, latitude2, longitude2 float64) float64 {
+	toRadian := func(degree float64) float64 { return degree * math.Pi / 180 }
+	deltaLatitude := toRadian(latitude2 - latitude1)
+	deltaLongitude := toRadian(longitude2 - longitude1)
+	a := math.Sin(deltaLatitude/2)*math.Sin(deltaLatitude/2) +
+		math.Cos(toRadian(latitude1))*math.Cos(toRadian(latitude2))*math.Sin(deltaLongitude/2)*math.Sin(deltaLongitude/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
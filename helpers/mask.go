@@ -0,0 +1,15 @@
+package helpers
+
+import "strings"
+
+// MaskDestination hides an otp destination for display, the first letter and
+// domain of an email or the last four digits of a phone number stay visible.
+func MaskDestination(destination string) string {
+	if at := strings.Index(destination, "@"); at > 0 {
+		return destination[:1] + strings.Repeat("*", at-1) + destination[at:]
+	}
+	if len(destination) <= 4 {
+		return destination
+	}
+	return strings.Repeat("*", len(destination)-4) + destination[len(destination)-4:]
+}
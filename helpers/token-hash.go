@@ -0,0 +1,13 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// HashToken is the sha256 hex of a jwt. Issued tokens are stored and
+// blacklisted by this hash so a leaked table or keyspace holds no usable token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
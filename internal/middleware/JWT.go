@@ -1,20 +1,20 @@
 package middleware
 
 import (
+	"backend-mobile-api/helpers"
 	"backend-mobile-api/model/dto"
-	"backend-mobile-api/model/entity"
 	"backend-mobile-api/model/enum"
 	"backend-mobile-api/model/enum/pkgErr"
 	"context"
 	"crypto/rsa"
 	"crypto/x509"
-	"encoding/base64"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 
 	"strings"
 )
@@ -24,6 +24,12 @@ type Claims struct {
 	Username  string `json:"username"`
 	Role      string `json:"role"`
 	Timestamp int64  `json:"timestamp"`
+	FamilyID  string `json:"fid"` //refresh-token family, one per device login
+	TokenID   string `json:"jti"` //shared by the access/refresh pair
+	Version   int    `json:"ver"` //token version of the user, see entity.User.TokenVersion
+	// Roles and Permissions are read from user_roles when the token is issued
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"perms"`
 }
 
 type TokenData struct {
@@ -31,19 +37,32 @@ type TokenData struct {
 	ExpiredAccess  time.Time `json:"expired_access"`
 	RefreshToken   string    `json:"refresh_token"`
 	ExpiredRefresh time.Time `json:"expired_refresh"`
+	// SigningSecret keys the v2 request signatures of this device until the refresh token expires
+	SigningSecret string `json:"signing_secret,omitempty"`
 }
 
 func (svc *customMiddleware) ValidateAuthorization(ctx context.Context, req *dto.ContextValue, excUrl *ExcludeURLValidation) (*dto.BaseResponse, error) {
-	var roles []enum.RolesEnum
-
 	//skip validation auth by list path
 	for _, value := range excUrl.Authorization.ExcludeURL {
 		if value == req.RequestPath {
 			return nil, nil
 		}
 	}
-	// vlaidate roles access
-	roles = excUrl.Authorization.AccessByRole[req.HeaderPath]
+	// route access declared in RouthInit
+	access, restricted := excUrl.Authorization.AccessByRoute[req.HeaderMethod+req.RequestPath]
+	// services authenticate internal routes with an api key, back-office users may still use their token
+	if strings.HasPrefix(req.RequestPath, INTERNAL_PATH_PREFIX) && req.HeaderXApiKey != "" {
+		// an api key is only accepted where a scope was declared with access.Require
+		if !restricted {
+			err := fmt.Errorf("%w: %s %s declares no api key scope", ErrApiKeyScope, req.HeaderMethod, req.RequestPath)
+			svc.logger.ErrorLogger(ctx, "AuthV2.ValidateApiKey.restricted", err)
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.AUTH_FORBIDDEN_CODE,
+				Message:    pkgErr.FORBIDDEN_MSG,
+			}, err
+		}
+		return svc.ValidateApiKey(ctx, req, access)
+	}
 
 	for {
 		tokenString := req.HeaderAuthorization
@@ -64,31 +83,23 @@ func (svc *customMiddleware) ValidateAuthorization(ctx context.Context, req *dto
 		}
 		tokenString = strings.TrimPrefix(tokenString, prefix)
 
-		if redisData, err := svc.Redis.GetBlaclistJwt(ctx, tokenString); redisData == "active" {
-			if err != nil {
-				svc.logger.ErrorLogger(ctx, "AuthV2.redisData.active", err)
-				return &dto.BaseResponse{
-					StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
-					Message:    pkgErr.SERVER_BUSY,
-					Error:      err.Error(),
-				}, err
-			}
-			err = errors.New("token is blacklisted")
-			return &dto.BaseResponse{
-				StatusCode: pkgErr.AUTH_UNAUTHORIZED_CODE,
-				Message:    pkgErr.UNAUTHORIZED_MSG,
-			}, err
-		}
-		rsaData, err := svc.EncodePublicKeyRSA(ctx, svc.jwtConfig.PublicKey)
+		blacklisted, err := svc.isTokenBlacklisted(ctx, tokenString)
 		if err != nil {
-			svc.logger.ErrorLogger(ctx, "AuthV2.rsaData.EncodePublicKeyRSA", err)
+			svc.logger.ErrorLogger(ctx, "AuthV2.isTokenBlacklisted", err)
 			return &dto.BaseResponse{
 				StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
 				Message:    pkgErr.SERVER_BUSY,
 				Error:      err.Error(),
 			}, err
 		}
-		token, err := svc.ParseJwtToken(ctx, tokenString, rsaData)
+		if blacklisted {
+			err = errors.New("token is blacklisted")
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.AUTH_UNAUTHORIZED_CODE,
+				Message:    pkgErr.UNAUTHORIZED_MSG,
+			}, err
+		}
+		token, err := svc.ParseAccessToken(ctx, tokenString)
 
 		if err != nil {
 			svc.logger.ErrorLogger(ctx, "AuthV2.token.ParseJwtToken", err)
@@ -107,94 +118,157 @@ func (svc *customMiddleware) ValidateAuthorization(ctx context.Context, req *dto
 				Message:    pkgErr.UNAUTHORIZED_MSG,
 			}, err
 		}
-		if len(roles) > 0 {
-			isUnAuthorized := false
-			for _, rolesEnum := range roles {
-				if strings.EqualFold(fmt.Sprint(rolesEnum), fmt.Sprint((*claimData)["role"])) {
-					isUnAuthorized = true
-				}
-			}
-			if !isUnAuthorized {
-				err = errors.New("role access is not authorized")
-				svc.logger.ErrorLogger(ctx, "AuthV2.isUnAuthorized.error", err)
-				return &dto.BaseResponse{
-					StatusCode: pkgErr.AUTH_UNAUTHORIZED_CODE,
-					Message:    pkgErr.UNAUTHORIZED_MSG,
-				}, err
-			}
+		// a credential change or a deletion request bumps the version and retires every older token
+		version, err := svc.tokenVersion(ctx, fmt.Sprint((*claimData)["uuid"]))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.AUTH_UNAUTHORIZED_CODE,
+				Message:    pkgErr.UNAUTHORIZED_MSG,
+			}, err
+		}
+		if err != nil {
+			svc.logger.ErrorLogger(ctx, "AuthV2.tokenVersion", err)
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+				Message:    pkgErr.SERVER_BUSY,
+				Error:      err.Error(),
+			}, err
+		}
+		if claimVersion(*claimData) < version {
+			err = errors.New("token version is outdated")
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.AUTH_UNAUTHORIZED_CODE,
+				Message:    pkgErr.UNAUTHORIZED_MSG,
+			}, err
+		}
+		roles, permissions := claimRoles(*claimData)
+		if restricted && !access.Allows(roles, permissions) {
+			err = fmt.Errorf("roles %v are not authorized for %s %s", roles, req.HeaderMethod, req.RequestPath)
+			svc.logger.ErrorLogger(ctx, "AuthV2.access.Allows", err)
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.AUTH_FORBIDDEN_CODE,
+				Message:    pkgErr.FORBIDDEN_MSG,
+			}, err
 		}
 
 		req.AuthEmail = fmt.Sprint((*claimData)["username"])
 		req.AuthUUID = fmt.Sprint((*claimData)["uuid"])
 		req.AuthRole = fmt.Sprint((*claimData)["role"])
+		req.AuthRoles = roles
+		req.AuthPermissions = permissions
+		if fid, ok := (*claimData)["fid"].(string); ok {
+			req.AuthFamilyID = fid
+		}
 
 		return nil, nil
 	}
 }
 
-func (s *customMiddleware) RefreshToken(ctx context.Context, stringToken string, user *entity.User) (bool, *TokenData, error) {
-	rsa, err := s.EncodePublicKeyRSA(ctx, s.jwtConfig.RefreshPublicKey)
+// isTokenBlacklisted asks redis first, a token redis does not know is looked up
+// in postgres so a revoked token stays revoked after redis lost its keys. A hit
+// is cached again for the lifetime of an access token.
+func (svc *customMiddleware) isTokenBlacklisted(ctx context.Context, tokenString string) (bool, error) {
+	tokenHash := helpers.HashToken(tokenString)
+	redisData, err := svc.Redis.GetBlaclistJwt(ctx, tokenHash)
+	if err == nil {
+		return redisData == "active", nil
+	}
+	// only an unavailable redis costs a query, a revoked family or an old
+	// token version is refused further on either way
+	svc.logger.ErrorLogger(ctx, "isTokenBlacklisted.Redis.GetBlaclistJwt", err)
+	return svc.tokenBlacklistRepository.IsBlaclistTokenActive(ctx, tokenString)
+}
+
+// tokenVersion reads the current token version of a user from redis, on a miss
+// it is read from the users table and cached again.
+func (svc *customMiddleware) tokenVersion(ctx context.Context, userUUID string) (int, error) {
+	version, found, err := svc.Redis.GetTokenVersion(ctx, userUUID)
+	if err != nil || found {
+		return version, err
+	}
+	user, err := svc.userRepository.SelectUserByUUID(ctx, userUUID)
 	if err != nil {
-		return false, nil, err
+		return 0, err
+	}
+	if err = svc.Redis.SetTokenVersion(ctx, userUUID, user.TokenVersion, svc.jwtConfig.Expiration); err != nil {
+		svc.logger.ErrorLogger(ctx, "tokenVersion.Redis.SetTokenVersion", err)
 	}
-	token, err := s.ParseJwtToken(ctx, stringToken, rsa)
+	return user.TokenVersion, nil
+}
+
+// ParseRefreshToken validates a refresh token signature and returns its claims.
+// Rotation and reuse checks are done by the token family service.
+func (s *customMiddleware) ParseRefreshToken(ctx context.Context, stringToken string) (*Claims, error) {
+	token, err := s.ParseRefreshJwtToken(ctx, stringToken)
 	if err != nil {
-		return false, nil, err
+		return nil, err
 	}
 	mapClaimData, err := s.ClaimJWT(ctx, token)
 	if err != nil {
-		return false, nil, err
+		return nil, err
 	}
 	MapRefresh := *mapClaimData
-	claimData := Claims{
+	claimData := &Claims{
 		Uuid:     fmt.Sprint(MapRefresh["uuid"]),
 		Username: fmt.Sprint(MapRefresh["username"]),
 		Role:     fmt.Sprint(MapRefresh["role"]),
 	}
-	if claimData.Uuid != user.UUID {
-		return false, nil, nil
+	if fid, ok := MapRefresh["fid"].(string); ok {
+		claimData.FamilyID = fid
 	}
-	tokenData, err := s.CreateTokens(ctx, &Claims{
-		Uuid:     fmt.Sprint(MapRefresh["uuid"]),
-		Username: fmt.Sprint(MapRefresh["username"]),
-		Role:     fmt.Sprint(MapRefresh["role"]),
-	})
-	if err != nil {
-		return false, nil, err
+	if jti, ok := MapRefresh["jti"].(string); ok {
+		claimData.TokenID = jti
 	}
-	return true, &tokenData, nil
+	claimData.Version = claimVersion(MapRefresh)
+	claimData.Roles, claimData.Permissions = claimRoles(MapRefresh)
+	return claimData, nil
 }
 
-func (s *customMiddleware) generateToken(ctx context.Context, user *Claims, key string, expiration time.Duration) (string, error) {
+// claimVersion reads the token version, tokens issued before versions existed are version 0.
+func claimVersion(claims map[string]interface{}) int {
+	if version, ok := claims["ver"].(float64); ok {
+		return int(version)
+	}
+	return 0
+}
+
+func (s *customMiddleware) generateToken(ctx context.Context, user *Claims, keyring *Keyring, expiration time.Duration) (string, error) {
 	claims := jwt.MapClaims{
 		"username": user.Username,
 		"role":     user.Role,
 
 		"uuid": user.Uuid,
 		"exp":  time.Now().Add(expiration).Unix(),
+		"ver":  user.Version,
 	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodRS512, claims)
-	block, _ := pem.Decode([]byte(key))
-	if block == nil {
-		s.logger.ErrorLogger(ctx, "generateToken.pem.Decode", errors.New("public key decode error"))
-		return "", errors.New("customMiddleware block is nil")
+	if user.FamilyID != "" {
+		claims["fid"] = user.FamilyID
 	}
-	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if user.TokenID != "" {
+		claims["jti"] = user.TokenID
+	}
+	if len(user.Roles) > 0 {
+		claims["roles"] = user.Roles
+		claims["perms"] = user.Permissions
+	}
+
+	key, err := keyring.Signing(ctx)
 	if err != nil {
-		s.logger.ErrorLogger(context.Background(), "generateToken.customMiddleware.ParseECP", err)
+		s.logger.ErrorLogger(ctx, "generateToken.keyring.Signing", err)
 		return "", err
 	}
-	return token.SignedString(privateKey)
+	token := jwt.NewWithClaims(jwt.SigningMethodRS512, claims)
+	token.Header["kid"] = key.Kid
+	return token.SignedString(key.PrivateKey)
 }
 
 func (s *customMiddleware) CreateTokens(ctx context.Context, user *Claims) (TokenData, error) {
 	curentTime := time.Now()
-	accessToken, err := s.generateToken(ctx, user, s.jwtConfig.SecreteKey, s.jwtConfig.Expiration)
+	accessToken, err := s.generateToken(ctx, user, s.accessKeyring, s.jwtConfig.Expiration)
 	if err != nil {
 		return TokenData{}, err
 	}
-	refreshToken, err := s.generateToken(ctx, user, s.jwtConfig.RefreshSecreteKey, s.jwtConfig.RefreshExpiration)
+	refreshToken, err := s.generateToken(ctx, user, s.refreshKeyring, s.jwtConfig.RefreshExpiration)
 	if err != nil {
 		return TokenData{}, err
 	}
@@ -227,6 +301,43 @@ func (s *customMiddleware) ParseJwtToken(ctx context.Context, strJwt string, rsa
 	return token, nil
 }
 
+// ParseAccessToken validates an access token against the access key named by its kid.
+func (s *customMiddleware) ParseAccessToken(ctx context.Context, stringToken string) (*jwt.Token, error) {
+	return s.parseWithKeyring(ctx, stringToken, s.accessKeyring)
+}
+
+// ParseRefreshJwtToken validates a refresh token against the refresh key named by its kid.
+func (s *customMiddleware) ParseRefreshJwtToken(ctx context.Context, stringToken string) (*jwt.Token, error) {
+	return s.parseWithKeyring(ctx, stringToken, s.refreshKeyring)
+}
+
+// JWKS publishes the access token keys, refresh tokens are only read by this service.
+func (s *customMiddleware) JWKS(ctx context.Context) JWKSet {
+	return s.accessKeyring.JWKS(ctx)
+}
+
+func (s *customMiddleware) parseWithKeyring(ctx context.Context, strJwt string, keyring *Keyring) (*jwt.Token, error) {
+	token, err := jwt.Parse(strJwt, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			err := fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+			s.logger.ErrorLogger(ctx, "parseWithKeyring.token.Method", err)
+			return nil, err
+		}
+		kid, _ := t.Header["kid"].(string)
+		return keyring.PublicKey(ctx, kid)
+	})
+	if err != nil {
+		s.logger.ErrorLogger(ctx, "parseWithKeyring.customMiddleware.Parse", err)
+		return nil, err
+	}
+	if !token.Valid {
+		err = fmt.Errorf("invalid token")
+		s.logger.ErrorLogger(ctx, "parseWithKeyring.token.Valid", err)
+		return nil, err
+	}
+	return token, nil
+}
+
 func (s *customMiddleware) ClaimJWT(ctx context.Context, token *jwt.Token) (*map[string]interface{}, error) {
 	var claimMap = make(map[string]interface{})
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
@@ -239,14 +350,27 @@ func (s *customMiddleware) ClaimJWT(ctx context.Context, token *jwt.Token) (*map
 	s.logger.ErrorLogger(ctx, "ClaimJWT.token.Claims.(customMiddleware.MapClaims)", err)
 	return nil, err
 }
-func (svc *customMiddleware) GenerateRsaKeyBioMetric(ctx context.Context) (string, error) {
-	privateKey, err := svc.EncodePrivateKeyRSA(ctx, svc.rootConfig.App.BiometricPrivateKey)
-	if err != nil {
-		svc.logger.ErrorLogger(ctx, "GenerateRsaKeyBioMetric", err)
-		return "", err
+
+// claimRoles reads roles and permissions from token claims, a token issued
+// before roles were added to it belongs to a customer.
+func claimRoles(claims map[string]interface{}) ([]string, []string) {
+	roles := claimStrings(claims["roles"])
+	if len(roles) == 0 {
+		roles = []string{string(enum.ROLE_USER)}
 	}
-	byteKey := svc.GeneratePublicKeyPem(ctx, privateKey)
-	endcodeData := base64.StdEncoding.EncodeToString(byteKey)
-	return endcodeData, nil
+	return roles, claimStrings(claims["perms"])
+}
 
+func claimStrings(value interface{}) []string {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		if str, ok := item.(string); ok {
+			result = append(result, str)
+		}
+	}
+	return result
 }
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"backend-mobile-api/model/dto"
+	"backend-mobile-api/model/entity"
+	"backend-mobile-api/model/enum"
+	"backend-mobile-api/model/enum/pkgErr"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// INTERNAL_PATH_PREFIX routes accept an api key in X-API-KEY instead of a user token
+const INTERNAL_PATH_PREFIX = "/api/internal/v1/"
+
+// an api key is bmk_<prefix>_<secret>, the prefix is stored in clear to find the key
+const (
+	apiKeyTag          = "bmk"
+	apiKeyPrefixBytes  = 4
+	apiKeySecretBytes  = 32
+	apiKeyListSeparate = ","
+)
+
+var (
+	ErrApiKeyInvalid = errors.New("api key is invalid")
+	ErrApiKeyExpired = errors.New("api key is expired or revoked")
+	ErrApiKeyIP      = errors.New("api key is not allowed from this ip")
+	ErrApiKeyScope   = errors.New("api key scope does not allow this route")
+)
+
+// GenerateApiKey returns a new key to hand out once, and its prefix and hash to store.
+func GenerateApiKey() (key string, prefix string, hash string, err error) {
+	prefixBytes := make([]byte, apiKeyPrefixBytes)
+	if _, err = rand.Read(prefixBytes); err != nil {
+		return "", "", "", err
+	}
+	secret := make([]byte, apiKeySecretBytes)
+	if _, err = rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+	prefix = hex.EncodeToString(prefixBytes)
+	key = fmt.Sprintf("%s_%s_%s", apiKeyTag, prefix, base64.RawURLEncoding.EncodeToString(secret))
+	return key, prefix, HashApiKey(key), nil
+}
+
+// HashApiKey keys carry 256 bits of randomness, a plain sha256 is enough and keeps lookups cheap.
+func HashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiKeyPrefix splits at most twice, the url safe base64 secret may contain "_" itself.
+func apiKeyPrefix(key string) (string, bool) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyTag || parts[1] == "" || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// SplitApiKeyList reads the comma separated scopes and ip allowlist of a key.
+func SplitApiKeyList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, apiKeyListSeparate) {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// JoinApiKeyList is the reverse of SplitApiKeyList.
+func JoinApiKeyList(values []string) string {
+	return strings.Join(SplitApiKeyList(strings.Join(values, apiKeyListSeparate)), apiKeyListSeparate)
+}
+
+// ValidateApiKey authenticates an internal route call by its X-API-KEY. The key
+// acts as the SERVICE role and its scopes as permissions.
+func (svc *customMiddleware) ValidateApiKey(ctx context.Context, req *dto.ContextValue, access RouteAccess) (*dto.BaseResponse, error) {
+	apiKey, err := svc.checkApiKey(ctx, req)
+	if err != nil {
+		svc.logger.ErrorLogger(ctx, "ValidateApiKey.checkApiKey", err)
+		if errors.Is(err, ErrApiKeyIP) || errors.Is(err, ErrApiKeyScope) {
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.AUTH_FORBIDDEN_CODE,
+				Message:    pkgErr.FORBIDDEN_MSG,
+			}, err
+		}
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_UNAUTHORIZED_CODE,
+			Message:    pkgErr.UNAUTHORIZED_MSG,
+		}, err
+	}
+
+	scopes := SplitApiKeyList(apiKey.Scopes)
+	if !access.Allows([]string{string(enum.ROLE_SERVICE)}, scopes) {
+		err = fmt.Errorf("%w: key %s on %s %s", ErrApiKeyScope, apiKey.Prefix, req.HeaderMethod, req.RequestPath)
+		svc.logger.ErrorLogger(ctx, "ValidateApiKey.access.Allows", err)
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_FORBIDDEN_CODE,
+			Message:    pkgErr.FORBIDDEN_MSG,
+		}, err
+	}
+	// usage is informative only, a failed update does not block the call
+	_ = svc.apiKeyRepository.UpdateApiKeyLastUsed(ctx, apiKey.ID)
+
+	req.AuthApiKey = apiKey.Prefix
+	req.AuthRole = string(enum.ROLE_SERVICE)
+	req.AuthRoles = []string{string(enum.ROLE_SERVICE)}
+	req.AuthPermissions = scopes
+	return nil, nil
+}
+
+func (svc *customMiddleware) checkApiKey(ctx context.Context, req *dto.ContextValue) (*entity.ApiKey, error) {
+	prefix, ok := apiKeyPrefix(req.HeaderXApiKey)
+	if !ok {
+		return nil, ErrApiKeyInvalid
+	}
+	apiKey, err := svc.apiKeyRepository.SelectApiKeyByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, ErrApiKeyInvalid
+	}
+	if subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(HashApiKey(req.HeaderXApiKey))) != 1 {
+		return nil, ErrApiKeyInvalid
+	}
+	if apiKey.RevokedAt != nil || (apiKey.ExpiredAt != nil && time.Now().After(*apiKey.ExpiredAt)) {
+		return nil, fmt.Errorf("%w: key %s", ErrApiKeyExpired, apiKey.Prefix)
+	}
+	if allowlist := SplitApiKeyList(apiKey.IPAllowlist); len(allowlist) > 0 && !ipAllowed(req.HeaderXRealIp, allowlist) {
+		return nil, fmt.Errorf("%w: key %s from %s", ErrApiKeyIP, apiKey.Prefix, req.HeaderXRealIp)
+	}
+	return apiKey, nil
+}
+
+func ipAllowed(remote string, allowlist []string) bool {
+	ip := net.ParseIP(remote)
+	if ip == nil {
+		return false
+	}
+	for _, allowed := range allowlist {
+		if strings.Contains(allowed, "/") {
+			if _, network, err := net.ParseCIDR(allowed); err == nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if allowedIP := net.ParseIP(allowed); allowedIP != nil && allowedIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
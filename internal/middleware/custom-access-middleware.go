@@ -30,8 +30,50 @@ type ExcludeURLValidation struct {
 	ValidationXNonce   []string
 }
 type AuthorizationMiddlewarePath struct {
-	ExcludeURL   []string
-	AccessByRole map[string][]enum.RolesEnum
+	ExcludeURL    []string
+	AccessByRoute RouteAccessList
+}
+
+// RouteAccess is what a token needs to call a route: any of Roles and all of Permissions.
+type RouteAccess struct {
+	Roles       []enum.RolesEnum
+	Permissions []enum.PermissionEnum
+}
+
+// RouteAccessList is keyed by method and route path, like ListRouth.
+type RouteAccessList map[string]RouteAccess
+
+// Require restricts a registered route, routes not in the list only need a valid token.
+func (l RouteAccessList) Require(route *echo.Route, roles []enum.RolesEnum, permissions ...enum.PermissionEnum) {
+	l[route.Method+route.Path] = RouteAccess{Roles: roles, Permissions: permissions}
+}
+
+func (a RouteAccess) Allows(roles []string, permissions []string) bool {
+	if len(a.Roles) > 0 {
+		allowed := false
+		for _, required := range a.Roles {
+			for _, role := range roles {
+				if strings.EqualFold(string(required), role) {
+					allowed = true
+				}
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	for _, required := range a.Permissions {
+		granted := false
+		for _, permission := range permissions {
+			if string(required) == permission {
+				granted = true
+			}
+		}
+		if !granted {
+			return false
+		}
+	}
+	return true
 }
 
 type ListRouth map[string]bool
@@ -41,22 +83,25 @@ func (svc *customMiddleware) AccessMiddleware(excludeUrl *ExcludeURLValidation,
 		return func(c echo.Context) error {
 			var (
 				SourceData = &dto.ContextValue{
-					HeaderContentType:   c.Request().Header.Get(echo.HeaderContentType),
-					HeaderUserAgent:     c.Request().Header.Get(string(enum.HEADER_USER_AGENT)),
-					HeaderXTimestamp:    c.Request().Header.Get(string(enum.HEADER_X_TIMESTAMP)),
-					HeaderXSignature:    c.Request().Header.Get(string(enum.HEADER_X_SIGNATURE)),
-					HeaderXRealIp:       c.RealIP(),
-					HeaderXNonce:        c.Request().Header.Get(string(enum.HEADER_X_NONCE)),
-					HeaderXDeviceID:     c.Request().Header.Get(string(enum.HEADER_X_DEVICE_ID)),
-					HeaderXLatitude:     c.Request().Header.Get(string(enum.HEADER_X_LATITUDE)),
-					HeaderXLongitude:    c.Request().Header.Get(string(enum.HEADER_X_LONGITUDE)),
-					HeaderXApiKey:       c.Request().Header.Get(string(enum.HEADER_X_API_KEY)),
-					HeaderAuthorization: c.Request().Header.Get(echo.HeaderAuthorization),
-					HeaderRequestId:     c.Response().Header().Get(echo.HeaderXRequestID),
-					HeaderHost:          c.Request().URL.Host,
-					HeaderPath:          c.Request().URL.Path,
-					RequestPath:         c.Path(),
-					HeaderMethod:        c.Request().Method,
+					HeaderContentType:       c.Request().Header.Get(echo.HeaderContentType),
+					HeaderUserAgent:         c.Request().Header.Get(string(enum.HEADER_USER_AGENT)),
+					HeaderXTimestamp:        c.Request().Header.Get(string(enum.HEADER_X_TIMESTAMP)),
+					HeaderXSignature:        c.Request().Header.Get(string(enum.HEADER_X_SIGNATURE)),
+					HeaderXSignatureVersion: c.Request().Header.Get(string(enum.HEADER_X_SIGNATURE_VERSION)),
+					HeaderXRealIp:           c.RealIP(),
+					HeaderXNonce:            c.Request().Header.Get(string(enum.HEADER_X_NONCE)),
+					HeaderXDeviceID:         c.Request().Header.Get(string(enum.HEADER_X_DEVICE_ID)),
+					HeaderXLatitude:         c.Request().Header.Get(string(enum.HEADER_X_LATITUDE)),
+					HeaderXLongitude:        c.Request().Header.Get(string(enum.HEADER_X_LONGITUDE)),
+					HeaderXApiKey:           c.Request().Header.Get(string(enum.HEADER_X_API_KEY)),
+					HeaderXAppVersion:       c.Request().Header.Get(string(enum.HEADER_X_APP_VERSION)),
+					HeaderAuthorization:     c.Request().Header.Get(echo.HeaderAuthorization),
+					HeaderRequestId:         c.Response().Header().Get(echo.HeaderXRequestID),
+					HeaderHost:              c.Request().URL.Host,
+					HeaderPath:              c.Request().URL.Path,
+					HeaderRawQuery:          c.Request().URL.RawQuery,
+					RequestPath:             c.Path(),
+					HeaderMethod:            c.Request().Method,
 				}
 				errRes  *dto.BaseResponse
 				LogData = &dto.CustomLoggerRequest{Remarks: SourceData.HeaderPath}
@@ -89,29 +134,23 @@ func (svc *customMiddleware) AccessMiddleware(excludeUrl *ExcludeURLValidation,
 
 			//validation
 
+			// the validations run concurrently, each keeps its own response and the header
+			// check reads a copy since the authorization writes the auth fields of SourceData
+			var (
+				headerRes, authRes *dto.BaseResponse
+				headerData         = *SourceData
+			)
 			g := errgroup.Group{}
 			g.Go(func() error {
 				resTmp, errTMP := svc.ValidateMandatoryHeader(
 					c.Request().Context(),
-					SourceData, excludeUrl,
+					&headerData, excludeUrl,
 				)
 				if errTMP != nil {
-					errRes = resTmp
+					headerRes = resTmp
 				}
 				return errTMP
 			})
-			g.Go(func() error {
-				resTmp, errTmp := svc.ValidateSignature(
-					c.Request().Context(),
-					SourceData,
-					body,
-					excludeUrl,
-				)
-				if errTmp != nil {
-					errRes = resTmp
-				}
-				return errTmp
-			})
 			g.Go(func() error {
 				resTmp, errTmp := svc.ValidateAuthorization(
 					c.Request().Context(),
@@ -119,7 +158,7 @@ func (svc *customMiddleware) AccessMiddleware(excludeUrl *ExcludeURLValidation,
 					excludeUrl,
 				)
 				if errTmp != nil {
-					errRes = resTmp
+					authRes = resTmp
 				}
 				return errTmp
 			})
@@ -127,17 +166,33 @@ func (svc *customMiddleware) AccessMiddleware(excludeUrl *ExcludeURLValidation,
 			//finish validate
 			err = g.Wait()
 			if err != nil {
+				LogData.Error = err.Error()
+				errRes = authRes
 				if errRes == nil {
-					LogData.Error = err.Error()
-					switch errRes.StatusCode {
-					case pkgErr.AUTH_INVALID_REQUEST_PAYLOAD_CODE:
-						return c.JSON(http.StatusBadRequest, *errRes)
-					case pkgErr.AUTH_INVALID_SIGNATURE_CODE:
-						return c.JSON(http.StatusBadRequest, *errRes)
+					errRes = headerRes
+				}
+				if errRes == nil {
+					errRes = &dto.BaseResponse{
+						StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+						Message:    pkgErr.SERVER_BUSY,
+						Error:      err.Error(),
 					}
-
 				}
 				svc.logger.ErrorLogger(c.Request().Context(), "CommonCustomHeaderMiddleware2.err", err)
+				if errRes.StatusCode == pkgErr.AUTH_FORBIDDEN_CODE {
+					return c.JSON(http.StatusForbidden, *errRes)
+				}
+				return c.JSON(http.StatusBadRequest, *errRes)
+			}
+
+			// the signing secret belongs to the token family, so the signature is checked once the token was validated,
+			// and only a signed request may claim its nonce, otherwise a forged request burns the nonce of a real one
+			if errRes, err = svc.ValidateSignature(c.Request().Context(), SourceData, body, excludeUrl); err != nil {
+				LogData.Error = err.Error()
+				return c.JSON(http.StatusBadRequest, *errRes)
+			}
+			if errRes, err = svc.ValidateXNonce(c.Request().Context(), SourceData, excludeUrl); err != nil {
+				LogData.Error = err.Error()
 				return c.JSON(http.StatusBadRequest, *errRes)
 			}
 
@@ -211,6 +266,33 @@ func (svc *customMiddleware) ValidateSignature(ctx context.Context, req *dto.Con
 			}
 		}
 	}
+	if res, err := svc.validateTimestamp(ctx, req); err != nil {
+		return res, err
+	}
+
+	version := req.HeaderXSignatureVersion
+	if version == "" {
+		version = SIGNATURE_V1
+	}
+	if !svc.signatureVersionAccepted(version) || (version == SIGNATURE_V1 && !authorizationExcluded(req, excUrl)) {
+		err := fmt.Errorf("signature version %q is not accepted on %s", version, req.RequestPath)
+		svc.logger.ErrorLogger(ctx, "SignatureValidate.version", err)
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_INVALID_SIGNATURE_CODE,
+			Message:    pkgErr.INVALID_SIGNATURE_MSG,
+			Error:      err.Error(),
+		}, err
+	}
+	switch version {
+	case SIGNATURE_V2:
+		return svc.validateSignatureV2(ctx, req, reader, excUrl)
+	default:
+		return svc.validateSignatureV1(ctx, req, reader)
+	}
+}
+
+// validateSignatureV1 is the legacy unkeyed sha256 of the body, kept until old app builds are phased out.
+func (svc *customMiddleware) validateSignatureV1(ctx context.Context, req *dto.ContextValue, reader []byte) (*dto.BaseResponse, error) {
 	var (
 		hashedRequestBody string
 		err               error
@@ -265,36 +347,40 @@ func (svc *customMiddleware) ValidateSignature(ctx context.Context, req *dto.Con
 	return nil, nil
 }
 
-func (svc *customMiddleware) ValidateXNonce(ctx context.Context, req *dto.ContextValue, excUrl ExcludeURLValidation) (*dto.BaseResponse, error) {
+// ValidateXNonce rejects a nonce the device already used. A nonce is kept as
+// long as a request carrying it can pass the timestamp check, twice the window
+// since X-TIMESTAMP may be ahead of or behind server time.
+func (svc *customMiddleware) ValidateXNonce(ctx context.Context, req *dto.ContextValue, excUrl *ExcludeURLValidation) (*dto.BaseResponse, error) {
 	for _, value := range excUrl.ValidationXNonce {
 		if value == req.RequestPath {
 			return nil, nil
 		}
 	}
-	var xNonce = req.HeaderXNonce
-
-	value, err := svc.Redis.GetXNonce(ctx, xNonce)
-	if err != nil {
-		log.Errorf("XNonceValidate.s.Redis.GetXSessionI err: %v", err)
+	if req.HeaderXNonce == "" {
+		err := errors.New("X-NONCE is empty")
+		svc.logger.ErrorLogger(ctx, "ValidateXNonce.empty", err)
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.AUTH_INVALID_REQUEST_PAYLOAD_CODE,
 			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
-			Error:      "X-NONCE must be unix",
+			Error:      err.Error(),
 		}, err
 	}
-	if value == "active" {
+
+	claimed, err := svc.Redis.ClaimXNonce(ctx, req.HeaderXDeviceID, req.HeaderXNonce, 2*svc.rootConfig.Signature.TimestampWindow)
+	if err != nil {
+		svc.logger.ErrorLogger(ctx, "ValidateXNonce.Redis.ClaimXNonce", err)
 		return &dto.BaseResponse{
-			StatusCode: pkgErr.AUTH_INVALID_REQUEST_PAYLOAD_CODE,
-			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
-			Error:      "X-NONCE must be unix",
-		}, errors.New("X-NONCE must be unix")
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}, err
 	}
-	err = svc.Redis.SetXNONCE(ctx, xNonce)
-	if err != nil {
-		log.Errorf("XNonceValidate.s.Redis.SetExSessionId err: %v ", err)
+	if !claimed {
+		err = fmt.Errorf("nonce %s replayed by device %s", req.HeaderXNonce, req.HeaderXDeviceID)
+		svc.logger.ErrorLogger(ctx, "ValidateXNonce.replay", err)
 		return &dto.BaseResponse{
-			StatusCode: pkgErr.AUTH_INVALID_REQUEST_PAYLOAD_CODE,
-			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			StatusCode: pkgErr.AUTH_NONCE_REPLAY_CODE,
+			Message:    pkgErr.NONCE_REPLAY_MSG,
 			Error:      err.Error(),
 		}, err
 	}
@@ -3,8 +3,8 @@ package middleware
 import (
 	"backend-mobile-api/app/config"
 	"backend-mobile-api/helpers"
+	"backend-mobile-api/internal/repository/postgres"
 	"backend-mobile-api/internal/repository/redis"
-	"backend-mobile-api/model/entity"
 	"context"
 	"crypto/rsa"
 	"crypto/x509"
@@ -21,6 +21,14 @@ type customMiddleware struct {
 	jwtConfig  *config.Jwt
 	Redis      redis.Redis
 	rootConfig *config.Root
+	// accessKeyring and refreshKeyring sign new tokens with their active key and verify by kid
+	accessKeyring    *Keyring
+	refreshKeyring   *Keyring
+	apiKeyRepository postgres.ApiKeyRepository
+	// tokenBlacklistRepository answers the blacklist when redis is unavailable
+	tokenBlacklistRepository postgres.TokenBlacklistTokenRepository
+	// userRepository answers the token version when redis does not have it
+	userRepository postgres.UserRepository
 }
 
 func NewCustomMiddleware(
@@ -28,25 +36,38 @@ func NewCustomMiddleware(
 	logger *helpers.CustomLogger,
 	Redis redis.Redis,
 	rootConfig *config.Root,
+	accessKeyring *Keyring,
+	refreshKeyring *Keyring,
+	apiKeyRepository postgres.ApiKeyRepository,
+	tokenBlacklistRepository postgres.TokenBlacklistTokenRepository,
+	userRepository postgres.UserRepository,
 ) CustomMiddleware {
 	return &customMiddleware{
-		jwtConfig:  jwtConfig,
-		logger:     logger,
-		Redis:      Redis,
-		rootConfig: rootConfig,
+		jwtConfig:        jwtConfig,
+		logger:           logger,
+		Redis:            Redis,
+		rootConfig:       rootConfig,
+		accessKeyring:    accessKeyring,
+		refreshKeyring:   refreshKeyring,
+		apiKeyRepository: apiKeyRepository,
+
+		tokenBlacklistRepository: tokenBlacklistRepository,
+		userRepository:           userRepository,
 	}
 }
 
 type CustomMiddleware interface {
 	CreateTokens(ctx context.Context, user *Claims) (TokenData, error)
-	generateToken(ctx context.Context, user *Claims, key string, expiration time.Duration) (string, error)
+	generateToken(ctx context.Context, user *Claims, keyring *Keyring, expiration time.Duration) (string, error)
 	ParseJwtToken(context.Context, string, *rsa.PublicKey) (*jwt.Token, error)
+	ParseAccessToken(ctx context.Context, stringToken string) (*jwt.Token, error)
+	ParseRefreshJwtToken(ctx context.Context, stringToken string) (*jwt.Token, error)
+	JWKS(ctx context.Context) JWKSet
 	EncodePublicKeyRSA(ctx context.Context, strKey string) (*rsa.PublicKey, error)
 	ClaimJWT(context.Context, *jwt.Token) (*map[string]interface{}, error)
-	RefreshToken(ctx context.Context, stringToken string, user *entity.User) (bool, *TokenData, error)
+	ParseRefreshToken(ctx context.Context, stringToken string) (*Claims, error)
 	EncodePrivateKeyRSA(ctx context.Context, strKey string) (*rsa.PrivateKey, error)
 	GeneratePublicKeyPem(ctx context.Context, privateKey *rsa.PrivateKey) []byte
-	GenerateRsaKeyBioMetric(ctx context.Context) (string, error)
 
 	AccessMiddleware(excludeUrl *ExcludeURLValidation, list *ListRouth) echo.MiddlewareFunc
 	AccessLogger(ctx context.Context)
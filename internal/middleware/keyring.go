@@ -0,0 +1,349 @@
+package middleware
+
+import (
+	"backend-mobile-api/helpers"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// keys for access and refresh tokens are kept apart, an access token must never pass as a refresh token
+const (
+	KEY_USE_ACCESS  = "access"
+	KEY_USE_REFRESH = "refresh"
+)
+
+const (
+	keyringActiveFile = "active"
+	keyringKeyExt     = ".pem"
+	keyringKeyBits    = 2048
+	// an unknown kid triggers a reload, at most once per keyringMinReload
+	keyringMinReload = 5 * time.Second
+)
+
+var (
+	ErrUnknownKid       = errors.New("unknown signing key id")
+	ErrNoSigningKey     = errors.New("no active signing key")
+	ErrActiveKeyRetired = errors.New("active signing key can not be retired")
+)
+
+type SigningKey struct {
+	Kid        string
+	PrivateKey *rsa.PrivateKey
+	PublicKey  *rsa.PublicKey
+}
+
+// Keyring holds every key a token can be verified with and the one new tokens
+// are signed with. Keys are read from <dir>/<use>/<kid>.pem, the signing kid
+// from <dir>/<use>/active, and the directory is read again every reload so
+// all instances follow a promotion without restart. The key pair from env is
+// kept as the legacy entry for tokens issued before kid was set.
+type Keyring struct {
+	mu       sync.RWMutex
+	dir      string
+	use      string
+	legacy   *SigningKey
+	keys     map[string]*SigningKey
+	active   string
+	reload   time.Duration
+	loadedAt time.Time
+	clogger  *helpers.CustomLogger
+}
+
+func NewKeyring(dir string, use string, legacyKid string, legacyPrivateKey string, legacyPublicKey string, reload time.Duration, clogger *helpers.CustomLogger) (*Keyring, error) {
+	k := &Keyring{
+		dir:     dir,
+		use:     use,
+		keys:    map[string]*SigningKey{},
+		reload:  reload,
+		clogger: clogger,
+	}
+	if legacyPrivateKey != "" || legacyPublicKey != "" {
+		legacy, err := parseLegacyKey(legacyKid, legacyPrivateKey, legacyPublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("%s legacy key: %w", use, err)
+		}
+		k.legacy = legacy
+	}
+	if err := k.load(); err != nil {
+		return nil, err
+	}
+	if _, err := k.Signing(context.Background()); err != nil {
+		return nil, fmt.Errorf("%s keyring: %w", use, err)
+	}
+	return k, nil
+}
+
+// Signing returns the key new tokens are signed with.
+func (k *Keyring) Signing(ctx context.Context) (*SigningKey, error) {
+	k.reloadIfStale(ctx, k.reload)
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.active != "" {
+		return k.keys[k.active], nil
+	}
+	if k.legacy != nil && k.legacy.PrivateKey != nil {
+		return k.legacy, nil
+	}
+	return nil, ErrNoSigningKey
+}
+
+// PublicKey returns the key a token with kid was signed with, an empty kid is the legacy key.
+func (k *Keyring) PublicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	k.reloadIfStale(ctx, k.reload)
+	if key := k.lookup(kid); key != nil {
+		return key.PublicKey, nil
+	}
+	// promoted on another instance before this one reloaded
+	k.reloadIfStale(ctx, keyringMinReload)
+	if key := k.lookup(kid); key != nil {
+		return key.PublicKey, nil
+	}
+	return nil, ErrUnknownKid
+}
+
+// JWKS lists the public keys that tokens are still verified with.
+func (k *Keyring) JWKS(ctx context.Context) JWKSet {
+	k.reloadIfStale(ctx, k.reload)
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	set := JWKSet{Keys: []JWK{}}
+	if k.legacy != nil {
+		set.Keys = append(set.Keys, toJWK(k.legacy))
+	}
+	kids := make([]string, 0, len(k.keys))
+	for kid := range k.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+	for _, kid := range kids {
+		set.Keys = append(set.Keys, toJWK(k.keys[kid]))
+	}
+	return set
+}
+
+func (k *Keyring) lookup(kid string) *SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.legacy != nil && (kid == "" || kid == k.legacy.Kid) {
+		return k.legacy
+	}
+	return k.keys[kid]
+}
+
+func (k *Keyring) reloadIfStale(ctx context.Context, maxAge time.Duration) {
+	if k.dir == "" || maxAge <= 0 {
+		return
+	}
+	k.mu.RLock()
+	stale := time.Since(k.loadedAt) >= maxAge
+	k.mu.RUnlock()
+	if !stale {
+		return
+	}
+	// a broken directory keeps the keys already loaded, tokens keep working
+	if err := k.load(); err != nil {
+		k.clogger.ErrorLogger(ctx, "Keyring.reload", err)
+	}
+}
+
+func (k *Keyring) load() error {
+	keys, active, err := readKeyringDir(k.dir, k.use)
+	if err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = keys
+	k.active = active
+	k.loadedAt = time.Now()
+	return nil
+}
+
+func readKeyringDir(dir string, use string) (map[string]*SigningKey, string, error) {
+	keys := map[string]*SigningKey{}
+	if dir == "" {
+		return keys, "", nil
+	}
+	useDir := filepath.Join(dir, use)
+	entries, err := os.ReadDir(useDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return keys, "", nil
+		}
+		return nil, "", err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), keyringKeyExt) {
+			continue
+		}
+		kid := strings.TrimSuffix(entry.Name(), keyringKeyExt)
+		data, err := os.ReadFile(filepath.Join(useDir, entry.Name()))
+		if err != nil {
+			return nil, "", err
+		}
+		privateKey, err := parseRSAPrivateKey(data)
+		if err != nil {
+			return nil, "", fmt.Errorf("key %s: %w", kid, err)
+		}
+		keys[kid] = &SigningKey{Kid: kid, PrivateKey: privateKey, PublicKey: &privateKey.PublicKey}
+	}
+	active, err := os.ReadFile(filepath.Join(useDir, keyringActiveFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return keys, "", nil
+		}
+		return nil, "", err
+	}
+	kid := strings.TrimSpace(string(active))
+	if _, ok := keys[kid]; kid != "" && !ok {
+		return nil, "", fmt.Errorf("active %s key %s: %w", use, kid, ErrUnknownKid)
+	}
+	return keys, kid, nil
+}
+
+// GenerateKeyringKey writes a new key next to the others without promoting it,
+// so it is published in the JWKS before any token is signed with it.
+func GenerateKeyringKey(dir string, use string) (string, error) {
+	if dir == "" {
+		return "", errors.New("JWT_KEYRING_DIR is not set")
+	}
+	privateKey, err := rsa.GenerateKey(rand.Reader, keyringKeyBits)
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	useDir := filepath.Join(dir, use)
+	if err = os.MkdirAll(useDir, 0o700); err != nil {
+		return "", err
+	}
+	kid := fmt.Sprintf("%s-%s", time.Now().UTC().Format("20060102"), strings.Split(uuid.NewString(), "-")[0])
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err = os.WriteFile(filepath.Join(useDir, kid+keyringKeyExt), data, 0o600); err != nil {
+		return "", err
+	}
+	return kid, nil
+}
+
+// PromoteKeyringKey makes kid the signing key, the previous key stays for verification.
+func PromoteKeyringKey(dir string, use string, kid string) error {
+	keys, _, err := readKeyringDir(dir, use)
+	if err != nil {
+		return err
+	}
+	if _, ok := keys[kid]; !ok {
+		return fmt.Errorf("%s key %s: %w", use, kid, ErrUnknownKid)
+	}
+	useDir := filepath.Join(dir, use)
+	tmp := filepath.Join(useDir, keyringActiveFile+".tmp")
+	if err = os.WriteFile(tmp, []byte(kid+"\n"), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(useDir, keyringActiveFile))
+}
+
+// RetireKeyringKey removes a key, tokens signed with it stop validating. Only
+// retire a key once the longest token lifetime has passed since it was replaced.
+func RetireKeyringKey(dir string, use string, kid string) error {
+	keys, active, err := readKeyringDir(dir, use)
+	if err != nil {
+		return err
+	}
+	if _, ok := keys[kid]; !ok {
+		return fmt.Errorf("%s key %s: %w", use, kid, ErrUnknownKid)
+	}
+	if kid == active {
+		return ErrActiveKeyRetired
+	}
+	return os.Remove(filepath.Join(dir, use, kid+keyringKeyExt))
+}
+
+// ListKeyringKeys returns the kids found for use and the active one.
+func ListKeyringKeys(dir string, use string) ([]string, string, error) {
+	keys, active, err := readKeyringDir(dir, use)
+	if err != nil {
+		return nil, "", err
+	}
+	kids := make([]string, 0, len(keys))
+	for kid := range keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+	return kids, active, nil
+}
+
+func parseLegacyKey(kid string, privatePem string, publicPem string) (*SigningKey, error) {
+	key := &SigningKey{Kid: kid}
+	if privatePem != "" {
+		privateKey, err := parseRSAPrivateKey([]byte(privatePem))
+		if err != nil {
+			return nil, err
+		}
+		key.PrivateKey = privateKey
+		key.PublicKey = &privateKey.PublicKey
+	}
+	if publicPem != "" {
+		block, _ := pem.Decode([]byte(publicPem))
+		if block == nil {
+			return nil, errors.New("failed decode key")
+		}
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		rsaPubKey, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("not an RSA public key")
+		}
+		key.PublicKey = rsaPubKey
+	}
+	return key, nil
+}
+
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("failed decode key")
+	}
+	if rsaKey, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return rsaKey, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA private key")
+	}
+	return rsaKey, nil
+}
+
+func toJWK(key *SigningKey) JWK {
+	return JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: jwt.SigningMethodRS512.Alg(),
+		Kid: key.Kid,
+		N:   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"backend-mobile-api/model/dto"
+	"backend-mobile-api/model/enum/pkgErr"
+	"net"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// LoopbackOnlyMiddleware keeps developer tooling to callers on the host itself,
+// the client ip comes from the peer or a trusted proxy, see rest.ipExtractor.
+func LoopbackOnlyMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if ip := net.ParseIP(c.RealIP()); ip == nil || !ip.IsLoopback() {
+				return c.JSON(http.StatusForbidden, dto.BaseResponse{
+					StatusCode: pkgErr.AUTH_FORBIDDEN_CODE,
+					Message:    pkgErr.FORBIDDEN_MSG,
+				})
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"backend-mobile-api/model/dto"
+	"backend-mobile-api/model/enum/pkgErr"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// X-SIGNATURE-VERSION values, a request without the header is v1 and v1 is refused once logged in
+const (
+	SIGNATURE_V1 = "v1"
+	SIGNATURE_V2 = "v2"
+)
+
+const signingSecretBytes = 32
+
+// GenerateSigningSecret returns the per-device secret v2 signatures are keyed with after login.
+func GenerateSigningSecret() (string, error) {
+	secret := make([]byte, signingSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// CanonicalRequestV2 is the string a v2 signature is computed over:
+// method, path with query, X-TIMESTAMP, X-NONCE, X-DEVICE-ID and the hex sha256 of the raw body, one per line.
+// The device id is signed because nonces are remembered per device.
+func CanonicalRequestV2(method string, path string, timestamp string, nonce string, deviceID string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	return strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		timestamp,
+		nonce,
+		deviceID,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+}
+
+// SignRequestV2 returns the hex hmac-sha256 of the canonical request.
+func SignRequestV2(secret string, canonical string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (svc *customMiddleware) signatureVersionAccepted(version string) bool {
+	for _, accepted := range svc.rootConfig.Signature.Versions {
+		if strings.EqualFold(strings.TrimSpace(accepted), version) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTimestamp rejects requests whose X-TIMESTAMP is outside the window around server time.
+func (svc *customMiddleware) validateTimestamp(ctx context.Context, req *dto.ContextValue) (*dto.BaseResponse, error) {
+	timestamp, err := time.Parse(time.RFC3339, req.HeaderXTimestamp)
+	if err != nil {
+		svc.logger.ErrorLogger(ctx, "SignatureValidate.time.Parse", err)
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_INVALID_REQUEST_PAYLOAD_CODE,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		}, err
+	}
+	skew := time.Since(timestamp)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > svc.rootConfig.Signature.TimestampWindow {
+		err = fmt.Errorf("request timestamp %s is %s away from server time", req.HeaderXTimestamp, skew.Round(time.Second))
+		svc.logger.ErrorLogger(ctx, "SignatureValidate.timestamp", err)
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_TIMESTAMP_SKEW_CODE,
+			Message:    pkgErr.TIMESTAMP_SKEW_MSG,
+			Error:      err.Error(),
+		}, err
+	}
+	return nil, nil
+}
+
+// validateSignatureV2 checks the hmac of the canonical request. Routes that need
+// no login are keyed with the client secret of the app, every other route with
+// the secret the device received at login.
+func (svc *customMiddleware) validateSignatureV2(ctx context.Context, req *dto.ContextValue, reader []byte, excUrl *ExcludeURLValidation) (*dto.BaseResponse, error) {
+	secret, err := svc.signingSecret(ctx, req, excUrl)
+	if err != nil {
+		svc.logger.ErrorLogger(ctx, "SignatureValidate.signingSecret", err)
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_INVALID_SIGNATURE_CODE,
+			Message:    pkgErr.INVALID_SIGNATURE_MSG,
+			Error:      err.Error(),
+		}, err
+	}
+
+	path := req.HeaderPath
+	if req.HeaderRawQuery != "" {
+		path += "?" + req.HeaderRawQuery
+	}
+	canonical := CanonicalRequestV2(req.HeaderMethod, path, req.HeaderXTimestamp, req.HeaderXNonce, req.HeaderXDeviceID, reader)
+	expected := SignRequestV2(secret, canonical)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(req.HeaderXSignature))) {
+		err = errors.New("signature verification failed")
+		svc.logger.ErrorLogger(ctx, "SignatureValidate.v2", err)
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_INVALID_SIGNATURE_CODE,
+			Message:    pkgErr.INVALID_SIGNATURE_MSG,
+			Error:      err.Error(),
+		}, err
+	}
+	return nil, nil
+}
+
+// signingSecret is the client secret on routes that need no login, otherwise
+// the secret of the token family the access token belongs to. The family id
+// comes from the validated token, so a client can not pick another secret.
+func (svc *customMiddleware) signingSecret(ctx context.Context, req *dto.ContextValue, excUrl *ExcludeURLValidation) (string, error) {
+	if authorizationExcluded(req, excUrl) {
+		if svc.rootConfig.Signature.ClientSecret == "" {
+			return "", errors.New("client signing secret is not configured")
+		}
+		return svc.rootConfig.Signature.ClientSecret, nil
+	}
+	if req.AuthFamilyID == "" {
+		// tokens issued before families have no secret, the app has to refresh its token
+		return "", errors.New("access token belongs to no token family")
+	}
+	secret, err := svc.Redis.GetSigningSecret(ctx, req.AuthFamilyID)
+	if err != nil {
+		return "", err
+	}
+	if secret == "" {
+		return "", fmt.Errorf("no signing secret for token family %s", req.AuthFamilyID)
+	}
+	return secret, nil
+}
+
+func authorizationExcluded(req *dto.ContextValue, excUrl *ExcludeURLValidation) bool {
+	for _, value := range excUrl.Authorization.ExcludeURL {
+		if value == req.RequestPath {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package bankInquiry
+
+import (
+	"backend-mobile-api/app/config"
+	"backend-mobile-api/helpers"
+	"context"
+	"errors"
+	"strings"
+)
+
+var ErrAccountNotFound = errors.New("beneficiary account not found")
+
+type AccountInquiryRequest struct {
+	BankCode           string
+	AccountNo          string
+	PartnerReferenceNo string
+}
+
+type AccountInquiryResult struct {
+	AccountNo          string
+	AccountName        string
+	BankCode           string
+	BankName           string
+	ReferenceNo        string
+	PartnerReferenceNo string
+}
+
+// AccountInquiry resolves the holder name of a beneficiary account before money is sent to it.
+type AccountInquiry interface {
+	AccountInquiry(ctx context.Context, req *AccountInquiryRequest) (*AccountInquiryResult, error)
+}
+
+// NewAccountInquiry picks the adapter configured in BANK_INQUIRY_PROVIDER.
+func NewAccountInquiry(rootConfig *config.Root, clog *helpers.CustomLogger) (AccountInquiry, error) {
+	cfg := &rootConfig.BankInquiry
+	switch strings.ToLower(cfg.Provider) {
+	case "", "snap":
+		return NewSnapAccountInquiry(cfg, clog)
+	case "fake":
+		if rootConfig.App.IsProduction() {
+			return nil, errors.New("fake bank inquiry provider is not allowed in production")
+		}
+		return NewFakeAccountInquiry(), nil
+	}
+	return nil, errors.New("unknown bank inquiry provider: " + cfg.Provider)
+}
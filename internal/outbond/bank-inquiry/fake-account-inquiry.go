@@ -0,0 +1,43 @@
+package bankInquiry
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+var fakeAccountNames = []string{
+	"BUDI SANTOSO",
+	"SITI RAHAYU",
+	"AGUS PRASETYO",
+	"DEWI LESTARI",
+	"RINA WULANDARI",
+	"HENDRA GUNAWAN",
+	"NUR AINI",
+	"EKO SAPUTRA",
+}
+
+type fakeAccountInquiry struct{}
+
+// NewFakeAccountInquiry answers locally without calling a bank. The same bank code and
+// account number always return the same name, accounts ending in "000" do not exist.
+func NewFakeAccountInquiry() AccountInquiry {
+	return &fakeAccountInquiry{}
+}
+
+func (f *fakeAccountInquiry) AccountInquiry(ctx context.Context, req *AccountInquiryRequest) (*AccountInquiryResult, error) {
+	if strings.HasSuffix(req.AccountNo, "000") {
+		return nil, ErrAccountNotFound
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(req.BankCode + ":" + req.AccountNo))
+	sum := h.Sum32()
+	return &AccountInquiryResult{
+		AccountNo:          req.AccountNo,
+		AccountName:        fakeAccountNames[sum%uint32(len(fakeAccountNames))],
+		BankCode:           req.BankCode,
+		ReferenceNo:        fmt.Sprintf("FAKE%010d", sum),
+		PartnerReferenceNo: req.PartnerReferenceNo,
+	}, nil
+}
@@ -0,0 +1,230 @@
+package bankInquiry
+
+import (
+	"backend-mobile-api/app/config"
+	"backend-mobile-api/helpers"
+	snapDto "backend-mobile-api/model/outbond/snap-dto"
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	snapAccessTokenPath            = "/v1.0/access-token/b2b"
+	snapAccountInquiryExternalPath = "/v1.0/account-inquiry-external"
+	snapTimestampLayout            = "2006-01-02T15:04:05-07:00"
+
+	// service code 16 is the interbank account inquiry
+	snapAccountInquirySuccessCode = "2001600"
+	snapAccessTokenSuccessCode    = "2007300"
+)
+
+type snapAccountInquiry struct {
+	cfg        *config.BankInquiry
+	clog       *helpers.CustomLogger
+	privateKey *rsa.PrivateKey
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiredAt   time.Time
+}
+
+// NewSnapAccountInquiry implements the Bank Indonesia SNAP transfer-inquiry contract:
+// a B2B access token signed with the partner private key, then an HMAC-SHA512 signed
+// account-inquiry-external call.
+func NewSnapAccountInquiry(cfg *config.BankInquiry, clog *helpers.CustomLogger) (AccountInquiry, error) {
+	privateKey, err := parseRsaPrivateKey(cfg.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return &snapAccountInquiry{
+		cfg:        cfg,
+		clog:       clog,
+		privateKey: privateKey,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+func (svc *snapAccountInquiry) AccountInquiry(ctx context.Context, req *AccountInquiryRequest) (*AccountInquiryResult, error) {
+	accessToken, err := svc.getAccessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	jsonReq, err := json.Marshal(snapDto.AccountInquiryExternalRequest{
+		BeneficiaryBankCode:  req.BankCode,
+		BeneficiaryAccountNo: req.AccountNo,
+		PartnerReferenceNo:   req.PartnerReferenceNo,
+	})
+	if err != nil {
+		svc.clog.ErrorLogger(ctx, "snapAccountInquiry.AccountInquiry.json.Marshal", err)
+		return nil, err
+	}
+	timestamp := time.Now().Format(snapTimestampLayout)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, svc.cfg.Domain+snapAccountInquiryExternalPath, bytes.NewBuffer(jsonReq))
+	if err != nil {
+		svc.clog.ErrorLogger(ctx, "snapAccountInquiry.AccountInquiry.http.NewRequest", err)
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer "+accessToken)
+	request.Header.Set("X-TIMESTAMP", timestamp)
+	request.Header.Set("X-SIGNATURE", svc.transactionSignature(http.MethodPost, snapAccountInquiryExternalPath, accessToken, jsonReq, timestamp))
+	request.Header.Set("X-PARTNER-ID", svc.cfg.PartnerID)
+	request.Header.Set("X-EXTERNAL-ID", strconv.FormatInt(time.Now().UnixNano(), 10))
+	request.Header.Set("CHANNEL-ID", svc.cfg.ChannelID)
+	if svc.cfg.Origin != "" {
+		request.Header.Set("ORIGIN", svc.cfg.Origin)
+	}
+
+	var responseBody snapDto.AccountInquiryExternalResponse
+	statusCode, err := svc.do(ctx, request, &responseBody, "snapAccountInquiry.AccountInquiry")
+	if err != nil {
+		return nil, err
+	}
+	if statusCode == http.StatusNotFound || strings.HasPrefix(responseBody.ResponseCode, "404") {
+		return nil, ErrAccountNotFound
+	}
+	if responseBody.ResponseCode != snapAccountInquirySuccessCode {
+		err = fmt.Errorf("account inquiry failed: %s %s", responseBody.ResponseCode, responseBody.ResponseMessage)
+		svc.clog.ErrorLogger(ctx, "snapAccountInquiry.AccountInquiry.responseCode", err)
+		return nil, err
+	}
+	return &AccountInquiryResult{
+		AccountNo:          responseBody.BeneficiaryAccountNo,
+		AccountName:        responseBody.BeneficiaryAccountName,
+		BankCode:           responseBody.BeneficiaryBankCode,
+		BankName:           responseBody.BeneficiaryBankName,
+		ReferenceNo:        responseBody.ReferenceNo,
+		PartnerReferenceNo: responseBody.PartnerReferenceNo,
+	}, nil
+}
+
+// getAccessToken reuses the B2B token until shortly before it expires.
+func (svc *snapAccountInquiry) getAccessToken(ctx context.Context) (string, error) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	if svc.accessToken != "" && time.Now().Before(svc.expiredAt) {
+		return svc.accessToken, nil
+	}
+
+	jsonReq, _ := json.Marshal(snapDto.AccessTokenB2BRequest{GrantType: "client_credentials"})
+	timestamp := time.Now().Format(snapTimestampLayout)
+	signature, err := svc.asymmetricSignature(timestamp)
+	if err != nil {
+		svc.clog.ErrorLogger(ctx, "snapAccountInquiry.getAccessToken.asymmetricSignature", err)
+		return "", err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, svc.cfg.Domain+snapAccessTokenPath, bytes.NewBuffer(jsonReq))
+	if err != nil {
+		svc.clog.ErrorLogger(ctx, "snapAccountInquiry.getAccessToken.http.NewRequest", err)
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-TIMESTAMP", timestamp)
+	request.Header.Set("X-CLIENT-KEY", svc.cfg.ClientKey)
+	request.Header.Set("X-SIGNATURE", signature)
+
+	var responseBody snapDto.AccessTokenB2BResponse
+	if _, err = svc.do(ctx, request, &responseBody, "snapAccountInquiry.getAccessToken"); err != nil {
+		return "", err
+	}
+	if responseBody.ResponseCode != snapAccessTokenSuccessCode || responseBody.AccessToken == "" {
+		err = fmt.Errorf("access token failed: %s %s", responseBody.ResponseCode, responseBody.ResponseMessage)
+		svc.clog.ErrorLogger(ctx, "snapAccountInquiry.getAccessToken.responseCode", err)
+		return "", err
+	}
+	expiresIn, err := strconv.Atoi(responseBody.ExpiresIn)
+	if err != nil || expiresIn <= 0 {
+		expiresIn = 900
+	}
+	svc.accessToken = responseBody.AccessToken
+	svc.expiredAt = time.Now().Add(time.Duration(expiresIn)*time.Second - 30*time.Second)
+	return svc.accessToken, nil
+}
+
+func (svc *snapAccountInquiry) do(ctx context.Context, request *http.Request, out interface{}, tag string) (int, error) {
+	response, err := svc.httpClient.Do(request)
+	if err != nil {
+		svc.clog.ErrorLogger(ctx, tag+".httpClient.Do", err)
+		return 0, err
+	}
+	defer response.Body.Close()
+	jsonBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		svc.clog.ErrorLogger(ctx, tag+".io.ReadAll", err)
+		return response.StatusCode, err
+	}
+	if err = json.Unmarshal(jsonBody, out); err != nil {
+		svc.clog.ErrorLogger(ctx, tag+".json.Unmarshal", errors.New(string(jsonBody)))
+		return response.StatusCode, errors.New(http.StatusText(response.StatusCode))
+	}
+	return response.StatusCode, nil
+}
+
+// asymmetricSignature is SHA256withRSA over "clientKey|timestamp".
+func (svc *snapAccountInquiry) asymmetricSignature(timestamp string) (string, error) {
+	digest := sha256.Sum256([]byte(svc.cfg.ClientKey + "|" + timestamp))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, svc.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// transactionSignature is HMAC-SHA512 over
+// "METHOD:path:accessToken:lowercase(hex(sha256(minified body))):timestamp".
+func (svc *snapAccountInquiry) transactionSignature(method string, path string, accessToken string, body []byte, timestamp string) string {
+	var minified bytes.Buffer
+	if err := json.Compact(&minified, body); err != nil {
+		minified.Write(body)
+	}
+	bodyHash := sha256.Sum256(minified.Bytes())
+	stringToSign := strings.Join([]string{
+		method,
+		path,
+		accessToken,
+		strings.ToLower(hex.EncodeToString(bodyHash[:])),
+		timestamp,
+	}, ":")
+	mac := hmac.New(sha512.New, []byte(svc.cfg.ClientSecret))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func parseRsaPrivateKey(pemKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(strings.ReplaceAll(pemKey, `\n`, "\n")))
+	if block == nil {
+		return nil, errors.New("invalid bank inquiry private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("bank inquiry private key is not RSA")
+	}
+	return rsaKey, nil
+}
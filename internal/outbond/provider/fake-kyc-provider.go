@@ -0,0 +1,108 @@
+package provider
+
+import (
+	verihubsDto "backend-mobile-api/model/outbond/verihubs-dto"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// scripted answers of the fake kyc adapter
+const (
+	FAKE_KYC_VERIFIED = "verified"
+	FAKE_KYC_REJECTED = "rejected"
+	FAKE_KYC_ERROR    = "error"
+)
+
+var (
+	ErrFakeKycRejected    = errors.New("fake kyc provider: document could not be read")
+	ErrFakeKycUnavailable = errors.New("fake kyc provider: service unavailable")
+)
+
+type fakeKycProvider struct {
+	result string
+}
+
+// NewFakeKycProvider answers every kyc call with the scripted result: verified
+// returns a fixed identity, rejected fails the document reads and rejects the
+// selfie, error fails every call as an unreachable provider would.
+func NewFakeKycProvider(result string) (KycProvider, error) {
+	result = strings.ToLower(result)
+	switch result {
+	case FAKE_KYC_VERIFIED, FAKE_KYC_REJECTED, FAKE_KYC_ERROR:
+		return &fakeKycProvider{result: result}, nil
+	}
+	return nil, errors.New("unknown fake kyc result: " + result)
+}
+
+func (f *fakeKycProvider) SendKYCIdenityKTP(ctx context.Context, req *verihubsDto.VerihubIdentityRequest) (*verihubsDto.IdentityKTPResponse, error) {
+	if err := f.documentError(); err != nil {
+		return nil, err
+	}
+	return &verihubsDto.IdentityKTPResponse{
+		Message: "OK",
+		Data: verihubsDto.IdentityKTPVerihubs{
+			FullName:     "FAKE KYC USER",
+			Gender:       "LAKI-LAKI",
+			Address:      "JL. CONTOH NO. 1",
+			City:         "JAKARTA SELATAN",
+			DateOfBirth:  "01-01-1990",
+			District:     "KEBAYORAN BARU",
+			Nationality:  "WNI",
+			Nik:          "3174010101900001",
+			PlaceOfBirth: "JAKARTA",
+			State:        "DKI JAKARTA",
+		},
+	}, nil
+}
+func (f *fakeKycProvider) SendKYCIdenityPassport(ctx context.Context, req *verihubsDto.VerihubIdentityRequest) (*verihubsDto.IdentityPassportResponse, error) {
+	if err := f.documentError(); err != nil {
+		return nil, err
+	}
+	return &verihubsDto.IdentityPassportResponse{
+		Message: "OK",
+		Data: verihubsDto.IdentityPassportVerihubs{
+			Id:           uuid.New().String(),
+			Reference_id: uuid.New().String(),
+			ResultData: verihubsDto.ResultDataKycPassport{
+				Authority:    "JAKARTA",
+				DateOfBirth:  "01-01-1990",
+				DateOfExpiry: "01-01-2030",
+				DateOfIssue:  "01-01-2020",
+				FullName:     "FAKE KYC USER",
+				Gender:       "M",
+				Nationality:  "IDN",
+				PassportNo:   "X0000001",
+				PlaceOfBirth: "JAKARTA",
+			},
+		},
+	}, nil
+}
+func (f *fakeKycProvider) SendVerifySelfie(ctx context.Context, req *verihubsDto.VerifyKycSelfie) (*verihubsDto.VerifySelfieResponse, error) {
+	if f.result == FAKE_KYC_ERROR {
+		return nil, ErrFakeKycUnavailable
+	}
+	data := verihubsDto.DetailDataSelfieVerification{
+		ID:          uuid.New().String(),
+		Status:      "VERIFIED",
+		RejectField: []string{},
+		ReferenceID: uuid.New().String(),
+	}
+	if f.result == FAKE_KYC_REJECTED {
+		data.Status = "REJECTED"
+		data.RejectField = []string{"selfie_photo"}
+	}
+	return &verihubsDto.VerifySelfieResponse{Message: "OK", Data: data}, nil
+}
+
+func (f *fakeKycProvider) documentError() error {
+	switch f.result {
+	case FAKE_KYC_REJECTED:
+		return ErrFakeKycRejected
+	case FAKE_KYC_ERROR:
+		return ErrFakeKycUnavailable
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package provider
+
+import (
+	"backend-mobile-api/helpers"
+	redisRepos "backend-mobile-api/internal/repository/redis"
+	"backend-mobile-api/model/dto"
+	"backend-mobile-api/model/enum"
+	verihubsDto "backend-mobile-api/model/outbond/verihubs-dto"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrFakeOtpInvalid = errors.New("fake otp provider: otp code is invalid")
+
+type fakeOtpProvider struct {
+	redis *redisRepos.Redis
+	clog  *helpers.CustomLogger
+}
+
+// NewFakeOtpProvider sends nothing: the code of each destination is kept in redis
+// where the developer endpoint reads it back. No delivery report ever arrives, run
+// locally with a single OTP_CHANNELS entry or a long OTP_DELIVERY_TIMEOUT.
+func NewFakeOtpProvider(redis *redisRepos.Redis, clog *helpers.CustomLogger) OtpProvider {
+	return &fakeOtpProvider{
+		redis: redis,
+		clog:  clog,
+	}
+}
+
+func (f *fakeOtpProvider) SendSMSOtpService(ctx context.Context, req *verihubsDto.SendOtpBaseRequest) (*verihubsDto.SendOtpSMSResponse, error) {
+	sent, err := f.send(ctx, enum.TYPE_SMS, req.MSISDN, req.Otp, req.TimeLimit)
+	if err != nil {
+		return nil, err
+	}
+	return &verihubsDto.SendOtpSMSResponse{
+		Message:      "OTP sent",
+		Otp:          sent.Otp,
+		MSISDN:       req.MSISDN,
+		SessionID:    sent.SessionId,
+		SegmentCount: 1,
+	}, nil
+}
+func (f *fakeOtpProvider) SendWhatsappsService(ctx context.Context, req *verihubsDto.SendWhatsappOtpBaseRequest) (*verihubsDto.SendOtpWAResponse, error) {
+	sent, err := f.send(ctx, enum.TYPE_WHATSAPP, req.MSISDN, req.Otp, req.TimeLimit)
+	if err != nil {
+		return nil, err
+	}
+	return &verihubsDto.SendOtpWAResponse{
+		Message:   "OTP sent",
+		Otp:       sent.Otp,
+		MSISDN:    req.MSISDN,
+		SessionID: sent.SessionId,
+		TryCount:  1,
+	}, nil
+}
+func (f *fakeOtpProvider) VerifySMSOtpService(ctx context.Context, req *verihubsDto.VerifyOtpBaseRequest) (*verihubsDto.VerifyOtpBaseResponse, error) {
+	return f.verify(ctx, req)
+}
+func (f *fakeOtpProvider) VerifyWhatsappsOtpService(ctx context.Context, req *verihubsDto.VerifyOtpBaseRequest) (*verihubsDto.VerifyOtpBaseResponse, error) {
+	return f.verify(ctx, req)
+}
+
+// send keeps the code the caller chose, like verihubs it makes one up when none is given.
+func (f *fakeOtpProvider) send(ctx context.Context, method enum.OtpType, msisdn string, otp *string, timeLimit int64) (*dto.FakeOtp, error) {
+	sent := &dto.FakeOtp{
+		OtpMethod: method,
+		SessionId: uuid.New().String(),
+		SentAt:    time.Now(),
+	}
+	if otp != nil && *otp != "" {
+		sent.Otp = *otp
+	} else {
+		n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+		if err != nil {
+			return nil, err
+		}
+		sent.Otp = fmt.Sprintf("%06d", n.Int64())
+	}
+	expiration := time.Duration(timeLimit) * time.Second
+	if expiration <= 0 {
+		expiration = 5 * time.Minute
+	}
+	jsonSent, _ := json.Marshal(sent)
+	if err := f.redis.SetFakeOtp(ctx, msisdn, string(jsonSent), expiration); err != nil {
+		f.clog.ErrorLogger(ctx, "fakeOtpProvider.redis.SetFakeOtp", err)
+		return nil, err
+	}
+	f.clog.InfoLogger(ctx, fmt.Sprintf("fakeOtpProvider: %s otp sent to %s", method, helpers.MaskDestination(msisdn)))
+	return sent, nil
+}
+
+func (f *fakeOtpProvider) verify(ctx context.Context, req *verihubsDto.VerifyOtpBaseRequest) (*verihubsDto.VerifyOtpBaseResponse, error) {
+	value, err := f.redis.GetFakeOtp(ctx, req.MSISDN)
+	if err != nil {
+		f.clog.ErrorLogger(ctx, "fakeOtpProvider.redis.GetFakeOtp", err)
+		return nil, err
+	}
+	var sent dto.FakeOtp
+	if value == "" || json.Unmarshal([]byte(value), &sent) != nil || sent.Otp != req.Otp {
+		return nil, ErrFakeOtpInvalid
+	}
+	return &verihubsDto.VerifyOtpBaseResponse{Message: "OTP verified"}, nil
+}
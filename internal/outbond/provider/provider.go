@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"backend-mobile-api/app/config"
+	"backend-mobile-api/helpers"
+	"backend-mobile-api/internal/outbond/verihubs"
+	redisRepos "backend-mobile-api/internal/repository/redis"
+	verihubsDto "backend-mobile-api/model/outbond/verihubs-dto"
+	"context"
+	"errors"
+	"strings"
+)
+
+// OtpProvider sends and verifies otp codes over sms and whatsapp, email otp goes through smtp.
+type OtpProvider interface {
+	SendSMSOtpService(ctx context.Context, req *verihubsDto.SendOtpBaseRequest) (*verihubsDto.SendOtpSMSResponse, error)
+	SendWhatsappsService(ctx context.Context, req *verihubsDto.SendWhatsappOtpBaseRequest) (*verihubsDto.SendOtpWAResponse, error)
+	VerifySMSOtpService(ctx context.Context, req *verihubsDto.VerifyOtpBaseRequest) (*verihubsDto.VerifyOtpBaseResponse, error)
+	VerifyWhatsappsOtpService(ctx context.Context, req *verihubsDto.VerifyOtpBaseRequest) (*verihubsDto.VerifyOtpBaseResponse, error)
+}
+
+// KycProvider reads identity documents and matches a selfie against them.
+type KycProvider interface {
+	SendKYCIdenityKTP(ctx context.Context, req *verihubsDto.VerihubIdentityRequest) (*verihubsDto.IdentityKTPResponse, error)
+	SendKYCIdenityPassport(ctx context.Context, req *verihubsDto.VerihubIdentityRequest) (*verihubsDto.IdentityPassportResponse, error)
+	SendVerifySelfie(ctx context.Context, req *verihubsDto.VerifyKycSelfie) (*verihubsDto.VerifySelfieResponse, error)
+}
+
+// NewOtpProvider picks the adapter configured in PROVIDER_OTP.
+func NewOtpProvider(rootConfig *config.Root, outboundVeriHubsSvc verihubs.OutboundVeriHubsService, redis *redisRepos.Redis, clog *helpers.CustomLogger) (OtpProvider, error) {
+	switch strings.ToLower(rootConfig.Provider.Otp) {
+	case "", "verihubs":
+		return outboundVeriHubsSvc, nil
+	case "fake":
+		if rootConfig.App.IsProduction() {
+			return nil, errors.New("fake otp provider is not allowed in production")
+		}
+		return NewFakeOtpProvider(redis, clog), nil
+	}
+	return nil, errors.New("unknown otp provider: " + rootConfig.Provider.Otp)
+}
+
+// NewKycProvider picks the adapter configured in PROVIDER_KYC.
+func NewKycProvider(rootConfig *config.Root, outboundVeriHubsSvc verihubs.OutboundVeriHubsService) (KycProvider, error) {
+	switch strings.ToLower(rootConfig.Provider.Kyc) {
+	case "", "verihubs":
+		return outboundVeriHubsSvc, nil
+	case "fake":
+		if rootConfig.App.IsProduction() {
+			return nil, errors.New("fake kyc provider is not allowed in production")
+		}
+		return NewFakeKycProvider(rootConfig.Provider.FakeKycResult)
+	}
+	return nil, errors.New("unknown kyc provider: " + rootConfig.Provider.Kyc)
+}
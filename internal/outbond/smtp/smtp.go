@@ -4,11 +4,20 @@ import (
 	"backend-mobile-api/app/config"
 	"backend-mobile-api/helpers"
 	"backend-mobile-api/model/enum"
+	"bytes"
 	"context"
+	"embed"
 	"fmt"
+	"html/template"
 	"net/smtp"
+	"time"
 )
 
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var templates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
 type Smtp struct {
 	cfg     *config.Root
 	clogger *helpers.CustomLogger
@@ -23,13 +32,33 @@ func (s *Smtp) RegisterOtpMsg(otp string) string {
 	return body
 }
 
+// ResetPinMsg renders the forgot pin email. The link is marked trusted since
+// its callback was allowlisted, html/template would drop app deep-link
+// schemes otherwise.
+func (s *Smtp) ResetPinMsg(link string, expireAt time.Time) (string, error) {
+	var body bytes.Buffer
+	err := templates.ExecuteTemplate(&body, "reset-pin.html", map[string]any{
+		"Link":     template.URL(link),
+		"ExpireAt": expireAt.Format("02 Jan 2006 15:04 MST"),
+	})
+	return body.String(), err
+}
+
 func (s *Smtp) SendMail(c context.Context, to []string, subjectData enum.EmailSubject, bodyMsg string) error {
+	return s.send(c, to, subjectData, "text/plain", bodyMsg)
+}
+
+func (s *Smtp) SendHTMLMail(c context.Context, to []string, subjectData enum.EmailSubject, bodyMsg string) error {
+	return s.send(c, to, subjectData, "text/html", bodyMsg)
+}
+
+func (s *Smtp) send(c context.Context, to []string, subjectData enum.EmailSubject, contentType string, bodyMsg string) error {
 	var (
 		adress  = fmt.Sprintf("%s:%s", s.cfg.Smtp.Host, s.cfg.Smtp.Port)
 		auth    = smtp.PlainAuth("", s.cfg.Smtp.From, s.cfg.Smtp.Password, s.cfg.Smtp.Host)
 		from    = s.cfg.Smtp.From
 		subject = fmt.Sprintf("Subject: %s\n", subjectData)
-		mime    = "MIME-Version: 1.0\r\nContent-Type: text/plain; charset=\"utf-8\"\r\n"
+		mime    = fmt.Sprintf("MIME-Version: 1.0\r\nContent-Type: %s; charset=\"utf-8\"\r\n", contentType)
 
 		msg = []byte(subject + mime + "\r\n" + bodyMsg)
 	)
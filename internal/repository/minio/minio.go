@@ -40,6 +40,7 @@ func NewMinioRepository(
 type MinioRepository interface {
 	PutObject(ctx context.Context, file *multipart.FileHeader, path string, fileName *string) (*minio.UploadInfo, *string, error)
 	GenerateMinioPresignedURL(ctx context.Context, fileName *string, expires time.Duration) (string, error)
+	RemoveObject(ctx context.Context, objectName string) error
 }
 
 func (m *minioRepository) PutObject(ctx context.Context, file *multipart.FileHeader, path string, fileName *string) (*minio.UploadInfo, *string, error) {
@@ -82,3 +83,12 @@ func (m *minioRepository) GenerateMinioPresignedURL(ctx context.Context, fileNam
 	}
 	return url.String(), nil
 }
+
+// RemoveObject deletes an object, one that is already gone is not an error.
+func (m *minioRepository) RemoveObject(ctx context.Context, objectName string) error {
+	err := m.minioClinet.RemoveObject(ctx, m.BucketName, objectName, minio.RemoveObjectOptions{})
+	if err != nil {
+		m.Clogger.ErrorLogger(ctx, "RemoveObject.minioClinet.RemoveObject", err)
+	}
+	return err
+}
@@ -12,11 +12,11 @@ type accessStateRepository struct {
 	logger   *helpers.CustomLogger
 }
 type AccessStateRepository interface {
-	Tx(ctx context.Context) *gorm.DB
 	InsertAccessStateRepository(ctx context.Context, tx *gorm.DB, resetPin *entity.AccessState) error
 	SelectAccessByAccessTokenRepository(ctx context.Context, resetToken string) (*entity.AccessState, error)
 	SelectActiveAccessByUserId(ctx context.Context, userId int64) (*entity.AccessState, error)
 	UpdateAccessByStruct(ctx context.Context, tx *gorm.DB, curentPin *entity.AccessState, newPin *entity.AccessState) error
+	ConsumeAccessState(ctx context.Context, tx *gorm.DB, access *entity.AccessState) (bool, error)
 }
 
 func NewResetPinRepository(posgres *gorm.DB,
@@ -26,9 +26,6 @@ func NewResetPinRepository(posgres *gorm.DB,
 		logger:   logger,
 	}
 }
-func (repo *accessStateRepository) Tx(ctx context.Context) *gorm.DB {
-	return repo.masterDb.Begin()
-}
 func (repo *accessStateRepository) InsertAccessStateRepository(ctx context.Context, tx *gorm.DB, resetPin *entity.AccessState) error {
 	err := tx.Create(&resetPin).Error
 	if err != nil {
@@ -38,7 +35,7 @@ func (repo *accessStateRepository) InsertAccessStateRepository(ctx context.Conte
 }
 func (repo *accessStateRepository) SelectAccessByAccessTokenRepository(ctx context.Context, resetToken string) (*entity.AccessState, error) {
 	var resetPin entity.AccessState
-	err := repo.masterDb.Table("access_states").Where("access_token = ? and used = false", resetToken).Order("created_at DESC").First(&resetPin).Error
+	err := conn(ctx, repo.masterDb).Table("access_states").Where("access_token = ? and used = false", resetToken).Order("created_at DESC").First(&resetPin).Error
 	if err != nil {
 		repo.logger.ErrorLogger(ctx, "SelectAccessByAccessTokenRepository", err)
 		return nil, err
@@ -47,7 +44,7 @@ func (repo *accessStateRepository) SelectAccessByAccessTokenRepository(ctx conte
 }
 func (repo *accessStateRepository) SelectActiveAccessByUserId(ctx context.Context, userId int64) (*entity.AccessState, error) {
 	var pinData entity.AccessState
-	err := repo.masterDb.Table("access_states").Where("user_id = ? and used = true", userId).First(&pinData).Error
+	err := conn(ctx, repo.masterDb).Table("access_states").Where("user_id = ? and used = true", userId).First(&pinData).Error
 	if err != nil {
 		repo.logger.ErrorLogger(ctx, "SelectActiveAccessByUserId", err)
 		return nil, err
@@ -61,3 +58,14 @@ func (repo *accessStateRepository) UpdateAccessByStruct(ctx context.Context, tx
 	}
 	return err
 }
+
+// ConsumeAccessState marks the access used only if nobody did before, false
+// means a concurrent request already spent it.
+func (repo *accessStateRepository) ConsumeAccessState(ctx context.Context, tx *gorm.DB, access *entity.AccessState) (bool, error) {
+	result := tx.Model(&entity.AccessState{}).Where("id = ? and used = false", access.ID).Update("used", true)
+	if result.Error != nil {
+		repo.logger.ErrorLogger(ctx, "ConsumeAccessState", result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,180 @@
+package postgres
+
+import (
+	"backend-mobile-api/helpers"
+	"backend-mobile-api/model/entity"
+	"backend-mobile-api/model/enum"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type accountRepository struct {
+	masterDb *gorm.DB
+	clogger  *helpers.CustomLogger
+}
+
+func NewAccountRepository(posgres *gorm.DB, clogger *helpers.CustomLogger) AccountRepository {
+	return &accountRepository{masterDb: posgres, clogger: clogger}
+}
+
+type AccountRepository interface {
+	InsertAccountDeletion(ctx context.Context, tx *gorm.DB, deletion *entity.AccountDeletion) error
+	SelectRequestedAccountDeletion(ctx context.Context, userID int64) (*entity.AccountDeletion, error)
+	SelectDueAccountDeletions(ctx context.Context, now time.Time, limit int) ([]entity.AccountDeletion, error)
+	UpdateAccountDeletion(ctx context.Context, tx *gorm.DB, deletion *entity.AccountDeletion, updater *entity.AccountDeletion) error
+	SelectAccountObjects(ctx context.Context, userID int64) ([]string, error)
+	PurgeAccount(ctx context.Context, tx *gorm.DB, userID int64) error
+	SelectAccountExport(ctx context.Context, userID int64) (*entity.AccountExport, error)
+}
+
+func (repo *accountRepository) InsertAccountDeletion(ctx context.Context, tx *gorm.DB, deletion *entity.AccountDeletion) error {
+	err := tx.WithContext(ctx).Create(deletion).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "InsertAccountDeletion.gorm.DB", err)
+	}
+	return err
+}
+
+// SelectRequestedAccountDeletion returns the open request of the user, nil when there is none.
+func (repo *accountRepository) SelectRequestedAccountDeletion(ctx context.Context, userID int64) (*entity.AccountDeletion, error) {
+	var deletion entity.AccountDeletion
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Where("user_id = ? AND status = ?", userID, enum.ACCOUNT_DELETION_REQUESTED).
+		First(&deletion).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		repo.clogger.ErrorLogger(ctx, "SelectRequestedAccountDeletion.gorm.DB", err)
+		return nil, err
+	}
+	return &deletion, nil
+}
+
+// SelectDueAccountDeletions returns the requests whose grace period ended, oldest first.
+func (repo *accountRepository) SelectDueAccountDeletions(ctx context.Context, now time.Time, limit int) ([]entity.AccountDeletion, error) {
+	var deletions []entity.AccountDeletion
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Where("status = ? AND purge_at <= ?", enum.ACCOUNT_DELETION_REQUESTED, now).
+		Order("purge_at").
+		Limit(limit).
+		Find(&deletions).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectDueAccountDeletions.gorm.DB", err)
+	}
+	return deletions, err
+}
+
+func (repo *accountRepository) UpdateAccountDeletion(ctx context.Context, tx *gorm.DB, deletion *entity.AccountDeletion, updater *entity.AccountDeletion) error {
+	err := tx.WithContext(ctx).Model(deletion).Updates(updater).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "UpdateAccountDeletion.gorm.DB", err)
+	}
+	return err
+}
+
+// SelectAccountObjects lists the minio objects of the user: identity document
+// images and the profile picture.
+func (repo *accountRepository) SelectAccountObjects(ctx context.Context, userID int64) ([]string, error) {
+	var objects []string
+	err := conn(ctx, repo.masterDb).WithContext(ctx).Raw(`
+		SELECT identity_image FROM identity_ktps WHERE user_id = @user_id AND identity_image <> ''
+		UNION
+		SELECT identity_image FROM identity_passports WHERE user_id = @user_id AND identity_image <> ''
+		UNION
+		SELECT profile_picture FROM user_details WHERE user_id = @user_id AND COALESCE(profile_picture, '') <> ''
+	`, map[string]interface{}{"user_id": userID}).Scan(&objects).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectAccountObjects.gorm.DB", err)
+	}
+	return objects, err
+}
+
+// PurgeAccount removes the personal data of the user. Transactions, payment
+// requests and the identity records without their images are kept, financial
+// regulation requires them to outlive the account; the user row stays so they
+// keep their owner, with every personal field blanked. Consents stay without
+// the client details as the record of what the data was processed under.
+func (repo *accountRepository) PurgeAccount(ctx context.Context, tx *gorm.DB, userID int64) error {
+	statements := []string{
+		`DELETE FROM otp_delivery_events WHERE otp_id IN (SELECT id FROM otps WHERE user_id = @user_id)`,
+		`DELETE FROM otps WHERE user_id = @user_id`,
+		`DELETE FROM access_states WHERE user_id = @user_id`,
+		`DELETE FROM biometric_keys WHERE user_id = @user_id`,
+		`DELETE FROM login_events WHERE user_id = @user_id`,
+		`DELETE FROM token_family_tokens WHERE family_id IN (SELECT family_id FROM token_families WHERE user_id = @user_id)`,
+		`DELETE FROM token_families WHERE user_id = @user_id`,
+		`DELETE FROM devices WHERE user_id = @user_id`,
+		`DELETE FROM tb_recipient WHERE user_id = @user_id`,
+		`DELETE FROM user_payment_accounts WHERE user_id = @user_id`,
+		`DELETE FROM user_roles WHERE user_id = @user_id`,
+		`UPDATE user_consents SET ip_address = '', app_version = '', updated_at = now() WHERE user_id = @user_id`,
+		`UPDATE identity_ktps SET identity_image = '', updated_at = now() WHERE user_id = @user_id`,
+		`UPDATE identity_passports SET identity_image = '', updated_at = now() WHERE user_id = @user_id`,
+		`UPDATE user_details SET country = NULL, province = NULL, regency = NULL, district = NULL, address = NULL,
+			profile_picture = NULL, biometric = @biometric, updated_at = now(), deleted_at = now()
+		WHERE user_id = @user_id`,
+		`UPDATE users SET full_name = '', email = '', phone_number = '', password = '', pin = NULL, device_id = '',
+			status = @status, updated_at = now(), deleted_at = now()
+		WHERE id = @user_id`,
+	}
+	params := map[string]interface{}{
+		"user_id":   userID,
+		"biometric": enum.BIOMETRIC_IN_ACTIVE,
+		"status":    enum.USER_PURGED,
+	}
+	for _, statement := range statements {
+		if err := tx.WithContext(ctx).Exec(statement, params).Error; err != nil {
+			repo.clogger.ErrorLogger(ctx, "PurgeAccount.gorm.DB", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (repo *accountRepository) SelectAccountExport(ctx context.Context, userID int64) (*entity.AccountExport, error) {
+	var (
+		export entity.AccountExport
+		detail entity.UserDetail
+		db     = conn(ctx, repo.masterDb).WithContext(ctx)
+	)
+	if err := db.Where("id = ?", userID).First(&export.User).Error; err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectAccountExport.users", err)
+		return nil, err
+	}
+	err := db.Where("user_id = ?", userID).First(&detail).Error
+	switch {
+	case err == nil:
+		export.Detail = &detail
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		repo.clogger.ErrorLogger(ctx, "SelectAccountExport.user_details", err)
+		return nil, err
+	}
+	finds := []struct {
+		name string
+		dest interface{}
+	}{
+		{"devices", &export.Devices},
+		{"token_families", &export.Sessions},
+		{"identity_ktps", &export.Ktps},
+		{"identity_passports", &export.Passports},
+		{"tb_recipient", &export.Recipients},
+		{"user_payment_accounts", &export.PaymentAccounts},
+		{"transactions", &export.Transactions},
+		{"login_events", &export.LoginEvents},
+	}
+	for _, find := range finds {
+		if err = db.Where("user_id = ?", userID).Find(find.dest).Error; err != nil {
+			repo.clogger.ErrorLogger(ctx, "SelectAccountExport."+find.name, err)
+			return nil, err
+		}
+	}
+	if err = db.Preload("Document").Where("user_id = ?", userID).Find(&export.Consents).Error; err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectAccountExport.user_consents", err)
+		return nil, err
+	}
+	return &export, nil
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"backend-mobile-api/helpers"
+	"backend-mobile-api/model/entity"
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+type apiKeyRepository struct {
+	masterDb *gorm.DB
+	clogger  *helpers.CustomLogger
+}
+type ApiKeyRepository interface {
+	InsertApiKey(ctx context.Context, tx *gorm.DB, key *entity.ApiKey) error
+	SelectApiKeyByPrefix(ctx context.Context, prefix string) (*entity.ApiKey, error)
+	SelectApiKeys(ctx context.Context) ([]entity.ApiKey, error)
+	RevokeApiKey(ctx context.Context, tx *gorm.DB, prefix string) (int64, error)
+	UpdateApiKeyLastUsed(ctx context.Context, id uint) error
+}
+
+func NewApiKeyRepository(db *gorm.DB, clogger *helpers.CustomLogger) ApiKeyRepository {
+	return &apiKeyRepository{
+		masterDb: db,
+		clogger:  clogger,
+	}
+}
+
+func (repo *apiKeyRepository) InsertApiKey(ctx context.Context, tx *gorm.DB, key *entity.ApiKey) error {
+	err := tx.WithContext(ctx).Create(key).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "InsertApiKey.gorm.DB", err)
+	}
+	return err
+}
+
+func (repo *apiKeyRepository) SelectApiKeyByPrefix(ctx context.Context, prefix string) (*entity.ApiKey, error) {
+	var key entity.ApiKey
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Where("prefix = ?", prefix).
+		First(&key).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectApiKeyByPrefix.gorm.DB", err)
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (repo *apiKeyRepository) SelectApiKeys(ctx context.Context) ([]entity.ApiKey, error) {
+	var keys []entity.ApiKey
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Order("created_at").
+		Find(&keys).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectApiKeys.gorm.DB", err)
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (repo *apiKeyRepository) RevokeApiKey(ctx context.Context, tx *gorm.DB, prefix string) (int64, error) {
+	result := tx.WithContext(ctx).
+		Model(&entity.ApiKey{}).
+		Where("prefix = ? AND revoked_at IS NULL", prefix).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		repo.clogger.ErrorLogger(ctx, "RevokeApiKey.gorm.DB", result.Error)
+	}
+	return result.RowsAffected, result.Error
+}
+
+func (repo *apiKeyRepository) UpdateApiKeyLastUsed(ctx context.Context, id uint) error {
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Model(&entity.ApiKey{}).
+		Where("id = ?", id).
+		UpdateColumn("last_used_at", time.Now()).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "UpdateApiKeyLastUsed.gorm.DB", err)
+	}
+	return err
+}
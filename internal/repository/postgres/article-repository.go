@@ -18,7 +18,6 @@ func NewArticleRepository(masterDb *gorm.DB, clogger *helpers.CustomLogger) Arti
 }
 
 type ArticleRepository interface {
-	Tx(ctx context.Context) *gorm.DB
 	InsertArticle(ctx context.Context, tx *gorm.DB, news *entity.Article) error
 	UpdateArticle(ctx context.Context, tx *gorm.DB, news *entity.Article, updater *entity.Article) error
 	DeleteArticle(ctx context.Context, tx *gorm.DB, news *entity.Article) error
@@ -36,7 +35,7 @@ func (r *articleRepository) CountArticle(ctx context.Context, req *request.Count
 	clause.Category = req.Category
 	clause.ActiveAfterDay = req.ActiveAfterDay
 
-	err = r.masterDb.WithContext(ctx).Where(&clause).Find(&[]entity.Article{}).Count(&count).Error
+	err = conn(ctx, r.masterDb).WithContext(ctx).Where(&clause).Find(&[]entity.Article{}).Count(&count).Error
 	if err != nil {
 		r.clogger.ErrorLogger(ctx, "CountArticle.masterDb.WithContext(ctx).Where(&clause).Find(&[]entity.Article{}).Count(&count).Error", err)
 	}
@@ -56,7 +55,7 @@ func (r *articleRepository) SelectListArticles(ctx context.Context, req request.
 	if req.ActiveAfterDay != nil {
 		clause.ActiveAfterDay = req.ActiveAfterDay
 	}
-	err := r.masterDb.WithContext(ctx).Where(&clause).Limit(req.Limit).Offset(req.Offset).Find(&news).Error
+	err := conn(ctx, r.masterDb).WithContext(ctx).Where(&clause).Limit(req.Limit).Offset(req.Offset).Find(&news).Error
 	if err != nil {
 		r.clogger.ErrorLogger(ctx, "SelectListArticles.masterDb.WithContext(ctx).Find", err)
 	}
@@ -65,7 +64,7 @@ func (r *articleRepository) SelectListArticles(ctx context.Context, req request.
 
 func (r *articleRepository) SelectByArticleId(ctx context.Context, newsId uint) (*entity.Article, error) {
 	var news entity.Article
-	err := r.masterDb.WithContext(ctx).Where("id = ?", newsId).First(&news).Error
+	err := conn(ctx, r.masterDb).WithContext(ctx).Where("id = ?", newsId).First(&news).Error
 	if err != nil {
 		r.clogger.ErrorLogger(ctx, "SelectByArticleId.masterDb.WithContext(ctx).Where(newsId).First", err)
 		return nil, err
@@ -73,10 +72,6 @@ func (r *articleRepository) SelectByArticleId(ctx context.Context, newsId uint)
 	return &news, err
 }
 
-func (r *articleRepository) Tx(ctx context.Context) *gorm.DB {
-	return r.masterDb.Begin()
-}
-
 func (r articleRepository) InsertArticle(ctx context.Context, tx *gorm.DB, news *entity.Article) error {
 	err := tx.Create(news).Error
 	if err != nil {
@@ -13,6 +13,7 @@ type BankListRepository interface {
 	SearchBanks(ctx context.Context, keyword string) ([]entity.Bank, error)
 	GetBanksByType(ctx context.Context, bankType string) ([]entity.Bank, error)
 	SearchBanksByType(ctx context.Context, bankType, keyword string) ([]entity.Bank, error)
+	FindBankByID(ctx context.Context, bankID int) (*entity.Bank, error)
 }
 
 type bankListRepository struct {
@@ -27,7 +28,7 @@ func NewBankListRepository(masterDb *gorm.DB, clogger *helpers.CustomLogger) Ban
 // ✅ ambil semua bank/ewallet
 func (r *bankListRepository) GetAllBanks(ctx context.Context) ([]entity.Bank, error) {
 	var banks []entity.Bank
-	err := r.masterDb.WithContext(ctx).
+	err := conn(ctx, r.masterDb).WithContext(ctx).
 		Order("nama_bank ASC"). // pastikan sesuai dengan entity.Bank
 		Find(&banks).Error
 	if err != nil {
@@ -40,7 +41,7 @@ func (r *bankListRepository) GetAllBanks(ctx context.Context) ([]entity.Bank, er
 // ✅ search by nama bank / va_name tapi dengan filter type (BANK / EWALLET)
 func (r *bankListRepository) SearchBanksByType(ctx context.Context, bankType, keyword string) ([]entity.Bank, error) {
 	var banks []entity.Bank
-	err := r.masterDb.WithContext(ctx).
+	err := conn(ctx, r.masterDb).WithContext(ctx).
 		Where("type = ? AND (nama_bank ILIKE ? OR va_name ILIKE ?)", bankType, "%"+keyword+"%", "%"+keyword+"%").
 		Order("nama_bank ASC").
 		Find(&banks).Error
@@ -54,7 +55,7 @@ func (r *bankListRepository) SearchBanksByType(ctx context.Context, bankType, ke
 // ✅ search by nama bank atau va_name
 func (r *bankListRepository) SearchBanks(ctx context.Context, keyword string) ([]entity.Bank, error) {
 	var banks []entity.Bank
-	err := r.masterDb.WithContext(ctx).
+	err := conn(ctx, r.masterDb).WithContext(ctx).
 		Where("nama_bank ILIKE ? OR va_name ILIKE ?", "%"+keyword+"%", "%"+keyword+"%").
 		Order("nama_bank ASC").
 		Find(&banks).Error
@@ -68,7 +69,7 @@ func (r *bankListRepository) SearchBanks(ctx context.Context, keyword string) ([
 // ✅ filter by type (BANK / EWALLET)
 func (r *bankListRepository) GetBanksByType(ctx context.Context, bankType string) ([]entity.Bank, error) {
 	var banks []entity.Bank
-	err := r.masterDb.WithContext(ctx).
+	err := conn(ctx, r.masterDb).WithContext(ctx).
 		Where("type = ?", bankType).
 		Order("nama_bank ASC").
 		Find(&banks).Error
@@ -78,3 +79,16 @@ func (r *bankListRepository) GetBanksByType(ctx context.Context, bankType string
 	}
 	return banks, nil
 }
+
+// ✅ ambil satu bank by id (dipakai untuk dapat bank_code)
+func (r *bankListRepository) FindBankByID(ctx context.Context, bankID int) (*entity.Bank, error) {
+	var bank entity.Bank
+	err := conn(ctx, r.masterDb).WithContext(ctx).
+		Where("bank_id = ?", bankID).
+		First(&bank).Error
+	if err != nil {
+		r.clogger.ErrorLogger(ctx, "FindBankByID", err)
+		return nil, err
+	}
+	return &bank, nil
+}
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"backend-mobile-api/helpers"
+	"backend-mobile-api/model/entity"
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+type biometricKeyRepository struct {
+	masterDb *gorm.DB
+	clogger  *helpers.CustomLogger
+}
+type BiometricKeyRepository interface {
+	InsertBiometricKey(ctx context.Context, tx *gorm.DB, key *entity.BiometricKey) error
+	SelectActiveBiometricKey(ctx context.Context, userID int64, deviceID string) (*entity.BiometricKey, error)
+	SelectActiveBiometricKeys(ctx context.Context, userID int64) ([]entity.BiometricKey, error)
+	RevokeBiometricKeysByDevice(ctx context.Context, tx *gorm.DB, userID int64, deviceID string) (int64, error)
+	RevokeBiometricKeyByKeyID(ctx context.Context, tx *gorm.DB, userID int64, keyID string) (int64, error)
+	UpdateBiometricKeyLastUsed(ctx context.Context, id uint) error
+}
+
+func NewBiometricKeyRepository(db *gorm.DB, clogger *helpers.CustomLogger) BiometricKeyRepository {
+	return &biometricKeyRepository{
+		masterDb: db,
+		clogger:  clogger,
+	}
+}
+
+func (repo *biometricKeyRepository) InsertBiometricKey(ctx context.Context, tx *gorm.DB, key *entity.BiometricKey) error {
+	err := tx.WithContext(ctx).Create(key).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "InsertBiometricKey.gorm.DB", err)
+	}
+	return err
+}
+
+func (repo *biometricKeyRepository) SelectActiveBiometricKey(ctx context.Context, userID int64, deviceID string) (*entity.BiometricKey, error) {
+	var key entity.BiometricKey
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Where("user_id = ? AND device_id = ? AND revoked_at IS NULL", userID, deviceID).
+		First(&key).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectActiveBiometricKey.gorm.DB", err)
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (repo *biometricKeyRepository) SelectActiveBiometricKeys(ctx context.Context, userID int64) ([]entity.BiometricKey, error) {
+	var keys []entity.BiometricKey
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&keys).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectActiveBiometricKeys.gorm.DB", err)
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (repo *biometricKeyRepository) RevokeBiometricKeysByDevice(ctx context.Context, tx *gorm.DB, userID int64, deviceID string) (int64, error) {
+	result := tx.WithContext(ctx).
+		Model(&entity.BiometricKey{}).
+		Where("user_id = ? AND device_id = ? AND revoked_at IS NULL", userID, deviceID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		repo.clogger.ErrorLogger(ctx, "RevokeBiometricKeysByDevice.gorm.DB", result.Error)
+	}
+	return result.RowsAffected, result.Error
+}
+
+func (repo *biometricKeyRepository) RevokeBiometricKeyByKeyID(ctx context.Context, tx *gorm.DB, userID int64, keyID string) (int64, error) {
+	result := tx.WithContext(ctx).
+		Model(&entity.BiometricKey{}).
+		Where("user_id = ? AND key_id = ? AND revoked_at IS NULL", userID, keyID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		repo.clogger.ErrorLogger(ctx, "RevokeBiometricKeyByKeyID.gorm.DB", result.Error)
+	}
+	return result.RowsAffected, result.Error
+}
+
+func (repo *biometricKeyRepository) UpdateBiometricKeyLastUsed(ctx context.Context, id uint) error {
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Model(&entity.BiometricKey{}).
+		Where("id = ?", id).
+		UpdateColumn("last_used_at", time.Now()).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "UpdateBiometricKeyLastUsed.gorm.DB", err)
+	}
+	return err
+}
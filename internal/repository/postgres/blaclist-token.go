@@ -13,7 +13,6 @@ type tokenBlacklistRepository struct {
 	clogger  *helpers.CustomLogger
 }
 type TokenBlacklistTokenRepository interface {
-	Tx(ctx context.Context) *gorm.DB
 	InsertBlaclistToken(ctx context.Context, tx *gorm.DB, blaclistToken *entity.TokenBlacklist) error
 	IsBlaclistTokenActive(ctx context.Context, token string) (bool, error)
 }
@@ -21,9 +20,6 @@ type TokenBlacklistTokenRepository interface {
 func NewTokenBlacklistTokenRepository(masterDb *gorm.DB, clogger *helpers.CustomLogger) TokenBlacklistTokenRepository {
 	return &tokenBlacklistRepository{masterDb: masterDb, clogger: clogger}
 }
-func (repo *tokenBlacklistRepository) Tx(ctx context.Context) *gorm.DB {
-	return repo.masterDb.Begin()
-}
 func (repo *tokenBlacklistRepository) InsertBlaclistToken(ctx context.Context, tx *gorm.DB, blaclistToken *entity.TokenBlacklist) error {
 	err := tx.Create(blaclistToken).Error
 	if err != nil {
@@ -31,9 +27,11 @@ func (repo *tokenBlacklistRepository) InsertBlaclistToken(ctx context.Context, t
 	}
 	return err
 }
+
+// IsBlaclistTokenActive takes the raw token, the blacklist only stores its sha256.
 func (repo *tokenBlacklistRepository) IsBlaclistTokenActive(ctx context.Context, token string) (bool, error) {
 	var count int64
-	err := repo.masterDb.Table("token_blacklists").Where("token = ? AND expired_at <= ?", token, time.Now()).Count(&count).Error
+	err := conn(ctx, repo.masterDb).Table("token_blacklists").Where("token = ? AND expired_at > ?", helpers.HashToken(token), time.Now()).Count(&count).Error
 	if err != nil {
 		return false, err
 	}
@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"backend-mobile-api/helpers"
+	"backend-mobile-api/model/entity"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type consentRepository struct {
+	masterDb *gorm.DB
+	clogger  *helpers.CustomLogger
+}
+
+func NewConsentRepository(posgres *gorm.DB, clogger *helpers.CustomLogger) ConsentRepository {
+	return &consentRepository{masterDb: posgres, clogger: clogger}
+}
+
+type ConsentRepository interface {
+	InsertLegalDocument(ctx context.Context, tx *gorm.DB, document *entity.LegalDocument) error
+	SelectLegalDocuments(ctx context.Context) ([]entity.LegalDocument, error)
+	SelectCurrentLegalDocuments(ctx context.Context, now time.Time) ([]entity.LegalDocument, error)
+	InsertUserConsents(ctx context.Context, tx *gorm.DB, consents []entity.UserConsent) error
+	SelectUserConsents(ctx context.Context, userID int64) ([]entity.UserConsent, error)
+}
+
+func (repo *consentRepository) InsertLegalDocument(ctx context.Context, tx *gorm.DB, document *entity.LegalDocument) error {
+	err := tx.WithContext(ctx).Create(document).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "InsertLegalDocument.gorm.DB", err)
+	}
+	return err
+}
+
+// SelectLegalDocuments returns every version, scheduled ones included, newest first per type.
+func (repo *consentRepository) SelectLegalDocuments(ctx context.Context) ([]entity.LegalDocument, error) {
+	var documents []entity.LegalDocument
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Order("type").
+		Order("published_at DESC").
+		Find(&documents).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectLegalDocuments.gorm.DB", err)
+	}
+	return documents, err
+}
+
+// SelectCurrentLegalDocuments returns the latest version of each type published before now.
+func (repo *consentRepository) SelectCurrentLegalDocuments(ctx context.Context, now time.Time) ([]entity.LegalDocument, error) {
+	var documents []entity.LegalDocument
+	err := conn(ctx, repo.masterDb).WithContext(ctx).Raw(`
+		SELECT DISTINCT ON (type) *
+		FROM legal_documents
+		WHERE deleted_at IS NULL AND published_at <= @now
+		ORDER BY type, published_at DESC, id DESC
+	`, map[string]interface{}{"now": now}).Scan(&documents).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectCurrentLegalDocuments.gorm.DB", err)
+	}
+	return documents, err
+}
+
+// InsertUserConsents skips the versions the user already accepted, the first
+// acceptance stays on record.
+func (repo *consentRepository) InsertUserConsents(ctx context.Context, tx *gorm.DB, consents []entity.UserConsent) error {
+	if len(consents) == 0 {
+		return nil
+	}
+	err := tx.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&consents).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "InsertUserConsents.gorm.DB", err)
+	}
+	return err
+}
+
+func (repo *consentRepository) SelectUserConsents(ctx context.Context, userID int64) ([]entity.UserConsent, error) {
+	var consents []entity.UserConsent
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Preload("Document").
+		Where("user_id = ?", userID).
+		Order("accepted_at DESC").
+		Find(&consents).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectUserConsents.gorm.DB", err)
+	}
+	return consents, err
+}
@@ -5,6 +5,7 @@ import (
 	"backend-mobile-api/model/entity"
 	"context"
 	"gorm.io/gorm"
+	"time"
 )
 
 type deviceRepository struct {
@@ -12,9 +13,13 @@ type deviceRepository struct {
 	clog     *helpers.CustomLogger
 }
 type DeviceRepository interface {
-	Tx(ctx context.Context) *gorm.DB
 	SelectDeviceByStruct(ctx context.Context, device *entity.Device) ([]entity.Device, error)
 	InsertDevice(ctx context.Context, tx *gorm.DB, device *entity.Device) error
+	SelectTrustedDevices(ctx context.Context, userID uint) ([]entity.Device, error)
+	SelectTrustedDevice(ctx context.Context, userID uint, deviceID string) (*entity.Device, error)
+	SelectTrustedDeviceByID(ctx context.Context, userID uint, id uint) (*entity.Device, error)
+	TrustDevice(ctx context.Context, tx *gorm.DB, device *entity.Device) error
+	RevokeTrustedDevice(ctx context.Context, tx *gorm.DB, userID uint, deviceID string) error
 }
 
 func NewDeviceRepository(db *gorm.DB, clog *helpers.CustomLogger) DeviceRepository {
@@ -24,12 +29,9 @@ func NewDeviceRepository(db *gorm.DB, clog *helpers.CustomLogger) DeviceReposito
 	}
 }
 
-func (repo *deviceRepository) Tx(ctx context.Context) *gorm.DB {
-	return repo.masterDb.Begin()
-}
 func (repo *deviceRepository) SelectDeviceByStruct(ctx context.Context, device *entity.Device) ([]entity.Device, error) {
 	var devicesData []entity.Device
-	err := repo.masterDb.WithContext(ctx).Where(device).Order("created_at DESC").Find(&devicesData).Error
+	err := conn(ctx, repo.masterDb).WithContext(ctx).Where(device).Order("created_at DESC").Find(&devicesData).Error
 	if err != nil {
 		repo.clog.ErrorLogger(ctx, "SelectDeviceByStruct.repo.masterDb.WithContext(ctx).Where(device).Find", err)
 		return nil, err
@@ -43,3 +45,79 @@ func (repo *deviceRepository) InsertDevice(ctx context.Context, tx *gorm.DB, dev
 	}
 	return err
 }
+
+func (repo *deviceRepository) SelectTrustedDevices(ctx context.Context, userID uint) ([]entity.Device, error) {
+	var devicesData []entity.Device
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Where("user_id = ? AND trusted_at IS NOT NULL AND revoked_at IS NULL", userID).
+		Order("trusted_at DESC").
+		Find(&devicesData).Error
+	if err != nil {
+		repo.clog.ErrorLogger(ctx, "SelectTrustedDevices.repo.masterDb.WithContext(ctx).Find", err)
+		return nil, err
+	}
+	return devicesData, nil
+}
+func (repo *deviceRepository) SelectTrustedDevice(ctx context.Context, userID uint, deviceID string) (*entity.Device, error) {
+	var device entity.Device
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Where("user_id = ? AND device_id = ? AND trusted_at IS NOT NULL AND revoked_at IS NULL", userID, deviceID).
+		First(&device).Error
+	if err != nil {
+		repo.clog.ErrorLogger(ctx, "SelectTrustedDevice.repo.masterDb.WithContext(ctx).First", err)
+		return nil, err
+	}
+	return &device, nil
+}
+func (repo *deviceRepository) SelectTrustedDeviceByID(ctx context.Context, userID uint, id uint) (*entity.Device, error) {
+	var device entity.Device
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Where("id = ? AND user_id = ? AND trusted_at IS NOT NULL AND revoked_at IS NULL", id, userID).
+		First(&device).Error
+	if err != nil {
+		repo.clog.ErrorLogger(ctx, "SelectTrustedDeviceByID.repo.masterDb.WithContext(ctx).First", err)
+		return nil, err
+	}
+	return &device, nil
+}
+
+// TrustDevice marks every row of the device as trusted again, a device seen for the first time is inserted.
+func (repo *deviceRepository) TrustDevice(ctx context.Context, tx *gorm.DB, device *entity.Device) error {
+	now := time.Now()
+	device.TrustedAt = &now
+	device.RevokedAt = nil
+	result := tx.WithContext(ctx).
+		Model(&entity.Device{}).
+		Where("user_id = ? AND device_id = ?", device.UserID, device.DeviceID).
+		Updates(map[string]interface{}{
+			"user_uuid":        device.UserUUID,
+			"app_version_code": device.AppVersionCode,
+			"app_version_name": device.AppVersionName,
+			"manufacturer":     device.Manufacturer,
+			"brand":            device.Brand,
+			"device_model":     device.DeviceModel,
+			"product":          device.Product,
+			"version_sdk":      device.VersionSdk,
+			"version_release":  device.VersionRelease,
+			"trusted_at":       now,
+			"revoked_at":       nil,
+		})
+	if result.Error != nil {
+		repo.clog.ErrorLogger(ctx, "TrustDevice.repo.masterDb.WithContext(ctx).Updates", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+	return repo.InsertDevice(ctx, tx, device)
+}
+func (repo *deviceRepository) RevokeTrustedDevice(ctx context.Context, tx *gorm.DB, userID uint, deviceID string) error {
+	err := tx.WithContext(ctx).
+		Model(&entity.Device{}).
+		Where("user_id = ? AND device_id = ? AND revoked_at IS NULL", userID, deviceID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		repo.clog.ErrorLogger(ctx, "RevokeTrustedDevice.repo.masterDb.WithContext(ctx).Update", err)
+	}
+	return err
+}
@@ -23,13 +23,7 @@ func (k *kycKtpRepository) SaveKYCKtp(ctx context.Context, orm *gorm.DB, ktp *en
 	return err
 }
 
-// Tx implements KycKtpRepository.
-func (k *kycKtpRepository) Tx(ctx context.Context) *gorm.DB {
-	return k.masterDb.Begin()
-}
-
 type KycKtpRepository interface {
-	Tx(ctx context.Context) *gorm.DB
 	SaveKYCKtp(ctx context.Context, orm *gorm.DB, ktp *entity.IdentityKtp) error
 }
 
@@ -23,13 +23,7 @@ func (k *kycPassportRepository) SaveKYCPassport(orm *gorm.DB, ctx context.Contex
 	return err
 }
 
-// Tx implements KycPassportRepository.
-func (k *kycPassportRepository) Tx(ctx context.Context) *gorm.DB {
-	return k.masterDb.Begin()
-}
-
 type KycPassportRepository interface {
-	Tx(ctx context.Context) *gorm.DB
 	SaveKYCPassport(orm *gorm.DB, ctx context.Context, ktp *entity.IdentityPassport) error
 }
 
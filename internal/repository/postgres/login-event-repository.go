@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"backend-mobile-api/helpers"
+	"backend-mobile-api/model/entity"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type loginEventRepository struct {
+	masterDb *gorm.DB
+	clogger  *helpers.CustomLogger
+}
+
+func NewLoginEventRepository(posgres *gorm.DB, clogger *helpers.CustomLogger) LoginEventRepository {
+	return &loginEventRepository{masterDb: posgres, clogger: clogger}
+}
+
+type LoginEventRepository interface {
+	InsertLoginEvent(ctx context.Context, tx *gorm.DB, event *entity.LoginEvent) error
+	SelectLoginEvents(ctx context.Context, userID int64, limit int, offset int) ([]entity.LoginEvent, int64, error)
+	SelectLastLoginEvent(ctx context.Context, where *entity.LoginEvent) (*entity.LoginEvent, error)
+	CountLoginEvents(ctx context.Context, where *entity.LoginEvent) (int64, error)
+}
+
+func (repo *loginEventRepository) InsertLoginEvent(ctx context.Context, tx *gorm.DB, event *entity.LoginEvent) error {
+	err := tx.WithContext(ctx).Create(event).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "InsertLoginEvent.gorm.DB", err)
+	}
+	return err
+}
+
+// SelectLoginEvents returns one page of the user's logins, newest first, with the total count.
+func (repo *loginEventRepository) SelectLoginEvents(ctx context.Context, userID int64, limit int, offset int) ([]entity.LoginEvent, int64, error) {
+	var (
+		events []entity.LoginEvent
+		total  int64
+	)
+	query := conn(ctx, repo.masterDb).WithContext(ctx).Model(&entity.LoginEvent{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectLoginEvents.gorm.DB", err)
+		return nil, 0, err
+	}
+	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&events).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectLoginEvents.gorm.DB", err)
+	}
+	return events, total, err
+}
+
+// SelectLastLoginEvent returns the latest event matching the non-zero fields, nil when there is none.
+func (repo *loginEventRepository) SelectLastLoginEvent(ctx context.Context, where *entity.LoginEvent) (*entity.LoginEvent, error) {
+	var event entity.LoginEvent
+	err := conn(ctx, repo.masterDb).WithContext(ctx).Where(where).Order("created_at DESC").First(&event).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		repo.clogger.ErrorLogger(ctx, "SelectLastLoginEvent.gorm.DB", err)
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (repo *loginEventRepository) CountLoginEvents(ctx context.Context, where *entity.LoginEvent) (int64, error) {
+	var count int64
+	err := conn(ctx, repo.masterDb).WithContext(ctx).Model(&entity.LoginEvent{}).Where(where).Count(&count).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "CountLoginEvents.gorm.DB", err)
+	}
+	return count, err
+}
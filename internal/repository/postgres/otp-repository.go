@@ -3,6 +3,7 @@ package postgres
 import (
 	"backend-mobile-api/helpers"
 	"backend-mobile-api/model/entity"
+	"backend-mobile-api/model/enum"
 	"context"
 	"gorm.io/gorm"
 	"time"
@@ -19,18 +20,19 @@ func NewOtpRepository(posgres *gorm.DB, clogger *helpers.CustomLogger) OtpReposi
 
 type OtpRepository interface {
 	InsertOtpDataRepository(ctx context.Context, tx *gorm.DB, otpData *entity.OTP) error
-	Tx(ctx context.Context) *gorm.DB
 	SelectOtpBySessionId(ctx context.Context, sessionId string) (*entity.OTP, error)
 	SelectOtpByVerifyKey(ctx context.Context, verifyKey string) (*entity.OTP, error)
 	SelectOtpByVerifyKeyBeforeExpire(ctx context.Context, verifyKey string) (*entity.OTP, error)
 	UpdateOtpDataRepository(ctx context.Context, tx *gorm.DB, otpData *entity.OTP, updater *entity.OTP) error
+	ExpireOtpByVerifyKey(ctx context.Context, tx *gorm.DB, verifyKey string) error
+	UpdateOpenOtpStatus(ctx context.Context, tx *gorm.DB, otpData *entity.OTP, status enum.OTPStatus) (int64, error)
+	InsertOtpDeliveryEvent(ctx context.Context, tx *gorm.DB, event *entity.OtpDeliveryEvent) error
+	SelectOtpDeliveryEvents(ctx context.Context, verifyKey string) ([]entity.OtpDeliveryEvent, error)
+	SummarizeOtpDelivery(ctx context.Context, from time.Time, to time.Time) ([]entity.OtpChannelMetric, error)
 }
 
-func (repo *otpRepository) Tx(ctx context.Context) *gorm.DB {
-	return repo.masterDb.Begin()
-}
 func (r *otpRepository) InsertOtpDataRepository(ctx context.Context, tx *gorm.DB, otpData *entity.OTP) error {
-	err := r.masterDb.Create(otpData).Error
+	err := conn(ctx, r.masterDb).Create(otpData).Error
 	if err != nil {
 		r.clogger.ErrorLogger(ctx, "InsertOtpData.masterDb.Create", err)
 	}
@@ -38,7 +40,7 @@ func (r *otpRepository) InsertOtpDataRepository(ctx context.Context, tx *gorm.DB
 }
 func (repo *otpRepository) SelectOtpBySessionId(ctx context.Context, sessionId string) (*entity.OTP, error) {
 	var otp entity.OTP
-	err := repo.masterDb.Table("otps").Where("session_id = ?", sessionId).Order("created_at DESC").First(&otp).Error
+	err := conn(ctx, repo.masterDb).Table("otps").Where("session_id = ?", sessionId).Order("created_at DESC").First(&otp).Error
 	if err != nil {
 		repo.clogger.ErrorLogger(ctx, "SelectOtpBySessionId.gorm.DB", err)
 		return nil, err
@@ -47,7 +49,7 @@ func (repo *otpRepository) SelectOtpBySessionId(ctx context.Context, sessionId s
 }
 func (repo *otpRepository) SelectOtpByVerifyKey(ctx context.Context, verifyKey string) (*entity.OTP, error) {
 	var otp entity.OTP
-	err := repo.masterDb.Table("otps").Where("verify_key = ?", verifyKey).Order("created_at DESC").First(&otp).Error
+	err := conn(ctx, repo.masterDb).Table("otps").Where("verify_key = ?", verifyKey).Order("created_at DESC").First(&otp).Error
 	if err != nil {
 		repo.clogger.ErrorLogger(ctx, "SelectOtpByVerifyKey.gorm.DB", err)
 		return nil, err
@@ -63,10 +65,93 @@ func (repo *otpRepository) UpdateOtpDataRepository(ctx context.Context, tx *gorm
 }
 func (repo *otpRepository) SelectOtpByVerifyKeyBeforeExpire(ctx context.Context, verifyKey string) (*entity.OTP, error) {
 	var otp entity.OTP
-	err := repo.masterDb.Table("otps").Where("verify_key = ? AND expired_at > ?", verifyKey, time.Now()).Order("created_at DESC").First(&otp).Error
+	err := conn(ctx, repo.masterDb).Table("otps").Where("verify_key = ? AND expired_at > ?", verifyKey, time.Now()).Order("created_at DESC").First(&otp).Error
 	if err != nil {
 		repo.clogger.ErrorLogger(ctx, "SelectOtpByVerifyKeyBeforeExpire.gorm.DB", err)
 		return nil, err
 	}
 	return &otp, nil
 }
+
+// ExpireOtpByVerifyKey ends every code sent under a verify key, one of them was verified.
+func (repo *otpRepository) ExpireOtpByVerifyKey(ctx context.Context, tx *gorm.DB, verifyKey string) error {
+	err := tx.WithContext(ctx).
+		Model(&entity.OTP{}).
+		Where("verify_key = ? AND expired_at > ?", verifyKey, time.Now()).
+		Update("expired_at", time.Now()).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "ExpireOtpByVerifyKey.gorm.DB", err)
+	}
+	return err
+}
+
+// UpdateOpenOtpStatus writes a delivery report on an otp that is neither verified
+// nor expired, the returned row count tells a late or replayed report apart.
+func (repo *otpRepository) UpdateOpenOtpStatus(ctx context.Context, tx *gorm.DB, otpData *entity.OTP, status enum.OTPStatus) (int64, error) {
+	result := tx.WithContext(ctx).
+		Model(&entity.OTP{}).
+		Where("id = ? AND status <> ? AND expired_at > ?", otpData.ID, enum.OTP_VERIFIED, time.Now()).
+		Update("status", status)
+	if result.Error != nil {
+		repo.clogger.ErrorLogger(ctx, "UpdateOpenOtpStatus.gorm.DB", result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+func (repo *otpRepository) InsertOtpDeliveryEvent(ctx context.Context, tx *gorm.DB, event *entity.OtpDeliveryEvent) error {
+	err := tx.WithContext(ctx).Create(event).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "InsertOtpDeliveryEvent.gorm.DB", err)
+	}
+	return err
+}
+
+// SelectOtpDeliveryEvents returns the delivery timeline of a verify key across every channel it was sent on.
+func (repo *otpRepository) SelectOtpDeliveryEvents(ctx context.Context, verifyKey string) ([]entity.OtpDeliveryEvent, error) {
+	var events []entity.OtpDeliveryEvent
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Where("verify_key = ?", verifyKey).
+		Order("created_at ASC, id ASC").
+		Find(&events).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectOtpDeliveryEvents.gorm.DB", err)
+		return nil, err
+	}
+	return events, nil
+}
+
+// SummarizeOtpDelivery counts the otps sent between from and to per channel, an
+// otp is counted once per bucket whatever the number of reports it received.
+func (repo *otpRepository) SummarizeOtpDelivery(ctx context.Context, from time.Time, to time.Time) ([]entity.OtpChannelMetric, error) {
+	var metrics []entity.OtpChannelMetric
+	err := conn(ctx, repo.masterDb).WithContext(ctx).Raw(`
+		SELECT e.otp_method,
+		       COUNT(DISTINCT e.otp_id) AS sent,
+		       COUNT(DISTINCT e.otp_id) FILTER (WHERE e.status IN @delivered) AS delivered,
+		       COUNT(DISTINCT e.otp_id) FILTER (WHERE e.status IN @failed) AS failed,
+		       COUNT(DISTINCT e.otp_id) FILTER (WHERE e.status = @verified) AS verified,
+		       COUNT(DISTINCT e.otp_id) FILTER (WHERE (e.otp_method = @sms AND e.status IN @sms_charged)
+		                                         OR (e.otp_method = @whatsapp AND e.status IN @whatsapp_charged)) AS charged
+		FROM otp_delivery_events e
+		JOIN otps o ON o.id = e.otp_id
+		WHERE o.created_at >= @from AND o.created_at < @to AND e.deleted_at IS NULL
+		GROUP BY e.otp_method
+		ORDER BY e.otp_method`,
+		map[string]interface{}{
+			"delivered":        enum.OtpDeliveryConfirmedStatus,
+			"failed":           enum.OtpDeliveryFailedStatus,
+			"verified":         enum.OTP_VERIFIED,
+			"sms":              enum.TYPE_SMS,
+			"sms_charged":      enum.OtpSMSChargedStatusVerihubs,
+			"whatsapp":         enum.TYPE_WHATSAPP,
+			"whatsapp_charged": enum.OtpWhatsappChargedStatusVerihubs,
+			"from":             from,
+			"to":               to,
+		}).Scan(&metrics).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SummarizeOtpDelivery.gorm.DB", err)
+		return nil, err
+	}
+	return metrics, nil
+}
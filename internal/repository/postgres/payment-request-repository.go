@@ -0,0 +1,209 @@
+package postgres
+
+import (
+	"backend-mobile-api/helpers"
+	"backend-mobile-api/model/entity"
+	"backend-mobile-api/model/enum"
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type paymentRequestRepository struct {
+	masterDb *gorm.DB
+	clogger  *helpers.CustomLogger
+}
+type PaymentRequestRepository interface {
+	InsertPaymentRequest(ctx context.Context, tx *gorm.DB, paymentRequest *entity.PaymentRequest) error
+	SelectPaymentRequestByRequestID(ctx context.Context, requestID string) (*entity.PaymentRequest, error)
+	SelectPaymentRequestByTransactionID(ctx context.Context, transactionID string) (*entity.PaymentRequest, error)
+	SelectPaymentRequestsByRequester(ctx context.Context, requesterUUID string) ([]entity.PaymentRequest, error)
+	SelectPaymentRequestsByParticipant(ctx context.Context, userUUID string) ([]entity.PaymentRequest, error)
+	UpdatePendingParticipant(ctx context.Context, tx *gorm.DB, participant *entity.PaymentRequestParticipant, newParticipant *entity.PaymentRequestParticipant) (int64, error)
+	UpdateAwaitingParticipant(ctx context.Context, tx *gorm.DB, participant *entity.PaymentRequestParticipant, newParticipant *entity.PaymentRequestParticipant) (int64, error)
+	ReleaseAwaitingParticipant(ctx context.Context, tx *gorm.DB, participant *entity.PaymentRequestParticipant) (int64, error)
+	UpdatePaymentRequest(ctx context.Context, tx *gorm.DB, paymentRequest *entity.PaymentRequest, newPaymentRequest *entity.PaymentRequest) error
+	LockPaymentRequest(ctx context.Context, tx *gorm.DB, paymentRequestID uint) (*entity.PaymentRequest, error)
+	SelectParticipants(ctx context.Context, tx *gorm.DB, paymentRequestID uint) ([]entity.PaymentRequestParticipant, error)
+	ExpirePaymentRequests(ctx context.Context) error
+}
+
+func NewPaymentRequestRepository(db *gorm.DB, clogger *helpers.CustomLogger) PaymentRequestRepository {
+	return &paymentRequestRepository{
+		masterDb: db,
+		clogger:  clogger,
+	}
+}
+
+func (repo *paymentRequestRepository) InsertPaymentRequest(ctx context.Context, tx *gorm.DB, paymentRequest *entity.PaymentRequest) error {
+	err := tx.WithContext(ctx).Create(paymentRequest).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "InsertPaymentRequest.gorm.DB", err)
+	}
+	return err
+}
+
+func (repo *paymentRequestRepository) SelectPaymentRequestByRequestID(ctx context.Context, requestID string) (*entity.PaymentRequest, error) {
+	var paymentRequest entity.PaymentRequest
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Preload("Participants").
+		Where("request_id = ?", requestID).
+		First(&paymentRequest).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectPaymentRequestByRequestID.gorm.DB", err)
+		return nil, err
+	}
+	return &paymentRequest, nil
+}
+
+// SelectPaymentRequestByTransactionID finds the request a participant pays with the transaction.
+func (repo *paymentRequestRepository) SelectPaymentRequestByTransactionID(ctx context.Context, transactionID string) (*entity.PaymentRequest, error) {
+	var paymentRequest entity.PaymentRequest
+	paid := conn(ctx, repo.masterDb).Model(&entity.PaymentRequestParticipant{}).
+		Select("payment_request_id").
+		Where("transaction_id = ?", transactionID)
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Preload("Participants").
+		Where("id IN (?)", paid).
+		First(&paymentRequest).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectPaymentRequestByTransactionID.gorm.DB", err)
+		return nil, err
+	}
+	return &paymentRequest, nil
+}
+
+func (repo *paymentRequestRepository) SelectPaymentRequestsByRequester(ctx context.Context, requesterUUID string) ([]entity.PaymentRequest, error) {
+	var paymentRequests []entity.PaymentRequest
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Preload("Participants").
+		Where("requester_uuid = ?", requesterUUID).
+		Order("created_at DESC").
+		Find(&paymentRequests).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectPaymentRequestsByRequester.gorm.DB", err)
+		return nil, err
+	}
+	return paymentRequests, nil
+}
+
+func (repo *paymentRequestRepository) SelectPaymentRequestsByParticipant(ctx context.Context, userUUID string) ([]entity.PaymentRequest, error) {
+	var paymentRequests []entity.PaymentRequest
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Preload("Participants").
+		Where("id IN (?)", conn(ctx, repo.masterDb).Model(&entity.PaymentRequestParticipant{}).
+			Select("payment_request_id").
+			Where("user_uuid = ?", userUUID)).
+		Order("created_at DESC").
+		Find(&paymentRequests).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectPaymentRequestsByParticipant.gorm.DB", err)
+		return nil, err
+	}
+	return paymentRequests, nil
+}
+
+// UpdatePendingParticipant only touches a participant that is still PENDING, the
+// returned row count tells the caller whether another request already answered it.
+func (repo *paymentRequestRepository) UpdatePendingParticipant(ctx context.Context, tx *gorm.DB, participant *entity.PaymentRequestParticipant, newParticipant *entity.PaymentRequestParticipant) (int64, error) {
+	result := tx.WithContext(ctx).
+		Model(participant).
+		Where("status = ?", enum.PARTICIPANT_PENDING).
+		Updates(newParticipant)
+	if result.Error != nil {
+		repo.clogger.ErrorLogger(ctx, "UpdatePendingParticipant.gorm.DB", result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// UpdateAwaitingParticipant only updates a participant still waiting on the transaction it paid with.
+func (repo *paymentRequestRepository) UpdateAwaitingParticipant(ctx context.Context, tx *gorm.DB, participant *entity.PaymentRequestParticipant, newParticipant *entity.PaymentRequestParticipant) (int64, error) {
+	result := tx.WithContext(ctx).
+		Model(participant).
+		Where("status = ? AND transaction_id = ?", enum.PARTICIPANT_AWAITING_PAYMENT, participant.TransactionID).
+		Updates(newParticipant)
+	if result.Error != nil {
+		repo.clogger.ErrorLogger(ctx, "UpdateAwaitingParticipant.gorm.DB", result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// ReleaseAwaitingParticipant gives a participant whose transaction did not go through its pending answer back.
+func (repo *paymentRequestRepository) ReleaseAwaitingParticipant(ctx context.Context, tx *gorm.DB, participant *entity.PaymentRequestParticipant) (int64, error) {
+	result := tx.WithContext(ctx).
+		Model(participant).
+		Where("status = ? AND transaction_id = ?", enum.PARTICIPANT_AWAITING_PAYMENT, participant.TransactionID).
+		Updates(map[string]interface{}{
+			"status":         enum.PARTICIPANT_PENDING,
+			"transaction_id": nil,
+			"responded_at":   nil,
+		})
+	if result.Error != nil {
+		repo.clogger.ErrorLogger(ctx, "ReleaseAwaitingParticipant.gorm.DB", result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+func (repo *paymentRequestRepository) UpdatePaymentRequest(ctx context.Context, tx *gorm.DB, paymentRequest *entity.PaymentRequest, newPaymentRequest *entity.PaymentRequest) error {
+	err := tx.WithContext(ctx).Model(paymentRequest).Omit("Participants").Updates(newPaymentRequest).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "UpdatePaymentRequest.gorm.DB", err)
+	}
+	return err
+}
+
+// LockPaymentRequest reads the request with SELECT ... FOR UPDATE, answers to
+// the same request wait for each other until the first one committed.
+func (repo *paymentRequestRepository) LockPaymentRequest(ctx context.Context, tx *gorm.DB, paymentRequestID uint) (*entity.PaymentRequest, error) {
+	var paymentRequest entity.PaymentRequest
+	err := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", paymentRequestID).
+		First(&paymentRequest).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "LockPaymentRequest.gorm.DB", err)
+		return nil, err
+	}
+	return &paymentRequest, nil
+}
+
+// SelectParticipants reads the participants of a request as they are in tx.
+func (repo *paymentRequestRepository) SelectParticipants(ctx context.Context, tx *gorm.DB, paymentRequestID uint) ([]entity.PaymentRequestParticipant, error) {
+	var participants []entity.PaymentRequestParticipant
+	err := tx.WithContext(ctx).
+		Where("payment_request_id = ?", paymentRequestID).
+		Find(&participants).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectParticipants.gorm.DB", err)
+		return nil, err
+	}
+	return participants, nil
+}
+
+// ExpirePaymentRequests closes open requests past their expiry together with every participant that never answered.
+func (repo *paymentRequestRepository) ExpirePaymentRequests(ctx context.Context) error {
+	now := time.Now()
+	return conn(ctx, repo.masterDb).WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		expired := tx.Model(&entity.PaymentRequest{}).
+			Select("id").
+			Where("status = ? AND expired_at < ?", enum.PAYMENT_REQUEST_OPEN, now)
+		err := tx.Model(&entity.PaymentRequestParticipant{}).
+			Where("status = ? AND payment_request_id IN (?)", enum.PARTICIPANT_PENDING, expired).
+			Update("status", enum.PARTICIPANT_EXPIRED).Error
+		if err != nil {
+			repo.clogger.ErrorLogger(ctx, "ExpirePaymentRequests.participants.gorm.DB", err)
+			return err
+		}
+		err = tx.Model(&entity.PaymentRequest{}).
+			Where("status = ? AND expired_at < ?", enum.PAYMENT_REQUEST_OPEN, now).
+			Update("status", enum.PAYMENT_REQUEST_EXPIRED).Error
+		if err != nil {
+			repo.clogger.ErrorLogger(ctx, "ExpirePaymentRequests.gorm.DB", err)
+		}
+		return err
+	})
+}
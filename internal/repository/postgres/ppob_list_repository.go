@@ -24,7 +24,7 @@ func NewPpobListRepository(masterDb *gorm.DB, clogger *helpers.CustomLogger) Ppo
 
 func (r *ppobListRepository) GetAllPpob(ctx context.Context) ([]entity.PPOB, error) {
 	var banks []entity.PPOB
-	err := r.masterDb.WithContext(ctx).
+	err := conn(ctx, r.masterDb).WithContext(ctx).
 		Order("name_provider ASC"). // pastikan sesuai dengan entity.Bank
 		Find(&banks).Error
 	if err != nil {
@@ -36,7 +36,7 @@ func (r *ppobListRepository) GetAllPpob(ctx context.Context) ([]entity.PPOB, err
 
 func (r *ppobListRepository) SearchPpob(ctx context.Context, keyword string) ([]entity.PPOB, error) {
 	var banks []entity.PPOB
-	err := r.masterDb.WithContext(ctx).
+	err := conn(ctx, r.masterDb).WithContext(ctx).
 		Where("name_provider ILIKE ?", "%"+keyword+"%").
 		Order("name_provider ASC").
 		Find(&banks).Error
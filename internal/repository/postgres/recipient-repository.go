@@ -5,16 +5,22 @@ import (
 	"backend-mobile-api/model/entity"
 	"context"
 	"log"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type RecipientRepository interface {
-	GetAllRecipients(ctx context.Context) ([]entity.RecipientWithBank, error)
-	SearchRecipients(ctx context.Context, keyword string) ([]entity.RecipientWithBank, error)
+	GetAllRecipients(ctx context.Context, userID int64) ([]entity.RecipientWithBank, error)
+	SearchRecipients(ctx context.Context, userID int64, keyword string) ([]entity.RecipientWithBank, error)
 	InsertRecipient(ctx context.Context, recipient *entity.Recipient) error
 	FindUserByUUID(ctx context.Context, uuid string) (*entity.User, error) // ✅ tambahan
-
+	FindRecipientByID(ctx context.Context, userID int64, recipientID uint) (*entity.Recipient, error)
+	FindRecipientByAccount(ctx context.Context, userID int64, bankID int, noRekening string) (*entity.Recipient, error)
+	FindRecipientByBankName(ctx context.Context, userID int64, bankName string, noRekening string) (*entity.Recipient, error)
+	UpdateRecipient(ctx context.Context, recipient *entity.Recipient, updates map[string]interface{}) error
+	DeleteRecipient(ctx context.Context, recipient *entity.Recipient) error
+	GetRecipientSuggestions(ctx context.Context, userID int64, since time.Time, halfLifeDays float64, limit int) ([]entity.RecipientSuggestion, error)
 }
 
 type recipientRepository struct {
@@ -32,21 +38,25 @@ func NewRecipientRepository(masterDb *gorm.DB, clogger *helpers.CustomLogger) Re
 	return &recipientRepository{masterDb: masterDb, clogger: clogger}
 }
 
-// ✅ Ambil semua recipient beserta url_image bank
-func (r *recipientRepository) GetAllRecipients(ctx context.Context) ([]entity.RecipientWithBank, error) {
+// ✅ Ambil semua recipient milik user beserta url_image bank, favorit di urutan atas
+func (r *recipientRepository) GetAllRecipients(ctx context.Context, userID int64) ([]entity.RecipientWithBank, error) {
 	var results []entity.RecipientWithBank
 
-	err := r.masterDb.WithContext(ctx).
+	err := conn(ctx, r.masterDb).WithContext(ctx).
 		Table("tb_recipient as r").
 		Select(`r.recipient_id, 
 		        r.nama_penerima, 
 		        r.no_rekening, 
 		        r.user_id,
 		        r.bank_id, 
+		        r.alias,
+		        r.is_favorite,
 		        b.url_image as bank_image_url,
 		        b.nama_bank as nama_bank
 				`).
 		Joins("LEFT JOIN tb_bank_list b ON r.bank_id = b.bank_id").
+		Where("r.user_id = ?", userID).
+		Order("r.is_favorite DESC, r.recipient_id DESC").
 		Scan(&results).Error
 
 	if err != nil {
@@ -56,22 +66,26 @@ func (r *recipientRepository) GetAllRecipients(ctx context.Context) ([]entity.Re
 	return results, nil
 }
 
-// ✅ Search berdasarkan nama penerima / no rekening
-func (r *recipientRepository) SearchRecipients(ctx context.Context, keyword string) ([]entity.RecipientWithBank, error) {
+// ✅ Search recipient milik user berdasarkan nama penerima / alias / no rekening
+func (r *recipientRepository) SearchRecipients(ctx context.Context, userID int64, keyword string) ([]entity.RecipientWithBank, error) {
 	var recipients []entity.RecipientWithBank
 
-	err := r.masterDb.WithContext(ctx).
+	err := conn(ctx, r.masterDb).WithContext(ctx).
 		Table("tb_recipient as r").
 		Select(`recipient_id, 
 		        r.nama_penerima, 
 		        r.no_rekening, 
 		        r.user_id,
 		        r.bank_id, 
+		        r.alias,
+		        r.is_favorite,
 		        b.url_image as bank_image_url,
 		        b.nama_bank as nama_bank
 				`).
 		Joins("LEFT JOIN tb_bank_list b ON r.bank_id = b.bank_id").
-		Where("LOWER(r.nama_penerima) LIKE ? OR r.no_rekening LIKE ?", "%"+keyword+"%", "%"+keyword+"%").
+		Where("r.user_id = ?", userID).
+		Where("LOWER(r.nama_penerima) LIKE LOWER(?) OR LOWER(r.alias) LIKE LOWER(?) OR r.no_rekening LIKE ?", "%"+keyword+"%", "%"+keyword+"%", "%"+keyword+"%").
+		Order("r.is_favorite DESC, r.recipient_id DESC").
 		Scan(&recipients).Error
 
 	if err != nil {
@@ -83,7 +97,7 @@ func (r *recipientRepository) SearchRecipients(ctx context.Context, keyword stri
 
 // ✅ Insert hanya ke tb_recipient
 func (r *recipientRepository) InsertRecipient(ctx context.Context, recipient *entity.Recipient) error {
-	err := r.masterDb.WithContext(ctx).Create(recipient).Error
+	err := conn(ctx, r.masterDb).WithContext(ctx).Create(recipient).Error
 	if err != nil {
 		r.clogger.ErrorLogger(ctx, "InsertRecipient", err)
 	}
@@ -93,10 +107,141 @@ func (r *recipientRepository) InsertRecipient(ctx context.Context, recipient *en
 // ✅ Cari user by UUID (biar dapet user_id dari DB)
 func (r *recipientRepository) FindUserByUUID(ctx context.Context, uuid string) (*entity.User, error) {
 	var user entity.User
-	err := r.masterDb.WithContext(ctx).Where("uuid = ?", uuid).First(&user).Error
+	err := conn(ctx, r.masterDb).WithContext(ctx).Where("uuid = ?", uuid).First(&user).Error
 	if err != nil {
 		r.clogger.ErrorLogger(ctx, "FindUserByUUID", err)
 		return nil, err
 	}
 	return &user, nil
 }
+
+// ✅ Cari recipient by id, hanya milik user tersebut
+func (r *recipientRepository) FindRecipientByID(ctx context.Context, userID int64, recipientID uint) (*entity.Recipient, error) {
+	var recipient entity.Recipient
+	err := conn(ctx, r.masterDb).WithContext(ctx).
+		Where("recipient_id = ? AND user_id = ?", recipientID, userID).
+		First(&recipient).Error
+	if err != nil {
+		r.clogger.ErrorLogger(ctx, "FindRecipientByID", err)
+		return nil, err
+	}
+	return &recipient, nil
+}
+
+// ✅ Cari recipient dengan bank & no rekening yang sama milik user (cek duplikat)
+func (r *recipientRepository) FindRecipientByAccount(ctx context.Context, userID int64, bankID int, noRekening string) (*entity.Recipient, error) {
+	var recipient entity.Recipient
+	err := conn(ctx, r.masterDb).WithContext(ctx).
+		Where("user_id = ? AND bank_id = ? AND no_rekening = ?", userID, bankID, noRekening).
+		First(&recipient).Error
+	if err != nil {
+		return nil, err
+	}
+	return &recipient, nil
+}
+
+// ✅ Cari recipient milik user dari nama bank & no rekening yang ada di detail transfer
+func (r *recipientRepository) FindRecipientByBankName(ctx context.Context, userID int64, bankName string, noRekening string) (*entity.Recipient, error) {
+	var recipient entity.Recipient
+	err := conn(ctx, r.masterDb).WithContext(ctx).
+		Table("tb_recipient as r").
+		Select("r.*").
+		Joins("JOIN tb_bank_list b ON r.bank_id = b.bank_id").
+		Where("r.user_id = ? AND r.no_rekening = ? AND LOWER(b.nama_bank) = LOWER(?)", userID, noRekening, bankName).
+		Order("r.created_at ASC").
+		Take(&recipient).Error
+	if err != nil {
+		return nil, err
+	}
+	return &recipient, nil
+}
+
+// ✅ Update recipient, map dipakai supaya nilai false / kosong tetap tersimpan
+func (r *recipientRepository) UpdateRecipient(ctx context.Context, recipient *entity.Recipient, updates map[string]interface{}) error {
+	err := conn(ctx, r.masterDb).WithContext(ctx).
+		Model(recipient).
+		Where("user_id = ?", recipient.User).
+		Updates(updates).Error
+	if err != nil {
+		r.clogger.ErrorLogger(ctx, "UpdateRecipient", err)
+	}
+	return err
+}
+
+// ✅ Hapus recipient milik user
+func (r *recipientRepository) DeleteRecipient(ctx context.Context, recipient *entity.Recipient) error {
+	err := conn(ctx, r.masterDb).WithContext(ctx).
+		Where("user_id = ?", recipient.User).
+		Delete(recipient).Error
+	if err != nil {
+		r.clogger.ErrorLogger(ctx, "DeleteRecipient", err)
+	}
+	return err
+}
+
+// ✅ Ranking tujuan transaksi dari riwayat transaksi sukses user.
+// score = jumlah transaksi * 0.5^(umur transaksi terakhir / halfLifeDays), jadi tujuan yang sering
+// dan baru dipakai ada di atas. last_amount diambil dari transaksi terakhir ke tujuan tersebut.
+func (r *recipientRepository) GetRecipientSuggestions(ctx context.Context, userID int64, since time.Time, halfLifeDays float64, limit int) ([]entity.RecipientSuggestion, error) {
+	var results []entity.RecipientSuggestion
+
+	err := conn(ctx, r.masterDb).WithContext(ctx).Raw(`
+		WITH history AS (
+			SELECT 'bank_transfer' AS type, bt.recipient_name, bt.account_number, bt.bank_name AS provider,
+			       bt.image_url, t.nominal, t.transaction_id, t.created_at
+			FROM transactions t
+			JOIN transaction_bank_transfer bt ON bt.transaction_id = t.transaction_id
+			WHERE t.user_id = @user_id AND t.status = 'success' AND t.created_at >= @since
+			UNION ALL
+			SELECT 'ewallet', ew.recipient_name, ew.account_number, ew.ewallet_name,
+			       ew.image_url, t.nominal, t.transaction_id, t.created_at
+			FROM transactions t
+			JOIN transaction_ewallet ew ON ew.transaction_id = t.transaction_id
+			WHERE t.user_id = @user_id AND t.status = 'success' AND t.created_at >= @since
+			UNION ALL
+			SELECT 'phone_credit', '', pc.phone_number, pc.product_name,
+			       '', t.nominal, t.transaction_id, t.created_at
+			FROM transactions t
+			JOIN transaction_phone_credit pc ON pc.transaction_id = t.transaction_id
+			WHERE t.user_id = @user_id AND t.status = 'success' AND t.created_at >= @since
+		), ranked AS (
+			SELECT h.*,
+			       ROW_NUMBER() OVER (w ORDER BY h.created_at DESC) AS rn,
+			       COUNT(*) OVER (w) AS transaction_count
+			FROM history h
+			-- produk pulsa beda-beda tiap beli, jadi nomor hp cukup dikelompokkan per nomor
+			WINDOW w AS (PARTITION BY h.type, h.account_number, CASE WHEN h.type = 'phone_credit' THEN '' ELSE LOWER(h.provider) END)
+		)
+		SELECT rk.type,
+		       (SELECT rc.recipient_id FROM tb_recipient rc
+		        JOIN tb_bank_list b ON b.bank_id = rc.bank_id
+		        WHERE rk.type = 'bank_transfer' AND rc.user_id = @user_id
+		          AND rc.no_rekening = rk.account_number AND LOWER(b.nama_bank) = LOWER(rk.provider)
+		        LIMIT 1) AS recipient_id,
+		       rk.recipient_name,
+		       rk.account_number,
+		       rk.provider,
+		       rk.image_url,
+		       rk.nominal AS last_amount,
+		       rk.transaction_id AS last_transaction_id,
+		       rk.created_at AS last_transaction_at,
+		       rk.transaction_count,
+		       rk.transaction_count * POWER(0.5, EXTRACT(EPOCH FROM (NOW() - rk.created_at)) / 86400.0 / @half_life) AS score
+		FROM ranked rk
+		WHERE rk.rn = 1
+		ORDER BY score DESC, rk.created_at DESC
+		LIMIT @limit`,
+		map[string]interface{}{
+			"user_id":   userID,
+			"since":     since,
+			"half_life": halfLifeDays,
+			"limit":     limit,
+		}).
+		Scan(&results).Error
+
+	if err != nil {
+		r.clogger.ErrorLogger(ctx, "GetRecipientSuggestions", err)
+		return nil, err
+	}
+	return results, nil
+}
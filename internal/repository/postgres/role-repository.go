@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"backend-mobile-api/helpers"
+	"backend-mobile-api/model/entity"
+	"backend-mobile-api/model/enum"
+	"context"
+	"gorm.io/gorm"
+)
+
+type roleRepository struct {
+	masterDb *gorm.DB
+	clogger  *helpers.CustomLogger
+}
+type RoleRepository interface {
+	SelectRolesByUserID(ctx context.Context, userID int64) ([]entity.Role, error)
+	SelectRoleByName(ctx context.Context, name enum.RolesEnum) (*entity.Role, error)
+	AssignUserRole(ctx context.Context, tx *gorm.DB, userID int64, roleID uint) error
+	RemoveUserRole(ctx context.Context, tx *gorm.DB, userID int64, roleID uint) error
+}
+
+func NewRoleRepository(db *gorm.DB, clogger *helpers.CustomLogger) RoleRepository {
+	return &roleRepository{
+		masterDb: db,
+		clogger:  clogger,
+	}
+}
+
+// SelectRolesByUserID returns the roles of a user with their permissions.
+func (repo *roleRepository) SelectRolesByUserID(ctx context.Context, userID int64) ([]entity.Role, error) {
+	var roles []entity.Role
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Preload("Permissions").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.id").
+		Find(&roles).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectRolesByUserID.gorm.DB", err)
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (repo *roleRepository) SelectRoleByName(ctx context.Context, name enum.RolesEnum) (*entity.Role, error) {
+	var role entity.Role
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Where("name = ?", name).
+		First(&role).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectRoleByName.gorm.DB", err)
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (repo *roleRepository) AssignUserRole(ctx context.Context, tx *gorm.DB, userID int64, roleID uint) error {
+	err := tx.WithContext(ctx).
+		Exec("INSERT INTO user_roles (role_id, user_id) VALUES (?, ?) ON CONFLICT DO NOTHING", roleID, userID).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "AssignUserRole.gorm.DB", err)
+	}
+	return err
+}
+
+func (repo *roleRepository) RemoveUserRole(ctx context.Context, tx *gorm.DB, userID int64, roleID uint) error {
+	err := tx.WithContext(ctx).
+		Exec("DELETE FROM user_roles WHERE role_id = ? AND user_id = ?", roleID, userID).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "RemoveUserRole.gorm.DB", err)
+	}
+	return err
+}
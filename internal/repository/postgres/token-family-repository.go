@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"backend-mobile-api/helpers"
+	"backend-mobile-api/model/entity"
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+type tokenFamilyRepository struct {
+	masterDb *gorm.DB
+	clogger  *helpers.CustomLogger
+}
+type TokenFamilyRepository interface {
+	InsertTokenFamily(ctx context.Context, tx *gorm.DB, family *entity.TokenFamily) error
+	InsertFamilyToken(ctx context.Context, tx *gorm.DB, token *entity.TokenFamilyToken) error
+	SelectTokenFamilyByFamilyID(ctx context.Context, familyID string) (*entity.TokenFamily, error)
+	SelectFamilyTokenByRefreshHash(ctx context.Context, refreshTokenHash string) (*entity.TokenFamilyToken, error)
+	SelectActiveFamiliesByDevice(ctx context.Context, userUUID string, deviceID string) ([]entity.TokenFamily, error)
+	SelectActiveFamiliesByUser(ctx context.Context, userUUID string) ([]entity.TokenFamily, error)
+	UpdateTokenFamilySession(ctx context.Context, tx *gorm.DB, family *entity.TokenFamily) error
+	SelectUnexpiredFamilyTokens(ctx context.Context, familyID string) ([]entity.TokenFamilyToken, error)
+	MarkFamilyTokenUsed(ctx context.Context, tx *gorm.DB, tokenID string) (int64, error)
+	RevokeTokenFamily(ctx context.Context, tx *gorm.DB, familyID string, reason string) error
+}
+
+func NewTokenFamilyRepository(db *gorm.DB, clogger *helpers.CustomLogger) TokenFamilyRepository {
+	return &tokenFamilyRepository{
+		masterDb: db,
+		clogger:  clogger,
+	}
+}
+
+func (repo *tokenFamilyRepository) InsertTokenFamily(ctx context.Context, tx *gorm.DB, family *entity.TokenFamily) error {
+	err := tx.WithContext(ctx).Create(family).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "InsertTokenFamily.gorm.DB", err)
+	}
+	return err
+}
+
+func (repo *tokenFamilyRepository) InsertFamilyToken(ctx context.Context, tx *gorm.DB, token *entity.TokenFamilyToken) error {
+	err := tx.WithContext(ctx).Create(token).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "InsertFamilyToken.gorm.DB", err)
+	}
+	return err
+}
+
+func (repo *tokenFamilyRepository) SelectTokenFamilyByFamilyID(ctx context.Context, familyID string) (*entity.TokenFamily, error) {
+	var family entity.TokenFamily
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Where("family_id = ?", familyID).
+		First(&family).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectTokenFamilyByFamilyID.gorm.DB", err)
+		return nil, err
+	}
+	return &family, nil
+}
+
+func (repo *tokenFamilyRepository) SelectFamilyTokenByRefreshHash(ctx context.Context, refreshTokenHash string) (*entity.TokenFamilyToken, error) {
+	var token entity.TokenFamilyToken
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Where("refresh_token_hash = ?", refreshTokenHash).
+		First(&token).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectFamilyTokenByRefreshHash.gorm.DB", err)
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (repo *tokenFamilyRepository) SelectActiveFamiliesByDevice(ctx context.Context, userUUID string, deviceID string) ([]entity.TokenFamily, error) {
+	var families []entity.TokenFamily
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Where("user_uuid = ? AND device_id = ? AND revoked_at IS NULL", userUUID, deviceID).
+		Find(&families).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectActiveFamiliesByDevice.gorm.DB", err)
+		return nil, err
+	}
+	return families, nil
+}
+
+func (repo *tokenFamilyRepository) SelectActiveFamiliesByUser(ctx context.Context, userUUID string) ([]entity.TokenFamily, error) {
+	var families []entity.TokenFamily
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Where("user_uuid = ? AND revoked_at IS NULL", userUUID).
+		Order("last_seen_at DESC").
+		Find(&families).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectActiveFamiliesByUser.gorm.DB", err)
+		return nil, err
+	}
+	return families, nil
+}
+
+func (repo *tokenFamilyRepository) UpdateTokenFamilySession(ctx context.Context, tx *gorm.DB, family *entity.TokenFamily) error {
+	err := tx.WithContext(ctx).
+		Model(&entity.TokenFamily{}).
+		Where("family_id = ?", family.FamilyID).
+		Select("app_version", "ip_address", "latitude", "longitude", "last_seen_at").
+		Updates(family).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "UpdateTokenFamilySession.gorm.DB", err)
+	}
+	return err
+}
+
+func (repo *tokenFamilyRepository) SelectUnexpiredFamilyTokens(ctx context.Context, familyID string) ([]entity.TokenFamilyToken, error) {
+	var tokens []entity.TokenFamilyToken
+	err := conn(ctx, repo.masterDb).WithContext(ctx).
+		Where("family_id = ? AND refresh_expired_at > ?", familyID, time.Now()).
+		Find(&tokens).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "SelectUnexpiredFamilyTokens.gorm.DB", err)
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// MarkFamilyTokenUsed only flips tokens that have not been used yet, so two
+// concurrent refreshes with the same token cannot both succeed.
+func (repo *tokenFamilyRepository) MarkFamilyTokenUsed(ctx context.Context, tx *gorm.DB, tokenID string) (int64, error) {
+	result := tx.WithContext(ctx).
+		Model(&entity.TokenFamilyToken{}).
+		Where("token_id = ? AND used_at IS NULL", tokenID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		repo.clogger.ErrorLogger(ctx, "MarkFamilyTokenUsed.gorm.DB", result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+func (repo *tokenFamilyRepository) RevokeTokenFamily(ctx context.Context, tx *gorm.DB, familyID string, reason string) error {
+	err := tx.WithContext(ctx).
+		Model(&entity.TokenFamily{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Updates(map[string]interface{}{
+			"revoked_at":     time.Now(),
+			"revoked_reason": reason,
+		}).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "RevokeTokenFamily.gorm.DB", err)
+	}
+	return err
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"backend-mobile-api/helpers"
 	"backend-mobile-api/model/entity"
+	"backend-mobile-api/model/enum"
 	"context"
 	"fmt"
 	"log"
@@ -34,8 +35,10 @@ type TransactionRepository interface {
 	ExpireOldTransactions(ctx context.Context) error
 	GetTransactionByID(ctx context.Context, id string) (*entity.Transaction, error)
 	UpdateStatus(ctx context.Context, id string, status string) error
+	UpdatePendingStatus(ctx context.Context, id string, status string) (int64, error)
 	GetUserFcmToken(ctx context.Context, userID uint) (string, error)
 	FindDeviceByUserUUID(ctx context.Context, uuid string) (*entity.Device, error)
+	SummarizeTransfersToAccount(ctx context.Context, userID int64, bankName string, accountNumber string, since time.Time) (int64, float64, error)
 	FindAllTransactionsByUserIDPaginated(
 		ctx context.Context,
 		userID int64,
@@ -52,12 +55,25 @@ type transactionRepository struct {
 
 // TransactionRepository.go
 func (r *transactionRepository) UpdateStatus(ctx context.Context, transactionID string, status string) error {
-	return r.masterDb.WithContext(ctx).
+	return conn(ctx, r.masterDb).WithContext(ctx).
 		Model(&entity.Transaction{}).
 		Where("transaction_id = ?", transactionID).
 		Update("status", status).Error
 }
 
+// UpdatePendingStatus moves a pending transaction to its final status, no row is
+// affected when the transaction was already settled by a concurrent update.
+func (r *transactionRepository) UpdatePendingStatus(ctx context.Context, transactionID string, status string) (int64, error) {
+	result := conn(ctx, r.masterDb).WithContext(ctx).
+		Model(&entity.Transaction{}).
+		Where("transaction_id = ? AND status = ?", transactionID, enum.TRANSACTION_STATUS_PENDING).
+		Update("status", status)
+	if result.Error != nil {
+		r.clogger.ErrorLogger(ctx, "UpdatePendingStatus", result.Error)
+	}
+	return result.RowsAffected, result.Error
+}
+
 func NewTransactionRepository(masterDb *gorm.DB, clogger *helpers.CustomLogger) TransactionRepository {
 	if masterDb == nil {
 		log.Println("[ERROR] masterDb nil saat init repository")
@@ -81,21 +97,21 @@ func generateTransactionID() string {
 }
 func (r *transactionRepository) FindDeviceByUserUUID(ctx context.Context, userUUID string) (*entity.Device, error) {
 	var device entity.Device
-	if err := r.masterDb.WithContext(ctx).Where("user_uuid = ?", userUUID).First(&device).Error; err != nil {
+	if err := conn(ctx, r.masterDb).WithContext(ctx).Where("user_uuid = ?", userUUID).First(&device).Error; err != nil {
 		return nil, err
 	}
 	return &device, nil
 }
 func (r *transactionRepository) GetTransactionByID(ctx context.Context, id string) (*entity.Transaction, error) {
 	var tx entity.Transaction
-	if err := r.masterDb.WithContext(ctx).Where("id = ?", id).First(&tx).Error; err != nil {
+	if err := conn(ctx, r.masterDb).WithContext(ctx).Where("id = ?", id).First(&tx).Error; err != nil {
 		return nil, err
 	}
 	return &tx, nil
 }
 func (r *transactionRepository) GetUserFcmToken(ctx context.Context, userID uint) (string, error) {
 	var device entity.Device
-	if err := r.masterDb.WithContext(ctx).
+	if err := conn(ctx, r.masterDb).WithContext(ctx).
 		Where("user_id = ?", userID).
 		Order("created_at DESC").
 		First(&device).Error; err != nil {
@@ -112,7 +128,7 @@ func (r *transactionRepository) GetUserFcmToken(ctx context.Context, userID uint
 func (r *transactionRepository) GenerateTransactionID(ctx context.Context) (string, error) {
 	// ambil count transaksi atau pakai sequence
 	var count int64
-	if err := r.masterDb.WithContext(ctx).Model(&entity.Transaction{}).Count(&count).Error; err != nil {
+	if err := conn(ctx, r.masterDb).WithContext(ctx).Model(&entity.Transaction{}).Count(&count).Error; err != nil {
 		return "", err
 	}
 
@@ -158,11 +174,14 @@ func (r *transactionRepository) CreateTransaction(ctx context.Context, tx *entit
 	// cari user_id dari uuid
 
 	var user entity.User
-	if err := r.masterDb.WithContext(ctx).Where("uuid = ?", userUUID).First(&user).Error; err != nil {
+	if err := conn(ctx, r.masterDb).WithContext(ctx).Where("uuid = ?", userUUID).First(&user).Error; err != nil {
 		return err
 	}
 
-	tx.TransactionID = r.lastTransactionID
+	// the caller sets the id it generated, the last generated one is only a fallback
+	if tx.TransactionID == "" {
+		tx.TransactionID = r.lastTransactionID
+	}
 	tx.UserID = user.ID
 	tx.CreatedAt = time.Now()
 	tx.UpdatedAt = time.Now()
@@ -176,7 +195,7 @@ func (r *transactionRepository) CreateTransaction(ctx context.Context, tx *entit
 		tx.UniqueCode = code
 
 	}
-	if err := r.masterDb.WithContext(ctx).Create(tx).Error; err != nil {
+	if err := conn(ctx, r.masterDb).WithContext(ctx).Create(tx).Error; err != nil {
 		r.clogger.ErrorLogger(ctx, "CreateTransaction", err)
 		return err
 	}
@@ -191,7 +210,7 @@ func (r *transactionRepository) CreateTransaction(ctx context.Context, tx *entit
 // find transaksi by id
 func (r *transactionRepository) FindTransactionByID(ctx context.Context, transactionID string) (*entity.Transaction, error) {
 	var transaction entity.Transaction
-	err := r.masterDb.WithContext(ctx).
+	err := conn(ctx, r.masterDb).WithContext(ctx).
 		Where("transaction_id = ?", transactionID).
 		Preload("BankTransfer").
 		Preload("Ewallet").
@@ -226,16 +245,16 @@ func (r *transactionRepository) CreateTransactionBankTransfer(ctx context.Contex
 	// 	}
 	// 	detail.UniqueCode = code
 	// }
-	// err := r.masterDb.WithContext(ctx).Create(detail).Error
+	// err := conn(ctx, r.masterDb).WithContext(ctx).Create(detail).Error
 	// if err != nil {
 	// 	return err
 	// }
 
-	// return r.masterDb.WithContext(ctx).
+	// return conn(ctx, r.masterDb).WithContext(ctx).
 	// 	Model(&entity.TransactionBankTransfer{}).
 	// 	Where("transaction_id = ?", detail.TransactionID).
 	// 	Update("is_reused", true).Error
-	return r.masterDb.WithContext(ctx).Create(detail).Error
+	return conn(ctx, r.masterDb).WithContext(ctx).Create(detail).Error
 }
 
 // ewallet
@@ -243,7 +262,7 @@ func (r *transactionRepository) CreateTransactionEwallet(ctx context.Context, de
 	if detail.EwalletName == "" {
 		return fmt.Errorf("ewallet name wajib diisi")
 	}
-	return r.masterDb.WithContext(ctx).Create(detail).Error
+	return conn(ctx, r.masterDb).WithContext(ctx).Create(detail).Error
 }
 
 // phone credit
@@ -251,7 +270,7 @@ func (r *transactionRepository) CreateTransactionPhoneCredit(ctx context.Context
 	if detail.PhoneNumber == "" {
 		return fmt.Errorf("phone number wajib diisi")
 	}
-	return r.masterDb.WithContext(ctx).Create(detail).Error
+	return conn(ctx, r.masterDb).WithContext(ctx).Create(detail).Error
 }
 
 // internet & tv
@@ -259,7 +278,7 @@ func (r *transactionRepository) CreateTransactionInternetTV(ctx context.Context,
 	if detail.CustomerName == "" {
 		return fmt.Errorf("customer name wajib diisi")
 	}
-	return r.masterDb.WithContext(ctx).Create(detail).Error
+	return conn(ctx, r.masterDb).WithContext(ctx).Create(detail).Error
 }
 
 // international transfer
@@ -267,11 +286,11 @@ func (r *transactionRepository) CreateTransactionInternational(ctx context.Conte
 	if detail.RecipientAcc == "" || detail.RecipientBank == "" {
 		return fmt.Errorf("recipient account & bank wajib diisi")
 	}
-	return r.masterDb.WithContext(ctx).Create(detail).Error
+	return conn(ctx, r.masterDb).WithContext(ctx).Create(detail).Error
 }
 func (r *transactionRepository) GetAllTransactions(ctx context.Context, userID int64) ([]entity.Transaction, error) {
 	var txns []entity.Transaction
-	err := r.masterDb.WithContext(ctx).
+	err := conn(ctx, r.masterDb).WithContext(ctx).
 		Where("user_id = ?", userID).
 		Order("created_at DESC").
 		Find(&txns).Error
@@ -286,7 +305,7 @@ func (r *transactionRepository) GetAllTransactions(ctx context.Context, userID i
 // internal/repository/postgres/transaction_repository.go
 func (r *transactionRepository) FindAllTransactionsByUserID(ctx context.Context, userID int64) ([]entity.Transaction, error) {
 	var txns []entity.Transaction
-	if err := r.masterDb.WithContext(ctx).
+	if err := conn(ctx, r.masterDb).WithContext(ctx).
 		Where("user_id = ?", userID).
 		Preload("BankTransfer").
 		Preload("Ewallet").
@@ -300,7 +319,7 @@ func (r *transactionRepository) FindAllTransactionsByUserID(ctx context.Context,
 }
 func (r *transactionRepository) FindUserByUUID(ctx context.Context, uuid string) (*entity.User, error) {
 	var user entity.User
-	if err := r.masterDb.WithContext(ctx).Where("uuid = ?", uuid).First(&user).Error; err != nil {
+	if err := conn(ctx, r.masterDb).WithContext(ctx).Where("uuid = ?", uuid).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
@@ -309,7 +328,7 @@ func (r *transactionRepository) FindUserByUUID(ctx context.Context, uuid string)
 // auto unique code
 func (r *transactionRepository) GenerateUniqueCode(ctx context.Context) (float64, error) {
 	var expiredList []entity.Transaction
-	err := r.masterDb.WithContext(ctx).
+	err := conn(ctx, r.masterDb).WithContext(ctx).
 		Where("status = ? AND unique_code IS NOT NULL", "expired").
 		Order("unique_code ASC").
 		Find(&expiredList).Error
@@ -320,12 +339,12 @@ func (r *transactionRepository) GenerateUniqueCode(ctx context.Context) (float64
 
 	// hitung total expired dan total transaksi bank transfer
 	var total, expired int64
-	if err := r.masterDb.WithContext(ctx).
+	if err := conn(ctx, r.masterDb).WithContext(ctx).
 		Model(&entity.Transaction{}).
 		Count(&total).Error; err != nil {
 		return 0, err
 	}
-	if err := r.masterDb.WithContext(ctx).
+	if err := conn(ctx, r.masterDb).WithContext(ctx).
 		Where("status = ? AND unique_code IS NOT NULL", "expired").
 		Model(&entity.Transaction{}).
 		Count(&expired).Error; err != nil {
@@ -339,7 +358,7 @@ func (r *transactionRepository) GenerateUniqueCode(ctx context.Context) (float64
 
 	// kalau tidak ada expired yang reusable → ambil kode terakhir + 1
 	var last entity.Transaction
-	if err := r.masterDb.WithContext(ctx).
+	if err := conn(ctx, r.masterDb).WithContext(ctx).
 		Order("unique_code DESC").
 		Limit(1).
 		Find(&last).Error; err != nil && err != gorm.ErrRecordNotFound {
@@ -359,7 +378,7 @@ func (r *transactionRepository) GenerateUniqueCode(ctx context.Context) (float64
 // ExpireOldTransactions akan mengubah status transaksi pending menjadi expired jika lebih dari 6 jam
 func (r *transactionRepository) ExpireOldTransactions(ctx context.Context) error {
 	cutoff := getCutoffTime()
-	return r.masterDb.WithContext(ctx).
+	return conn(ctx, r.masterDb).WithContext(ctx).
 		Model(&entity.Transaction{}).
 		Where("status = ? AND created_at < ?", "pending", cutoff).
 		Update("status", "expired").Error
@@ -375,7 +394,7 @@ func (r *transactionRepository) FindAllTransactionsByUserIDPaginated(
 	var total int64
 
 	// --- Base Query ---
-	query := r.masterDb.WithContext(ctx).
+	query := conn(ctx, r.masterDb).WithContext(ctx).
 		Model(&entity.Transaction{}).
 		Where("transactions.user_id = ?", userID).
 		Preload("BankTransfer").
@@ -440,3 +459,25 @@ func (r *transactionRepository) FindAllTransactionsByUserIDPaginated(
 
 	return txs, total, nil
 }
+
+// SummarizeTransfersToAccount menghitung jumlah & total nominal transfer user ke satu rekening sejak waktu tertentu.
+// transaksi gagal / expired / dibatalkan tidak dihitung.
+func (r *transactionRepository) SummarizeTransfersToAccount(ctx context.Context, userID int64, bankName string, accountNumber string, since time.Time) (int64, float64, error) {
+	var summary struct {
+		Count int64
+		Total float64
+	}
+	err := conn(ctx, r.masterDb).WithContext(ctx).
+		Model(&entity.Transaction{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(transactions.nominal), 0) AS total").
+		Joins("JOIN transaction_bank_transfer bt ON bt.transaction_id = transactions.transaction_id").
+		Where("transactions.user_id = ? AND transactions.status IN ?", userID, []string{"pending", "success"}).
+		Where("bt.account_number = ? AND LOWER(bt.bank_name) = LOWER(?)", accountNumber, bankName).
+		Where("transactions.created_at >= ?", since).
+		Scan(&summary).Error
+	if err != nil {
+		r.clogger.ErrorLogger(ctx, "SummarizeTransfersToAccount", err)
+		return 0, 0, err
+	}
+	return summary.Count, summary.Total, nil
+}
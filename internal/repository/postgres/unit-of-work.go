@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"backend-mobile-api/helpers"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type txContextKey struct{}
+
+// UnitOfWork runs the writes of several repositories in one transaction, the
+// transaction travels in the ctx so every repository called with it joins.
+type UnitOfWork interface {
+	// Do commits when fn returns nil and rolls back when it returns an error
+	// or panics, the panic is raised again after the rollback. A Do nested in
+	// another joins the outer transaction and leaves the commit to it.
+	Do(ctx context.Context, fn func(ctx context.Context, tx *gorm.DB) error) error
+}
+
+type unitOfWork struct {
+	masterDb *gorm.DB
+	clogger  *helpers.CustomLogger
+}
+
+func NewUnitOfWork(posgres *gorm.DB, clogger *helpers.CustomLogger) UnitOfWork {
+	return &unitOfWork{masterDb: posgres, clogger: clogger}
+}
+
+func (u *unitOfWork) Do(ctx context.Context, fn func(ctx context.Context, tx *gorm.DB) error) (err error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return fn(ctx, tx)
+	}
+	tx := u.masterDb.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		u.clogger.ErrorLogger(ctx, "UnitOfWork.Begin", tx.Error)
+		return tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			u.clogger.ErrorLogger(ctx, "UnitOfWork.Rollback", fmt.Errorf("panic: %v", r))
+			panic(r)
+		}
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		if err = tx.Commit().Error; err != nil {
+			u.clogger.ErrorLogger(ctx, "UnitOfWork.Commit", err)
+		}
+	}()
+	return fn(context.WithValue(ctx, txContextKey{}, tx), tx)
+}
+
+// TxFromContext returns the transaction of the unit of work ctx runs in.
+func TxFromContext(ctx context.Context) (*gorm.DB, bool) {
+	tx, ok := ctx.Value(txContextKey{}).(*gorm.DB)
+	return tx, ok && tx != nil
+}
+
+// conn is the handle a repository reads and writes through, inside a unit of
+// work it is the shared transaction so reads see the writes made before them.
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return db
+}
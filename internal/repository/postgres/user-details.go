@@ -18,7 +18,6 @@ func NewUserDetailRepository(posgres *gorm.DB, clogger *helpers.CustomLogger) Us
 }
 
 type UserDetailRepository interface {
-	Tx(ctx context.Context) *gorm.DB
 	InsertUserDetail(ctx context.Context, tx *gorm.DB, user *entity.UserDetail) error
 	SelectUserDetailByEmailOrPhoneNumber(ctx context.Context, emailOrNo string) (*entity.UserDetail, error)
 	UpdateUserDetail(ctx context.Context, tx *gorm.DB, curentUserDetail *entity.UserDetail, newUserDetail *entity.UserDetail) error
@@ -26,9 +25,6 @@ type UserDetailRepository interface {
 	SelectUserDetailByUserUUID(ctx context.Context, userUUID *string) (*entity.UserDetail, error)
 }
 
-func (ur *userDetailRepository) Tx(ctx context.Context) *gorm.DB {
-	return ur.masterDb.WithContext(ctx)
-}
 func (repo *userDetailRepository) InsertUserDetail(ctx context.Context, tx *gorm.DB, user *entity.UserDetail) error {
 	err := tx.Create(user).Error
 	if err != nil {
@@ -38,7 +34,7 @@ func (repo *userDetailRepository) InsertUserDetail(ctx context.Context, tx *gorm
 }
 func (repo *userDetailRepository) SelectUserDetailByEmailOrPhoneNumber(ctx context.Context, emailOrNo string) (*entity.UserDetail, error) {
 	var userDetail entity.UserDetail
-	err := repo.masterDb.Table(`user_details`).Where("email = ?", emailOrNo).Or("phone_number = ?", emailOrNo).First(&userDetail).Error
+	err := conn(ctx, repo.masterDb).Table(`user_details`).Where("email = ?", emailOrNo).Or("phone_number = ?", emailOrNo).First(&userDetail).Error
 
 	if err != nil {
 
@@ -64,7 +60,7 @@ func (repo *userDetailRepository) UpdateUserDetail(ctx context.Context, tx *gorm
 }
 func (repo *userDetailRepository) SelectUserDetailByUserId(ctx context.Context, userId int64) (*entity.UserDetail, error) {
 	var userDetail entity.UserDetail
-	err := repo.masterDb.WithContext(ctx).Where("user_id = ?", userId).First(&userDetail).Error
+	err := conn(ctx, repo.masterDb).WithContext(ctx).Where("user_id = ?", userId).First(&userDetail).Error
 	if err != nil {
 		repo.clogger.ErrorLogger(ctx, "SelectUserDetailByUserId.gorm.DB", err)
 	}
@@ -72,7 +68,7 @@ func (repo *userDetailRepository) SelectUserDetailByUserId(ctx context.Context,
 }
 func (repo *userDetailRepository) SelectUserDetailByUserUUID(ctx context.Context, userUUID *string) (*entity.UserDetail, error) {
 	var userDetail entity.UserDetail
-	err := repo.masterDb.WithContext(ctx).Where("user_uuid = ?", *userUUID).First(&userDetail).Error
+	err := conn(ctx, repo.masterDb).WithContext(ctx).Where("user_uuid = ?", *userUUID).First(&userDetail).Error
 	if err != nil {
 		repo.clogger.ErrorLogger(ctx, "SelectUserDetailByUserUUID.gorm.DB", err)
 	}
@@ -23,7 +23,7 @@ func NewUserPaymentsAccountRepository(masterDb *gorm.DB, clogger *helpers.Custom
 }
 func (r *userPaymentsAccountRepository) GetAllBanksAccountUserPayment(ctx context.Context) ([]entity.UserPaymentsAccount, error) {
 	var userPaymentsAccount []entity.UserPaymentsAccount
-	err := r.masterDb.WithContext(ctx).
+	err := conn(ctx, r.masterDb).WithContext(ctx).
 		Order("bank_name ASC"). // pastikan sesuai dengan entity.Bank
 		Find(&userPaymentsAccount).Error
 	if err != nil {
@@ -35,7 +35,7 @@ func (r *userPaymentsAccountRepository) GetAllBanksAccountUserPayment(ctx contex
 
 func (r *userPaymentsAccountRepository) SearchBanksAccountUserPayment(ctx context.Context, keyword string) ([]entity.UserPaymentsAccount, error) {
 	var userPaymentsAccount []entity.UserPaymentsAccount
-	err := r.masterDb.WithContext(ctx).
+	err := conn(ctx, r.masterDb).WithContext(ctx).
 		Where("bank_name ILIKE ? ", "%"+keyword+"%").
 		Order("bank_name ASC").
 		Find(&userPaymentsAccount).Error
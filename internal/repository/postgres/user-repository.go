@@ -7,6 +7,7 @@ import (
 	"errors"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type userRepository struct {
@@ -19,7 +20,6 @@ func NewUserRepository(posgres *gorm.DB, clogger *helpers.CustomLogger) UserRepo
 }
 
 type UserRepository interface {
-	Tx(ctx context.Context) *gorm.DB
 	SelectUserByEmailOrPhoneNumber(ctx context.Context, emailOrNo string) (*entity.User, error)
 	InsertUser(ctx context.Context, tx *gorm.DB, user *entity.User) error
 	SelectUserByStruct(ctx context.Context, user *entity.User) (*[]entity.User, error)
@@ -28,11 +28,13 @@ type UserRepository interface {
 	SelectUserByUUID(ctx context.Context, uuid string) (*entity.User, error)
 	DeleteUser(ctx context.Context, tx *gorm.DB, user *entity.User) error
 	SelectUserByID(ctx context.Context, id int64) (*entity.User, error)
+	BumpTokenVersion(ctx context.Context, tx *gorm.DB, user *entity.User) error
+	LockUser(ctx context.Context, tx *gorm.DB, id int64) (*entity.User, error)
 }
 
 // SelectUserByStructOne implements UserRepository.
 func (repo *userRepository) SelectUserByStructOne(ctx context.Context, user *entity.User) (*entity.User, error) {
-	err := repo.masterDb.Where(user).First(user).Error
+	err := conn(ctx, repo.masterDb).Where(user).First(user).Error
 	if err != nil {
 		repo.clogger.ErrorLogger(ctx, "SelectUserByStruct.gorm.DB", err)
 		return nil, err
@@ -40,14 +42,10 @@ func (repo *userRepository) SelectUserByStructOne(ctx context.Context, user *ent
 	return user, nil
 }
 
-func (repo *userRepository) Tx(ctx context.Context) *gorm.DB {
-	return repo.masterDb.Begin()
-}
-
 func (repo *userRepository) SelectUserByEmailOrPhoneNumber(ctx context.Context, emailOrNo string) (*entity.User, error) {
 	var user entity.User
 
-	err := repo.masterDb.Table(`users`).Where("email = ?", emailOrNo).Or("phone_number = ?", emailOrNo).First(&user).Error
+	err := conn(ctx, repo.masterDb).Table(`users`).Where("email = ?", emailOrNo).Or("phone_number = ?", emailOrNo).First(&user).Error
 
 	if err != nil {
 
@@ -69,7 +67,7 @@ func (repo *userRepository) InsertUser(ctx context.Context, tx *gorm.DB, user *e
 
 func (repo *userRepository) SelectUserByStruct(ctx context.Context, user *entity.User) (*[]entity.User, error) {
 	var users []entity.User
-	err := repo.masterDb.Find(&users, user).Error
+	err := conn(ctx, repo.masterDb).Find(&users, user).Error
 	if err != nil {
 		repo.clogger.ErrorLogger(ctx, "SelectUserByStruct.gorm.DB", err)
 	}
@@ -91,7 +89,7 @@ func (repo *userRepository) UpdateUser(ctx context.Context, tx *gorm.DB, current
 
 func (repo *userRepository) SelectUserByUUID(ctx context.Context, uuid string) (*entity.User, error) {
 	var user entity.User
-	err := repo.masterDb.Where("uuid = ?", uuid).First(&user).Error
+	err := conn(ctx, repo.masterDb).Where("uuid = ?", uuid).First(&user).Error
 	if err != nil {
 		repo.clogger.ErrorLogger(ctx, "SelectUserByUUID.gorm.DB", err)
 		return nil, err
@@ -100,7 +98,7 @@ func (repo *userRepository) SelectUserByUUID(ctx context.Context, uuid string) (
 }
 func (repo *userRepository) SelectUserByID(ctx context.Context, id int64) (*entity.User, error) {
 	var user entity.User
-	err := repo.masterDb.Where("id = ?", id).First(&user).Error
+	err := conn(ctx, repo.masterDb).Where("id = ?", id).First(&user).Error
 	if err != nil {
 		repo.clogger.ErrorLogger(ctx, "SelectUserByID.gorm.DB", err)
 		return nil, err
@@ -117,3 +115,32 @@ func (repo *userRepository) DeleteUser(ctx context.Context, tx *gorm.DB, user *e
 	return nil
 
 }
+
+// BumpTokenVersion increments the token version in the database, not from the
+// given value, and sets the new version on user.
+func (repo *userRepository) BumpTokenVersion(ctx context.Context, tx *gorm.DB, user *entity.User) error {
+	params := map[string]interface{}{
+		"id": user.ID,
+	}
+	err := tx.Raw("UPDATE users SET token_version = token_version + 1, updated_at = now() WHERE id = @id RETURNING token_version", params).
+		Scan(&user.TokenVersion).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "BumpTokenVersion.gorm.DB", err)
+	}
+	return err
+}
+
+// LockUser reads the user with SELECT ... FOR UPDATE, changes to the same user
+// wait for each other until the first one committed.
+func (repo *userRepository) LockUser(ctx context.Context, tx *gorm.DB, id int64) (*entity.User, error) {
+	var user entity.User
+	err := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", id).
+		First(&user).Error
+	if err != nil {
+		repo.clogger.ErrorLogger(ctx, "LockUser.gorm.DB", err)
+		return nil, err
+	}
+	return &user, nil
+}
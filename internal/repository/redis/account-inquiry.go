@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"backend-mobile-api/model/dto"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+func (r *Redis) SetAccountInquiry(ctx context.Context, bankCode string, accountNo string, value *dto.AccountInquiryCache, duration time.Duration) error {
+	key := fmt.Sprintf("ACCOUNT_INQUIRY:%s:%s", bankCode, accountNo)
+	jsonData, _ := json.Marshal(*value)
+	return r.client.Set(ctx, key, jsonData, duration).Err()
+}
+func (r *Redis) GetAccountInquiry(ctx context.Context, bankCode string, accountNo string) (*dto.AccountInquiryCache, error) {
+	return r.getAccountInquiry(ctx, fmt.Sprintf("ACCOUNT_INQUIRY:%s:%s", bankCode, accountNo))
+}
+
+// SetConfirmedAccount remembers that the user saw the inquiry result, saving a recipient requires it.
+func (r *Redis) SetConfirmedAccount(ctx context.Context, uuidKey string, bankCode string, accountNo string, value *dto.AccountInquiryCache, duration time.Duration) error {
+	key := fmt.Sprintf("%s:ACCOUNT_CONFIRMED:%s:%s", uuidKey, bankCode, accountNo)
+	jsonData, _ := json.Marshal(*value)
+	return r.client.Set(ctx, key, jsonData, duration).Err()
+}
+func (r *Redis) GetConfirmedAccount(ctx context.Context, uuidKey string, bankCode string, accountNo string) (*dto.AccountInquiryCache, error) {
+	return r.getAccountInquiry(ctx, fmt.Sprintf("%s:ACCOUNT_CONFIRMED:%s:%s", uuidKey, bankCode, accountNo))
+}
+
+func (r *Redis) getAccountInquiry(ctx context.Context, key string) (*dto.AccountInquiryCache, error) {
+	var value dto.AccountInquiryCache
+	strJsonValue, err := r.client.Get(ctx, key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal([]byte(strJsonValue), &value)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
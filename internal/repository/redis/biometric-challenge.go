@@ -0,0 +1,28 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// SetBiometricChallenge stores the nonce a device has to sign to log in with biometric.
+func (r *Redis) SetBiometricChallenge(ctx context.Context, challengeID string, value string, duration time.Duration) error {
+	key := fmt.Sprintf("BIOMETRIC_CHALLENGE:%s", challengeID)
+	return r.client.Set(ctx, key, value, duration).Err()
+}
+
+// TakeBiometricChallenge returns the challenge and deletes it, a challenge is answered once.
+func (r *Redis) TakeBiometricChallenge(ctx context.Context, challengeID string) (string, error) {
+	key := fmt.Sprintf("BIOMETRIC_CHALLENGE:%s", challengeID)
+	strValue, err := r.client.GetDel(ctx, key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strValue, nil
+}
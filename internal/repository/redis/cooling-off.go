@@ -0,0 +1,36 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ReserveCoolingOff reserves amount against the cooling-off cap of a transfer destination.
+// The counter is seeded once with the amount already transferred (SETNX) and then only moves
+// through INCRBYFLOAT, so concurrent transfers can never both pass the cap.
+func (r *Redis) ReserveCoolingOff(ctx context.Context, destination string, seed float64, amount float64, max float64, duration time.Duration) (bool, error) {
+	key := fmt.Sprintf("COOLING_OFF:%s", destination)
+	if err := r.client.SetNX(ctx, key, seed, duration).Err(); err != nil {
+		return false, err
+	}
+	total, err := r.client.IncrByFloat(ctx, key, amount).Result()
+	if err != nil {
+		return false, err
+	}
+	if total > max {
+		if err := r.client.IncrByFloat(ctx, key, -amount).Err(); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+	return true, nil
+}
+func (r *Redis) ReleaseCoolingOff(ctx context.Context, destination string, amount float64) error {
+	key := fmt.Sprintf("COOLING_OFF:%s", destination)
+	exists, err := r.client.Exists(ctx, key).Result()
+	if err != nil || exists == 0 {
+		return err
+	}
+	return r.client.IncrByFloat(ctx, key, -amount).Err()
+}
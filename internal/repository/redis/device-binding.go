@@ -0,0 +1,30 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// SetDeviceBinding binds a new-device otp verify key to the device that asked to be trusted.
+func (r *Redis) SetDeviceBinding(ctx context.Context, verifyKey string, deviceID string, duration time.Duration) error {
+	key := fmt.Sprintf("DEVICE_BINDING:%s", verifyKey)
+	return r.client.Set(ctx, key, deviceID, duration).Err()
+}
+func (r *Redis) GetDeviceBinding(ctx context.Context, verifyKey string) (string, error) {
+	key := fmt.Sprintf("DEVICE_BINDING:%s", verifyKey)
+	strValue, err := r.client.Get(ctx, key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strValue, nil
+}
+func (r *Redis) DeleteDeviceBinding(ctx context.Context, verifyKey string) error {
+	key := fmt.Sprintf("DEVICE_BINDING:%s", verifyKey)
+	return r.client.Del(ctx, key).Err()
+}
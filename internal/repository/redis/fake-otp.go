@@ -0,0 +1,25 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+func (r *Redis) SetFakeOtp(ctx context.Context, destination string, value string, expiration time.Duration) error {
+	key := fmt.Sprintf("FAKE_OTP:%s", destination)
+	return r.client.Set(ctx, key, value, expiration).Err()
+}
+func (r *Redis) GetFakeOtp(ctx context.Context, destination string) (string, error) {
+	key := fmt.Sprintf("FAKE_OTP:%s", destination)
+	value, err := r.client.Get(ctx, key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", nil
+		}
+		return "", err
+	}
+	return value, nil
+}
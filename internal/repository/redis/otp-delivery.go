@@ -0,0 +1,36 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+func (r *Redis) SetOtpDelivery(ctx context.Context, verifyKey string, value string, duration time.Duration) error {
+	key := fmt.Sprintf("OTP_DELIVERY:%s", verifyKey)
+	return r.client.Set(ctx, key, value, duration).Err()
+}
+func (r *Redis) GetOtpDelivery(ctx context.Context, verifyKey string) (string, error) {
+	key := fmt.Sprintf("OTP_DELIVERY:%s", verifyKey)
+	strValue, err := r.client.Get(ctx, key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strValue, nil
+}
+func (r *Redis) DeleteOtpDelivery(ctx context.Context, verifyKey string) error {
+	key := fmt.Sprintf("OTP_DELIVERY:%s", verifyKey)
+	return r.client.Del(ctx, key).Err()
+}
+
+// ClaimOtpFailover lets one trigger, a failed callback or the delivery timeout,
+// move an attempt to the next channel. It returns false when another one did.
+func (r *Redis) ClaimOtpFailover(ctx context.Context, verifyKey string, attempt int, duration time.Duration) (bool, error) {
+	key := fmt.Sprintf("OTP_FAILOVER:%s:%d", verifyKey, attempt)
+	return r.client.SetNX(ctx, key, "active", duration).Result()
+}
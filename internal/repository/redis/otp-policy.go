@@ -0,0 +1,100 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// otp sends are capped for the destination, the device and the ip they were requested from
+const (
+	OTP_SCOPE_DESTINATION = "DESTINATION"
+	OTP_SCOPE_DEVICE      = "DEVICE"
+	OTP_SCOPE_IP          = "IP"
+)
+
+// ClaimOtpCooldown starts the resend cooldown of a destination, false when it is still running.
+func (r *Redis) ClaimOtpCooldown(ctx context.Context, purpose string, destination string, cooldown time.Duration) (bool, error) {
+	key := fmt.Sprintf("OTP_COOLDOWN:%s:%s", purpose, destination)
+	return r.client.SetNX(ctx, key, "active", cooldown).Result()
+}
+
+// GetOtpCooldown returns how long the resend cooldown still runs, zero when there is none.
+func (r *Redis) GetOtpCooldown(ctx context.Context, purpose string, destination string) (time.Duration, error) {
+	key := fmt.Sprintf("OTP_COOLDOWN:%s:%s", purpose, destination)
+	ttl, err := r.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// IncrOtpSend counts an otp sent in scope, the counter lives for window since the first send.
+func (r *Redis) IncrOtpSend(ctx context.Context, scope string, id string, window time.Duration) (int64, error) {
+	key := fmt.Sprintf("OTP_SEND:%s:%s:%s", scope, window, id)
+	count, err := r.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		if err = r.client.Expire(ctx, key, window).Err(); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// GetOtpSend returns the sends counted in scope and how long until the counter resets.
+func (r *Redis) GetOtpSend(ctx context.Context, scope string, id string, window time.Duration) (int64, time.Duration, error) {
+	key := fmt.Sprintf("OTP_SEND:%s:%s:%s", scope, window, id)
+	count, err := r.client.Get(ctx, key).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+	ttl, err := r.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return count, 0, err
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+	return count, ttl, nil
+}
+
+// IncrOtpVerifyAttempt counts a code entered for a verify key, reset once the otp is verified.
+func (r *Redis) IncrOtpVerifyAttempt(ctx context.Context, verifyKey string, expiration time.Duration) (int64, error) {
+	key := fmt.Sprintf("OTP_VERIFY_ATTEMPT:%s", verifyKey)
+	count, err := r.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		if err = r.client.Expire(ctx, key, expiration).Err(); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+func (r *Redis) GetOtpVerifyAttempt(ctx context.Context, verifyKey string) (int64, error) {
+	key := fmt.Sprintf("OTP_VERIFY_ATTEMPT:%s", verifyKey)
+	count, err := r.client.Get(ctx, key).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return count, nil
+}
+func (r *Redis) DeleteOtpVerifyAttempt(ctx context.Context, verifyKey string) error {
+	key := fmt.Sprintf("OTP_VERIFY_ATTEMPT:%s", verifyKey)
+	return r.client.Del(ctx, key).Err()
+}
@@ -0,0 +1,100 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// pin attempts are tracked for the user and for the device the attempt came from
+const (
+	PIN_SCOPE_USER   = "USER"
+	PIN_SCOPE_DEVICE = "DEVICE"
+)
+
+// IncrPinAttempt counts a failed pin attempt, the counter lives for window since the first failure.
+func (r *Redis) IncrPinAttempt(ctx context.Context, scope string, id string, window time.Duration) (int64, error) {
+	key := fmt.Sprintf("PIN_ATTEMPT:%s:%s", scope, id)
+	count, err := r.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		if err = r.client.Expire(ctx, key, window).Err(); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+func (r *Redis) GetPinAttempt(ctx context.Context, scope string, id string) (int64, error) {
+	key := fmt.Sprintf("PIN_ATTEMPT:%s:%s", scope, id)
+	count, err := r.client.Get(ctx, key).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return count, nil
+}
+func (r *Redis) DecrPinAttempt(ctx context.Context, scope string, id string) error {
+	key := fmt.Sprintf("PIN_ATTEMPT:%s:%s", scope, id)
+	return r.client.Decr(ctx, key).Err()
+}
+func (r *Redis) DeletePinAttempt(ctx context.Context, scope string, id string) error {
+	key := fmt.Sprintf("PIN_ATTEMPT:%s:%s", scope, id)
+	return r.client.Del(ctx, key).Err()
+}
+
+// SetPinLock blocks pin attempts for duration, a zero duration keeps the lock until DeletePinLock.
+func (r *Redis) SetPinLock(ctx context.Context, scope string, id string, duration time.Duration) error {
+	key := fmt.Sprintf("PIN_LOCK:%s:%s", scope, id)
+	return r.client.Set(ctx, key, "active", duration).Err()
+}
+
+// GetPinLock returns whether the lock is set and how long it still holds, a permanent lock has a negative ttl.
+func (r *Redis) GetPinLock(ctx context.Context, scope string, id string) (bool, time.Duration, error) {
+	key := fmt.Sprintf("PIN_LOCK:%s:%s", scope, id)
+	ttl, err := r.client.TTL(ctx, key).Result()
+	if err != nil {
+		return false, 0, err
+	}
+	// -2 means the key does not exist, -1 means it has no expiry
+	if ttl == -2 {
+		return false, 0, nil
+	}
+	return true, ttl, nil
+}
+func (r *Redis) DeletePinLock(ctx context.Context, scope string, id string) error {
+	key := fmt.Sprintf("PIN_LOCK:%s:%s", scope, id)
+	return r.client.Del(ctx, key).Err()
+}
+
+// SetPinDelay makes the next attempt wait for duration.
+func (r *Redis) SetPinDelay(ctx context.Context, id string, duration time.Duration) error {
+	key := fmt.Sprintf("PIN_DELAY:%s", id)
+	return r.client.Set(ctx, key, "active", duration).Err()
+}
+
+// ClaimPinDelay sets the delay only when none is running, so one pin attempt at a time can pass.
+func (r *Redis) ClaimPinDelay(ctx context.Context, id string, duration time.Duration) (bool, error) {
+	key := fmt.Sprintf("PIN_DELAY:%s", id)
+	return r.client.SetNX(ctx, key, "active", duration).Result()
+}
+func (r *Redis) GetPinDelay(ctx context.Context, id string) (time.Duration, error) {
+	key := fmt.Sprintf("PIN_DELAY:%s", id)
+	ttl, err := r.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+func (r *Redis) DeletePinDelay(ctx context.Context, id string) error {
+	key := fmt.Sprintf("PIN_DELAY:%s", id)
+	return r.client.Del(ctx, key).Err()
+}
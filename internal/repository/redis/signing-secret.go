@@ -0,0 +1,30 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// SetSigningSecret stores the request signing secret of a token family, it lives as long as the refresh token.
+func (r *Redis) SetSigningSecret(ctx context.Context, familyID string, secret string, duration time.Duration) error {
+	key := fmt.Sprintf("SIGNING_SECRET:%s", familyID)
+	return r.client.Set(ctx, key, secret, duration).Err()
+}
+func (r *Redis) GetSigningSecret(ctx context.Context, familyID string) (string, error) {
+	key := fmt.Sprintf("SIGNING_SECRET:%s", familyID)
+	strValue, err := r.client.Get(ctx, key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strValue, nil
+}
+func (r *Redis) DeleteSigningSecret(ctx context.Context, familyID string) error {
+	key := fmt.Sprintf("SIGNING_SECRET:%s", familyID)
+	return r.client.Del(ctx, key).Err()
+}
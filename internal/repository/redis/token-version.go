@@ -0,0 +1,35 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+// SetTokenVersion publishes the token version of a user to the auth middleware.
+// It only has to outlive the tokens signed with an older version.
+func (r *Redis) SetTokenVersion(ctx context.Context, userUUID string, version int, duration time.Duration) error {
+	key := fmt.Sprintf("TOKEN_VERSION:%s", userUUID)
+	return r.client.Set(ctx, key, version, duration).Err()
+}
+
+// GetTokenVersion reports false when the version is not cached, the database
+// holds the current one then.
+func (r *Redis) GetTokenVersion(ctx context.Context, userUUID string) (int, bool, error) {
+	key := fmt.Sprintf("TOKEN_VERSION:%s", userUUID)
+	strValue, err := r.client.Get(ctx, key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	version, err := strconv.Atoi(strValue)
+	if err != nil {
+		return 0, false, err
+	}
+	return version, true, nil
+}
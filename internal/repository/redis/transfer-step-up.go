@@ -0,0 +1,30 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// SetTransferStepUp binds a step-up otp verify key to the transfer destination it was requested for.
+func (r *Redis) SetTransferStepUp(ctx context.Context, verifyKey string, destination string, duration time.Duration) error {
+	key := fmt.Sprintf("TRANSFER_STEP_UP:%s", verifyKey)
+	return r.client.Set(ctx, key, destination, duration).Err()
+}
+func (r *Redis) GetTransferStepUp(ctx context.Context, verifyKey string) (string, error) {
+	key := fmt.Sprintf("TRANSFER_STEP_UP:%s", verifyKey)
+	strValue, err := r.client.Get(ctx, key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strValue, nil
+}
+func (r *Redis) DeleteTransferStepUp(ctx context.Context, verifyKey string) error {
+	key := fmt.Sprintf("TRANSFER_STEP_UP:%s", verifyKey)
+	return r.client.Del(ctx, key).Err()
+}
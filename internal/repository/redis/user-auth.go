@@ -64,21 +64,13 @@ func (r *Redis) DeleteAccessKey(ctx context.Context, uuid string) error {
 	return r.client.Del(ctx, uuid).Err()
 }
 
-func (r *Redis) SetBlaclistJwt(ctx context.Context, jwt string, duration time.Duration) error {
-	return r.client.Set(ctx, jwt, "active", duration).Err()
-}
-func (r *Redis) GetBlaclistJwt(ctx context.Context, jwt string) (string, error) {
-	strValue, err := r.client.Get(ctx, jwt).Result()
-	if err != nil {
-		if errors.Is(err, redis.Nil) {
-			return "", nil
-		}
-		return "", err
-	}
-	return strValue, nil
+// SetBlaclistJwt takes the helpers.HashToken of the jwt, never the jwt itself.
+func (r *Redis) SetBlaclistJwt(ctx context.Context, tokenHash string, duration time.Duration) error {
+	key := fmt.Sprintf("JWT_BLACKLIST:%s", tokenHash)
+	return r.client.Set(ctx, key, "active", duration).Err()
 }
-func (r *Redis) GetXNonce(ctx context.Context, uuid string) (string, error) {
-	key := fmt.Sprintf("%s:%s", uuid, "xsession")
+func (r *Redis) GetBlaclistJwt(ctx context.Context, tokenHash string) (string, error) {
+	key := fmt.Sprintf("JWT_BLACKLIST:%s", tokenHash)
 	strValue, err := r.client.Get(ctx, key).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
@@ -88,8 +80,10 @@ func (r *Redis) GetXNonce(ctx context.Context, uuid string) (string, error) {
 	}
 	return strValue, nil
 }
-func (r *Redis) SetXNONCE(ctx context.Context, uuid string) error {
-	key := fmt.Sprintf("%s:%s", uuid, "xsession")
-	duration := r.config.App.XsessionExpire
-	return r.client.Set(ctx, key, "active", duration).Err()
+
+// ClaimXNonce records a request nonce of a device, it returns false when the
+// nonce was already used. SET NX makes two concurrent replays race safely.
+func (r *Redis) ClaimXNonce(ctx context.Context, deviceID string, nonce string, duration time.Duration) (bool, error) {
+	key := fmt.Sprintf("X_NONCE:%s:%s", deviceID, nonce)
+	return r.client.SetNX(ctx, key, "active", duration).Result()
 }
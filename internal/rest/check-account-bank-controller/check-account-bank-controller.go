@@ -1,77 +1,76 @@
 package checkaccountbankcontroller
 
 import (
+	"backend-mobile-api/helpers"
+	"backend-mobile-api/model/dto"
 	"backend-mobile-api/model/entity"
-
-	"math/rand"
+	"backend-mobile-api/model/enum"
+	"backend-mobile-api/model/enum/pkgErr"
+	service "backend-mobile-api/service/account-inquiry-svc"
+	"errors"
 	"net/http"
-	"strconv"
-	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 )
 
-type CheckAccountBankController struct{}
-
-func NewCheckAccountBankController() CheckAccountBankController {
-	return CheckAccountBankController{}
+type CheckAccountBankController struct {
+	service service.AccountInquiryService
 }
 
-// GeneratePartnerReferenceNo bikin angka random sepanjang 12 digit
-func GeneratePartnerReferenceNo() string {
-	rand.Seed(time.Now().UnixNano())
-	num := rand.Int63n(999999999999) // max 12 digit
-	return leftPad(strconv.FormatInt(num, 10), "0", 12)
-}
-
-// helper: padding ke kiri biar tetap 12 digit
-func leftPad(s, pad string, length int) string {
-	for len(s) < length {
-		s = pad + s
+func NewCheckAccountBankController(service service.AccountInquiryService) CheckAccountBankController {
+	if service == nil {
+		panic("check account bank controller: account inquiry service is nil")
 	}
-	return s
+	return CheckAccountBankController{service: service}
 }
 
 // POST /check-account
-func (CheckAccountBankController) CheckAccount(ctx echo.Context) error {
+func (c CheckAccountBankController) CheckAccount(ctx echo.Context) error {
 	var req entity.CheckAccountRequest
 	if err := ctx.Bind(&req); err != nil {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{
-			"error": "invalid payload",
+		return ctx.JSON(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.INVALID_REQUEST_PAYLOAD_CODE,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
 		})
 	}
-	//struct status hanya dummy
-	type BaseResponse struct {
-		StatusCode string      `json:"status_code"`
-		Message    string      `json:"message"`
-		Error      string      `json:"error"`
-		Data       interface{} `json:"data"`
+	if err := validator.New().Struct(req); err != nil {
+		err = helpers.CustomValidatePayload(err, req)
+		return ctx.JSON(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.INVALID_REQUEST_PAYLOAD_CODE,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		})
 	}
-	// ✅ dummy response
-	var err error
-	// override partnerReferenceNo kalau kosong / ingin selalu generate
-	req.PartnerReferenceNo = GeneratePartnerReferenceNo()
-	resp := entity.CheckAccountResponseData{
 
-		BeneficiaryAccountNo:   req.BeneficiaryAccountNo,
-		BeneficiaryAccountName: "TIMO TEST", // dummy fixed
-
-		BeneficiaryBankCode: req.BeneficiaryBankCode,
+	customResource, ok := ctx.Request().Context().Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue)
+	if !ok || customResource.AuthUUID == "" {
+		return ctx.JSON(http.StatusUnauthorized, dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_UNAUTHORIZED_CODE,
+			Message:    pkgErr.UNAUTHORIZED_MSG,
+		})
 	}
 
+	resp, err := c.service.CheckAccount(ctx.Request().Context(), customResource.AuthUUID, &req)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, BaseResponse{
-			StatusCode: "99", // contoh error code
-			Message:    "failed",
+		if errors.Is(err, service.ErrAccountNotFound) {
+			return ctx.JSON(http.StatusNotFound, dto.BaseResponse{
+				StatusCode: pkgErr.ACCOUNT_INQUIRY_NOT_FOUND_CODE,
+				Message:    pkgErr.ACCOUNT_NOT_FOUND_MSG,
+				Error:      err.Error(),
+			})
+		}
+		return ctx.JSON(http.StatusBadGateway, dto.BaseResponse{
+			StatusCode: pkgErr.ACCOUNT_INQUIRY_FAILED_CODE,
+			Message:    pkgErr.SERVER_BUSY,
 			Error:      err.Error(),
-			Data:       nil,
 		})
 	}
 
-	return ctx.JSON(http.StatusOK, BaseResponse{
-		StatusCode: "00", // sukses selalu 00
-		Message:    "success",
-		Error:      "",
-		Data:       resp, // langsung balikin response check account
+	return ctx.JSON(http.StatusOK, dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+		Data:       resp,
 	})
 }
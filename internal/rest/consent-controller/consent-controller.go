@@ -0,0 +1,166 @@
+package consentController
+
+import (
+	_ "backend-mobile-api/docs"
+	"backend-mobile-api/helpers"
+	"backend-mobile-api/model/dto"
+	"backend-mobile-api/model/dto/request"
+	_ "backend-mobile-api/model/dto/swagger"
+	"backend-mobile-api/model/enum"
+	"backend-mobile-api/model/enum/pkgErr"
+	consentService "backend-mobile-api/service/consent-svc"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"net/http"
+)
+
+type consentController struct {
+	consentService consentService.ConsentService
+}
+
+func NewConsentController(consentService consentService.ConsentService) ConsentController {
+	return &consentController{
+		consentService: consentService,
+	}
+}
+
+type ConsentController interface {
+	InquiryDocumentController(e echo.Context) error
+	InquiryConsentController(e echo.Context) error
+	AcceptConsentController(e echo.Context) error
+}
+
+// @Tags Consent
+// @Summary inquiry legal documents
+// @Description current version of each legal document, the ids are sent back on register
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Success 200 {object} dto.BaseResponse
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/legal-documents [get]
+func (ctr *consentController) InquiryDocumentController(e echo.Context) error {
+	logData, okData := e.Request().Context().Value(enum.CUSTOM_LOG_DATA).(*dto.CustomLoggerRequest)
+	if !okData {
+		log.Warn("failed to get custom logger")
+	}
+	logData.Remarks = "inquiry-legal-document"
+	res := ctr.consentService.InquiryDocumentService(e.Request().Context(), logData)
+	return ctr.response(e, res)
+}
+
+// @Tags Consent
+// @Summary inquiry user consents
+// @Description legal documents the user accepted and the current ones still to accept
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Param Authorization header string true "Authorization"
+// @Success 200 {object} dto.BaseResponse
+// @Failure 401 {object} swagger.Unauthorized
+// @Failure 404 {object} swagger.UserNotFoundProfileFailureResponse
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/users/consents [get]
+func (ctr *consentController) InquiryConsentController(e echo.Context) error {
+	customResource, ok := e.Request().Context().Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue)
+	if !ok {
+		log.Error("failed to get custom resource")
+		return e.JSON(http.StatusInternalServerError, dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      "failed to get custom resource",
+		})
+	}
+	logData, okData := e.Request().Context().Value(enum.CUSTOM_LOG_DATA).(*dto.CustomLoggerRequest)
+	if !okData {
+		log.Warn("failed to get custom logger")
+	}
+	logData.Remarks = "inquiry-consent"
+	res := ctr.consentService.InquiryConsentService(e.Request().Context(), customResource.AuthUUID, logData)
+	return ctr.response(e, res)
+}
+
+// @Tags Consent
+// @Summary accept legal documents
+// @Description record the acceptance of current legal documents, used to accept a new version
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Param X-APP-VERSION header string false "X-APP-VERSION"
+// @Param Authorization header string true "Authorization"
+// @Param request body request.AcceptConsentRequest true "request body"
+// @Success 200 {object} dto.BaseResponse
+// @Failure 400 {object} dto.BaseResponse
+// @Failure 401 {object} swagger.Unauthorized
+// @Failure 404 {object} swagger.UserNotFoundProfileFailureResponse
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/users/consents [post]
+func (ctr *consentController) AcceptConsentController(e echo.Context) error {
+	var (
+		req      request.AcceptConsentRequest
+		validate = validator.New()
+	)
+	customResource, ok := e.Request().Context().Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue)
+	if !ok {
+		log.Error("failed to get custom resource")
+		return e.JSON(http.StatusInternalServerError, dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      "failed to get custom resource",
+		})
+	}
+	logData, okData := e.Request().Context().Value(enum.CUSTOM_LOG_DATA).(*dto.CustomLoggerRequest)
+	if !okData {
+		log.Warn("failed to get custom logger")
+	}
+	logData.Remarks = "accept-consent"
+	err := e.Bind(&req)
+	if err != nil {
+		logData.Error = err.Error()
+		return echo.NewHTTPError(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.PROFILE_INVALID_PAYLOAD_CODE,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		})
+	}
+	if err = validate.Struct(req); err != nil {
+		err = helpers.CustomValidatePayload(err, req)
+		logData.Error = err.Error()
+		return echo.NewHTTPError(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.PROFILE_INVALID_PAYLOAD_CODE,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		})
+	}
+	res := ctr.consentService.AcceptConsentService(e.Request().Context(), &req, customResource.AuthUUID, logData)
+	return ctr.response(e, res)
+}
+
+func (ctr *consentController) response(e echo.Context, res *dto.BaseResponse) error {
+	switch res.StatusCode {
+	case pkgErr.SUCCESS_CODE:
+		return e.JSON(http.StatusOK, res)
+	case pkgErr.PROFILE_USER_NOT_FOUND_CODE:
+		return e.JSON(http.StatusNotFound, res)
+	case pkgErr.CONSENT_DOCUMENT_INVALID_CODE:
+		return e.JSON(http.StatusBadRequest, res)
+	default:
+		return e.JSON(http.StatusInternalServerError, res)
+	}
+}
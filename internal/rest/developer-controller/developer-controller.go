@@ -0,0 +1,86 @@
+package developerController
+
+import (
+	_ "backend-mobile-api/docs"
+	"backend-mobile-api/helpers"
+	"backend-mobile-api/model/dto"
+	"backend-mobile-api/model/dto/request"
+	_ "backend-mobile-api/model/dto/swagger"
+	"backend-mobile-api/model/enum"
+	"backend-mobile-api/model/enum/pkgErr"
+	developerService "backend-mobile-api/service/developer-svc"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"net/http"
+)
+
+type developerController struct {
+	developerService developerService.DeveloperService
+}
+
+func NewDeveloperController(developerService developerService.DeveloperService) DeveloperController {
+	return &developerController{
+		developerService: developerService,
+	}
+}
+
+type DeveloperController interface {
+	LastOtpController(e echo.Context) error
+}
+
+// @Tags Developer
+// @Summary last otp
+// @Description read the last otp the fake provider sent to a destination, not available in production
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Param destination query string true "phone number the otp was sent to"
+// @Success 200 {object} dto.BaseResponse
+// @Failure 400 {object} swagger.CommonError
+// @Failure 404 {object} swagger.CommonError
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/dev/otp [get]
+func (ctr *developerController) LastOtpController(e echo.Context) error {
+	var (
+		req      = request.LastOtpRequest{}
+		validate = validator.New()
+	)
+	logData, okData := e.Request().Context().Value(enum.CUSTOM_LOG_DATA).(*dto.CustomLoggerRequest)
+	if !okData {
+		log.Warn("failed to get custom logger")
+		logData = &dto.CustomLoggerRequest{}
+	}
+	logData.Remarks = "developer-last-otp"
+	if err := e.Bind(&req); err != nil {
+		logData.Error = err.Error()
+		return e.JSON(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_INVALID_REQUEST_PAYLOAD_CODE,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		})
+	}
+	if err := validate.Struct(&req); err != nil {
+		err = helpers.CustomValidatePayload(err, req)
+		logData.Error = err.Error()
+		return e.JSON(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_INVALID_REQUEST_PAYLOAD_CODE,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		})
+	}
+	res := ctr.developerService.LastOtpService(e.Request().Context(), &req, logData)
+	switch res.StatusCode {
+	case pkgErr.SUCCESS_CODE:
+		return e.JSON(http.StatusOK, res)
+	case pkgErr.OUTBOUND_RECORD_NOT_FOUND_CODE:
+		return e.JSON(http.StatusNotFound, res)
+	default:
+		return e.JSON(http.StatusInternalServerError, res)
+	}
+}
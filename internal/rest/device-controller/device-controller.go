@@ -0,0 +1,114 @@
+package deviceController
+
+import (
+	_ "backend-mobile-api/docs"
+	"backend-mobile-api/model/dto"
+	_ "backend-mobile-api/model/dto/swagger"
+	"backend-mobile-api/model/enum"
+	"backend-mobile-api/model/enum/pkgErr"
+	deviceService "backend-mobile-api/service/device-svc"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"net/http"
+)
+
+type deviceController struct {
+	deviceService deviceService.DeviceService
+}
+
+func NewDeviceController(deviceService deviceService.DeviceService) DeviceController {
+	return &deviceController{
+		deviceService: deviceService,
+	}
+}
+
+type DeviceController interface {
+	InquiryDeviceController(e echo.Context) error
+	RevokeDeviceController(e echo.Context) error
+}
+
+// @Tags Device
+// @Summary inquiry trusted devices
+// @Description list devices that can log in without a new-device otp
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Param Authorization header string true "Authorization"
+// @Success 200 {object} dto.BaseResponse
+// @Failure 401 {object} swagger.Unauthorized
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/users/devices [get]
+func (ctr *deviceController) InquiryDeviceController(e echo.Context) error {
+	customResource, ok := e.Request().Context().Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue)
+	if !ok {
+		log.Error("failed to get custom resource")
+		return e.JSON(http.StatusInternalServerError, dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      "failed to get custom resource",
+		})
+	}
+	logData, okData := e.Request().Context().Value(enum.CUSTOM_LOG_DATA).(*dto.CustomLoggerRequest)
+	if !okData {
+		log.Warn("failed to get custom logger")
+	}
+	logData.Remarks = "inquiry-device"
+	res := ctr.deviceService.InquiryDeviceService(e.Request().Context(), customResource.AuthUUID, customResource.HeaderXDeviceID, logData)
+	return ctr.response(e, res)
+}
+
+// @Tags Device
+// @Summary remove trusted device
+// @Description untrust a device and log out its sessions, the device needs a new-device otp on its next login
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Param Authorization header string true "Authorization"
+// @Param id path string true "device id"
+// @Success 200 {object} dto.BaseResponse
+// @Failure 400 {object} dto.BaseResponse
+// @Failure 401 {object} swagger.Unauthorized
+// @Failure 404 {object} dto.BaseResponse
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/users/devices/{id} [delete]
+func (ctr *deviceController) RevokeDeviceController(e echo.Context) error {
+	customResource, ok := e.Request().Context().Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue)
+	if !ok {
+		log.Error("failed to get custom resource")
+		return e.JSON(http.StatusInternalServerError, dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      "failed to get custom resource",
+		})
+	}
+	logData, okData := e.Request().Context().Value(enum.CUSTOM_LOG_DATA).(*dto.CustomLoggerRequest)
+	if !okData {
+		log.Warn("failed to get custom logger")
+	}
+	logData.Remarks = "revoke-device"
+	res := ctr.deviceService.RevokeDeviceService(e.Request().Context(), customResource.AuthUUID, customResource.HeaderXDeviceID, e.Param("id"), logData)
+	return ctr.response(e, res)
+}
+
+func (ctr *deviceController) response(e echo.Context, res *dto.BaseResponse) error {
+	switch res.StatusCode {
+	case pkgErr.SUCCESS_CODE:
+		return e.JSON(http.StatusOK, res)
+	case pkgErr.DEVICE_NOT_FOUND_CODE, pkgErr.PROFILE_USER_NOT_FOUND_CODE:
+		return e.JSON(http.StatusNotFound, res)
+	case pkgErr.DEVICE_CURRENT_REVOKE_CODE:
+		return e.JSON(http.StatusBadRequest, res)
+	default:
+		return e.JSON(http.StatusInternalServerError, res)
+	}
+}
@@ -0,0 +1,22 @@
+package rest
+
+import (
+	customMiddleware "backend-mobile-api/internal/middleware"
+	"net/http"
+
+	echo "github.com/labstack/echo/v4"
+)
+
+// jwksHandler godoc
+// @Summary JSON Web Key Set
+// @Description Public keys access tokens are signed with, selected by the kid in the token header. Internal services cache the set and fetch it again on an unknown kid.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} middleware.JWKSet
+// @Router /.well-known/jwks.json [get]
+func jwksHandler(middlewareCustom customMiddleware.CustomMiddleware) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Set("Cache-Control", "public, max-age=300")
+		return c.JSON(http.StatusOK, middlewareCustom.JWKS(c.Request().Context()))
+	}
+}
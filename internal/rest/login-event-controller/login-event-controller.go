@@ -0,0 +1,105 @@
+package loginEventController
+
+import (
+	_ "backend-mobile-api/docs"
+	"backend-mobile-api/model/dto"
+	_ "backend-mobile-api/model/dto/swagger"
+	"backend-mobile-api/model/enum"
+	"backend-mobile-api/model/enum/pkgErr"
+	loginEventService "backend-mobile-api/service/login-event-svc"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"net/http"
+	"strconv"
+)
+
+type loginEventController struct {
+	loginEventService loginEventService.LoginEventService
+}
+
+func NewLoginEventController(loginEventService loginEventService.LoginEventService) LoginEventController {
+	return &loginEventController{
+		loginEventService: loginEventService,
+	}
+}
+
+type LoginEventController interface {
+	InquiryLoginHistoryController(e echo.Context) error
+}
+
+// @Tags Session
+// @Summary inquiry login history
+// @Description successful and failed logins of the user, newest first
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Param Authorization header string true "Authorization"
+// @Param page query int false "page, starts at 1"
+// @Param limit query int false "events per page"
+// @Success 200 {object} dto.BaseResponse
+// @Failure 400 {object} dto.BaseResponse
+// @Failure 401 {object} swagger.Unauthorized
+// @Failure 404 {object} swagger.UserNotFoundProfileFailureResponse
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/users/login-history [get]
+func (ctr *loginEventController) InquiryLoginHistoryController(e echo.Context) error {
+	customResource, ok := e.Request().Context().Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue)
+	if !ok {
+		log.Error("failed to get custom resource")
+		return e.JSON(http.StatusInternalServerError, dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      "failed to get custom resource",
+		})
+	}
+	logData, okData := e.Request().Context().Value(enum.CUSTOM_LOG_DATA).(*dto.CustomLoggerRequest)
+	if !okData {
+		log.Warn("failed to get custom logger")
+	}
+	logData.Remarks = "inquiry-login-history"
+	page, err := positiveQuery(e, "page", 1)
+	if err != nil {
+		logData.Error = err.Error()
+		return e.JSON(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.PROFILE_INVALID_PAYLOAD_CODE,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		})
+	}
+	limit, err := positiveQuery(e, "limit", 0)
+	if err != nil {
+		logData.Error = err.Error()
+		return e.JSON(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.PROFILE_INVALID_PAYLOAD_CODE,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		})
+	}
+	res := ctr.loginEventService.InquiryLoginHistoryService(e.Request().Context(), customResource.AuthUUID, page, limit, logData)
+	switch res.StatusCode {
+	case pkgErr.SUCCESS_CODE:
+		return e.JSON(http.StatusOK, res)
+	case pkgErr.PROFILE_USER_NOT_FOUND_CODE:
+		return e.JSON(http.StatusNotFound, res)
+	}
+	return e.JSON(http.StatusInternalServerError, res)
+}
+
+// positiveQuery reads an optional positive number from the query, fallback when absent.
+func positiveQuery(e echo.Context, name string, fallback int) (int, error) {
+	raw := e.QueryParam(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.Atoi(raw)
+	if err != nil || parsed < 1 {
+		return 0, fmt.Errorf("%s must be a positive number", name)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,281 @@
+package paymentRequestController
+
+import (
+	"backend-mobile-api/helpers"
+	"backend-mobile-api/model/dto"
+	"backend-mobile-api/model/dto/request"
+	_ "backend-mobile-api/model/dto/swagger"
+	"backend-mobile-api/model/enum"
+	"backend-mobile-api/model/enum/pkgErr"
+	paymentRequestService "backend-mobile-api/service/payment-request-svc"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"net/http"
+)
+
+type paymentRequestController struct {
+	paymentRequestService paymentRequestService.PaymentRequestService
+}
+
+func NewPaymentRequestController(paymentRequestService paymentRequestService.PaymentRequestService) PaymentRequestController {
+	return &paymentRequestController{
+		paymentRequestService: paymentRequestService,
+	}
+}
+
+type PaymentRequestController interface {
+	CreatePaymentRequestController(e echo.Context) error
+	InquiryPaymentRequestController(e echo.Context) error
+	OutgoingPaymentRequestController(e echo.Context) error
+	IncomingPaymentRequestController(e echo.Context) error
+	PayPaymentRequestController(e echo.Context) error
+	DeclinePaymentRequestController(e echo.Context) error
+}
+
+// @Tags Payment Request
+// @Summary create payment request
+// @Description request money from one or more registered users, the bill is split evenly or by custom amounts
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Param Authorization header string true "Authorization"
+// @Param data body request.CreatePaymentRequest true "Create Payment Request"
+// @Success 200 {object} swagger.BasicSuccess
+// @Failure 400 {object} swagger.CommonError
+// @Failure 401 {object} swagger.Unauthorized
+// @Failure 404 {object} swagger.CommonError
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/users/payment-requests/create [post]
+func (ctr *paymentRequestController) CreatePaymentRequestController(e echo.Context) error {
+	var (
+		req      request.CreatePaymentRequest
+		validate = validator.New()
+	)
+	userUUID, logData, errResp := contextData(e, "create-payment-request")
+	if errResp != nil {
+		return e.JSON(http.StatusInternalServerError, errResp)
+	}
+	if errResp = bindAndValidate(e, validate, &req, logData); errResp != nil {
+		return e.JSON(http.StatusBadRequest, errResp)
+	}
+	res := ctr.paymentRequestService.CreatePaymentRequestService(e.Request().Context(), &req, &userUUID, logData)
+	return e.JSON(httpStatus(res), res)
+}
+
+// @Tags Payment Request
+// @Summary inquiry payment request
+// @Description detail of a payment request with the status of every participant
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Param Authorization header string true "Authorization"
+// @Param request_id query string true "request id"
+// @Success 200 {object} swagger.BasicSuccess
+// @Failure 401 {object} swagger.Unauthorized
+// @Failure 404 {object} swagger.CommonError
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/users/payment-requests/inquiry [get]
+func (ctr *paymentRequestController) InquiryPaymentRequestController(e echo.Context) error {
+	userUUID, logData, errResp := contextData(e, "inquiry-payment-request")
+	if errResp != nil {
+		return e.JSON(http.StatusInternalServerError, errResp)
+	}
+	requestID := e.QueryParam("request_id")
+	if requestID == "" {
+		logData.Error = "request_id is required"
+		return e.JSON(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.PAYMENT_REQUEST_INVALID_PAYLOAD_CODE,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      logData.Error,
+		})
+	}
+	res := ctr.paymentRequestService.InquiryPaymentRequestService(e.Request().Context(), &requestID, &userUUID, logData)
+	return e.JSON(httpStatus(res), res)
+}
+
+// @Tags Payment Request
+// @Summary outgoing payment request
+// @Description payment requests created by the user
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Param Authorization header string true "Authorization"
+// @Success 200 {object} swagger.BasicSuccess
+// @Failure 401 {object} swagger.Unauthorized
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/users/payment-requests/outgoing [get]
+func (ctr *paymentRequestController) OutgoingPaymentRequestController(e echo.Context) error {
+	userUUID, logData, errResp := contextData(e, "outgoing-payment-request")
+	if errResp != nil {
+		return e.JSON(http.StatusInternalServerError, errResp)
+	}
+	res := ctr.paymentRequestService.OutgoingPaymentRequestService(e.Request().Context(), &userUUID, logData)
+	return e.JSON(httpStatus(res), res)
+}
+
+// @Tags Payment Request
+// @Summary incoming payment request
+// @Description payment requests where the user is a participant
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Param Authorization header string true "Authorization"
+// @Success 200 {object} swagger.BasicSuccess
+// @Failure 401 {object} swagger.Unauthorized
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/users/payment-requests/incoming [get]
+func (ctr *paymentRequestController) IncomingPaymentRequestController(e echo.Context) error {
+	userUUID, logData, errResp := contextData(e, "incoming-payment-request")
+	if errResp != nil {
+		return e.JSON(http.StatusInternalServerError, errResp)
+	}
+	res := ctr.paymentRequestService.IncomingPaymentRequestService(e.Request().Context(), &userUUID, logData)
+	return e.JSON(httpStatus(res), res)
+}
+
+// @Tags Payment Request
+// @Summary pay payment request
+// @Description participant pays their share with a pending transaction, the share is marked paid once the transaction succeeds
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Param Authorization header string true "Authorization"
+// @Param data body request.PayPaymentRequest true "Pay Payment Request"
+// @Success 200 {object} swagger.BasicSuccess
+// @Failure 400 {object} swagger.CommonError
+// @Failure 401 {object} swagger.Unauthorized
+// @Failure 404 {object} swagger.CommonError
+// @Failure 409 {object} swagger.CommonError
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/users/payment-requests/pay [post]
+func (ctr *paymentRequestController) PayPaymentRequestController(e echo.Context) error {
+	var (
+		req      request.PayPaymentRequest
+		validate = validator.New()
+	)
+	userUUID, logData, errResp := contextData(e, "pay-payment-request")
+	if errResp != nil {
+		return e.JSON(http.StatusInternalServerError, errResp)
+	}
+	if errResp = bindAndValidate(e, validate, &req, logData); errResp != nil {
+		return e.JSON(http.StatusBadRequest, errResp)
+	}
+	res := ctr.paymentRequestService.PayPaymentRequestService(e.Request().Context(), &req, &userUUID, logData)
+	return e.JSON(httpStatus(res), res)
+}
+
+// @Tags Payment Request
+// @Summary decline payment request
+// @Description participant declines their share
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Param Authorization header string true "Authorization"
+// @Param data body request.DeclinePaymentRequest true "Decline Payment Request"
+// @Success 200 {object} swagger.BasicSuccess
+// @Failure 400 {object} swagger.CommonError
+// @Failure 401 {object} swagger.Unauthorized
+// @Failure 404 {object} swagger.CommonError
+// @Failure 409 {object} swagger.CommonError
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/users/payment-requests/decline [post]
+func (ctr *paymentRequestController) DeclinePaymentRequestController(e echo.Context) error {
+	var (
+		req      request.DeclinePaymentRequest
+		validate = validator.New()
+	)
+	userUUID, logData, errResp := contextData(e, "decline-payment-request")
+	if errResp != nil {
+		return e.JSON(http.StatusInternalServerError, errResp)
+	}
+	if errResp = bindAndValidate(e, validate, &req, logData); errResp != nil {
+		return e.JSON(http.StatusBadRequest, errResp)
+	}
+	res := ctr.paymentRequestService.DeclinePaymentRequestService(e.Request().Context(), &req, &userUUID, logData)
+	return e.JSON(httpStatus(res), res)
+}
+
+func contextData(e echo.Context, remarks string) (string, *dto.CustomLoggerRequest, *dto.BaseResponse) {
+	customResource, ok := e.Request().Context().Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue)
+	if !ok {
+		log.Error("failed to get custom resource")
+		return "", nil, &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      "failed to get custom resource",
+		}
+	}
+	logData, okData := e.Request().Context().Value(enum.CUSTOM_LOG_DATA).(*dto.CustomLoggerRequest)
+	if !okData {
+		log.Warn("failed to get custom logger")
+		logData = &dto.CustomLoggerRequest{}
+	}
+	logData.Remarks = remarks
+	return customResource.AuthUUID, logData, nil
+}
+
+func bindAndValidate(e echo.Context, validate *validator.Validate, req any, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	err := e.Bind(req)
+	if err == nil {
+		if err = validate.Struct(req); err != nil {
+			err = helpers.CustomValidatePayload(err, req)
+		}
+	}
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.PAYMENT_REQUEST_INVALID_PAYLOAD_CODE,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		}
+	}
+	return nil
+}
+
+func httpStatus(res *dto.BaseResponse) int {
+	switch res.StatusCode {
+	case pkgErr.SUCCESS_CODE:
+		return http.StatusOK
+	case pkgErr.PAYMENT_REQUEST_INVALID_PAYLOAD_CODE, pkgErr.PAYMENT_REQUEST_INVALID_AMOUNT_CODE:
+		return http.StatusBadRequest
+	case pkgErr.PAYMENT_REQUEST_USER_NOT_FOUND_CODE, pkgErr.PAYMENT_REQUEST_NOT_FOUND_CODE:
+		return http.StatusNotFound
+	case pkgErr.PAYMENT_REQUEST_EXPIRED_CODE:
+		return http.StatusGone
+	case pkgErr.PAYMENT_REQUEST_ALREADY_RESPONDED_CODE:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
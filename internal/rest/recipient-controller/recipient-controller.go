@@ -1,14 +1,21 @@
 package recipientController
 
 import (
+	"backend-mobile-api/helpers"
 	"backend-mobile-api/model/dto"
 	"backend-mobile-api/model/entity"
+	"backend-mobile-api/model/enum"
 	"backend-mobile-api/model/enum/pkgErr"
+	accountinquirysvc "backend-mobile-api/service/account-inquiry-svc"
 	service "backend-mobile-api/service/recipient-svc"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 )
 
 type RecipientController struct {
@@ -22,9 +29,34 @@ func NewRecipientController(service service.RecipientService) RecipientControlle
 	return RecipientController{service: service}
 }
 
+// DTO khusus request
+type CreateRecipientRequest struct {
+	Bank         int    `json:"bank_id" validate:"required"`
+	NamaPenerima string `json:"nama_penerima"` // opsional, harus sama dengan hasil check-account
+	NoRekening   string `json:"no_rekening" validate:"required"`
+	Alias        string `json:"alias" validate:"max=100"`
+}
+type UpdateRecipientRequest struct {
+	RecipientID uint    `json:"recipient_id" validate:"required"`
+	Bank        *int    `json:"bank_id"`
+	NoRekening  *string `json:"no_rekening" validate:"omitempty,min=1"`
+	Alias       *string `json:"alias" validate:"omitempty,max=100"`
+}
+type DeleteRecipientRequest struct {
+	RecipientID uint `json:"recipient_id" validate:"required"`
+}
+type FavoriteRecipientRequest struct {
+	RecipientID uint `json:"recipient_id" validate:"required"`
+	IsFavorite  bool `json:"is_favorite"`
+}
+
 // ✅ GET /recipients?search=xxx
 func (c RecipientController) GetRecipients(ctx echo.Context) error {
 	keyword := ctx.QueryParam("search")
+	userUUID, ok := authUUID(ctx)
+	if !ok {
+		return unauthorized(ctx)
+	}
 
 	var (
 		recipients []entity.RecipientWithBank
@@ -32,17 +64,13 @@ func (c RecipientController) GetRecipients(ctx echo.Context) error {
 	)
 
 	if keyword != "" {
-		recipients, err = c.service.SearchRecipients(ctx.Request().Context(), keyword)
+		recipients, err = c.service.SearchRecipients(ctx.Request().Context(), userUUID, keyword)
 	} else {
-		recipients, err = c.service.GetRecipients(ctx.Request().Context())
+		recipients, err = c.service.GetRecipients(ctx.Request().Context(), userUUID)
 	}
 
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, dto.BaseResponse{
-			StatusCode: pkgErr.INTERNAL_SERVER_ERROR_CODE,
-			Message:    pkgErr.INTERNAL_SERVER_MSG,
-			Error:      err.Error(),
-		})
+		return errorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, dto.BaseResponse{
@@ -52,52 +80,209 @@ func (c RecipientController) GetRecipients(ctx echo.Context) error {
 	})
 }
 
+// ✅ GET /recipient/suggestions?limit=10
+func (c RecipientController) GetRecipientSuggestions(ctx echo.Context) error {
+	userUUID, ok := authUUID(ctx)
+	if !ok {
+		return unauthorized(ctx)
+	}
+
+	limit := 0
+	if raw := ctx.QueryParam("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			return invalidPayload(ctx, errors.New("limit must be a positive number"))
+		}
+		limit = parsed
+	}
+
+	suggestions, err := c.service.GetRecipientSuggestions(ctx.Request().Context(), userUUID, limit)
+	if err != nil {
+		return errorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+		Data:       suggestions,
+	})
+}
+
 // ✅ POST /recipients
 func (c RecipientController) CreateRecipient(ctx echo.Context) error {
-	var req entity.Recipient
+	var req CreateRecipientRequest
 
-	// bind payload
-	if err := ctx.Bind(&req); err != nil {
-		return ctx.JSON(http.StatusBadRequest, dto.BaseResponse{
-			StatusCode: pkgErr.INVALID_REQUEST_PAYLOAD_CODE,
-			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
-			Error:      err.Error(),
-		})
+	// bind & validasi payload
+	if err := bindAndValidate(ctx, &req); err != nil {
+		return invalidPayload(ctx, err)
 	}
 
-	// pastikan UUID ada di payload
-	if req.UserUUID == "" {
-		return ctx.JSON(http.StatusBadRequest, dto.BaseResponse{
-			StatusCode: pkgErr.INVALID_REQUEST_PAYLOAD_CODE,
-			Message:    "user uuid is required",
-		})
+	// owner recipient selalu user dari JWT, bukan dari payload
+	userUUID, ok := authUUID(ctx)
+	if !ok {
+		return unauthorized(ctx)
 	}
 
-	// 🔑 ambil user dari service (yg nanti ke repo)
-	user, err := c.service.GetUserByUUID(ctx.Request().Context(), req.UserUUID)
-	if err != nil {
-		return ctx.JSON(http.StatusNotFound, dto.BaseResponse{
-			StatusCode: pkgErr.INVALID_REQUEST_PAYLOAD_CODE,
-			Message:    "user not found",
-			Error:      err.Error(),
-		})
+	recipient := entity.Recipient{
+		Bank:         req.Bank,
+		NamaPenerima: req.NamaPenerima,
+		NoRekening:   req.NoRekening,
+		Alias:        req.Alias,
 	}
 
-	// isi user_id dari DB
-	req.User = user.ID
-
 	// simpan recipient
-	if err := c.service.AddRecipient(ctx.Request().Context(), &req); err != nil {
-		return ctx.JSON(http.StatusInternalServerError, dto.BaseResponse{
-			StatusCode: pkgErr.INTERNAL_SERVER_ERROR_CODE,
-			Message:    pkgErr.INTERNAL_SERVER_MSG,
-			Error:      err.Error(),
-		})
+	if err := c.service.AddRecipient(ctx.Request().Context(), userUUID, &recipient); err != nil {
+		return errorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusCreated, dto.BaseResponse{
 		StatusCode: pkgErr.SUCCESS_CODE,
 		Message:    pkgErr.SUCCES_MSG,
-		Data:       req,
+		Data:       recipient,
+	})
+}
+
+// ✅ PATCH /recipient/update-recipient
+func (c RecipientController) UpdateRecipient(ctx echo.Context) error {
+	var req UpdateRecipientRequest
+	if err := bindAndValidate(ctx, &req); err != nil {
+		return invalidPayload(ctx, err)
+	}
+	userUUID, ok := authUUID(ctx)
+	if !ok {
+		return unauthorized(ctx)
+	}
+
+	recipient, err := c.service.UpdateRecipient(ctx.Request().Context(), userUUID, req.RecipientID, &service.RecipientUpdate{
+		Bank:       req.Bank,
+		NoRekening: req.NoRekening,
+		Alias:      req.Alias,
+	})
+	if err != nil {
+		return errorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+		Data:       recipient,
+	})
+}
+
+// ✅ POST /recipient/delete-recipient
+func (c RecipientController) DeleteRecipient(ctx echo.Context) error {
+	var req DeleteRecipientRequest
+	if err := bindAndValidate(ctx, &req); err != nil {
+		return invalidPayload(ctx, err)
+	}
+	userUUID, ok := authUUID(ctx)
+	if !ok {
+		return unauthorized(ctx)
+	}
+
+	if err := c.service.DeleteRecipient(ctx.Request().Context(), userUUID, req.RecipientID); err != nil {
+		return errorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+	})
+}
+
+// ✅ POST /recipient/favorite-recipient
+func (c RecipientController) FavoriteRecipient(ctx echo.Context) error {
+	var req FavoriteRecipientRequest
+	if err := bindAndValidate(ctx, &req); err != nil {
+		return invalidPayload(ctx, err)
+	}
+	userUUID, ok := authUUID(ctx)
+	if !ok {
+		return unauthorized(ctx)
+	}
+
+	recipient, err := c.service.SetFavoriteRecipient(ctx.Request().Context(), userUUID, req.RecipientID, req.IsFavorite)
+	if err != nil {
+		return errorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+		Data:       recipient,
+	})
+}
+
+// ambil uuid user dari JWT yang sudah divalidasi middleware
+func authUUID(ctx echo.Context) (string, bool) {
+	customResource, ok := ctx.Request().Context().Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue)
+	if !ok || customResource.AuthUUID == "" {
+		return "", false
+	}
+	return customResource.AuthUUID, true
+}
+
+func bindAndValidate(ctx echo.Context, req interface{}) error {
+	if err := ctx.Bind(req); err != nil {
+		return err
+	}
+	if err := validator.New().Struct(req); err != nil {
+		return helpers.CustomValidatePayload(err, req)
+	}
+	return nil
+}
+
+func invalidPayload(ctx echo.Context, err error) error {
+	return ctx.JSON(http.StatusBadRequest, dto.BaseResponse{
+		StatusCode: pkgErr.INVALID_REQUEST_PAYLOAD_CODE,
+		Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+		Error:      err.Error(),
+	})
+}
+
+func unauthorized(ctx echo.Context) error {
+	return ctx.JSON(http.StatusUnauthorized, dto.BaseResponse{
+		StatusCode: pkgErr.AUTH_UNAUTHORIZED_CODE,
+		Message:    pkgErr.UNAUTHORIZED_MSG,
+	})
+}
+
+func errorResponse(ctx echo.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrRecipientNotFound):
+		return ctx.JSON(http.StatusNotFound, dto.BaseResponse{
+			StatusCode: pkgErr.RECIPIENT_NOT_FOUND_CODE,
+			Message:    pkgErr.RECIPIENT_NOT_FOUND_MSG,
+			Error:      err.Error(),
+		})
+	case errors.Is(err, service.ErrRecipientAlreadyExists):
+		return ctx.JSON(http.StatusConflict, dto.BaseResponse{
+			StatusCode: pkgErr.RECIPIENT_ALREADY_EXISTS_CODE,
+			Message:    pkgErr.RECIPIENT_ALREADY_EXISTS_MSG,
+			Error:      err.Error(),
+		})
+	case errors.Is(err, accountinquirysvc.ErrAccountNotConfirmed), errors.Is(err, service.ErrRecipientNameMismatch):
+		return ctx.JSON(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.ACCOUNT_NOT_CONFIRMED_CODE,
+			Message:    pkgErr.ACCOUNT_NOT_CONFIRMED_MSG,
+			Error:      err.Error(),
+		})
+	case errors.Is(err, service.ErrBankNotFound), errors.Is(err, service.ErrBankCodeMissing):
+		return ctx.JSON(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.INVALID_REQUEST_PAYLOAD_CODE,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ctx.JSON(http.StatusNotFound, dto.BaseResponse{
+			StatusCode: pkgErr.INVALID_REQUEST_PAYLOAD_CODE,
+			Message:    pkgErr.USER_NOT_FOUND_MSG,
+			Error:      err.Error(),
+		})
+	}
+	return ctx.JSON(http.StatusInternalServerError, dto.BaseResponse{
+		StatusCode: pkgErr.INTERNAL_SERVER_ERROR_CODE,
+		Message:    pkgErr.INTERNAL_SERVER_MSG,
+		Error:      err.Error(),
 	})
 }
@@ -5,14 +5,21 @@ import (
 	articleController "backend-mobile-api/internal/rest/article-controller"
 	bankListController "backend-mobile-api/internal/rest/bank-list-controller"
 	checkaccountbankcontroller "backend-mobile-api/internal/rest/check-account-bank-controller"
+	consentController "backend-mobile-api/internal/rest/consent-controller"
+	developerController "backend-mobile-api/internal/rest/developer-controller"
+	deviceController "backend-mobile-api/internal/rest/device-controller"
 	kycCtr "backend-mobile-api/internal/rest/kyc-controller"
+	loginEventController "backend-mobile-api/internal/rest/login-event-controller"
+	paymentRequestController "backend-mobile-api/internal/rest/payment-request-controller"
 	ppobListController "backend-mobile-api/internal/rest/ppob-list-controller"
 	recipientController "backend-mobile-api/internal/rest/recipient-controller"
+	sessionController "backend-mobile-api/internal/rest/session-controller"
 	transactionController "backend-mobile-api/internal/rest/transactions-controller"
 	userPaymentAccountController "backend-mobile-api/internal/rest/user-account-payments-controller"
 	userAuthCtr "backend-mobile-api/internal/rest/user-auth-controller"
 	userProfileController "backend-mobile-api/internal/rest/user-profile-controller"
 	verihubsInvokerCtr "backend-mobile-api/internal/rest/verihubs-invoker-controller"
+	"backend-mobile-api/model/enum"
 
 	"github.com/labstack/gommon/log"
 
@@ -32,14 +39,27 @@ type Controller struct {
 	PpobListController            ppobListController.PpobListController
 	TransactionController         transactionController.TransactionController
 	UserAccountPaymentsController userPaymentAccountController.UserAccountPaymentsController
+	PaymentRequestController      paymentRequestController.PaymentRequestController
+	SessionController             sessionController.SessionController
+	DeviceController              deviceController.DeviceController
+	ConsentController             consentController.ConsentController
+	LoginEventController          loginEventController.LoginEventController
+	// DeveloperController is only set outside production when DEV_OTP_ENDPOINT_ENABLED is true
+	DeveloperController developerController.DeveloperController
 }
 
-func RouthInit(e *echo.Group, ctr *Controller, middlewareCustom customMiddleware.CustomMiddleware) {
+// RouthInit registers the routes and returns the roles and permissions each
+// restricted route needs, every internal route is for admins and services only.
+func RouthInit(e *echo.Group, ctr *Controller, middlewareCustom customMiddleware.CustomMiddleware) customMiddleware.RouteAccessList {
 	log.Info("RouthInit")
+	access := customMiddleware.RouteAccessList{}
+	internalRoles := []enum.RolesEnum{enum.ROLE_ADMIN, enum.ROLE_SERVICE}
+
 	e.GET("/health", func(c echo.Context) error {
 		return c.String(200, "OK")
 	})
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
+	e.GET("/.well-known/jwks.json", jwksHandler(middlewareCustom))
 
 	e.Use(customMiddleware.RateLimitMiddleware())
 	internalV1 := e.Group("/api/internal/v1")
@@ -56,15 +76,39 @@ func RouthInit(e *echo.Group, ctr *Controller, middlewareCustom customMiddleware
 	authRouth.POST("/refresh-token", ctr.UserAuthController.RefreshTokenController)
 	authRouth.POST("/otp/send", ctr.UserAuthController.SendOtpController)
 	authRouth.POST("/otp/verify", ctr.UserAuthController.VerifyOtpController)
+	authRouth.POST("/otp/status", ctr.UserAuthController.OtpStatusController)
 	authRouth.POST("/set-pin", ctr.UserAuthController.SetPinController)
 	authRouth.POST("/forgot-pin", ctr.UserAuthController.ForgotPinController)
+	authRouth.POST("/device/verify", ctr.UserAuthController.VerifyDeviceController)
+	authRouth.POST("/biometric/challenge", ctr.UserAuthController.BiometricChallengeController)
+
+	//sessions
+	sessions := users.Group("/sessions")
+	sessions.GET("", ctr.SessionController.InquirySessionController)
+	sessions.POST("/logout-others", ctr.SessionController.RevokeOtherSessionController)
+	sessions.DELETE("/:id", ctr.SessionController.RevokeSessionController)
+	users.GET("/login-history", ctr.LoginEventController.InquiryLoginHistoryController)
+
+	//legal consents
+	v1.GET("/legal-documents", ctr.ConsentController.InquiryDocumentController)
+	consents := users.Group("/consents")
+	consents.GET("", ctr.ConsentController.InquiryConsentController)
+	consents.POST("", ctr.ConsentController.AcceptConsentController)
+
+	//trusted devices
+	devices := users.Group("/devices")
+	devices.GET("", ctr.DeviceController.InquiryDeviceController)
+	devices.DELETE("/:id", ctr.DeviceController.RevokeDeviceController)
 
 	//verhubs
 	verihubs := internalV1.Group("/verihubs")
+	// verihubs can not send an api key, each callback carries the token of its otp instead
 	verihubs.GET("/otp-invoker", ctr.VerihubsInvoker.InvokerOtpController)
-	verihubs.POST("/verify-ktp", ctr.KycController.VerifyKycKTP)
-	verihubs.POST("/verify-passport", ctr.KycController.VerifyKycPassport)
-	verihubs.POST("/verify-selfie", ctr.KycController.VerifySelfie)
+	access.Require(verihubs.GET("/otp-metrics", ctr.VerihubsInvoker.OtpMetricsController), internalRoles, enum.PERMISSION_OTP_METRICS)
+	access.Require(verihubs.GET("/otp-timeline", ctr.VerihubsInvoker.OtpTimelineController), internalRoles, enum.PERMISSION_OTP_METRICS)
+	access.Require(verihubs.POST("/verify-ktp", ctr.KycController.VerifyKycKTP), internalRoles, enum.PERMISSION_KYC_VERIFY)
+	access.Require(verihubs.POST("/verify-passport", ctr.KycController.VerifyKycPassport), internalRoles, enum.PERMISSION_KYC_VERIFY)
+	access.Require(verihubs.POST("/verify-selfie", ctr.KycController.VerifySelfie), internalRoles, enum.PERMISSION_KYC_VERIFY)
 
 	// kyc controller
 	userKyc := users.Group("/kyc")
@@ -82,16 +126,19 @@ func RouthInit(e *echo.Group, ctr *Controller, middlewareCustom customMiddleware
 	profileRouth.POST("/reset/profile-image", ctr.UserProfileController.ResetProfileImageController)
 	profileRouth.POST("/otp/verify", ctr.UserProfileController.VerifyOtpController)
 	profileRouth.POST("/biometric", ctr.UserProfileController.BiometricController)
+	profileRouth.GET("/biometric/keys", ctr.UserProfileController.InquiryBiometricKeyController)
+	profileRouth.DELETE("/biometric/keys/:id", ctr.UserProfileController.RevokeBiometricKeyController)
 	profileRouth.POST("/delete-account", ctr.UserProfileController.DeleteAccountController)
 	profileRouth.GET("/profile-image", ctr.UserProfileController.GetProfilePictureController)
+	profileRouth.GET("/export", ctr.UserProfileController.ExportDataController)
 
 	//articles
 	internalArticle := internalV1.Group("/articles")
 	articles := v1.Group("/articles")
-	internalArticle.POST("/insert", ctr.ArticleController.InsertNewArticleController)
-	internalArticle.PATCH("/update", ctr.ArticleController.UpdateArticleController)
-	internalArticle.POST("/delete", ctr.ArticleController.DeleteArticleController)
-	internalArticle.POST("/list", ctr.ArticleController.InternalGetArticleController)
+	access.Require(internalArticle.POST("/insert", ctr.ArticleController.InsertNewArticleController), internalRoles, enum.PERMISSION_ARTICLE_WRITE)
+	access.Require(internalArticle.PATCH("/update", ctr.ArticleController.UpdateArticleController), internalRoles, enum.PERMISSION_ARTICLE_WRITE)
+	access.Require(internalArticle.POST("/delete", ctr.ArticleController.DeleteArticleController), internalRoles, enum.PERMISSION_ARTICLE_WRITE)
+	access.Require(internalArticle.POST("/list", ctr.ArticleController.InternalGetArticleController), internalRoles, enum.PERMISSION_ARTICLE_READ)
 	articles.POST("/list", ctr.ArticleController.GetArticleController)
 
 	//inquiry bank list
@@ -104,6 +151,10 @@ func RouthInit(e *echo.Group, ctr *Controller, middlewareCustom customMiddleware
 	recipient := users.Group("/recipient")
 	recipient.POST("/save-recipient", ctr.RecipientController.CreateRecipient)
 	recipient.GET("/inquiry-recipient", ctr.RecipientController.GetRecipients)
+	recipient.GET("/suggestions", ctr.RecipientController.GetRecipientSuggestions)
+	recipient.PATCH("/update-recipient", ctr.RecipientController.UpdateRecipient)
+	recipient.POST("/delete-recipient", ctr.RecipientController.DeleteRecipient)
+	recipient.POST("/favorite-recipient", ctr.RecipientController.FavoriteRecipient)
 
 	//check account bank
 	checkAccountBank := users.Group("/check-account")
@@ -114,9 +165,29 @@ func RouthInit(e *echo.Group, ctr *Controller, middlewareCustom customMiddleware
 	transactions.POST("/generate", ctr.TransactionController.GenerateTransactionCode)
 	transactions.POST("", ctr.TransactionController.CreateTransaction) // create transaksi
 	transactions.GET("", ctr.TransactionController.GetAllTransactions)
+	// users may only cancel their own pending transaction, settling is done by the payment callback
 	transactions.POST("/status-update", ctr.TransactionController.UpdateTransactionStatus)
+	internalTransactions := internalV1.Group("/transactions")
+	access.Require(internalTransactions.POST("/status-update", ctr.TransactionController.SettleTransactionStatus), internalRoles, enum.PERMISSION_TRANSACTION_SETTLE)
 	// get userAccountPayments
 
 	userAccountPayment := users.Group("/user-account-payment")
 	userAccountPayment.GET("", ctr.UserAccountPaymentsController.GetUserAccountPaymentsController)
+
+	// payment request & split bill
+	paymentRequest := users.Group("/payment-requests")
+	paymentRequest.POST("/create", ctr.PaymentRequestController.CreatePaymentRequestController)
+	paymentRequest.GET("/inquiry", ctr.PaymentRequestController.InquiryPaymentRequestController)
+	paymentRequest.GET("/outgoing", ctr.PaymentRequestController.OutgoingPaymentRequestController)
+	paymentRequest.GET("/incoming", ctr.PaymentRequestController.IncomingPaymentRequestController)
+	paymentRequest.POST("/pay", ctr.PaymentRequestController.PayPaymentRequestController)
+	paymentRequest.POST("/decline", ctr.PaymentRequestController.DeclinePaymentRequestController)
+
+	// developer tooling, only registered on DEV_OTP_ENDPOINT_ENABLED and only answered to the host itself
+	if ctr.DeveloperController != nil {
+		dev := v1.Group("/dev", customMiddleware.LoopbackOnlyMiddleware())
+		dev.GET("/otp", ctr.DeveloperController.LastOtpController)
+	}
+
+	return access
 }
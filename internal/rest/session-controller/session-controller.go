@@ -0,0 +1,149 @@
+package sessionController
+
+import (
+	_ "backend-mobile-api/docs"
+	"backend-mobile-api/model/dto"
+	_ "backend-mobile-api/model/dto/swagger"
+	"backend-mobile-api/model/enum"
+	"backend-mobile-api/model/enum/pkgErr"
+	sessionService "backend-mobile-api/service/session-svc"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"net/http"
+)
+
+type sessionController struct {
+	sessionService sessionService.SessionService
+}
+
+func NewSessionController(sessionService sessionService.SessionService) SessionController {
+	return &sessionController{
+		sessionService: sessionService,
+	}
+}
+
+type SessionController interface {
+	InquirySessionController(e echo.Context) error
+	RevokeSessionController(e echo.Context) error
+	RevokeOtherSessionController(e echo.Context) error
+}
+
+// @Tags Session
+// @Summary inquiry active sessions
+// @Description list devices where the user is still logged in
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Param X-APP-VERSION header string false "X-APP-VERSION"
+// @Param Authorization header string true "Authorization"
+// @Success 200 {object} dto.BaseResponse
+// @Failure 401 {object} swagger.Unauthorized
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/users/sessions [get]
+func (ctr *sessionController) InquirySessionController(e echo.Context) error {
+	customResource, ok := e.Request().Context().Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue)
+	if !ok {
+		log.Error("failed to get custom resource")
+		return e.JSON(http.StatusInternalServerError, dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      "failed to get custom resource",
+		})
+	}
+	logData, okData := e.Request().Context().Value(enum.CUSTOM_LOG_DATA).(*dto.CustomLoggerRequest)
+	if !okData {
+		log.Warn("failed to get custom logger")
+	}
+	logData.Remarks = "inquiry-session"
+	res := ctr.sessionService.InquirySessionService(e.Request().Context(), customResource.AuthUUID, customResource.AuthFamilyID, logData)
+	return ctr.response(e, res)
+}
+
+// @Tags Session
+// @Summary revoke session
+// @Description log out one session, its tokens are blacklisted
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Param Authorization header string true "Authorization"
+// @Param id path string true "session id"
+// @Success 200 {object} dto.BaseResponse
+// @Failure 401 {object} swagger.Unauthorized
+// @Failure 404 {object} dto.BaseResponse
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/users/sessions/{id} [delete]
+func (ctr *sessionController) RevokeSessionController(e echo.Context) error {
+	customResource, ok := e.Request().Context().Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue)
+	if !ok {
+		log.Error("failed to get custom resource")
+		return e.JSON(http.StatusInternalServerError, dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      "failed to get custom resource",
+		})
+	}
+	logData, okData := e.Request().Context().Value(enum.CUSTOM_LOG_DATA).(*dto.CustomLoggerRequest)
+	if !okData {
+		log.Warn("failed to get custom logger")
+	}
+	logData.Remarks = "revoke-session"
+	res := ctr.sessionService.RevokeSessionService(e.Request().Context(), customResource.AuthUUID, e.Param("id"), logData)
+	return ctr.response(e, res)
+}
+
+// @Tags Session
+// @Summary log out other devices
+// @Description revoke every session except the one making the request
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Param Authorization header string true "Authorization"
+// @Success 200 {object} dto.BaseResponse
+// @Failure 401 {object} swagger.Unauthorized
+// @Failure 404 {object} dto.BaseResponse
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/users/sessions/logout-others [post]
+func (ctr *sessionController) RevokeOtherSessionController(e echo.Context) error {
+	customResource, ok := e.Request().Context().Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue)
+	if !ok {
+		log.Error("failed to get custom resource")
+		return e.JSON(http.StatusInternalServerError, dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      "failed to get custom resource",
+		})
+	}
+	logData, okData := e.Request().Context().Value(enum.CUSTOM_LOG_DATA).(*dto.CustomLoggerRequest)
+	if !okData {
+		log.Warn("failed to get custom logger")
+	}
+	logData.Remarks = "revoke-other-session"
+	res := ctr.sessionService.RevokeOtherSessionService(e.Request().Context(), customResource.AuthUUID, customResource.AuthFamilyID, logData)
+	return ctr.response(e, res)
+}
+
+func (ctr *sessionController) response(e echo.Context, res *dto.BaseResponse) error {
+	switch res.StatusCode {
+	case pkgErr.SUCCESS_CODE:
+		return e.JSON(http.StatusOK, res)
+	case pkgErr.SESSION_NOT_FOUND_CODE:
+		return e.JSON(http.StatusNotFound, res)
+	default:
+		return e.JSON(http.StatusInternalServerError, res)
+	}
+}
@@ -3,15 +3,21 @@ package transactionController
 import (
 	"backend-mobile-api/helpers"
 	"backend-mobile-api/model/dto"
+	"backend-mobile-api/model/dto/response"
 	"backend-mobile-api/model/entity"
+	"backend-mobile-api/model/enum"
 	"backend-mobile-api/model/enum/pkgErr"
+	"backend-mobile-api/service/otp"
+	pinAttemptSvc "backend-mobile-api/service/pin-attempt-svc"
 	service "backend-mobile-api/service/transactions-svc"
 	"encoding/json"
+	"errors"
 
 	"log"
 	"net/http"
 	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 )
 
@@ -44,10 +50,12 @@ type TransactionRequest struct {
 	PhoneCredit   *entity.TransactionPhoneCredit   `json:"phone_credit,omitempty"`
 	InternetTV    *entity.TransactionInternetTV    `json:"internet_tv,omitempty"`
 	International *entity.TransactionInternational `json:"international,omitempty"`
+	StepUp        *service.StepUpVerification      `json:"step_up,omitempty"`
+	Pin           string                           `json:"pin"`
 }
 type UpdateStatusRequest struct {
 	TransactionID string `json:"transaction_id" validate:"required"`
-	Status        string `json:"status" validate:"required,oneof=pending success failed canceled expired"`
+	Status        string `json:"status" validate:"required,oneof=success failed canceled expired"`
 }
 
 // object request
@@ -94,6 +102,29 @@ func (c TransactionController) CreateTransaction(ctx echo.Context) error {
 		})
 	}
 
+	// user dari JWT lebih diutamakan daripada user_uuid di payload
+	userUUID := req.UserUUID
+	deviceID := ""
+	if customResource, ok := ctx.Request().Context().Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue); ok {
+		if customResource.AuthUUID != "" {
+			userUUID = customResource.AuthUUID
+		}
+		deviceID = customResource.HeaderXDeviceID
+	}
+
+	// transaksi diotorisasi pin, pin kosong ditolak tanpa dihitung sebagai percobaan
+	if pinStatus, err := c.service.AuthorizePin(ctx.Request().Context(), userUUID, deviceID, req.Pin); err != nil {
+		return pinAuthorizationError(ctx, pinStatus, err)
+	}
+
+	// policy recipient baru: batas cooling-off & otp transfer pertama
+	if req.Type == "bank_transfer" && req.BankTransfer != nil {
+		challenge, err := c.service.AuthorizeBankTransfer(ctx.Request().Context(), userUUID, req.BankTransfer, float64(req.Nominal), req.StepUp)
+		if err != nil {
+			return recipientPolicyError(ctx, challenge, err)
+		}
+	}
+
 	// hitung total otomatis
 	total := req.Nominal + req.AdminFee
 
@@ -111,8 +142,11 @@ func (c TransactionController) CreateTransaction(ctx echo.Context) error {
 	}
 
 	// create transaksi utama
-	newtx, err := c.service.CreateTransaction(ctx.Request().Context(), &tx, req.UserUUID)
+	newtx, err := c.service.CreateTransaction(ctx.Request().Context(), &tx, userUUID)
 	if err != nil {
+		if req.Type == "bank_transfer" && req.BankTransfer != nil {
+			c.service.ReleaseBankTransfer(ctx.Request().Context(), userUUID, req.BankTransfer, float64(req.Nominal))
+		}
 		return ctx.JSON(http.StatusInternalServerError, dto.BaseResponse{
 			StatusCode: pkgErr.INTERNAL_SERVER_ERROR_CODE,
 			Message:    pkgErr.INTERNAL_SERVER_MSG,
@@ -126,6 +160,7 @@ func (c TransactionController) CreateTransaction(ctx echo.Context) error {
 		if req.BankTransfer != nil {
 			req.BankTransfer.TransactionID = newtx.TransactionID
 			if err := c.service.AddTransactionBankTransfer(ctx.Request().Context(), req.BankTransfer); err != nil {
+				c.service.ReleaseBankTransfer(ctx.Request().Context(), userUUID, req.BankTransfer, float64(req.Nominal))
 				return ctx.JSON(http.StatusInternalServerError, dto.BaseResponse{
 					StatusCode: pkgErr.INTERNAL_SERVER_ERROR_CODE,
 					Message:    pkgErr.INTERNAL_SERVER_MSG,
@@ -210,24 +245,37 @@ func (c TransactionController) GetTransaction(ctx echo.Context) error {
 	})
 }
 
-// ✅ PUT /transactions/status
+// ✅ POST /users/transactions/status-update, user hanya bisa membatalkan transaksi miliknya yang masih pending
 func (c TransactionController) UpdateTransactionStatus(ctx echo.Context) error {
 	var req UpdateStatusRequest
-	if err := ctx.Bind(&req); err != nil {
-		return ctx.JSON(http.StatusBadRequest, dto.BaseResponse{
-			StatusCode: pkgErr.INVALID_REQUEST_PAYLOAD_CODE,
-			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
-			Error:      err.Error(),
-		})
+	if errResp := bindStatusRequest(ctx, &req); errResp != nil {
+		return ctx.JSON(http.StatusBadRequest, errResp)
+	}
+	userUUID := ""
+	if customResource, ok := ctx.Request().Context().Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue); ok {
+		userUUID = customResource.AuthUUID
+	}
+
+	if err := c.service.UpdateOwnTransactionStatus(ctx.Request().Context(), userUUID, req.TransactionID, req.Status); err != nil {
+		return transactionStatusError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    "Transaction status updated successfully",
+	})
+}
+
+// ✅ POST /internal/transactions/status-update, dipanggil callback pembayaran dengan permission transaction:settle
+func (c TransactionController) SettleTransactionStatus(ctx echo.Context) error {
+	var req UpdateStatusRequest
+	if errResp := bindStatusRequest(ctx, &req); errResp != nil {
+		return ctx.JSON(http.StatusBadRequest, errResp)
 	}
 
 	// panggil service untuk update status + kirim notif
 	if err := c.service.UpdateTransactionStatus(ctx.Request().Context(), req.TransactionID, req.Status); err != nil {
-		return ctx.JSON(http.StatusInternalServerError, dto.BaseResponse{
-			StatusCode: pkgErr.INTERNAL_SERVER_ERROR_CODE,
-			Message:    pkgErr.INTERNAL_SERVER_MSG,
-			Error:      err.Error(),
-		})
+		return transactionStatusError(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, dto.BaseResponse{
@@ -236,6 +284,43 @@ func (c TransactionController) UpdateTransactionStatus(ctx echo.Context) error {
 	})
 }
 
+func bindStatusRequest(ctx echo.Context, req *UpdateStatusRequest) *dto.BaseResponse {
+	err := ctx.Bind(req)
+	if err == nil {
+		err = validator.New().Struct(req)
+	}
+	if err != nil {
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.INVALID_REQUEST_PAYLOAD_CODE,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		}
+	}
+	return nil
+}
+
+func transactionStatusError(ctx echo.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrTransactionNotFound):
+		return ctx.JSON(http.StatusNotFound, dto.BaseResponse{
+			StatusCode: pkgErr.INVALID_REQUEST_PAYLOAD_CODE,
+			Message:    "transaction not found",
+			Error:      err.Error(),
+		})
+	case errors.Is(err, service.ErrTransactionStatusChange):
+		return ctx.JSON(http.StatusConflict, dto.BaseResponse{
+			StatusCode: pkgErr.INVALID_REQUEST_PAYLOAD_CODE,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		})
+	}
+	return ctx.JSON(http.StatusInternalServerError, dto.BaseResponse{
+		StatusCode: pkgErr.INTERNAL_SERVER_ERROR_CODE,
+		Message:    pkgErr.INTERNAL_SERVER_MSG,
+		Error:      err.Error(),
+	})
+}
+
 // ✅ GET /transactions
 func (c TransactionController) GetAllTransactions(ctx echo.Context) error {
 	userUUID := ctx.QueryParam("user_uuid")
@@ -315,3 +400,58 @@ func (c TransactionController) GetAllTransactions(ctx echo.Context) error {
 		Data:       response,
 	})
 }
+
+func recipientPolicyError(ctx echo.Context, challenge *service.StepUpChallenge, err error) error {
+	if res := otp.ErrorResponse(err, pkgErr.TRANSFER_STEP_UP_INVALID_CODE, pkgErr.INVALID_OTP_MSG); res != nil {
+		if res.StatusCode == pkgErr.TRANSFER_STEP_UP_INVALID_CODE {
+			return ctx.JSON(http.StatusBadRequest, res)
+		}
+		return ctx.JSON(http.StatusTooManyRequests, res)
+	}
+	switch {
+	case errors.Is(err, service.ErrCoolingOffLimitExceeded):
+		return ctx.JSON(http.StatusForbidden, dto.BaseResponse{
+			StatusCode: pkgErr.TRANSFER_COOLING_OFF_LIMIT_CODE,
+			Message:    pkgErr.COOLING_OFF_LIMIT_MSG,
+			Error:      err.Error(),
+		})
+	case errors.Is(err, service.ErrStepUpRequired):
+		// app menampilkan input otp lalu kirim ulang transaksi dengan step_up
+		return ctx.JSON(http.StatusForbidden, dto.BaseResponse{
+			StatusCode: pkgErr.TRANSFER_STEP_UP_REQUIRED_CODE,
+			Message:    pkgErr.STEP_UP_REQUIRED_MSG,
+			Data:       challenge,
+		})
+	case errors.Is(err, service.ErrStepUpInvalid):
+		return ctx.JSON(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.TRANSFER_STEP_UP_INVALID_CODE,
+			Message:    pkgErr.INVALID_OTP_MSG,
+			Error:      err.Error(),
+		})
+	}
+	return ctx.JSON(http.StatusInternalServerError, dto.BaseResponse{
+		StatusCode: pkgErr.INTERNAL_SERVER_ERROR_CODE,
+		Message:    pkgErr.INTERNAL_SERVER_MSG,
+		Error:      err.Error(),
+	})
+}
+
+func pinAuthorizationError(ctx echo.Context, status *response.PinAttemptResponse, err error) error {
+	if errors.Is(err, service.ErrPinRequired) {
+		return ctx.JSON(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.INVALID_REQUEST_PAYLOAD_CODE,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		})
+	}
+	res := pinAttemptSvc.ErrorResponse(err, status, pkgErr.AUTH_UNAUTHORIZED_CODE, pkgErr.INVALID_PIN)
+	switch res.StatusCode {
+	case pkgErr.AUTH_UNAUTHORIZED_CODE:
+		return ctx.JSON(http.StatusUnauthorized, res)
+	case pkgErr.PIN_ATTEMPT_TOO_SOON_CODE, pkgErr.PIN_TEMPORARY_LOCKED_CODE:
+		return ctx.JSON(http.StatusTooManyRequests, res)
+	case pkgErr.PIN_PERMANENT_LOCKED_CODE:
+		return ctx.JSON(http.StatusForbidden, res)
+	}
+	return ctx.JSON(http.StatusInternalServerError, res)
+}
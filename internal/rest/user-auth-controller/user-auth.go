@@ -41,8 +41,11 @@ type UserAuthController interface {
 	RefreshTokenController(c echo.Context) error
 	VerifyOtpController(c echo.Context) error
 	SendOtpController(c echo.Context) error
+	OtpStatusController(c echo.Context) error
 	SetPinController(c echo.Context) error
 	ForgotPinController(c echo.Context) error
+	VerifyDeviceController(c echo.Context) error
+	BiometricChallengeController(c echo.Context) error
 }
 
 // @Tags Auth
@@ -102,6 +105,8 @@ func (ctr *userAuthController) RegisterController(e echo.Context) error {
 		return e.JSON(http.StatusBadRequest, resp)
 	case pkgErr.AUTH_USER_NOT_FOUND_CODE:
 		return e.JSON(http.StatusNotFound, resp)
+	case pkgErr.CONSENT_REQUIRED_CODE, pkgErr.CONSENT_DOCUMENT_INVALID_CODE:
+		return e.JSON(http.StatusBadRequest, resp)
 	}
 	return e.JSON(http.StatusInternalServerError, resp)
 }
@@ -227,18 +232,30 @@ func (ctr *userAuthController) LoginController(c echo.Context) error {
 	switch resp.StatusCode {
 	case pkgErr.SUCCESS_CODE:
 		return c.JSON(http.StatusOK, resp)
+	case pkgErr.OTP_RESEND_COOLDOWN_CODE, pkgErr.OTP_SEND_LIMIT_CODE, pkgErr.OTP_VERIFY_LIMIT_CODE:
+		return c.JSON(http.StatusTooManyRequests, resp)
 	case pkgErr.AUTH_USER_NOT_FOUND_CODE:
 		return c.JSON(http.StatusNotFound, resp)
 	case pkgErr.AUTH_UNAUTHORIZED_CODE:
 		return c.JSON(http.StatusUnauthorized, resp)
+	case pkgErr.AUTH_TOKEN_REUSED_CODE:
+		return c.JSON(http.StatusUnauthorized, resp)
+	case pkgErr.PIN_ATTEMPT_TOO_SOON_CODE, pkgErr.PIN_TEMPORARY_LOCKED_CODE:
+		return c.JSON(http.StatusTooManyRequests, resp)
+	case pkgErr.PIN_PERMANENT_LOCKED_CODE:
+		return c.JSON(http.StatusForbidden, resp)
 	case pkgErr.AUTH_UNVERIFIED_CODE:
 		return c.JSON(http.StatusUnauthorized, resp)
 	case pkgErr.AUTH_DEFERENCE_DEVICE_CODE:
 		return c.JSON(http.StatusForbidden, resp)
+	case pkgErr.DEVICE_VERIFICATION_REQUIRED_CODE:
+		return c.JSON(http.StatusForbidden, resp)
 	case pkgErr.BIOMETRIC_INVALID_REQUEST_CODE:
 		return c.JSON(http.StatusBadRequest, resp)
-	case pkgErr.AUTH_BIOMETRC_INACTIVE_CODE:
+	case pkgErr.AUTH_BIOMETRC_INACTIVE_CODE, pkgErr.BIOMETRIC_KEY_NOT_FOUND_CODE:
 		return c.JSON(http.StatusForbidden, resp)
+	case pkgErr.BIOMETRIC_CHALLENGE_INVALID_CODE, pkgErr.BIOMETRIC_SIGNATURE_INVALID_CODE:
+		return c.JSON(http.StatusUnauthorized, resp)
 	default:
 		return c.JSON(http.StatusInternalServerError, resp)
 	}
@@ -353,6 +370,8 @@ func (ctr *userAuthController) RefreshTokenController(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, resp)
 	case pkgErr.AUTH_UNAUTHORIZED_CODE:
 		return c.JSON(http.StatusUnauthorized, resp)
+	case pkgErr.AUTH_TOKEN_REUSED_CODE:
+		return c.JSON(http.StatusUnauthorized, resp)
 	case pkgErr.AUTH_DEFERENCE_DEVICE_CODE:
 		return c.JSON(http.StatusForbidden, resp)
 	default:
@@ -410,6 +429,8 @@ func (ctr *userAuthController) VerifyOtpController(c echo.Context) error {
 	switch resp.StatusCode {
 	case pkgErr.SUCCESS_CODE:
 		return c.JSON(http.StatusOK, resp)
+	case pkgErr.OTP_RESEND_COOLDOWN_CODE, pkgErr.OTP_SEND_LIMIT_CODE, pkgErr.OTP_VERIFY_LIMIT_CODE:
+		return c.JSON(http.StatusTooManyRequests, resp)
 	case pkgErr.AUTH_INVALID_OTP_CODE:
 		return c.JSON(http.StatusBadRequest, resp)
 	case pkgErr.AUTH_UNVERIFIED_CODE:
@@ -475,6 +496,8 @@ func (ctr *userAuthController) SendOtpController(c echo.Context) error {
 	switch resp.StatusCode {
 	case pkgErr.SUCCESS_CODE:
 		return c.JSON(http.StatusOK, resp)
+	case pkgErr.OTP_RESEND_COOLDOWN_CODE, pkgErr.OTP_SEND_LIMIT_CODE, pkgErr.OTP_VERIFY_LIMIT_CODE:
+		return c.JSON(http.StatusTooManyRequests, resp)
 	case pkgErr.AUTH_USER_NOT_FOUND_CODE:
 		return c.JSON(http.StatusNotFound, resp)
 	case pkgErr.AUTH_ALREADY_VERIFIED_CODE:
@@ -489,6 +512,62 @@ func (ctr *userAuthController) SendOtpController(c echo.Context) error {
 	}
 }
 
+// @Tags Auth
+// @Summary otp delivery status
+// @Description channel the otp was finally sent on, an otp that is not delivered falls back to the next channel
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Param data body request.OtpStatusRequest true "Otp Status Request"
+// @Success 200 {object} dto.BaseResponse
+// @Failure 404 {object} dto.BaseResponse
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/users/auth/otp/status [post]
+func (ctr *userAuthController) OtpStatusController(c echo.Context) error {
+	var (
+		req      request.OtpStatusRequest
+		validate = validator.New()
+	)
+	logData, okData := c.Request().Context().Value(enum.CUSTOM_LOG_DATA).(*dto.CustomLoggerRequest)
+	if !okData {
+		log.Warn("failed to get custom logger")
+	}
+	logData.Remarks = "otp-status"
+	err := c.Bind(&req)
+	if err != nil {
+		logData.Error = err.Error()
+		return c.JSON(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_INVALID_REQUEST_PAYLOAD_CODE,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		})
+	}
+	err = validate.Struct(&req)
+	if err != nil {
+		err = helpers.CustomValidatePayload(err, req)
+		logData.Error = err.Error()
+		return c.JSON(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_INVALID_REQUEST_PAYLOAD_CODE,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		})
+	}
+	resp := ctr.userAuthService.OtpStatusService(c.Request().Context(), &req, logData)
+	switch resp.StatusCode {
+	case pkgErr.SUCCESS_CODE:
+		return c.JSON(http.StatusOK, resp)
+	case pkgErr.AUTH_RECORD_NOT_FOUND_CODE:
+		return c.JSON(http.StatusNotFound, resp)
+	default:
+		return c.JSON(http.StatusInternalServerError, resp)
+	}
+}
+
 // @Tags Auth
 // @Summary Set Pin new pin
 // @Description Set Pin with Req
@@ -602,9 +681,123 @@ func (ctr *userAuthController) ForgotPinController(c echo.Context) error {
 		return c.JSON(http.StatusOK, rest)
 	case pkgErr.AUTH_USER_NOT_FOUND_CODE:
 		return c.JSON(http.StatusNotFound, rest)
+	case pkgErr.RESET_LINK_NOT_ALLOWED_CODE:
+		return c.JSON(http.StatusBadRequest, rest)
 	default:
 		return c.JSON(http.StatusInternalServerError, rest)
 
 	}
 
 }
+
+// @Tags Auth
+// @Summary verify new device
+// @Description verify the otp sent when logging in from an unknown device, the device is trusted and tokens are issued
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Param data body request.VerifyDeviceRequest true "Verify Device Request"
+// @Success 200 {object} dto.BaseResponse
+// @Failure 400 {object} dto.BaseResponse
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/users/auth/device/verify [post]
+func (ctr *userAuthController) VerifyDeviceController(c echo.Context) error {
+	var (
+		req      request.VerifyDeviceRequest
+		validate = validator.New()
+	)
+	logData, okData := c.Request().Context().Value(enum.CUSTOM_LOG_DATA).(*dto.CustomLoggerRequest)
+	if !okData {
+		log.Warn("failed to get custom logger")
+	}
+	logData.Remarks = "verify-device"
+	err := c.Bind(&req)
+	if err != nil {
+		logData.Error = err.Error()
+		return c.JSON(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_INVALID_REQUEST_PAYLOAD_CODE,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		})
+	}
+	err = validate.Struct(&req)
+	if err != nil {
+		err = helpers.CustomValidatePayload(err, req)
+		logData.Error = err.Error()
+		return c.JSON(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_INVALID_REQUEST_PAYLOAD_CODE,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		})
+	}
+	resp := ctr.userAuthService.VerifyDeviceService(c.Request().Context(), &req, logData)
+	switch resp.StatusCode {
+	case pkgErr.SUCCESS_CODE:
+		return c.JSON(http.StatusOK, resp)
+	case pkgErr.OTP_RESEND_COOLDOWN_CODE, pkgErr.OTP_SEND_LIMIT_CODE, pkgErr.OTP_VERIFY_LIMIT_CODE:
+		return c.JSON(http.StatusTooManyRequests, resp)
+	case pkgErr.DEVICE_VERIFICATION_INVALID_CODE:
+		return c.JSON(http.StatusBadRequest, resp)
+	default:
+		return c.JSON(http.StatusInternalServerError, resp)
+	}
+}
+
+// @Tags Auth
+// @Summary biometric login challenge
+// @Description get a single use nonce to sign with the device biometric key, the signature is sent to login with type BIOMETRIC
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Param data body request.BiometricChallengeRequest true "Biometric Challenge Request"
+// @Success 200 {object} dto.BaseResponse
+// @Failure 400 {object} dto.BaseResponse
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/users/auth/biometric/challenge [post]
+func (ctr *userAuthController) BiometricChallengeController(c echo.Context) error {
+	var (
+		req      request.BiometricChallengeRequest
+		validate = validator.New()
+	)
+	logData, okData := c.Request().Context().Value(enum.CUSTOM_LOG_DATA).(*dto.CustomLoggerRequest)
+	if !okData {
+		log.Warn("failed to get custom logger")
+	}
+	logData.Remarks = "biometric-challenge"
+	err := c.Bind(&req)
+	if err != nil {
+		logData.Error = err.Error()
+		return c.JSON(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_INVALID_REQUEST_PAYLOAD_CODE,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		})
+	}
+	err = validate.Struct(&req)
+	if err != nil {
+		err = helpers.CustomValidatePayload(err, req)
+		logData.Error = err.Error()
+		return c.JSON(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_INVALID_REQUEST_PAYLOAD_CODE,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		})
+	}
+	resp := ctr.biometricService.ChallengeService(c.Request().Context(), &req, logData)
+	switch resp.StatusCode {
+	case pkgErr.SUCCESS_CODE:
+		return c.JSON(http.StatusOK, resp)
+	default:
+		return c.JSON(http.StatusInternalServerError, resp)
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"backend-mobile-api/model/enum"
 	"backend-mobile-api/model/enum/pkgErr"
+	"backend-mobile-api/service/biometricSvc"
 	userProfileService "backend-mobile-api/service/user-profile-svc"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
@@ -18,11 +19,13 @@ import (
 
 type userProfileController struct {
 	userProfileService userProfileService.UserProfileService
+	biometricService   biometricSvc.BiometricService
 }
 
-func NewUserProfileController(userProfileService userProfileService.UserProfileService) UserProfileController {
+func NewUserProfileController(userProfileService userProfileService.UserProfileService, biometricService biometricSvc.BiometricService) UserProfileController {
 	return &userProfileController{
 		userProfileService: userProfileService,
+		biometricService:   biometricService,
 	}
 }
 
@@ -36,8 +39,11 @@ type UserProfileController interface {
 	ResetFullNameController(e echo.Context) error
 	DeleteAccountController(e echo.Context) error
 	BiometricController(e echo.Context) error
+	InquiryBiometricKeyController(e echo.Context) error
+	RevokeBiometricKeyController(e echo.Context) error
 	ResetProfileImageController(e echo.Context) error
 	GetProfilePictureController(e echo.Context) error
+	ExportDataController(e echo.Context) error
 }
 
 // @Tags Profile
@@ -156,6 +162,8 @@ func (ctr *userProfileController) ResetPinController(e echo.Context) error {
 	switch res.StatusCode {
 	case pkgErr.SUCCESS_CODE:
 		return e.JSON(http.StatusOK, res)
+	case pkgErr.OTP_RESEND_COOLDOWN_CODE, pkgErr.OTP_SEND_LIMIT_CODE, pkgErr.OTP_VERIFY_LIMIT_CODE:
+		return e.JSON(http.StatusTooManyRequests, res)
 	case pkgErr.PROFILE_INVALID_PAYLOAD_CODE:
 		return e.JSON(http.StatusBadRequest, res)
 	case pkgErr.PROFILE_USER_NOT_FOUND_CODE:
@@ -235,6 +243,8 @@ func (ctr *userProfileController) ResetEmailController(e echo.Context) error {
 	switch res.StatusCode {
 	case pkgErr.SUCCESS_CODE:
 		return e.JSON(http.StatusOK, res)
+	case pkgErr.OTP_RESEND_COOLDOWN_CODE, pkgErr.OTP_SEND_LIMIT_CODE, pkgErr.OTP_VERIFY_LIMIT_CODE:
+		return e.JSON(http.StatusTooManyRequests, res)
 	case pkgErr.PROFILE_INVALID_PAYLOAD_CODE:
 		return e.JSON(http.StatusBadRequest, res)
 	case pkgErr.PROFILE_USER_NOT_FOUND_CODE:
@@ -313,6 +323,8 @@ func (ctr *userProfileController) ResetPhoneNumberController(e echo.Context) err
 	switch res.StatusCode {
 	case pkgErr.SUCCESS_CODE:
 		return e.JSON(http.StatusOK, res)
+	case pkgErr.OTP_RESEND_COOLDOWN_CODE, pkgErr.OTP_SEND_LIMIT_CODE, pkgErr.OTP_VERIFY_LIMIT_CODE:
+		return e.JSON(http.StatusTooManyRequests, res)
 	case pkgErr.PROFILE_INVALID_PAYLOAD_CODE:
 		return e.JSON(http.StatusBadRequest, res)
 	case pkgErr.PROFILE_USER_NOT_FOUND_CODE:
@@ -400,6 +412,10 @@ func (ctr *userProfileController) AccessTokenController(e echo.Context) error {
 		return e.JSON(http.StatusForbidden, res)
 	case pkgErr.AUTH_UNAUTHORIZED_CODE:
 		return e.JSON(http.StatusUnauthorized, res)
+	case pkgErr.PIN_ATTEMPT_TOO_SOON_CODE, pkgErr.PIN_TEMPORARY_LOCKED_CODE:
+		return e.JSON(http.StatusTooManyRequests, res)
+	case pkgErr.PIN_PERMANENT_LOCKED_CODE:
+		return e.JSON(http.StatusForbidden, res)
 	case pkgErr.PROFILE_INVALID_ACCESS_CODE:
 		e.JSON(http.StatusUnauthorized, res)
 	}
@@ -471,6 +487,8 @@ func (ctr userProfileController) VerifyOtpController(e echo.Context) error {
 	switch res.StatusCode {
 	case pkgErr.SUCCESS_CODE:
 		return e.JSON(http.StatusOK, res)
+	case pkgErr.OTP_RESEND_COOLDOWN_CODE, pkgErr.OTP_SEND_LIMIT_CODE, pkgErr.OTP_VERIFY_LIMIT_CODE:
+		return e.JSON(http.StatusTooManyRequests, res)
 	case pkgErr.PROFILE_INVALID_ACCESS_CODE:
 		return e.JSON(http.StatusUnauthorized, res)
 	case pkgErr.PROFILE_USER_NOT_FOUND_CODE:
@@ -639,7 +657,7 @@ func (ctr userProfileController) DeleteAccountController(e echo.Context) error {
 
 // @Tags Profile
 // @Summary set biometric active in_active
-// @Description user change biometric active in_activeemail active
+// @Description user change biometric active in_active for the device of the current session, activating registers the public key of the device keystore pair and requires the pin
 // @Accept json
 // @Produce json
 // @Param X-NONCE header string true "X-NONCE"
@@ -651,6 +669,7 @@ func (ctr userProfileController) DeleteAccountController(e echo.Context) error {
 // @Param Authorization header string true "Authorization"
 // @Param data body request.BiometrictStatusRequest true "biometric status Request"
 // @Success 200 {object} swagger.BasicSuccess
+// @Failure 401 {object} swagger.Unauthorized
 // @Failure 403 {object} swagger.DeferenceDeviceProfileRequest
 // @Failure 500 {object} swagger.CommonError
 // @Router /api/v1/users/profile/biometric [post]
@@ -698,16 +717,103 @@ func (ctr *userProfileController) BiometricController(e echo.Context) error {
 	switch res.StatusCode {
 	case pkgErr.SUCCESS_CODE:
 		return e.JSON(http.StatusOK, res)
-	case pkgErr.PROFILE_INVALID_PAYLOAD_CODE:
+	case pkgErr.PROFILE_INVALID_PAYLOAD_CODE, pkgErr.BIOMETRIC_KEY_INVALID_CODE:
 		return e.JSON(http.StatusBadRequest, res)
 	case pkgErr.PROFILE_USER_NOT_FOUND_CODE:
 		return e.JSON(http.StatusNotFound, res)
-	case pkgErr.PROFILE_DEFERENCE_DEVICE_CODE:
+	case pkgErr.PROFILE_DEFERENCE_DEVICE_CODE, pkgErr.PIN_PERMANENT_LOCKED_CODE:
 		return e.JSON(http.StatusForbidden, res)
+	case pkgErr.AUTH_UNAUTHORIZED_CODE:
+		return e.JSON(http.StatusUnauthorized, res)
+	case pkgErr.PIN_ATTEMPT_TOO_SOON_CODE, pkgErr.PIN_TEMPORARY_LOCKED_CODE:
+		return e.JSON(http.StatusTooManyRequests, res)
 	}
 	return e.JSON(http.StatusInternalServerError, res)
 }
 
+// @Tags Profile
+// @Summary inquiry biometric keys
+// @Description list devices that can log in with biometric
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Param Authorization header string true "Authorization"
+// @Success 200 {object} dto.BaseResponse
+// @Failure 401 {object} swagger.Unauthorized
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/users/profile/biometric/keys [get]
+func (ctr *userProfileController) InquiryBiometricKeyController(e echo.Context) error {
+	customResource, ok := e.Request().Context().Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue)
+	if !ok {
+		log.Error("failed to get custom resource")
+		return e.JSON(http.StatusInternalServerError, dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      "failed to get custom resource",
+		})
+	}
+	logData, okData := e.Request().Context().Value(enum.CUSTOM_LOG_DATA).(*dto.CustomLoggerRequest)
+	if !okData {
+		log.Warn("failed to get custom logger")
+	}
+	logData.Remarks = "inquiry-biometric-key"
+	res := ctr.biometricService.InquiryKeyService(e.Request().Context(), customResource.AuthUUID, customResource.HeaderXDeviceID, logData)
+	return ctr.biometricKeyResponse(e, res)
+}
+
+// @Tags Profile
+// @Summary revoke biometric key
+// @Description remove biometric login from a device, biometric is turned off once no device has a key
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Param Authorization header string true "Authorization"
+// @Param id path string true "key id"
+// @Success 200 {object} dto.BaseResponse
+// @Failure 401 {object} swagger.Unauthorized
+// @Failure 404 {object} dto.BaseResponse
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/users/profile/biometric/keys/{id} [delete]
+func (ctr *userProfileController) RevokeBiometricKeyController(e echo.Context) error {
+	customResource, ok := e.Request().Context().Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue)
+	if !ok {
+		log.Error("failed to get custom resource")
+		return e.JSON(http.StatusInternalServerError, dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      "failed to get custom resource",
+		})
+	}
+	logData, okData := e.Request().Context().Value(enum.CUSTOM_LOG_DATA).(*dto.CustomLoggerRequest)
+	if !okData {
+		log.Warn("failed to get custom logger")
+	}
+	logData.Remarks = "revoke-biometric-key"
+	res := ctr.biometricService.RevokeKeyService(e.Request().Context(), customResource.AuthUUID, e.Param("id"), logData)
+	return ctr.biometricKeyResponse(e, res)
+}
+
+func (ctr *userProfileController) biometricKeyResponse(e echo.Context, res *dto.BaseResponse) error {
+	switch res.StatusCode {
+	case pkgErr.SUCCESS_CODE:
+		return e.JSON(http.StatusOK, res)
+	case pkgErr.BIOMETRIC_KEY_NOT_FOUND_CODE, pkgErr.PROFILE_USER_NOT_FOUND_CODE:
+		return e.JSON(http.StatusNotFound, res)
+	default:
+		return e.JSON(http.StatusInternalServerError, res)
+	}
+}
+
 // @Tags Profile
 // @Summary set new profile picture
 // @Description user change profile picture
@@ -833,3 +939,52 @@ func (ctr *userProfileController) GetProfilePictureController(e echo.Context) er
 	}
 	return e.JSON(http.StatusInternalServerError, res)
 }
+
+// @Tags Profile
+// @Summary Export account data
+// @Description user downloads every personal record held for the account as json, trusted device only
+// @Accept json
+// @Produce json
+// @Param X-NONCE header string true "X-NONCE"
+// @Param X-SIGNATURE header string true "X-SIGNATURE"
+// @Param X-DEVICE-ID header string true "X-DEVICE-ID"
+// @Param X-TIMESTAMP header string true "X-TIMESTAMP"
+// @Param X-LATITUDE header string true "X-LATITUDE"
+// @Param X-LONGITUDE header string true "X-LONGITUDE"
+// @Param Authorization header string true "Authorization"
+// @Success 200 {object} swagger.BasicSuccess
+// @Failure 404 {object} swagger.UserNotFoundProfileFailureResponse
+// @Failure 403 {object} swagger.DeferenceDeviceProfileRequest
+// @Failure 500 {object} swagger.CommonError
+// @Router /api/v1/users/profile/export [get]
+func (ctr *userProfileController) ExportDataController(e echo.Context) error {
+	var (
+		userUUID string
+	)
+	customResource, ok := e.Request().Context().Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue)
+	if !ok {
+		log.Error("failed to get custom resource")
+		return e.JSON(http.StatusInternalServerError, dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      "failed to get custom resource",
+			Data:       nil,
+		})
+	}
+	userUUID = customResource.AuthUUID
+	logData, okData := e.Request().Context().Value(enum.CUSTOM_LOG_DATA).(*dto.CustomLoggerRequest)
+	if !okData {
+		log.Warn("failed to get custom logger")
+	}
+	logData.Remarks = "export-data"
+	res := ctr.userProfileService.ExportDataService(e.Request().Context(), &userUUID, logData)
+	switch res.StatusCode {
+	case pkgErr.SUCCESS_CODE:
+		return e.JSON(http.StatusOK, res)
+	case pkgErr.PROFILE_USER_NOT_FOUND_CODE:
+		return e.JSON(http.StatusNotFound, res)
+	case pkgErr.PROFILE_DEFERENCE_DEVICE_CODE:
+		return e.JSON(http.StatusForbidden, res)
+	}
+	return e.JSON(http.StatusInternalServerError, res)
+}
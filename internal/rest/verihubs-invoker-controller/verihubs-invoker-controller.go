@@ -17,6 +17,8 @@ type verihubsInvokerController struct {
 }
 type VerihubsInvokerController interface {
 	InvokerOtpController(e echo.Context) error
+	OtpMetricsController(e echo.Context) error
+	OtpTimelineController(e echo.Context) error
 }
 
 func NewVerihubsInvokerController(verihubsInvokerService verihubsInvokerService.VerihubsInvokerService) VerihubsInvokerController {
@@ -50,6 +52,67 @@ func (ctr *verihubsInvokerController) InvokerOtpController(e echo.Context) error
 		})
 	}
 	resp := ctr.verihubsInvokerService.OtpInvokerService(e.Request().Context(), &req)
+	switch resp.StatusCode {
+	case pkgErr.SUCCESS_CODE:
+		return e.JSON(http.StatusOK, resp)
+	case pkgErr.OUTBOUND_INVALID_PAYLOAD:
+		return e.JSON(http.StatusBadRequest, resp)
+	case pkgErr.OTP_CALLBACK_UNAUTHORIZED_CODE:
+		return e.JSON(http.StatusUnauthorized, resp)
+	default:
+		return e.JSON(http.StatusInternalServerError, resp)
+	}
+}
+
+// OtpMetricsController counts the otps sent per channel for cost monitoring.
+func (ctr *verihubsInvokerController) OtpMetricsController(e echo.Context) error {
+	var (
+		req      = request.OtpMetricsRequest{}
+		validate = validator.New()
+	)
+	if err := e.Bind(&req); err != nil {
+		return e.JSON(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.OUTBOUND_INVALID_PAYLOAD,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		})
+	}
+	if err := validate.Struct(&req); err != nil {
+		err = helpers.CustomValidatePayload(err, req)
+		return e.JSON(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.OUTBOUND_INVALID_PAYLOAD,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		})
+	}
+	return ctr.response(e, ctr.verihubsInvokerService.OtpMetricsService(e.Request().Context(), &req))
+}
+
+// OtpTimelineController lists the delivery events of a verify key.
+func (ctr *verihubsInvokerController) OtpTimelineController(e echo.Context) error {
+	var (
+		req      = request.OtpTimelineRequest{}
+		validate = validator.New()
+	)
+	if err := e.Bind(&req); err != nil {
+		return e.JSON(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.OUTBOUND_INVALID_PAYLOAD,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		})
+	}
+	if err := validate.Struct(&req); err != nil {
+		err = helpers.CustomValidatePayload(err, req)
+		return e.JSON(http.StatusBadRequest, dto.BaseResponse{
+			StatusCode: pkgErr.OUTBOUND_INVALID_PAYLOAD,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      err.Error(),
+		})
+	}
+	return ctr.response(e, ctr.verihubsInvokerService.OtpTimelineService(e.Request().Context(), &req))
+}
+
+func (ctr *verihubsInvokerController) response(e echo.Context, resp *dto.BaseResponse) error {
 	switch resp.StatusCode {
 	case pkgErr.SUCCESS_CODE:
 		return e.JSON(http.StatusOK, resp)
@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS payment_requests (
+    created_at timestamp with time zone not null,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    id bigserial not null primary key,
+    request_id varchar(36) not null unique,
+    requester_id bigint not null
+        constraint fk_requester_id_payment_request
+            references users (id),
+    requester_uuid varchar(36) not null,
+    split_type varchar(20) not null,
+    total_amount numeric(18, 2) not null,
+    note varchar(255),
+    status varchar(20) not null,
+    expired_at timestamp with time zone not null
+);
+
+CREATE TABLE IF NOT EXISTS payment_request_participants (
+    created_at timestamp with time zone not null,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    id bigserial not null primary key,
+    payment_request_id bigint not null
+        constraint fk_payment_request_id
+            references payment_requests (id),
+    user_id bigint not null
+        constraint fk_user_id_payment_participant
+            references users (id),
+    user_uuid varchar(36) not null,
+    amount numeric(18, 2) not null,
+    status varchar(20) not null,
+    transaction_id varchar(50),
+    responded_at timestamp with time zone,
+    constraint uq_payment_request_participant unique (payment_request_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_payment_request_participants_user_uuid ON payment_request_participants (user_uuid);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS uq_tb_recipient_user_bank_account;
+
+ALTER TABLE tb_recipient
+    DROP COLUMN IF EXISTS alias,
+    DROP COLUMN IF EXISTS is_favorite;
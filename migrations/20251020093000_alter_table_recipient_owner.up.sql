@@ -0,0 +1,14 @@
+ALTER TABLE tb_recipient
+    ADD COLUMN IF NOT EXISTS alias varchar(100),
+    ADD COLUMN IF NOT EXISTS is_favorite boolean not null default false;
+
+-- keep the oldest copy of an account saved more than once by the same user
+DELETE FROM tb_recipient r
+    USING tb_recipient k
+WHERE r.user_id = k.user_id
+  AND r.bank_id = k.bank_id
+  AND r.no_rekening = k.no_rekening
+  AND r.recipient_id > k.recipient_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_tb_recipient_user_bank_account
+    ON tb_recipient (user_id, bank_id, no_rekening);
@@ -0,0 +1,2 @@
+ALTER TABLE tb_bank_list
+    DROP COLUMN IF EXISTS bank_code;
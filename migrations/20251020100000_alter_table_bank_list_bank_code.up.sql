@@ -0,0 +1,29 @@
+ALTER TABLE tb_bank_list
+    ADD COLUMN IF NOT EXISTS bank_code varchar(10);
+
+-- SNAP BI bank codes for the banks already listed, the more specific names first
+-- so e.g. BCA Syariah is not taken for BCA
+UPDATE tb_bank_list SET bank_code = '536' WHERE bank_code IS NULL AND upper(nama_bank) LIKE '%BCA SYARIAH%';
+UPDATE tb_bank_list SET bank_code = '451' WHERE bank_code IS NULL AND (upper(nama_bank) LIKE '%SYARIAH INDONESIA%' OR upper(nama_bank) LIKE '%BSI%'
+    OR upper(nama_bank) LIKE '%BRI SYARIAH%' OR upper(nama_bank) LIKE '%BNI SYARIAH%' OR upper(nama_bank) LIKE '%MANDIRI SYARIAH%');
+UPDATE tb_bank_list SET bank_code = '506' WHERE bank_code IS NULL AND upper(nama_bank) LIKE '%MEGA SYARIAH%';
+UPDATE tb_bank_list SET bank_code = '494' WHERE bank_code IS NULL AND upper(nama_bank) LIKE '%AGRONIAGA%';
+UPDATE tb_bank_list SET bank_code = '014' WHERE bank_code IS NULL AND (upper(nama_bank) LIKE '%BCA%' OR upper(nama_bank) LIKE '%CENTRAL ASIA%');
+UPDATE tb_bank_list SET bank_code = '002' WHERE bank_code IS NULL AND (upper(nama_bank) LIKE '%BRI%' OR upper(nama_bank) LIKE '%RAKYAT INDONESIA%');
+UPDATE tb_bank_list SET bank_code = '008' WHERE bank_code IS NULL AND upper(nama_bank) LIKE '%MANDIRI%';
+UPDATE tb_bank_list SET bank_code = '009' WHERE bank_code IS NULL AND (upper(nama_bank) LIKE '%BNI%' OR upper(nama_bank) LIKE '%NEGARA INDONESIA%');
+UPDATE tb_bank_list SET bank_code = '200' WHERE bank_code IS NULL AND (upper(nama_bank) LIKE '%BTN%' OR upper(nama_bank) LIKE '%TABUNGAN NEGARA%');
+UPDATE tb_bank_list SET bank_code = '022' WHERE bank_code IS NULL AND upper(nama_bank) LIKE '%CIMB%';
+UPDATE tb_bank_list SET bank_code = '011' WHERE bank_code IS NULL AND upper(nama_bank) LIKE '%DANAMON%';
+UPDATE tb_bank_list SET bank_code = '013' WHERE bank_code IS NULL AND upper(nama_bank) LIKE '%PERMATA%';
+UPDATE tb_bank_list SET bank_code = '016' WHERE bank_code IS NULL AND upper(nama_bank) LIKE '%MAYBANK%';
+UPDATE tb_bank_list SET bank_code = '019' WHERE bank_code IS NULL AND upper(nama_bank) LIKE '%PANIN%';
+UPDATE tb_bank_list SET bank_code = '028' WHERE bank_code IS NULL AND upper(nama_bank) LIKE '%OCBC%';
+UPDATE tb_bank_list SET bank_code = '426' WHERE bank_code IS NULL AND upper(nama_bank) LIKE '%MEGA%';
+UPDATE tb_bank_list SET bank_code = '213' WHERE bank_code IS NULL AND upper(nama_bank) LIKE '%BTPN%';
+UPDATE tb_bank_list SET bank_code = '542' WHERE bank_code IS NULL AND upper(nama_bank) LIKE '%JAGO%';
+UPDATE tb_bank_list SET bank_code = '535' WHERE bank_code IS NULL AND upper(nama_bank) LIKE '%SEABANK%';
+UPDATE tb_bank_list SET bank_code = '147' WHERE bank_code IS NULL AND upper(nama_bank) LIKE '%MUAMALAT%';
+UPDATE tb_bank_list SET bank_code = '153' WHERE bank_code IS NULL AND upper(nama_bank) LIKE '%SINARMAS%';
+UPDATE tb_bank_list SET bank_code = '111' WHERE bank_code IS NULL AND upper(nama_bank) LIKE '%DKI%';
+UPDATE tb_bank_list SET bank_code = '110' WHERE bank_code IS NULL AND upper(nama_bank) LIKE '%BJB%';
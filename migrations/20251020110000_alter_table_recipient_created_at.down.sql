@@ -0,0 +1,2 @@
+ALTER TABLE tb_recipient
+    DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,5 @@
+ALTER TABLE tb_recipient ADD COLUMN IF NOT EXISTS created_at timestamptz;
+-- recipient lama tidak boleh dianggap baru oleh cooling-off
+UPDATE tb_recipient SET created_at = '1970-01-01 00:00:00+00' WHERE created_at IS NULL;
+ALTER TABLE tb_recipient ALTER COLUMN created_at SET DEFAULT now();
+ALTER TABLE tb_recipient ALTER COLUMN created_at SET NOT NULL;
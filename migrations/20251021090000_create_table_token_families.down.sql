@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS token_family_tokens;
+DROP TABLE IF EXISTS token_families;
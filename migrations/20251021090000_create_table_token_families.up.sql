@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS token_families (
+    created_at timestamp with time zone not null,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    id bigserial not null primary key,
+    family_id varchar(36) not null unique,
+    user_id bigint not null
+        constraint fk_user_id_token_family
+            references users (id),
+    user_uuid varchar(36) not null,
+    device_id varchar(255) not null,
+    revoked_at timestamp with time zone,
+    revoked_reason varchar(100)
+);
+CREATE INDEX IF NOT EXISTS idx_token_families_user_device ON token_families (user_uuid, device_id);
+
+CREATE TABLE IF NOT EXISTS token_family_tokens (
+    created_at timestamp with time zone not null,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    id bigserial not null primary key,
+    family_id varchar(36) not null
+        constraint fk_family_id_token_family_token
+            references token_families (family_id),
+    token_id varchar(36) not null unique,
+    access_token_hash char(64) not null,
+    refresh_token_hash char(64) not null unique,
+    access_expired_at timestamp with time zone not null,
+    refresh_expired_at timestamp with time zone not null,
+    used_at timestamp with time zone
+);
+CREATE INDEX IF NOT EXISTS idx_token_family_tokens_family_id ON token_family_tokens (family_id);
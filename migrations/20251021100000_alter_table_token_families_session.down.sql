@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_token_families_user_uuid_active;
+ALTER TABLE token_families
+    DROP COLUMN IF EXISTS app_version,
+    DROP COLUMN IF EXISTS ip_address,
+    DROP COLUMN IF EXISTS latitude,
+    DROP COLUMN IF EXISTS longitude,
+    DROP COLUMN IF EXISTS last_seen_at;
@@ -0,0 +1,8 @@
+ALTER TABLE token_families
+    ADD COLUMN IF NOT EXISTS app_version varchar(50),
+    ADD COLUMN IF NOT EXISTS ip_address varchar(45),
+    ADD COLUMN IF NOT EXISTS latitude numeric(6, 2),
+    ADD COLUMN IF NOT EXISTS longitude numeric(6, 2),
+    ADD COLUMN IF NOT EXISTS last_seen_at timestamp with time zone;
+UPDATE token_families SET last_seen_at = created_at WHERE last_seen_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_token_families_user_uuid_active ON token_families (user_uuid) WHERE revoked_at IS NULL;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_devices_user_id_trusted;
+ALTER TABLE devices
+    DROP COLUMN IF EXISTS trusted_at,
+    DROP COLUMN IF EXISTS revoked_at;
@@ -0,0 +1,13 @@
+ALTER TABLE devices
+    ADD COLUMN IF NOT EXISTS fcm_token varchar(255),
+    ADD COLUMN IF NOT EXISTS trusted_at timestamp with time zone,
+    ADD COLUMN IF NOT EXISTS revoked_at timestamp with time zone;
+UPDATE devices d SET user_uuid = u.uuid FROM users u WHERE d.user_id = u.id AND (d.user_uuid IS NULL OR d.user_uuid = '');
+UPDATE devices d SET trusted_at = d.created_at FROM users u WHERE d.user_id = u.id AND d.device_id = u.device_id;
+INSERT INTO devices (created_at, updated_at, device_id, user_id, user_uuid, app_version_code, app_version_name,
+                     manufacturer, brand, device_model, product, version_sdk, version_release, trusted_at)
+SELECT now(), now(), u.device_id, u.id, u.uuid, '', '', '', '', '', '', '', '', now()
+FROM users u
+WHERE u.device_id <> '' AND u.deleted_at IS NULL
+  AND NOT EXISTS (SELECT 1 FROM devices d WHERE d.user_id = u.id AND d.device_id = u.device_id);
+CREATE INDEX IF NOT EXISTS idx_devices_user_id_trusted ON devices (user_id, device_id) WHERE trusted_at IS NOT NULL AND revoked_at IS NULL;
@@ -0,0 +1,64 @@
+CREATE TABLE IF NOT EXISTS roles (
+    created_at timestamp with time zone not null,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    id bigserial not null primary key,
+    name varchar(50) not null unique,
+    description varchar(255)
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    created_at timestamp with time zone not null,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    id bigserial not null primary key,
+    name varchar(100) not null unique,
+    description varchar(255)
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id bigint not null
+        constraint fk_role_id_role_permission
+            references roles (id),
+    permission_id bigint not null
+        constraint fk_permission_id_role_permission
+            references permissions (id),
+    primary key (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    role_id bigint not null
+        constraint fk_role_id_user_role
+            references roles (id),
+    user_id bigint not null
+        constraint fk_user_id_user_role
+            references users (id),
+    primary key (role_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_user_roles_user_id ON user_roles (user_id);
+
+INSERT INTO roles (created_at, name, description) VALUES
+    (now(), 'USER', 'mobile app customer'),
+    (now(), 'ADMIN', 'back office operator'),
+    (now(), 'SERVICE', 'internal service account')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permissions (created_at, name, description) VALUES
+    (now(), 'article:read', 'list articles including unpublished'),
+    (now(), 'article:write', 'create, update and delete articles'),
+    (now(), 'kyc:verify', 'run KYC verification')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON (r.name = 'ADMIN')
+    OR (r.name = 'SERVICE' AND p.name IN ('article:read', 'kyc:verify'))
+ON CONFLICT DO NOTHING;
+
+-- every existing account is a customer
+INSERT INTO user_roles (role_id, user_id)
+SELECT r.id, u.id
+FROM users u
+JOIN roles r ON r.name = 'USER'
+ON CONFLICT DO NOTHING;
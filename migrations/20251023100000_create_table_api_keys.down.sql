@@ -0,0 +1,3 @@
+DELETE FROM role_permissions WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'otp:callback');
+DELETE FROM permissions WHERE name = 'otp:callback';
+DROP TABLE IF EXISTS api_keys;
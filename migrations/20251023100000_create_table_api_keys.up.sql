@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    created_at timestamp with time zone not null,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    id bigserial not null primary key,
+    name varchar(100) not null,
+    prefix varchar(16) not null unique,
+    key_hash varchar(64) not null,
+    scopes varchar(500) not null default '',
+    ip_allowlist varchar(500) not null default '',
+    expired_at timestamp with time zone,
+    revoked_at timestamp with time zone,
+    last_used_at timestamp with time zone
+);
+
+INSERT INTO permissions (created_at, name, description) VALUES
+    (now(), 'otp:callback', 'report otp delivery status')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.name = 'otp:callback'
+WHERE r.name IN ('ADMIN', 'SERVICE')
+ON CONFLICT DO NOTHING;
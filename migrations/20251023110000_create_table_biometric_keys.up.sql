@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS biometric_keys (
+    created_at timestamp with time zone not null,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    id bigserial not null primary key,
+    key_id varchar(36) not null unique,
+    user_id bigint not null
+        constraint fk_user_id_biometric_key
+            references users (id),
+    user_uuid varchar(36) not null,
+    device_id varchar(255) not null,
+    algorithm varchar(30) not null,
+    public_key text not null,
+    last_used_at timestamp with time zone,
+    revoked_at timestamp with time zone
+);
+-- one usable key per device, enabling biometric again replaces it
+CREATE UNIQUE INDEX IF NOT EXISTS idx_biometric_keys_user_device_active ON biometric_keys (user_id, device_id) WHERE revoked_at IS NULL;
+
+-- biometric login used to replay a refresh token, nobody has a device key yet
+UPDATE user_details SET biometric = 'IN_ACTIVE' WHERE biometric = 'ACTIVE';
@@ -0,0 +1,3 @@
+DELETE FROM role_permissions WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'otp:metrics');
+DELETE FROM permissions WHERE name = 'otp:metrics';
+DROP TABLE IF EXISTS otp_delivery_events;
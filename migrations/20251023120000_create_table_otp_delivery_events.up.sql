@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS otp_delivery_events (
+    created_at timestamp with time zone not null,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    id bigserial not null primary key,
+    otp_id bigint not null
+        constraint fk_otp_id_otp_delivery_event
+            references otps (id),
+    verify_key varchar(255) not null,
+    session_id varchar(255) not null default '',
+    otp_method varchar(20) not null,
+    status varchar(30) not null,
+    provider_status varchar(10) not null default ''
+);
+CREATE INDEX IF NOT EXISTS idx_otp_delivery_events_otp_id ON otp_delivery_events (otp_id);
+CREATE INDEX IF NOT EXISTS idx_otp_delivery_events_created_at ON otp_delivery_events (created_at);
+
+INSERT INTO permissions (created_at, name, description) VALUES
+    (now(), 'otp:metrics', 'read otp delivery metrics and timelines')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.name = 'otp:metrics'
+WHERE r.name IN ('ADMIN', 'SERVICE')
+ON CONFLICT DO NOTHING;
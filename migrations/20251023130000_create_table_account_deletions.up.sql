@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS account_deletions (
+    created_at timestamp with time zone not null,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    id bigserial not null primary key,
+    user_id bigint not null
+        constraint fk_user_id_account_deletion
+            references users (id),
+    user_uuid varchar(36) not null,
+    previous_status varchar(50) not null,
+    status varchar(20) not null,
+    purge_at timestamp with time zone not null,
+    cancelled_at timestamp with time zone,
+    purged_at timestamp with time zone
+);
+-- one open request per user, a cancelled or purged one stays as history
+CREATE UNIQUE INDEX IF NOT EXISTS idx_account_deletions_user_requested ON account_deletions (user_id) WHERE status = 'REQUESTED';
+CREATE INDEX IF NOT EXISTS idx_account_deletions_purge_at ON account_deletions (purge_at) WHERE status = 'REQUESTED';
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS legal_documents (
+    created_at timestamp with time zone not null,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    id bigserial not null primary key,
+    type varchar(30) not null,
+    version varchar(20) not null,
+    title varchar(255) not null,
+    url text not null,
+    mandatory boolean not null default true,
+    published_at timestamp with time zone not null
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_legal_documents_type_version ON legal_documents (type, version) WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS user_consents (
+    created_at timestamp with time zone not null,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    id bigserial not null primary key,
+    user_id bigint not null
+        constraint fk_user_id_user_consent
+            references users (id),
+    document_id bigint not null
+        constraint fk_document_id_user_consent
+            references legal_documents (id),
+    accepted_at timestamp with time zone not null,
+    ip_address varchar(45),
+    app_version varchar(50)
+);
+-- a version is accepted once, the first acceptance is the one on record
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_consents_user_document ON user_consents (user_id, document_id);
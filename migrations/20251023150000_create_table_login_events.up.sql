@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS login_events (
+    created_at timestamp with time zone not null,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    id bigserial not null primary key,
+    user_id bigint not null
+        constraint fk_user_id_login_event
+            references users (id),
+    user_uuid varchar(36) not null,
+    method varchar(20) not null,
+    status varchar(10) not null,
+    failure_reason varchar(255),
+    device_id varchar(255),
+    ip_address varchar(45),
+    app_version varchar(50),
+    place varchar(255),
+    latitude double precision,
+    longitude double precision,
+    new_device boolean not null default false,
+    far_location boolean not null default false
+);
+CREATE INDEX IF NOT EXISTS idx_login_events_user_created_at ON login_events (user_id, created_at DESC);
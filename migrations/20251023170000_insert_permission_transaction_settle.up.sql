@@ -0,0 +1,10 @@
+INSERT INTO permissions (created_at, name, description) VALUES
+    (now(), 'transaction:settle', 'set the final status of a transaction from the payment callback')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.name = 'transaction:settle'
+WHERE r.name IN ('ADMIN', 'SERVICE')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_token_blacklists_token;
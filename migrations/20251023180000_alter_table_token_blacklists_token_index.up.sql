@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_token_blacklists_token ON token_blacklists (token);
@@ -0,0 +1,2 @@
+-- a hash can not be turned back into the token, nothing to undo
+SELECT 1;
@@ -0,0 +1,4 @@
+-- the blacklist only keeps sha256 hex of a token, raw jwts written before are hashed in place
+UPDATE token_blacklists
+SET token = encode(sha256(convert_to(token, 'UTF8')), 'hex')
+WHERE token LIKE 'eyJ%';
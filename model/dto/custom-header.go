@@ -11,10 +11,14 @@ type ContextValue struct {
 	HeaderXLatitude   string `header:"X-LATITUDE" json:"X-LATITUDE" validate:"required"`
 	HeaderXLongitude  string `header:"X-LONGITUDE" json:"X-LONGITUDE" validate:"required"`
 	HeaderXApiKey     string `header:"X-API-KEY" json:"X-API-KEY"`
+	HeaderXAppVersion string `header:"X-APP-VERSION" json:"X-APP-VERSION"`
+	// HeaderXSignatureVersion selects the signing scheme, empty is the legacy v1
+	HeaderXSignatureVersion string `header:"X-SIGNATURE-VERSION" json:"X-SIGNATURE-VERSION"`
 
 	HeaderRequestId     string
 	HeaderHost          string
 	HeaderPath          string
+	HeaderRawQuery      string
 	RequestPath         string
 	HeaderMethod        string
 	HeaderAuthorization string
@@ -23,4 +27,9 @@ type ContextValue struct {
 	AuthEmail    string
 	AuthDeviceID string
 	AuthRole     string
+	AuthFamilyID string
+
+	AuthRoles       []string
+	AuthPermissions []string
+	AuthApiKey      string //prefix of the api key an internal call was made with
 }
@@ -3,6 +3,7 @@ package dto
 import (
 	"backend-mobile-api/model/entity"
 	"backend-mobile-api/model/enum"
+	"time"
 )
 
 type SendOtp struct {
@@ -13,6 +14,34 @@ type SendOtp struct {
 	UserId         int64           `json:"user_id"`
 	UserUUID       string          `json:"user_uuid"`
 	VerifyKey      string          `json:"verify_key"`
+	// FallbackEmail and FallbackPhoneNumber let delivery move to a channel of the other kind,
+	// leave them empty when the otp proves ownership of OtpDestination itself
+	FallbackEmail       string `json:"fallback_email"`
+	FallbackPhoneNumber string `json:"fallback_phone_number"`
+}
+
+// OtpDelivery follows one verify key across the channels it is sent on,
+// every code sent under the key stays valid until one is verified.
+type OtpDelivery struct {
+	VerifyKey  string               `json:"verify_key"`
+	OtpPurpose enum.OtpService      `json:"otp_purpose"`
+	UserId     int64                `json:"user_id"`
+	UserUUID   string               `json:"user_uuid"`
+	Channels   []OtpDeliveryChannel `json:"channels"`
+	// Attempt indexes Channels, the channel the otp currently waits on
+	Attempt   int            `json:"attempt"`
+	SessionId string         `json:"session_id"`
+	Status    enum.OTPStatus `json:"status"`
+	Confirmed bool           `json:"confirmed"`
+	Deadline  time.Time      `json:"deadline"`
+	ExpiredAt time.Time      `json:"expired_at"`
+	Code      string         `json:"code"`
+	Codes     []string       `json:"codes"`
+}
+
+type OtpDeliveryChannel struct {
+	OtpMethod      enum.OtpType `json:"otp_method"`
+	OtpDestination string       `json:"otp_destination"`
 }
 
 type ProfileUpdateRequest struct {
@@ -20,3 +49,19 @@ type ProfileUpdateRequest struct {
 	Value string
 	User  *entity.User
 }
+
+type AccountInquiryCache struct {
+	AccountNo   string `json:"account_no"`
+	AccountName string `json:"account_name"`
+	BankCode    string `json:"bank_code"`
+	BankName    string `json:"bank_name"`
+	ReferenceNo string `json:"reference_no"`
+}
+
+// FakeOtp is the last code the fake otp provider sent to a destination.
+type FakeOtp struct {
+	Otp       string       `json:"otp"`
+	OtpMethod enum.OtpType `json:"otp_method"`
+	SessionId string       `json:"session_id"`
+	SentAt    time.Time    `json:"sent_at"`
+}
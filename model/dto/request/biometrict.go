@@ -4,7 +4,14 @@ import "backend-mobile-api/model/enum"
 
 type BiometricRequest struct {
 	ServiceType enum.BiometricType `json:"service_type"`
-	Token       string             `json:"token"`
-	UUID        string             `json:"uuid"`
-	DeviceID    string             `json:"device_id"`
+	UUID        string             `json:"uuid" validate:"required"`
+	DeviceID    string             `json:"device_id" validate:"required"`
+	ChallengeID string             `json:"challenge_id" validate:"required"`
+	// base64 signature of the challenge nonce by the device key
+	Signature string `json:"signature" validate:"required"`
+}
+
+type BiometricChallengeRequest struct {
+	UUID     string `json:"uuid" validate:"required"`
+	DeviceID string `json:"device_id" validate:"required"`
 }
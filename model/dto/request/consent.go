@@ -0,0 +1,5 @@
+package request
+
+type AcceptConsentRequest struct {
+	DocumentIDs []uint `json:"document_ids" validate:"required,min=1"`
+}
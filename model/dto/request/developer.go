@@ -0,0 +1,5 @@
+package request
+
+type LastOtpRequest struct {
+	Destination string `query:"destination" json:"destination" validate:"required"`
+}
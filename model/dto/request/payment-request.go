@@ -0,0 +1,27 @@
+package request
+
+import "backend-mobile-api/model/enum"
+
+type CreatePaymentRequest struct {
+	SplitType        enum.SplitType                    `json:"split_type" validate:"required,oneof=EVEN CUSTOM"`
+	TotalAmount      float64                           `json:"total_amount" validate:"required_if=SplitType EVEN,gte=0"`
+	IncludeRequester bool                              `json:"include_requester"`
+	Note             string                            `json:"note" validate:"max=255"`
+	ExpiredInHours   int                               `json:"expired_in_hours" validate:"omitempty,min=1,max=720"`
+	Participants     []CreatePaymentRequestParticipant `json:"participants" validate:"required,min=1,max=20,dive"`
+}
+
+type CreatePaymentRequestParticipant struct {
+	// Identity is the registered email or phone number of the participant.
+	Identity string  `json:"identity" validate:"required"`
+	Amount   float64 `json:"amount" validate:"gte=0"`
+}
+
+type PayPaymentRequest struct {
+	RequestID     string `json:"request_id" validate:"required"`
+	PaymentMethod string `json:"payment_method" validate:"required,oneof=bank_transfer virtual_account"`
+}
+
+type DeclinePaymentRequest struct {
+	RequestID string `json:"request_id" validate:"required"`
+}
@@ -35,6 +35,8 @@ type RegisterRequest struct {
 	FullName    string `json:"full_name" validate:"required"`
 	Email       string `json:"email" validate:"required,email"`
 	PhoneNumber string `json:"phone_number" validate:"required"`
+	// Consents are the ids of the current legal documents the user accepted
+	Consents []uint `json:"consents"`
 	//DeviceID    string `json:"device_id" validate:"required"`
 }
 type VerifyOtpRequest struct {
@@ -68,6 +70,10 @@ type SendOtpRequest struct {
 	DeviceID    string          `json:"device_id" validate:"required"`
 }
 
+type OtpStatusRequest struct {
+	VerifyID string `json:"verify_id" validate:"required"`
+}
+
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 	UUID         string `json:"uuid" validate:"required"`
@@ -89,3 +95,9 @@ type AccessTokenRequest struct {
 	Pin        string               `json:"pin" validate:"required,numeric"`
 	DeviceID   string               `json:"device_id" validate:"required"`
 }
+type VerifyDeviceRequest struct {
+	Otp        string     `json:"otp" validate:"required,numeric"`
+	VerifyID   string     `json:"verify_id" validate:"required"`
+	DeviceID   string     `json:"device_id" validate:"required"`
+	DeviceInfo DeviceData `json:"device_info" validate:"required"`
+}
@@ -1,6 +1,7 @@
 package request
 
 import (
+	"backend-mobile-api/model/enum"
 	"mime/multipart"
 )
 
@@ -32,6 +33,11 @@ type DeletetAccountRequest struct {
 type BiometrictStatusRequest struct {
 	Active   bool   `json:"active"`
 	DeviceID string `json:"device_id" validate:"required"`
+	// public key of the device keystore pair, base64 der (spki) or pem, required to activate
+	PublicKey string                     `json:"public_key" validate:"required_if=Active true"`
+	Algorithm enum.BiometricKeyAlgorithm `json:"algorithm" validate:"required_if=Active true,omitempty,oneof=ECDSA_P256_SHA256 RSA_PKCS1_SHA256"`
+	// pin of the user, re-verified before a key is registered
+	Pin string `json:"pin" validate:"required_if=Active true,omitempty,numeric"`
 }
 type ResetProfilePictureRequest struct {
 	ProfilePicture *multipart.FileHeader `json:"profile_picture" validate:"required"`
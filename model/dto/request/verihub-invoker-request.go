@@ -1,7 +1,17 @@
 package request
 
 type VerihubsOtpInvoker struct {
-	Method    string `param:"method" json:"method" validate:"required"`
 	SessionId string `query:"session_id" validate:"required" json:"session_id"`
 	Status    string `query:"status" validate:"required,numeric" json:"status"`
+	// Token is added to the callback url when the otp is sent, see otp.CallbackToken
+	Token string `query:"token" validate:"required" json:"token"`
+}
+
+type OtpMetricsRequest struct {
+	From string `query:"from" json:"from" validate:"required,datetime=2006-01-02"`
+	To   string `query:"to" json:"to" validate:"required,datetime=2006-01-02"`
+}
+
+type OtpTimelineRequest struct {
+	VerifyID string `query:"verify_id" json:"verify_id" validate:"required"`
 }
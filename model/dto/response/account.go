@@ -0,0 +1,112 @@
+package response
+
+import (
+	"backend-mobile-api/model/enum"
+	"backend-mobile-api/model/types"
+	"time"
+)
+
+type AccountDeletionResponse struct {
+	Status  enum.AccountDeletionStatus `json:"status"`
+	PurgeAt time.Time                  `json:"purge_at"`
+}
+
+// AccountExportResponse is the machine-readable copy of a user's data, secrets
+// such as the pin hash and token values are left out.
+type AccountExportResponse struct {
+	ExportedAt       time.Time                     `json:"exported_at"`
+	Profile          AccountExportProfile          `json:"profile"`
+	Devices          []AccountExportDevice         `json:"devices"`
+	Sessions         []SessionResponse             `json:"sessions"`
+	IdentityKtp      []AccountExportKtp            `json:"identity_ktp"`
+	IdentityPassport []AccountExportPassport       `json:"identity_passport"`
+	Recipients       []AccountExportRecipient      `json:"recipients"`
+	PaymentAccounts  []AccountExportPaymentAccount `json:"payment_accounts"`
+	Transactions     []AccountExportTransaction    `json:"transactions"`
+	Consents         []UserConsentResponse         `json:"consents"`
+	LoginHistory     []LoginEventResponse          `json:"login_history"`
+}
+
+type AccountExportProfile struct {
+	UUID        string                `json:"uuid"`
+	FullName    string                `json:"full_name"`
+	Email       string                `json:"email"`
+	PhoneNumber string                `json:"phone_number"`
+	Status      enum.UserStatus       `json:"status"`
+	Country     string                `json:"country"`
+	Province    string                `json:"province"`
+	Regency     string                `json:"regency"`
+	District    string                `json:"district"`
+	Address     string                `json:"address"`
+	KycStatus   enum.UserKYCStatus    `json:"kyc_status"`
+	KycType     enum.UserKYCType      `json:"kyc_type"`
+	Biometric   enum.BiometricStatus  `json:"biometric"`
+	DetailState enum.UserDetailStatus `json:"detail_status"`
+	CreatedAt   time.Time             `json:"created_at"`
+}
+
+type AccountExportDevice struct {
+	DeviceID       string     `json:"device_id"`
+	Manufacturer   string     `json:"manufacturer"`
+	Brand          string     `json:"brand"`
+	DeviceModel    string     `json:"device_model"`
+	AppVersionName string     `json:"app_version_name"`
+	CreatedAt      time.Time  `json:"created_at"`
+	TrustedAt      *time.Time `json:"trusted_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+}
+
+type AccountExportKtp struct {
+	Nik           string    `json:"nik"`
+	FullName      string    `json:"full_name"`
+	PlaceOfBirth  string    `json:"place_of_birth"`
+	DateOfBirth   time.Time `json:"date_of_birth"`
+	Gender        string    `json:"gender"`
+	Occupation    string    `json:"occupation"`
+	Nationality   string    `json:"nationality"`
+	MartialStatus string    `json:"martial_status"`
+	Religion      string    `json:"religion"`
+	FullAddress   string    `json:"full_address"`
+	SubmitAt      time.Time `json:"submit_at"`
+}
+
+type AccountExportPassport struct {
+	PassportNumber string     `json:"passport_number"`
+	PassportType   string     `json:"passport_type"`
+	FullName       string     `json:"full_name"`
+	Gender         string     `json:"gender"`
+	Nationality    string     `json:"nationality"`
+	PlaceOfBirth   string     `json:"place_of_birth"`
+	DateOfBirth    types.Date `json:"date_of_birth"`
+	DateOfIssue    types.Date `json:"date_of_issue"`
+	DateOfExpired  types.Date `json:"date_of_expired"`
+	PlaceOfIssue   string     `json:"place_of_issue"`
+	SubmitAt       time.Time  `json:"submit_at"`
+}
+
+type AccountExportRecipient struct {
+	BankID        int       `json:"bank_id"`
+	RecipientName string    `json:"recipient_name"`
+	AccountNumber string    `json:"account_number"`
+	Alias         string    `json:"alias"`
+	IsFavorite    bool      `json:"is_favorite"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type AccountExportPaymentAccount struct {
+	BankName       string `json:"bank_name"`
+	AccountNumber  string `json:"account_number"`
+	VirtualAccount string `json:"virtual_account"`
+}
+
+type AccountExportTransaction struct {
+	TransactionID string    `json:"transaction_id"`
+	Type          string    `json:"type"`
+	PaymentMethod string    `json:"payment_method"`
+	Description   string    `json:"description"`
+	Nominal       float64   `json:"nominal"`
+	AdminFee      float64   `json:"admin_fee"`
+	Total         float64   `json:"total"`
+	Status        string    `json:"status"`
+	CreatedAt     time.Time `json:"created_at"`
+}
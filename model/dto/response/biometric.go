@@ -0,0 +1,21 @@
+package response
+
+import (
+	"backend-mobile-api/model/enum"
+	"time"
+)
+
+type BiometricChallengeResponse struct {
+	ChallengeID string    `json:"challenge_id"`
+	Nonce       string    `json:"nonce"`
+	ExpireAt    time.Time `json:"expire_at"`
+}
+
+type BiometricKeyResponse struct {
+	KeyID      string                     `json:"key_id"`
+	DeviceID   string                     `json:"device_id"`
+	Algorithm  enum.BiometricKeyAlgorithm `json:"algorithm"`
+	CreatedAt  time.Time                  `json:"created_at"`
+	LastUsedAt *time.Time                 `json:"last_used_at"`
+	Current    bool                       `json:"current"`
+}
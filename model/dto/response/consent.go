@@ -0,0 +1,31 @@
+package response
+
+import (
+	"backend-mobile-api/model/enum"
+	"time"
+)
+
+type LegalDocumentResponse struct {
+	ID          uint                   `json:"id"`
+	Type        enum.LegalDocumentType `json:"type"`
+	Version     string                 `json:"version"`
+	Title       string                 `json:"title"`
+	URL         string                 `json:"url"`
+	Mandatory   bool                   `json:"mandatory"`
+	PublishedAt time.Time              `json:"published_at"`
+}
+
+type UserConsentResponse struct {
+	Document   LegalDocumentResponse `json:"document"`
+	AcceptedAt time.Time             `json:"accepted_at"`
+	IPAddress  string                `json:"ip_address"`
+	AppVersion string                `json:"app_version"`
+}
+
+// ConsentResponse lists what the user accepted and the current documents they
+// have not, MustReaccept is set when one of those is mandatory.
+type ConsentResponse struct {
+	MustReaccept bool                    `json:"must_reaccept"`
+	Accepted     []UserConsentResponse   `json:"accepted"`
+	Pending      []LegalDocumentResponse `json:"pending"`
+}
@@ -0,0 +1,14 @@
+package response
+
+import (
+	"backend-mobile-api/model/enum"
+	"time"
+)
+
+type LastOtpResponse struct {
+	Destination string       `json:"destination"`
+	Otp         string       `json:"otp"`
+	OtpMethod   enum.OtpType `json:"otp_method"`
+	SessionId   string       `json:"session_id"`
+	SentAt      time.Time    `json:"sent_at"`
+}
@@ -0,0 +1,28 @@
+package response
+
+import (
+	"backend-mobile-api/model/enum"
+	"time"
+)
+
+type LoginEventResponse struct {
+	Method        enum.LoginType   `json:"method"`
+	Status        enum.LoginStatus `json:"status"`
+	FailureReason string           `json:"failure_reason,omitempty"`
+	DeviceID      string           `json:"device_id"`
+	IPAddress     string           `json:"ip_address"`
+	AppVersion    string           `json:"app_version"`
+	Place         string           `json:"place"`
+	Latitude      *float64         `json:"latitude"`
+	Longitude     *float64         `json:"longitude"`
+	NewDevice     bool             `json:"new_device"`
+	FarLocation   bool             `json:"far_location"`
+	CreatedAt     time.Time        `json:"created_at"`
+}
+
+type LoginHistoryResponse struct {
+	Page   int                  `json:"page"`
+	Limit  int                  `json:"limit"`
+	Total  int64                `json:"total"`
+	Events []LoginEventResponse `json:"events"`
+}
@@ -0,0 +1,37 @@
+package response
+
+import (
+	"backend-mobile-api/model/enum"
+	"time"
+)
+
+type PaymentRequestResponse struct {
+	RequestID     string                          `json:"request_id"`
+	RequesterUUID string                          `json:"requester_uuid"`
+	RequesterName string                          `json:"requester_name,omitempty"`
+	SplitType     enum.SplitType                  `json:"split_type"`
+	TotalAmount   float64                         `json:"total_amount"`
+	Note          string                          `json:"note"`
+	Status        enum.PaymentRequestStatus       `json:"status"`
+	ExpiredAt     time.Time                       `json:"expired_at"`
+	CreatedAt     time.Time                       `json:"created_at"`
+	Participants  []PaymentRequestParticipantData `json:"participants"`
+}
+
+type PaymentRequestParticipantData struct {
+	UserUUID      string                        `json:"user_uuid"`
+	Name          string                        `json:"name"`
+	Amount        float64                       `json:"amount"`
+	Status        enum.PaymentParticipantStatus `json:"status"`
+	TransactionID *string                       `json:"transaction_id"`
+	RespondedAt   *time.Time                    `json:"responded_at"`
+}
+
+type PayPaymentRequestResponse struct {
+	RequestID     string    `json:"request_id"`
+	TransactionID string    `json:"transaction_id"`
+	Amount        float64   `json:"amount"`
+	UniqueCode    float64   `json:"unique_code"`
+	Total         float64   `json:"total"`
+	ExpiredAt     time.Time `json:"expired_at"`
+}
@@ -2,6 +2,7 @@ package response
 
 import (
 	"backend-mobile-api/internal/middleware"
+	"backend-mobile-api/model/enum"
 	"time"
 )
 
@@ -10,12 +11,27 @@ type VerifyOtp struct {
 	ExpireAt  time.Time `json:"expire_at"`
 }
 type SendOtpResponse struct {
-	VerifyId string    `json:"verify_id"`
-	ExpireAt time.Time `json:"expire_at"`
+	VerifyId    string       `json:"verify_id"`
+	OtpMethod   enum.OtpType `json:"otp_method"`
+	Destination string       `json:"destination"`
+	ExpireAt    time.Time    `json:"expire_at"`
+}
+
+// OtpStatusResponse Fallback is true once the otp left the channel the app asked for
+type OtpStatusResponse struct {
+	VerifyId    string         `json:"verify_id"`
+	OtpMethod   enum.OtpType   `json:"otp_method"`
+	Destination string         `json:"destination"`
+	Status      enum.OTPStatus `json:"status"`
+	Delivered   bool           `json:"delivered"`
+	Fallback    bool           `json:"fallback"`
+	ExpireAt    time.Time      `json:"expire_at"`
 }
 type LoginResponse struct {
 	User  UserData             `json:"user"`
 	Token middleware.TokenData `json:"token"`
+	// MustReacceptConsent asks the app to show the new mandatory legal documents
+	MustReacceptConsent bool `json:"must_reaccept_consent"`
 }
 type UserData struct {
 	UUID  string `json:"uuid"`
@@ -28,3 +44,16 @@ type AccessTokenResponse struct {
 	AccessKey string    `json:"access_key"`
 	ExpireAt  time.Time `json:"expire_at"`
 }
+
+// PinAttemptResponse is sent back with a rejected pin so the app can show the remaining attempts.
+type PinAttemptResponse struct {
+	RemainingAttempts int64 `json:"remaining_attempts"`
+	RetryAfter        int64 `json:"retry_after"` // seconds
+}
+
+// OtpAttemptResponse is sent back with a rejected otp request or code, RetryAfter
+// is zero when a new otp can be requested right away.
+type OtpAttemptResponse struct {
+	RemainingAttempts int64 `json:"remaining_attempts"`
+	RetryAfter        int64 `json:"retry_after"` // seconds
+}
@@ -0,0 +1,24 @@
+package response
+
+import (
+	"backend-mobile-api/model/enum"
+	"time"
+)
+
+type DeviceChallengeResponse struct {
+	VerifyId    string       `json:"verify_id"`
+	OtpMethod   enum.OtpType `json:"otp_method"`
+	Destination string       `json:"destination"`
+	ExpireAt    time.Time    `json:"expire_at"`
+}
+
+type TrustedDeviceResponse struct {
+	ID             uint      `json:"id"`
+	DeviceID       string    `json:"device_id"`
+	Manufacturer   string    `json:"manufacturer"`
+	Brand          string    `json:"brand"`
+	DeviceModel    string    `json:"device_model"`
+	AppVersionName string    `json:"app_version_name"`
+	TrustedAt      time.Time `json:"trusted_at"`
+	Current        bool      `json:"current"`
+}
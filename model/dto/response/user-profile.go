@@ -1,6 +1,7 @@
 package response
 
 import (
+	"backend-mobile-api/internal/middleware"
 	"backend-mobile-api/model/enum"
 	"time"
 )
@@ -18,3 +19,9 @@ type GetProfilePictureResponse struct {
 	Url      string    `json:"url"`
 	ExpireAt time.Time `json:"expire_at"`
 }
+
+// CredentialChangedResponse carries the new tokens of the session that made the
+// change, the tokens it used before are no longer valid.
+type CredentialChangedResponse struct {
+	Token *middleware.TokenData `json:"token,omitempty"`
+}
@@ -0,0 +1,19 @@
+package response
+
+import "time"
+
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	DeviceID   string    `json:"device_id"`
+	AppVersion string    `json:"app_version"`
+	IPAddress  string    `json:"ip_address"`
+	Latitude   *float64  `json:"latitude"`
+	Longitude  *float64  `json:"longitude"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+type RevokeSessionsResponse struct {
+	Revoked int `json:"revoked"`
+}
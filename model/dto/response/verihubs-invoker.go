@@ -0,0 +1,21 @@
+package response
+
+import (
+	"backend-mobile-api/model/entity"
+	"backend-mobile-api/model/enum"
+	"time"
+)
+
+type OtpMetricsResponse struct {
+	From     string                    `json:"from"`
+	To       string                    `json:"to"`
+	Channels []entity.OtpChannelMetric `json:"channels"`
+}
+
+type OtpDeliveryEventResponse struct {
+	OtpMethod      enum.OtpType   `json:"otp_method"`
+	SessionId      string         `json:"session_id"`
+	Status         enum.OTPStatus `json:"status"`
+	ProviderStatus string         `json:"provider_status"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
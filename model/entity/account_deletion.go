@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"backend-mobile-api/model/enum"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AccountDeletion struct {
+	gorm.Model
+	UserID         int64                      `gorm:"column:user_id;type:bigint" json:"user_id"`
+	UserUUID       string                     `gorm:"column:user_uuid;type:varchar(36)" json:"user_uuid"`
+	PreviousStatus enum.UserStatus            `gorm:"column:previous_status;type:varchar(50)" json:"previous_status"`
+	Status         enum.AccountDeletionStatus `gorm:"column:status;type:varchar(20)" json:"status"`
+	PurgeAt        time.Time                  `gorm:"column:purge_at;type:timestamptz" json:"purge_at"`
+	CancelledAt    *time.Time                 `gorm:"column:cancelled_at;type:timestamptz" json:"cancelled_at"`
+	PurgedAt       *time.Time                 `gorm:"column:purged_at;type:timestamptz" json:"purged_at"`
+}
+
+func (a AccountDeletion) TableName() string { return "account_deletions" }
+
+// AccountExport is everything stored about one user, read for the data export.
+type AccountExport struct {
+	User            User
+	Detail          *UserDetail
+	Devices         []Device
+	Sessions        []TokenFamily
+	Ktps            []IdentityKtp
+	Passports       []IdentityPassport
+	Recipients      []Recipient
+	PaymentAccounts []UserPaymentsAccount
+	Transactions    []Transaction
+	Consents        []UserConsent
+	LoginEvents     []LoginEvent
+}
@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// ApiKey authenticates a service or back-office tool on the internal routes.
+// Only the sha256 of the key is stored, the prefix is the public part used to look it up.
+type ApiKey struct {
+	gorm.Model
+	Name        string     `gorm:"column:name;type:varchar(100)" json:"name"`
+	Prefix      string     `gorm:"column:prefix;type:varchar(16);uniqueIndex" json:"prefix"`
+	KeyHash     string     `gorm:"column:key_hash;type:varchar(64)" json:"-"`
+	Scopes      string     `gorm:"column:scopes;type:varchar(500)" json:"scopes"`             //comma separated permissions
+	IPAllowlist string     `gorm:"column:ip_allowlist;type:varchar(500)" json:"ip_allowlist"` //comma separated ip or cidr, empty allows any
+	ExpiredAt   *time.Time `gorm:"column:expired_at;type:timestamptz" json:"expired_at"`
+	RevokedAt   *time.Time `gorm:"column:revoked_at;type:timestamptz" json:"revoked_at"`
+	LastUsedAt  *time.Time `gorm:"column:last_used_at;type:timestamptz" json:"last_used_at"`
+}
+
+func (a ApiKey) TableName() string { return "api_keys" }
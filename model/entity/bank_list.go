@@ -3,6 +3,7 @@ package entity
 type Bank struct {
 	ID       uint    `gorm:"column:bank_id;primaryKey" json:"id"`
 	NamaBank string  `gorm:"column:nama_bank" json:"nama_bank"`
+	BankCode string  `gorm:"column:bank_code" json:"bank_code"`
 	UrlImage string  `gorm:"column:url_image" json:"url_image"`
 	VAName   string  `json:"va_name" gorm:"column:va_name"`
 	Price    float64 `json:"price" gorm:"column:price"`
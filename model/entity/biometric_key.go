@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"backend-mobile-api/model/enum"
+	"gorm.io/gorm"
+	"time"
+)
+
+// BiometricKey is the public half of a key pair generated in a device keystore,
+// the private half only signs after a local biometric unlock.
+type BiometricKey struct {
+	gorm.Model
+	KeyID      string                     `gorm:"column:key_id;type:varchar(36);uniqueIndex" json:"key_id"`
+	UserID     int64                      `gorm:"column:user_id;type:bigint" json:"user_id"`
+	UserUUID   string                     `gorm:"column:user_uuid;type:varchar(36)" json:"user_uuid"`
+	DeviceID   string                     `gorm:"column:device_id;type:varchar(255)" json:"device_id"`
+	Algorithm  enum.BiometricKeyAlgorithm `gorm:"column:algorithm;type:varchar(30)" json:"algorithm"`
+	PublicKey  string                     `gorm:"column:public_key;type:text" json:"-"`
+	LastUsedAt *time.Time                 `gorm:"column:last_used_at;type:timestamptz" json:"last_used_at"`
+	RevokedAt  *time.Time                 `gorm:"column:revoked_at;type:timestamptz" json:"revoked_at"`
+}
+
+func (b BiometricKey) TableName() string { return "biometric_keys" }
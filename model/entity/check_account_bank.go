@@ -2,8 +2,8 @@ package entity
 
 type CheckAccountRequest struct {
 	PartnerReferenceNo   string `json:"partnerReferenceNo"`
-	BeneficiaryAccountNo string `json:"beneficiaryAccountNo"`
-	BeneficiaryBankCode  string `json:"beneficiaryBankCode"`
+	BeneficiaryAccountNo string `json:"beneficiaryAccountNo" validate:"required,numeric"`
+	BeneficiaryBankCode  string `json:"beneficiaryBankCode" validate:"required"`
 	Type                 string `json:"type"`
 }
 
@@ -12,4 +12,7 @@ type CheckAccountResponseData struct {
 	BeneficiaryAccountName string `json:"beneficiaryAccountName"`
 
 	BeneficiaryBankCode string `json:"beneficiaryBankCode"`
+	BeneficiaryBankName string `json:"beneficiaryBankName"`
+	PartnerReferenceNo  string `json:"partnerReferenceNo"`
+	ReferenceNo         string `json:"referenceNo"`
 }
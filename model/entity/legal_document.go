@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"backend-mobile-api/model/enum"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LegalDocument is one published version of a legal text, the latest version
+// of a type published before now is the current one.
+type LegalDocument struct {
+	gorm.Model
+	Type        enum.LegalDocumentType `gorm:"column:type;type:varchar(30)" json:"type"`
+	Version     string                 `gorm:"column:version;type:varchar(20)" json:"version"`
+	Title       string                 `gorm:"column:title;type:varchar(255)" json:"title"`
+	URL         string                 `gorm:"column:url;type:text" json:"url"`
+	Mandatory   bool                   `gorm:"column:mandatory" json:"mandatory"`
+	PublishedAt time.Time              `gorm:"column:published_at;type:timestamptz" json:"published_at"`
+}
+
+func (l LegalDocument) TableName() string { return "legal_documents" }
+
+type UserConsent struct {
+	gorm.Model
+	UserID     int64         `gorm:"column:user_id;type:bigint" json:"user_id"`
+	DocumentID uint          `gorm:"column:document_id;type:bigint" json:"document_id"`
+	Document   LegalDocument `gorm:"foreignKey:DocumentID" json:"document"`
+	AcceptedAt time.Time     `gorm:"column:accepted_at;type:timestamptz" json:"accepted_at"`
+	IPAddress  string        `gorm:"column:ip_address;type:varchar(45)" json:"ip_address"`
+	AppVersion string        `gorm:"column:app_version;type:varchar(50)" json:"app_version"`
+}
+
+func (u UserConsent) TableName() string { return "user_consents" }
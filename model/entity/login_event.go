@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"backend-mobile-api/model/enum"
+
+	"gorm.io/gorm"
+)
+
+// LoginEvent is one login attempt of a known user, NewDevice and FarLocation
+// record why a successful one was alerted.
+type LoginEvent struct {
+	gorm.Model
+	UserID        int64            `gorm:"column:user_id;type:bigint" json:"user_id"`
+	UserUUID      string           `gorm:"column:user_uuid;type:varchar(36)" json:"user_uuid"`
+	Method        enum.LoginType   `gorm:"column:method;type:varchar(20)" json:"method"`
+	Status        enum.LoginStatus `gorm:"column:status;type:varchar(10)" json:"status"`
+	FailureReason string           `gorm:"column:failure_reason;type:varchar(255)" json:"failure_reason"`
+	DeviceID      string           `gorm:"column:device_id;type:varchar(255)" json:"device_id"`
+	IPAddress     string           `gorm:"column:ip_address;type:varchar(45)" json:"ip_address"`
+	AppVersion    string           `gorm:"column:app_version;type:varchar(50)" json:"app_version"`
+	Place         string           `gorm:"column:place;type:varchar(255)" json:"place"`
+	Latitude      *float64         `gorm:"column:latitude" json:"latitude"`
+	Longitude     *float64         `gorm:"column:longitude" json:"longitude"`
+	NewDevice     bool             `gorm:"column:new_device" json:"new_device"`
+	FarLocation   bool             `gorm:"column:far_location" json:"far_location"`
+}
+
+func (l LoginEvent) TableName() string { return "login_events" }
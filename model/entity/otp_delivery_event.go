@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"backend-mobile-api/model/enum"
+	"gorm.io/gorm"
+)
+
+// OtpDeliveryEvent is one step of an otp delivery: the send itself, then every
+// status the provider reported for it.
+type OtpDeliveryEvent struct {
+	gorm.Model
+	OtpID          uint           `gorm:"column:otp_id;type:bigint" json:"otp_id"`
+	VerifyKey      string         `gorm:"column:verify_key;type:varchar(255)" json:"verify_key"`
+	SessionId      string         `gorm:"column:session_id;type:varchar(255)" json:"session_id"`
+	OtpMethod      enum.OtpType   `gorm:"column:otp_method;type:varchar(20)" json:"otp_method"`
+	Status         enum.OTPStatus `gorm:"column:status;type:varchar(30)" json:"status"`
+	ProviderStatus string         `gorm:"column:provider_status;type:varchar(10)" json:"provider_status"`
+}
+
+func (e OtpDeliveryEvent) TableName() string { return "otp_delivery_events" }
+
+// OtpChannelMetric counts the otps of one channel by how far their delivery got.
+type OtpChannelMetric struct {
+	OtpMethod enum.OtpType `gorm:"column:otp_method" json:"otp_method"`
+	Sent      int64        `gorm:"column:sent" json:"sent"`
+	Delivered int64        `gorm:"column:delivered" json:"delivered"`
+	Failed    int64        `gorm:"column:failed" json:"failed"`
+	Verified  int64        `gorm:"column:verified" json:"verified"`
+	Charged   int64        `gorm:"column:charged" json:"charged"`
+}
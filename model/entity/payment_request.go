@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"backend-mobile-api/model/enum"
+	"gorm.io/gorm"
+	"time"
+)
+
+type PaymentRequest struct {
+	gorm.Model
+	RequestID     string                      `gorm:"column:request_id;type:varchar(36);uniqueIndex" json:"request_id"`
+	RequesterID   int64                       `gorm:"column:requester_id;type:bigint" json:"requester_id"`
+	RequesterUUID string                      `gorm:"column:requester_uuid;type:varchar(36)" json:"requester_uuid"`
+	SplitType     enum.SplitType              `gorm:"column:split_type;type:varchar(20)" json:"split_type"`
+	TotalAmount   float64                     `gorm:"column:total_amount" json:"total_amount"`
+	Note          string                      `gorm:"column:note;type:varchar(255)" json:"note"`
+	Status        enum.PaymentRequestStatus   `gorm:"column:status;type:varchar(20)" json:"status"`
+	ExpiredAt     time.Time                   `gorm:"column:expired_at" json:"expired_at"`
+	Participants  []PaymentRequestParticipant `gorm:"foreignKey:PaymentRequestID" json:"participants,omitempty"`
+}
+
+func (p PaymentRequest) TableName() string { return "payment_requests" }
+
+type PaymentRequestParticipant struct {
+	gorm.Model
+	PaymentRequestID uint                          `gorm:"column:payment_request_id" json:"payment_request_id"`
+	UserID           int64                         `gorm:"column:user_id;type:bigint" json:"user_id"`
+	UserUUID         string                        `gorm:"column:user_uuid;type:varchar(36)" json:"user_uuid"`
+	Amount           float64                       `gorm:"column:amount" json:"amount"`
+	Status           enum.PaymentParticipantStatus `gorm:"column:status;type:varchar(20)" json:"status"`
+	TransactionID    *string                       `gorm:"column:transaction_id;type:varchar(50)" json:"transaction_id"`
+	RespondedAt      *time.Time                    `gorm:"column:responded_at" json:"responded_at"`
+}
+
+func (p PaymentRequestParticipant) TableName() string { return "payment_request_participants" }
@@ -1,12 +1,17 @@
 package entity
 
+import "time"
+
 type Recipient struct {
-	RecipientID  uint   `gorm:"column:recipient_id;primaryKey;autoIncrement" json:"recipient_id"`
-	Bank         int    `gorm:"column:bank_id" json:"bank_id"`
-	UserUUID     string `gorm:"-" json:"user_uuid"`
-	User         int64  `gorm:"column:user_id" json:"user_id"`
-	NamaPenerima string `gorm:"column:nama_penerima" json:"nama_penerima"`
-	NoRekening   string `gorm:"column:no_rekening" json:"no_rekening"`
+	RecipientID  uint      `gorm:"column:recipient_id;primaryKey;autoIncrement" json:"recipient_id"`
+	Bank         int       `gorm:"column:bank_id" json:"bank_id"`
+	UserUUID     string    `gorm:"-" json:"user_uuid"`
+	User         int64     `gorm:"column:user_id" json:"user_id"`
+	NamaPenerima string    `gorm:"column:nama_penerima" json:"nama_penerima"`
+	NoRekening   string    `gorm:"column:no_rekening" json:"no_rekening"`
+	Alias        string    `gorm:"column:alias" json:"alias"`
+	IsFavorite   bool      `gorm:"column:is_favorite" json:"is_favorite"`
+	CreatedAt    time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
 }
 
 func (Recipient) TableName() string {
@@ -20,6 +25,8 @@ type RecipientWithBank struct {
 
 	NamaPenerima string `json:"nama_penerima"`
 	NoRekening   string `json:"no_rekening"`
+	Alias        string `json:"alias"`
+	IsFavorite   bool   `json:"is_favorite"`
 	BankImageURL string `json:"bank_image_url"`
 	NamaBank     string `json:"nama_bank"`
 }
@@ -31,3 +38,19 @@ func EncapsulateRequestRecipientToEntity(u *User) *Recipient {
 
 	}
 }
+
+// RecipientSuggestion adalah tujuan transaksi yang sering / baru dipakai user,
+// diambil dari riwayat transaction_bank_transfer, transaction_ewallet dan transaction_phone_credit
+type RecipientSuggestion struct {
+	Type              string    `json:"type"` // bank_transfer, ewallet, phone_credit
+	RecipientID       *uint     `json:"recipient_id"`
+	RecipientName     string    `json:"recipient_name"`
+	AccountNumber     string    `json:"account_number"`
+	Provider          string    `json:"provider"` // nama bank / ewallet / produk pulsa
+	ImageURL          string    `json:"image_url"`
+	LastAmount        float64   `json:"last_amount"`
+	LastTransactionID string    `json:"last_transaction_id"`
+	LastTransactionAt time.Time `json:"last_transaction_at"`
+	TransactionCount  int64     `json:"transaction_count"`
+	Score             float64   `json:"score"`
+}
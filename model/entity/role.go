@@ -9,9 +9,19 @@ type Role struct {
 	gorm.Model
 	Name        enum.RolesEnum
 	Description string
-	Users       []User `gorm:"many2many:user_roles;" json:"users"`
+	Users       []User       `gorm:"many2many:user_roles;" json:"users"`
+	Permissions []Permission `gorm:"many2many:role_permissions;" json:"permissions"`
+}
+
+type Permission struct {
+	gorm.Model
+	Name        enum.PermissionEnum
+	Description string
 }
 
 func (r Role) TableName() string {
 	return "roles"
 }
+func (p Permission) TableName() string {
+	return "permissions"
+}
@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+type TokenFamily struct {
+	gorm.Model
+	FamilyID      string     `gorm:"column:family_id;type:varchar(36);uniqueIndex" json:"family_id"`
+	UserID        int64      `gorm:"column:user_id;type:bigint" json:"user_id"`
+	UserUUID      string     `gorm:"column:user_uuid;type:varchar(36)" json:"user_uuid"`
+	DeviceID      string     `gorm:"column:device_id;type:varchar(255)" json:"device_id"`
+	AppVersion    string     `gorm:"column:app_version;type:varchar(50)" json:"app_version"`
+	IPAddress     string     `gorm:"column:ip_address;type:varchar(45)" json:"ip_address"`
+	Latitude      *float64   `gorm:"column:latitude" json:"latitude"`
+	Longitude     *float64   `gorm:"column:longitude" json:"longitude"`
+	LastSeenAt    time.Time  `gorm:"column:last_seen_at;type:timestamptz" json:"last_seen_at"`
+	RevokedAt     *time.Time `gorm:"column:revoked_at;type:timestamptz" json:"revoked_at"`
+	RevokedReason string     `gorm:"column:revoked_reason;type:varchar(100)" json:"revoked_reason"`
+}
+
+func (t TokenFamily) TableName() string { return "token_families" }
+
+type TokenFamilyToken struct {
+	gorm.Model
+	FamilyID         string     `gorm:"column:family_id;type:varchar(36)" json:"family_id"`
+	TokenID          string     `gorm:"column:token_id;type:varchar(36);uniqueIndex" json:"token_id"`
+	AccessTokenHash  string     `gorm:"column:access_token_hash;type:char(64)" json:"-"`
+	RefreshTokenHash string     `gorm:"column:refresh_token_hash;type:char(64);uniqueIndex" json:"-"`
+	AccessExpiredAt  time.Time  `gorm:"column:access_expired_at;type:timestamptz" json:"access_expired_at"`
+	RefreshExpiredAt time.Time  `gorm:"column:refresh_expired_at;type:timestamptz" json:"refresh_expired_at"`
+	UsedAt           *time.Time `gorm:"column:used_at;type:timestamptz" json:"used_at"`
+}
+
+func (t TokenFamilyToken) TableName() string { return "token_family_tokens" }
@@ -18,9 +18,11 @@ type User struct {
 	Pin         string          `gorm:"column:pin;type:varchar;size:60" json:"pin"` //hash
 	DeviceID    string          `gorm:"column:device_id;type:varchar" json:"device_id"`
 	Status      enum.UserStatus `gorm:"column:status;type:varchar" json:"status"`
-	CreatedAt   time.Time       `gorm:"type:timestamptz" json:"created_at"`
-	UpdatedAt   time.Time       `gorm:"type:timestamptz" json:"updated_at"`
-	DeletedAt   gorm.DeletedAt  `gorm:"type:timestamptz" json:"deleted_at"`
+	// TokenVersion is embedded in every token, a pin, email or phone change bumps it
+	TokenVersion int            `gorm:"column:token_version;not null;default:0" json:"token_version"`
+	CreatedAt    time.Time      `gorm:"type:timestamptz" json:"created_at"`
+	UpdatedAt    time.Time      `gorm:"type:timestamptz" json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"type:timestamptz" json:"deleted_at"`
 }
 type UserDetail struct {
 	gorm.Model     `json:"gorm_._model"`
@@ -1,5 +1,7 @@
 package enum
 
+import "slices"
+
 type VerihubsEnum string
 
 const (
@@ -72,6 +74,37 @@ var OtpWhatsappMapStatusVerihubs = map[int]OTPStatus{
 //7	Request Error	Verihubs cannot reach WhatsApp
 //10	Tier Limit Exceeded	Exceeding channel tier limit
 
+// OtpDeliveryFailedStatus move an otp to the next channel
+var OtpDeliveryFailedStatus = []OTPStatus{OTP_FAILED, OTP_REQUEST_ERROR, OTP_REJECTED, OTP_UNDELIVERED, OTP_BLOCKED, OTP_TIER_LIMIT_EXCEEDED}
+
+// OtpDeliveryConfirmedStatus prove the otp reached the user, no fallback follows
+var OtpDeliveryConfirmedStatus = []OTPStatus{OTP_DELIVERED, OTP_SENT, OTP_READ, OTP_VERIFIED, OTP_NO_DELIVERY_REPORT}
+
+// OtpSMSChargedStatusVerihubs and OtpWhatsappChargedStatusVerihubs are the statuses
+// verihubs bills an otp for, see the status tables above
+var (
+	OtpSMSChargedStatusVerihubs      = []OTPStatus{OTP_DELIVERED, OTP_VERIFIED, OTP_NOT_VERIFIED, OTP_UNDELIVERED, OTP_NO_DELIVERY_REPORT}
+	OtpWhatsappChargedStatusVerihubs = []OTPStatus{OTP_REQUESTED, OTP_SENT, OTP_DELIVERED, OTP_READ, OTP_VERIFIED, OTP_UNVERIFIED, OTP_FAILED}
+)
+
+func OtpDeliveryFailed(status OTPStatus) bool {
+	return slices.Contains(OtpDeliveryFailedStatus, status)
+}
+func OtpDeliveryConfirmed(status OTPStatus) bool {
+	return slices.Contains(OtpDeliveryConfirmedStatus, status)
+}
+
+// OtpMapStatusVerihubs reads a callback status with the table of the channel the otp went out on.
+func OtpMapStatusVerihubs(method OtpType, status int) OTPStatus {
+	switch method {
+	case TYPE_SMS:
+		return OtpSMSMapStatusVerihubs[status]
+	case TYPE_WHATSAPP:
+		return OtpWhatsappMapStatusVerihubs[status]
+	}
+	return ""
+}
+
 type OtpService string
 
 const (
@@ -80,6 +113,8 @@ const (
 	OTP_FORGOT_PIN         OtpService = "OTP_FORGOT_PIN"
 	OTP_RESET_EMAIL        OtpService = "OTP_RESET_EMAIL"
 	OTP_RESET_PHONE_NUMBER OtpService = "OTP_RESET_PHONE_NUMBER"
+	OTP_TRANSFER_STEP_UP   OtpService = "OTP_TRANSFER_STEP_UP"
+	OTP_NEW_DEVICE         OtpService = "OTP_NEW_DEVICE"
 )
 
 type RedisOtpTag string
@@ -6,3 +6,11 @@ const (
 	BIOMETRIC_LOGIN              BiometricType = "LOGIN"
 	BIOMETRIC_TRANSACTION_VERIFY BiometricType = "SIGNUP"
 )
+
+// BiometricKeyAlgorithm is how a device key signs the login challenge
+type BiometricKeyAlgorithm string
+
+const (
+	BIOMETRIC_KEY_ECDSA_P256 BiometricKeyAlgorithm = "ECDSA_P256_SHA256" // asn.1 der signature
+	BIOMETRIC_KEY_RSA        BiometricKeyAlgorithm = "RSA_PKCS1_SHA256"
+)
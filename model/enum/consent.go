@@ -0,0 +1,17 @@
+package enum
+
+type LegalDocumentType string
+
+const (
+	LEGAL_DOCUMENT_TERMS     LegalDocumentType = "TERMS_AND_CONDITIONS"
+	LEGAL_DOCUMENT_PRIVACY   LegalDocumentType = "PRIVACY_POLICY"
+	LEGAL_DOCUMENT_MARKETING LegalDocumentType = "MARKETING"
+)
+
+func (t LegalDocumentType) Valid() bool {
+	switch t {
+	case LEGAL_DOCUMENT_TERMS, LEGAL_DOCUMENT_PRIVACY, LEGAL_DOCUMENT_MARKETING:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package enum
+
+type PaymentRequestStatus string
+
+const (
+	PAYMENT_REQUEST_OPEN      PaymentRequestStatus = "OPEN"
+	PAYMENT_REQUEST_COMPLETED PaymentRequestStatus = "COMPLETED"
+	PAYMENT_REQUEST_CLOSED    PaymentRequestStatus = "CLOSED"
+	PAYMENT_REQUEST_EXPIRED   PaymentRequestStatus = "EXPIRED"
+)
+
+type PaymentParticipantStatus string
+
+const (
+	PARTICIPANT_PENDING PaymentParticipantStatus = "PENDING"
+	// PARTICIPANT_AWAITING_PAYMENT holds the transaction the participant pays with until it succeeds
+	PARTICIPANT_AWAITING_PAYMENT PaymentParticipantStatus = "AWAITING_PAYMENT"
+	PARTICIPANT_PAID             PaymentParticipantStatus = "PAID"
+	PARTICIPANT_DECLINED         PaymentParticipantStatus = "DECLINED"
+	PARTICIPANT_EXPIRED          PaymentParticipantStatus = "EXPIRED"
+)
+
+type SplitType string
+
+const (
+	SPLIT_EVEN   SplitType = "EVEN"
+	SPLIT_CUSTOM SplitType = "CUSTOM"
+)
+
+const TRANSACTION_TYPE_PAYMENT_REQUEST = "payment_request"
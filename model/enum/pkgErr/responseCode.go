@@ -18,6 +18,7 @@ const (
 	AUTH_USER_NOT_FOUND_CODE                  Code = "117"
 	AUTH_INVALID_OTP_CODE                     Code = "118"
 	//UNDEFINED_ERROR_CODE                      Code = "119"
+	AUTH_TOKEN_REUSED_CODE       Code = "120"
 	AUTH_UNAUTHORIZED_CODE       Code = "121"
 	AUTH_UNVERIFIED_CODE         Code = "122"
 	AUTH_BIOMETRC_INACTIVE_CODE  Code = "123"
@@ -51,6 +52,54 @@ const (
 	ARTICLE_RECORD_NOT_FOUND_CODE Code = "170"
 	ARTICLE_DEFERENCE_DEVICE_CODE Code = "171"
 	ARTICLE_USER_NOT_FOUNDCODE    Code = "172"
+
+	PAYMENT_REQUEST_INVALID_PAYLOAD_CODE   Code = "180"
+	PAYMENT_REQUEST_USER_NOT_FOUND_CODE    Code = "181"
+	PAYMENT_REQUEST_NOT_FOUND_CODE         Code = "182"
+	PAYMENT_REQUEST_INVALID_AMOUNT_CODE    Code = "183"
+	PAYMENT_REQUEST_EXPIRED_CODE           Code = "184"
+	PAYMENT_REQUEST_ALREADY_RESPONDED_CODE Code = "185"
+
+	RECIPIENT_NOT_FOUND_CODE       Code = "190"
+	RECIPIENT_ALREADY_EXISTS_CODE  Code = "191"
+	ACCOUNT_INQUIRY_NOT_FOUND_CODE Code = "192"
+	ACCOUNT_NOT_CONFIRMED_CODE     Code = "193"
+	ACCOUNT_INQUIRY_FAILED_CODE    Code = "194"
+
+	TRANSFER_COOLING_OFF_LIMIT_CODE Code = "200"
+	TRANSFER_STEP_UP_REQUIRED_CODE  Code = "201"
+	TRANSFER_STEP_UP_INVALID_CODE   Code = "202"
+
+	SESSION_NOT_FOUND_CODE    Code = "203"
+	PIN_ATTEMPT_TOO_SOON_CODE Code = "204"
+	PIN_TEMPORARY_LOCKED_CODE Code = "205"
+	PIN_PERMANENT_LOCKED_CODE Code = "206"
+
+	DEVICE_VERIFICATION_REQUIRED_CODE Code = "207"
+	DEVICE_VERIFICATION_INVALID_CODE  Code = "208"
+	DEVICE_NOT_FOUND_CODE             Code = "209"
+	DEVICE_CURRENT_REVOKE_CODE        Code = "210"
+
+	AUTH_FORBIDDEN_CODE      Code = "211"
+	AUTH_TIMESTAMP_SKEW_CODE Code = "212"
+	AUTH_NONCE_REPLAY_CODE   Code = "213"
+
+	BIOMETRIC_CHALLENGE_INVALID_CODE Code = "214"
+	BIOMETRIC_SIGNATURE_INVALID_CODE Code = "215"
+	BIOMETRIC_KEY_NOT_FOUND_CODE     Code = "216"
+	BIOMETRIC_KEY_INVALID_CODE       Code = "217"
+
+	OTP_RESEND_COOLDOWN_CODE Code = "218"
+	OTP_SEND_LIMIT_CODE      Code = "219"
+	OTP_VERIFY_LIMIT_CODE    Code = "220"
+
+	OTP_CALLBACK_UNAUTHORIZED_CODE Code = "221"
+	RESET_LINK_NOT_ALLOWED_CODE    Code = "222"
+
+	CONSENT_REQUIRED_CODE         Code = "223"
+	CONSENT_DOCUMENT_INVALID_CODE Code = "224"
+
+	AUTH_PENDING_DELETION_CODE Code = "225"
 )
 const (
 	SUCCES_MSG                           = "success"
@@ -78,4 +127,36 @@ const (
 	INVALID_PIN                          = "invalid pin"
 	IS_EXISTING_PIN                      = "is existing pin, no update"
 	INTERNAL_SERVER_MSG                  = "somtehing wen't wrong!"
+	PAYMENT_REQUEST_NOT_FOUND_MSG        = "payment request not found"
+	INVALID_AMOUNT_MSG                   = "invalid amount"
+	ALREADY_RESPONDED_MSG                = "payment request already responded"
+	RECIPIENT_NOT_FOUND_MSG              = "recipient not found"
+	RECIPIENT_ALREADY_EXISTS_MSG         = "recipient already exists"
+	ACCOUNT_NOT_FOUND_MSG                = "account not found"
+	ACCOUNT_NOT_CONFIRMED_MSG            = "account name not confirmed, please check account first"
+	COOLING_OFF_LIMIT_MSG                = "transfer limit for new recipient exceeded"
+	STEP_UP_REQUIRED_MSG                 = "otp verification required for first transfer"
+	TOKEN_REUSED_MSG                     = "session revoked, please login again"
+	SESSION_NOT_FOUND_MSG                = "session not found"
+	PIN_ATTEMPT_TOO_SOON_MSG             = "too many wrong pin, please wait before trying again"
+	PIN_TEMPORARY_LOCKED_MSG             = "pin temporarily locked, too many wrong attempts"
+	PIN_PERMANENT_LOCKED_MSG             = "pin locked, please reset pin through forgot pin"
+	DEVICE_VERIFICATION_REQUIRED_MSG     = "new device, please verify with the otp sent to you"
+	DEVICE_VERIFICATION_INVALID_MSG      = "device verification invalid or expired"
+	DEVICE_NOT_FOUND_MSG                 = "device not found"
+	DEVICE_CURRENT_REVOKE_MSG            = "current device can not be removed"
+	FORBIDDEN_MSG                        = "access to this resource is not allowed"
+	NONCE_REPLAY_MSG                     = "request already received"
+	TIMESTAMP_SKEW_MSG                   = "request timestamp is outside the allowed window, please check the device clock"
+	BIOMETRIC_CHALLENGE_INVALID_MSG      = "biometric challenge invalid or expired"
+	BIOMETRIC_SIGNATURE_INVALID_MSG      = "biometric signature invalid"
+	BIOMETRIC_KEY_NOT_FOUND_MSG          = "biometric key not found, please enable biometric again"
+	BIOMETRIC_KEY_INVALID_MSG            = "biometric public key invalid"
+	OTP_RESEND_COOLDOWN_MSG              = "otp already sent, please wait before requesting another one"
+	OTP_SEND_LIMIT_MSG                   = "too many otp requested, please try again later"
+	OTP_VERIFY_LIMIT_MSG                 = "too many wrong otp, please request a new one"
+	RESET_LINK_NOT_ALLOWED_MSG           = "callback url is not allowed"
+	CONSENT_REQUIRED_MSG                 = "please accept the latest terms and conditions and privacy policy"
+	CONSENT_DOCUMENT_INVALID_MSG         = "legal document is not the current version"
+	PENDING_DELETION_MSG                 = "account deletion requested, log in with your pin to cancel it"
 )
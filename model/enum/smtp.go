@@ -4,3 +4,5 @@ type EmailSubject string
 
 var VERIFY_OTP_SUBJECT EmailSubject = "Verify Your Account – OTP Code"
 var ACCESS_RESET_PIN_SUBJECT EmailSubject = "Forgot PIN – Request for Assistance"
+var NEW_DEVICE_SUBJECT EmailSubject = "New Device Linked to Your Account"
+var NEW_LOGIN_SUBJECT EmailSubject = "New Login to Your Account"
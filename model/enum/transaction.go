@@ -0,0 +1,10 @@
+package enum
+
+// statuses of transactions.status
+const (
+	TRANSACTION_STATUS_PENDING  = "pending"
+	TRANSACTION_STATUS_SUCCESS  = "success"
+	TRANSACTION_STATUS_FAILED   = "failed"
+	TRANSACTION_STATUS_CANCELED = "canceled"
+	TRANSACTION_STATUS_EXPIRED  = "expired"
+)
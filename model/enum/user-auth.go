@@ -14,6 +14,17 @@ const (
 	VERIFICATION_STATUS_UNVERIFIED UserStatus = "UNVERIFIED"
 	VERIFICATION_STATUS_VERIFIED   UserStatus = "VERIFIED"
 	USER_INACTIVE                  UserStatus = "IN_ACTIVE"
+	// USER_PENDING_DELETION can only log in with the pin, doing so cancels the deletion
+	USER_PENDING_DELETION UserStatus = "PENDING_DELETION"
+	USER_PURGED           UserStatus = "PURGED"
+)
+
+type AccountDeletionStatus string
+
+const (
+	ACCOUNT_DELETION_REQUESTED AccountDeletionStatus = "REQUESTED"
+	ACCOUNT_DELETION_CANCELLED AccountDeletionStatus = "CANCELLED"
+	ACCOUNT_DELETION_PURGED    AccountDeletionStatus = "PURGED"
 )
 
 type UserDetailStatus string
@@ -26,7 +37,22 @@ const (
 type RolesEnum string
 
 const (
-	ROLE_USER RolesEnum = "USER"
+	ROLE_USER    RolesEnum = "USER"
+	ROLE_ADMIN   RolesEnum = "ADMIN"
+	ROLE_SERVICE RolesEnum = "SERVICE"
+)
+
+// PermissionEnum is granted to roles in role_permissions and required per route in RouthInit
+type PermissionEnum string
+
+const (
+	PERMISSION_ARTICLE_READ  PermissionEnum = "article:read"
+	PERMISSION_ARTICLE_WRITE PermissionEnum = "article:write"
+	PERMISSION_KYC_VERIFY    PermissionEnum = "kyc:verify"
+	PERMISSION_OTP_CALLBACK  PermissionEnum = "otp:callback"
+	PERMISSION_OTP_METRICS   PermissionEnum = "otp:metrics"
+	// PERMISSION_TRANSACTION_SETTLE lets the payment callback set the final status of a transaction
+	PERMISSION_TRANSACTION_SETTLE PermissionEnum = "transaction:settle"
 )
 
 type authContext string
@@ -0,0 +1,12 @@
+package snapDto
+
+type AccessTokenB2BRequest struct {
+	GrantType string `json:"grantType"`
+}
+
+type AccountInquiryExternalRequest struct {
+	BeneficiaryBankCode  string            `json:"beneficiaryBankCode"`
+	BeneficiaryAccountNo string            `json:"beneficiaryAccountNo"`
+	PartnerReferenceNo   string            `json:"partnerReferenceNo,omitempty"`
+	AdditionalInfo       map[string]string `json:"additionalInfo,omitempty"`
+}
@@ -0,0 +1,22 @@
+package snapDto
+
+type AccessTokenB2BResponse struct {
+	ResponseCode    string `json:"responseCode"`
+	ResponseMessage string `json:"responseMessage"`
+	AccessToken     string `json:"accessToken"`
+	TokenType       string `json:"tokenType"`
+	ExpiresIn       string `json:"expiresIn"`
+}
+
+type AccountInquiryExternalResponse struct {
+	ResponseCode           string            `json:"responseCode"`
+	ResponseMessage        string            `json:"responseMessage"`
+	ReferenceNo            string            `json:"referenceNo"`
+	PartnerReferenceNo     string            `json:"partnerReferenceNo"`
+	BeneficiaryAccountName string            `json:"beneficiaryAccountName"`
+	BeneficiaryAccountNo   string            `json:"beneficiaryAccountNo"`
+	BeneficiaryBankCode    string            `json:"beneficiaryBankCode"`
+	BeneficiaryBankName    string            `json:"beneficiaryBankName"`
+	Currency               string            `json:"currency"`
+	AdditionalInfo         map[string]string `json:"additionalInfo"`
+}
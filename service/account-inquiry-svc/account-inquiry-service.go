@@ -0,0 +1,113 @@
+package accountinquirysvc
+
+import (
+	"backend-mobile-api/app/config"
+	"backend-mobile-api/helpers"
+	bankInquiry "backend-mobile-api/internal/outbond/bank-inquiry"
+	redisRepos "backend-mobile-api/internal/repository/redis"
+	"backend-mobile-api/model/dto"
+	"backend-mobile-api/model/entity"
+	"context"
+	"errors"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrAccountNotFound     = bankInquiry.ErrAccountNotFound
+	ErrAccountNotConfirmed = errors.New("account name not confirmed by inquiry")
+)
+
+type AccountInquiryService interface {
+	CheckAccount(ctx context.Context, userUUID string, req *entity.CheckAccountRequest) (*entity.CheckAccountResponseData, error)
+	ConfirmedAccountName(ctx context.Context, userUUID string, bankCode string, accountNo string) (string, error)
+}
+
+type accountInquiryService struct {
+	adapter bankInquiry.AccountInquiry
+	redis   *redisRepos.Redis
+	config  *config.BankInquiry
+	clog    *helpers.CustomLogger
+}
+
+func NewAccountInquiryService(adapter bankInquiry.AccountInquiry, redis *redisRepos.Redis, config *config.BankInquiry, clog *helpers.CustomLogger) AccountInquiryService {
+	return &accountInquiryService{
+		adapter: adapter,
+		redis:   redis,
+		config:  config,
+		clog:    clog,
+	}
+}
+
+// ✅ cek nama pemilik rekening, hasil di-cache sebentar dan dicatat sebagai sudah dikonfirmasi oleh user
+func (s *accountInquiryService) CheckAccount(ctx context.Context, userUUID string, req *entity.CheckAccountRequest) (*entity.CheckAccountResponseData, error) {
+	partnerReferenceNo := GeneratePartnerReferenceNo()
+
+	cached, err := s.redis.GetAccountInquiry(ctx, req.BeneficiaryBankCode, req.BeneficiaryAccountNo)
+	if err != nil {
+		// cache hanya optimasi, tetap lanjut ke bank
+		s.clog.ErrorLogger(ctx, "CheckAccount.redis.GetAccountInquiry", err)
+	}
+	if cached == nil {
+		result, err := s.adapter.AccountInquiry(ctx, &bankInquiry.AccountInquiryRequest{
+			BankCode:           req.BeneficiaryBankCode,
+			AccountNo:          req.BeneficiaryAccountNo,
+			PartnerReferenceNo: partnerReferenceNo,
+		})
+		if err != nil {
+			return nil, err
+		}
+		cached = &dto.AccountInquiryCache{
+			AccountNo:   req.BeneficiaryAccountNo,
+			AccountName: result.AccountName,
+			BankCode:    req.BeneficiaryBankCode,
+			BankName:    result.BankName,
+			ReferenceNo: result.ReferenceNo,
+		}
+		if err = s.redis.SetAccountInquiry(ctx, req.BeneficiaryBankCode, req.BeneficiaryAccountNo, cached, s.config.CacheExpire); err != nil {
+			s.clog.ErrorLogger(ctx, "CheckAccount.redis.SetAccountInquiry", err)
+		}
+	}
+
+	if err = s.redis.SetConfirmedAccount(ctx, userUUID, req.BeneficiaryBankCode, req.BeneficiaryAccountNo, cached, s.config.ConfirmedExpire); err != nil {
+		s.clog.ErrorLogger(ctx, "CheckAccount.redis.SetConfirmedAccount", err)
+		return nil, err
+	}
+
+	return &entity.CheckAccountResponseData{
+		BeneficiaryAccountNo:   cached.AccountNo,
+		BeneficiaryAccountName: cached.AccountName,
+		BeneficiaryBankCode:    cached.BankCode,
+		BeneficiaryBankName:    cached.BankName,
+		PartnerReferenceNo:     partnerReferenceNo,
+		ReferenceNo:            cached.ReferenceNo,
+	}, nil
+}
+
+// ✅ nama rekening yang sudah dilihat user lewat CheckAccount, dipakai sebelum simpan recipient
+func (s *accountInquiryService) ConfirmedAccountName(ctx context.Context, userUUID string, bankCode string, accountNo string) (string, error) {
+	confirmed, err := s.redis.GetConfirmedAccount(ctx, userUUID, bankCode, accountNo)
+	if err != nil {
+		s.clog.ErrorLogger(ctx, "ConfirmedAccountName.redis.GetConfirmedAccount", err)
+		return "", err
+	}
+	if confirmed == nil || confirmed.AccountName == "" {
+		return "", ErrAccountNotConfirmed
+	}
+	return confirmed.AccountName, nil
+}
+
+// GeneratePartnerReferenceNo bikin angka random sepanjang 12 digit
+func GeneratePartnerReferenceNo() string {
+	num := rand.New(rand.NewSource(time.Now().UnixNano())).Int63n(999999999999) // max 12 digit
+	return leftPad(strconv.FormatInt(num, 10), "0", 12)
+}
+
+// helper: padding ke kiri biar tetap 12 digit
+func leftPad(s, pad string, length int) string {
+	for len(s) < length {
+		s = pad + s
+	}
+	return s
+}
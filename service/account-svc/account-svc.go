@@ -0,0 +1,321 @@
+package accountSvc
+
+import (
+	"backend-mobile-api/app/config"
+	"backend-mobile-api/helpers"
+	"backend-mobile-api/internal/repository/minio"
+	"backend-mobile-api/internal/repository/postgres"
+	"backend-mobile-api/model/dto/response"
+	"backend-mobile-api/model/entity"
+	"backend-mobile-api/model/enum"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AccountService runs the end of an account. A deletion request waits out a
+// grace period during which logging in again with the pin cancels it, the purge job then
+// removes the personal data of every request that ran out. The data of an
+// account can be exported at any time before that.
+type AccountService interface {
+	RequestDeletion(ctx context.Context, user *entity.User) (*entity.AccountDeletion, error)
+	CancelDeletion(ctx context.Context, user *entity.User) error
+	PurgeDue(ctx context.Context, now time.Time) (int, error)
+	Export(ctx context.Context, user *entity.User) (*response.AccountExportResponse, error)
+}
+
+type accountService struct {
+	accountRepository postgres.AccountRepository
+	userRepository    postgres.UserRepository
+	minioRepository   minio.MinioRepository
+	unitOfWork        postgres.UnitOfWork
+	config            *config.AccountDeletion
+	clogger           *helpers.CustomLogger
+}
+
+func NewAccountService(
+	accountRepository postgres.AccountRepository,
+	userRepository postgres.UserRepository,
+	minioRepository minio.MinioRepository,
+	unitOfWork postgres.UnitOfWork,
+	config *config.AccountDeletion,
+	clogger *helpers.CustomLogger,
+) AccountService {
+	return &accountService{
+		accountRepository: accountRepository,
+		userRepository:    userRepository,
+		minioRepository:   minioRepository,
+		unitOfWork:        unitOfWork,
+		config:            config,
+		clogger:           clogger,
+	}
+}
+
+// RequestDeletion opens a deletion request, asking again returns the open one
+// so the grace period is not pushed back. Opening one bumps the token version,
+// the caller retires the sessions once the transaction is committed.
+func (svc *accountService) RequestDeletion(ctx context.Context, user *entity.User) (*entity.AccountDeletion, error) {
+	var deletion *entity.AccountDeletion
+	err := svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		// a second request waits here for the first one and then finds its row
+		locked, err := svc.userRepository.LockUser(ctx, tx, user.ID)
+		if err != nil {
+			return err
+		}
+		deletion, err = svc.accountRepository.SelectRequestedAccountDeletion(ctx, user.ID)
+		if err != nil || deletion != nil {
+			return err
+		}
+		deletion = &entity.AccountDeletion{
+			UserID:         user.ID,
+			UserUUID:       user.UUID,
+			PreviousStatus: locked.Status,
+			Status:         enum.ACCOUNT_DELETION_REQUESTED,
+			PurgeAt:        time.Now().Add(svc.config.GracePeriod),
+		}
+		if err = svc.accountRepository.InsertAccountDeletion(ctx, tx, deletion); err != nil {
+			return err
+		}
+		if err = svc.userRepository.UpdateUser(ctx, tx, user, &entity.User{Status: enum.USER_PENDING_DELETION}); err != nil {
+			return err
+		}
+		return svc.userRepository.BumpTokenVersion(ctx, tx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deletion, nil
+}
+
+// CancelDeletion gives the user back the status they had before the request,
+// it does nothing for a user without one.
+func (svc *accountService) CancelDeletion(ctx context.Context, user *entity.User) error {
+	if user.Status != enum.USER_PENDING_DELETION {
+		return nil
+	}
+	return svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		deletion, err := svc.accountRepository.SelectRequestedAccountDeletion(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		status := enum.VERIFICATION_STATUS_VERIFIED
+		if deletion != nil {
+			now := time.Now()
+			if err = svc.accountRepository.UpdateAccountDeletion(ctx, tx, deletion, &entity.AccountDeletion{
+				Status:      enum.ACCOUNT_DELETION_CANCELLED,
+				CancelledAt: &now,
+			}); err != nil {
+				return err
+			}
+			status = deletion.PreviousStatus
+		}
+		if err = svc.userRepository.UpdateUser(ctx, tx, user, &entity.User{Status: status}); err != nil {
+			return err
+		}
+		user.Status = status
+		return nil
+	})
+}
+
+// PurgeDue purges the accounts whose grace period ended before now. An account
+// that fails is left for the next run, the others go on.
+func (svc *accountService) PurgeDue(ctx context.Context, now time.Time) (int, error) {
+	deletions, err := svc.accountRepository.SelectDueAccountDeletions(ctx, now, svc.config.PurgeBatch)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		purged int
+		errs   []error
+	)
+	for i := range deletions {
+		if err = svc.purge(ctx, &deletions[i]); err != nil {
+			errs = append(errs, fmt.Errorf("user %s: %w", deletions[i].UserUUID, err))
+			continue
+		}
+		purged++
+	}
+	return purged, errors.Join(errs...)
+}
+
+// purge removes the stored documents first, the database still knows where
+// they are when that fails and the next run can retry.
+func (svc *accountService) purge(ctx context.Context, deletion *entity.AccountDeletion) error {
+	objects, err := svc.accountRepository.SelectAccountObjects(ctx, deletion.UserID)
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if err = svc.minioRepository.RemoveObject(ctx, object); err != nil {
+			return err
+		}
+	}
+	return svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		if err := svc.accountRepository.PurgeAccount(ctx, tx, deletion.UserID); err != nil {
+			return err
+		}
+		now := time.Now()
+		return svc.accountRepository.UpdateAccountDeletion(ctx, tx, deletion, &entity.AccountDeletion{
+			Status:   enum.ACCOUNT_DELETION_PURGED,
+			PurgedAt: &now,
+		})
+	})
+}
+
+func (svc *accountService) Export(ctx context.Context, user *entity.User) (*response.AccountExportResponse, error) {
+	data, err := svc.accountRepository.SelectAccountExport(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	export := &response.AccountExportResponse{
+		ExportedAt: time.Now(),
+		Profile: response.AccountExportProfile{
+			UUID:        data.User.UUID,
+			FullName:    data.User.FullName,
+			Email:       data.User.Email,
+			PhoneNumber: data.User.PhoneNumber,
+			Status:      data.User.Status,
+			CreatedAt:   data.User.CreatedAt,
+		},
+		Devices:          make([]response.AccountExportDevice, 0, len(data.Devices)),
+		Sessions:         make([]response.SessionResponse, 0, len(data.Sessions)),
+		IdentityKtp:      make([]response.AccountExportKtp, 0, len(data.Ktps)),
+		IdentityPassport: make([]response.AccountExportPassport, 0, len(data.Passports)),
+		Recipients:       make([]response.AccountExportRecipient, 0, len(data.Recipients)),
+		PaymentAccounts:  make([]response.AccountExportPaymentAccount, 0, len(data.PaymentAccounts)),
+		Transactions:     make([]response.AccountExportTransaction, 0, len(data.Transactions)),
+		Consents:         make([]response.UserConsentResponse, 0, len(data.Consents)),
+		LoginHistory:     make([]response.LoginEventResponse, 0, len(data.LoginEvents)),
+	}
+	if detail := data.Detail; detail != nil {
+		export.Profile.Country = detail.Country
+		export.Profile.Province = detail.Province
+		export.Profile.Regency = detail.Regency
+		export.Profile.District = detail.District
+		export.Profile.Address = detail.Address
+		export.Profile.KycStatus = detail.KycStatus
+		export.Profile.KycType = detail.KycType
+		export.Profile.Biometric = detail.Biometric
+		export.Profile.DetailState = detail.Status
+	}
+	for _, device := range data.Devices {
+		export.Devices = append(export.Devices, response.AccountExportDevice{
+			DeviceID:       device.DeviceID,
+			Manufacturer:   device.Manufacturer,
+			Brand:          device.Brand,
+			DeviceModel:    device.DeviceModel,
+			AppVersionName: device.AppVersionName,
+			CreatedAt:      device.CreatedAt,
+			TrustedAt:      device.TrustedAt,
+			RevokedAt:      device.RevokedAt,
+		})
+	}
+	for _, session := range data.Sessions {
+		export.Sessions = append(export.Sessions, response.SessionResponse{
+			ID:         session.FamilyID,
+			DeviceID:   session.DeviceID,
+			AppVersion: session.AppVersion,
+			IPAddress:  session.IPAddress,
+			Latitude:   session.Latitude,
+			Longitude:  session.Longitude,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+		})
+	}
+	for _, ktp := range data.Ktps {
+		export.IdentityKtp = append(export.IdentityKtp, response.AccountExportKtp{
+			Nik:           ktp.Nik,
+			FullName:      ktp.FullName,
+			PlaceOfBirth:  ktp.PlaceOfBirth,
+			DateOfBirth:   ktp.DateOfBirth,
+			Gender:        ktp.Gender,
+			Occupation:    ktp.Occupation,
+			Nationality:   ktp.Nationality,
+			MartialStatus: ktp.MartialStatus,
+			Religion:      ktp.Religion,
+			FullAddress:   ktp.FullAddress,
+			SubmitAt:      ktp.SubmitAt,
+		})
+	}
+	for _, passport := range data.Passports {
+		export.IdentityPassport = append(export.IdentityPassport, response.AccountExportPassport{
+			PassportNumber: passport.PassportNumber,
+			PassportType:   passport.PassportType,
+			FullName:       passport.FullName,
+			Gender:         passport.Gender,
+			Nationality:    passport.Nationality,
+			PlaceOfBirth:   passport.PlaceOfBirth,
+			DateOfBirth:    passport.DateOfBirth,
+			DateOfIssue:    passport.DateOfIssue,
+			DateOfExpired:  passport.DateOfExpired,
+			PlaceOfIssue:   passport.PlaceOfIssue,
+			SubmitAt:       passport.SubmitAt,
+		})
+	}
+	for _, recipient := range data.Recipients {
+		export.Recipients = append(export.Recipients, response.AccountExportRecipient{
+			BankID:        recipient.Bank,
+			RecipientName: recipient.NamaPenerima,
+			AccountNumber: recipient.NoRekening,
+			Alias:         recipient.Alias,
+			IsFavorite:    recipient.IsFavorite,
+			CreatedAt:     recipient.CreatedAt,
+		})
+	}
+	for _, account := range data.PaymentAccounts {
+		export.PaymentAccounts = append(export.PaymentAccounts, response.AccountExportPaymentAccount{
+			BankName:       account.BankName,
+			AccountNumber:  account.NoRek,
+			VirtualAccount: account.NoVa,
+		})
+	}
+	for _, transaction := range data.Transactions {
+		export.Transactions = append(export.Transactions, response.AccountExportTransaction{
+			TransactionID: transaction.TransactionID,
+			Type:          transaction.Type,
+			PaymentMethod: transaction.PaymentMethod,
+			Description:   transaction.Description,
+			Nominal:       transaction.Nominal,
+			AdminFee:      transaction.AdminFee,
+			Total:         transaction.Total,
+			Status:        transaction.Status,
+			CreatedAt:     transaction.CreatedAt,
+		})
+	}
+	for _, consent := range data.Consents {
+		export.Consents = append(export.Consents, response.UserConsentResponse{
+			Document: response.LegalDocumentResponse{
+				ID:          consent.Document.ID,
+				Type:        consent.Document.Type,
+				Version:     consent.Document.Version,
+				Title:       consent.Document.Title,
+				URL:         consent.Document.URL,
+				Mandatory:   consent.Document.Mandatory,
+				PublishedAt: consent.Document.PublishedAt,
+			},
+			AcceptedAt: consent.AcceptedAt,
+			IPAddress:  consent.IPAddress,
+			AppVersion: consent.AppVersion,
+		})
+	}
+	for _, event := range data.LoginEvents {
+		export.LoginHistory = append(export.LoginHistory, response.LoginEventResponse{
+			Method:        event.Method,
+			Status:        event.Status,
+			FailureReason: event.FailureReason,
+			DeviceID:      event.DeviceID,
+			IPAddress:     event.IPAddress,
+			AppVersion:    event.AppVersion,
+			Place:         event.Place,
+			Latitude:      event.Latitude,
+			Longitude:     event.Longitude,
+			NewDevice:     event.NewDevice,
+			FarLocation:   event.FarLocation,
+			CreatedAt:     event.CreatedAt,
+		})
+	}
+	return export, nil
+}
@@ -8,6 +8,7 @@ import (
 	"backend-mobile-api/model/dto/response"
 	"backend-mobile-api/model/entity"
 	"backend-mobile-api/model/enum/pkgErr"
+	deviceSvc "backend-mobile-api/service/device-svc"
 	"context"
 	"errors"
 	"golang.org/x/sync/errgroup"
@@ -18,13 +19,17 @@ type articleService struct {
 	userRepository    postgres.UserRepository
 	articleRepository postgres.ArticleRepository
 	clogger           *helpers.CustomLogger
+	deviceService     deviceSvc.DeviceService
+	unitOfWork        postgres.UnitOfWork
 }
 
-func NewArticleService(userRepository postgres.UserRepository, articleRepository postgres.ArticleRepository, clogger *helpers.CustomLogger) ArticleService {
+func NewArticleService(userRepository postgres.UserRepository, articleRepository postgres.ArticleRepository, clogger *helpers.CustomLogger, deviceService deviceSvc.DeviceService, unitOfWork postgres.UnitOfWork) ArticleService {
 	return &articleService{
 		userRepository:    userRepository,
 		articleRepository: articleRepository,
 		clogger:           clogger,
+		deviceService:     deviceService,
+		unitOfWork:        unitOfWork,
 	}
 }
 
@@ -38,15 +43,15 @@ type ArticleService interface {
 
 func (svc *articleService) InsertArticleService(ctx context.Context, req *request.NewArticleRequest, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
 
-	txArticle := svc.articleRepository.Tx(ctx)
-	err := svc.articleRepository.InsertArticle(ctx, txArticle, &entity.Article{
-		Name:           req.NewArticle.Name,
-		Url:            req.NewArticle.Url,
-		Category:       req.NewArticle.Category,
-		ActiveAfterDay: req.NewArticle.ActiveAfterDay,
+	err := svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		return svc.articleRepository.InsertArticle(ctx, tx, &entity.Article{
+			Name:           req.NewArticle.Name,
+			Url:            req.NewArticle.Url,
+			Category:       req.NewArticle.Category,
+			ActiveAfterDay: req.NewArticle.ActiveAfterDay,
+		})
 	})
 	if err != nil {
-		txArticle.Rollback()
 		logData.Error = err.Error()
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
@@ -55,7 +60,6 @@ func (svc *articleService) InsertArticleService(ctx context.Context, req *reques
 	}
 
 	logData.Success = true
-	txArticle.Commit()
 	return &dto.BaseResponse{
 		StatusCode: pkgErr.SUCCESS_CODE,
 		Message:    pkgErr.SUCCES_MSG,
@@ -100,10 +104,10 @@ func (svc *articleService) UpdateArticleService(ctx context.Context, req *reques
 	if req.Article.ActiveAfterDay != nil {
 		newArticle.ActiveAfterDay = req.Article.ActiveAfterDay
 	}
-	txArticle := svc.articleRepository.Tx(ctx)
-	err = svc.articleRepository.UpdateArticle(ctx, txArticle, article, &newArticle)
+	err = svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		return svc.articleRepository.UpdateArticle(ctx, tx, article, &newArticle)
+	})
 	if err != nil {
-		txArticle.Rollback()
 		logData.Error = err.Error()
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
@@ -112,7 +116,6 @@ func (svc *articleService) UpdateArticleService(ctx context.Context, req *reques
 			Data:       nil,
 		}
 	}
-	txArticle.Commit()
 	logData.Success = true
 	return &dto.BaseResponse{
 		StatusCode: pkgErr.SUCCESS_CODE,
@@ -146,11 +149,11 @@ func (svc *articleService) DeleteArticleService(ctx context.Context, req *reques
 			Data:       nil,
 		}
 	}
-	txArticle := svc.articleRepository.Tx(ctx)
-	err = svc.articleRepository.DeleteArticle(ctx, txArticle, article)
+	err = svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		return svc.articleRepository.DeleteArticle(ctx, tx, article)
+	})
 	if err != nil {
 		logData.Error = err.Error()
-		txArticle.Rollback()
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
 			Message:    pkgErr.SERVER_BUSY,
@@ -160,7 +163,6 @@ func (svc *articleService) DeleteArticleService(ctx context.Context, req *reques
 	}
 
 	logData.Success = true
-	txArticle.Commit()
 	return &dto.BaseResponse{
 		StatusCode: pkgErr.SUCCESS_CODE,
 		Message:    pkgErr.SUCCES_MSG,
@@ -229,7 +231,7 @@ func (svc articleService) SelectArticleListService(ctx context.Context, req *req
 	}
 	logData.UserUUID = user.UUID
 	logData.Email = user.Email
-	if user.DeviceID != req.DeviceID {
+	if !svc.deviceService.IsTrusted(ctx, user, req.DeviceID) {
 		logData.Error = "device id not match"
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.ARTICLE_DEFERENCE_DEVICE_CODE,
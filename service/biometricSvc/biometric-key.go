@@ -0,0 +1,91 @@
+package biometricSvc
+
+import (
+	"backend-mobile-api/model/enum"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const minBiometricRsaBits = 2048
+
+var (
+	ErrBiometricKeyInvalid       = errors.New("biometric public key invalid")
+	ErrBiometricSignatureInvalid = errors.New("biometric signature invalid")
+	ErrBiometricKeyNotFound      = errors.New("biometric key not found")
+)
+
+// parseBiometricPublicKey reads the public key exported from the device keystore,
+// android exports base64 der (spki) and ios apps usually wrap the same der in pem.
+func parseBiometricPublicKey(algorithm enum.BiometricKeyAlgorithm, encoded string) (crypto.PublicKey, error) {
+	encoded = strings.TrimSpace(encoded)
+	var der []byte
+	if block, _ := pem.Decode([]byte(encoded)); block != nil {
+		der = block.Bytes
+	} else {
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBiometricKeyInvalid, err)
+		}
+		der = decoded
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBiometricKeyInvalid, err)
+	}
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if algorithm != enum.BIOMETRIC_KEY_ECDSA_P256 || key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("%w: ecdsa key does not match %s", ErrBiometricKeyInvalid, algorithm)
+		}
+	case *rsa.PublicKey:
+		if algorithm != enum.BIOMETRIC_KEY_RSA || key.N.BitLen() < minBiometricRsaBits {
+			return nil, fmt.Errorf("%w: rsa key does not match %s or is shorter than %d bits", ErrBiometricKeyInvalid, algorithm, minBiometricRsaBits)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %T", ErrBiometricKeyInvalid, publicKey)
+	}
+	return publicKey, nil
+}
+
+// encodeBiometricPublicKey stores every key as base64 der whatever format the app sent.
+func encodeBiometricPublicKey(publicKey crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(der), nil
+}
+
+// verifyBiometricSignature checks the base64 signature of the challenge nonce, ecdsa
+// signatures are asn.1 der as produced by the android and ios keystores.
+func verifyBiometricSignature(algorithm enum.BiometricKeyAlgorithm, encodedKey string, nonce string, signature string) error {
+	publicKey, err := parseBiometricPublicKey(algorithm, encodedKey)
+	if err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBiometricSignatureInvalid, err)
+	}
+	digest := sha256.Sum256([]byte(nonce))
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest[:], sig) {
+			return ErrBiometricSignatureInvalid
+		}
+	case *rsa.PublicKey:
+		if err = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+			return fmt.Errorf("%w: %v", ErrBiometricSignatureInvalid, err)
+		}
+	}
+	return nil
+}
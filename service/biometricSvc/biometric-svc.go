@@ -1,68 +1,187 @@
 package biometricSvc
 
 import (
+	"backend-mobile-api/app/config"
+	"backend-mobile-api/helpers"
 	"backend-mobile-api/internal/middleware"
 	"backend-mobile-api/internal/repository/postgres"
+	redisRepos "backend-mobile-api/internal/repository/redis"
 	"backend-mobile-api/model/dto"
 	"backend-mobile-api/model/dto/request"
 	"backend-mobile-api/model/dto/response"
 	"backend-mobile-api/model/entity"
 	"backend-mobile-api/model/enum"
 	"backend-mobile-api/model/enum/pkgErr"
+	consentSvc "backend-mobile-api/service/consent-svc"
+	deviceSvc "backend-mobile-api/service/device-svc"
+	loginEventSvc "backend-mobile-api/service/login-event-svc"
+	tokenFamilySvc "backend-mobile-api/service/token-family-svc"
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"github.com/google/uuid"
 	"golang.org/x/sync/errgroup"
 	"gorm.io/gorm"
+	"time"
 )
 
+const biometricNonceBytes = 32
+
+type biometricChallenge struct {
+	UUID     string `json:"uuid"`
+	DeviceID string `json:"device_id"`
+	Nonce    string `json:"nonce"`
+}
+
+// biometricService logs a user in with a key pair generated in the device
+// keystore. The public key is registered when biometric is enabled, login asks
+// for a single use nonce and the app returns it signed by the private key,
+// which the keystore only releases after a local biometric unlock.
 type biometricService struct {
-	userRepository       postgres.UserRepository
-	userDetailRepository postgres.UserDetailRepository
-	middleware           middleware.CustomMiddleware
-	tokenBlacklist       postgres.TokenBlacklistTokenRepository
+	userRepository         postgres.UserRepository
+	userDetailRepository   postgres.UserDetailRepository
+	biometricKeyRepository postgres.BiometricKeyRepository
+	tokenFamilyService     tokenFamilySvc.TokenFamilyService
+	deviceService          deviceSvc.DeviceService
+	consentService         consentSvc.ConsentService
+	loginEventService      loginEventSvc.LoginEventService
+	redis                  *redisRepos.Redis
+	rootConfig             *config.Root
+	clogger                *helpers.CustomLogger
+	unitOfWork             postgres.UnitOfWork
 }
 
 func NewBiometricService(
 	userRepository postgres.UserRepository,
 	userDetailRepository postgres.UserDetailRepository,
-	middleware middleware.CustomMiddleware,
-	tokenBlacklist postgres.TokenBlacklistTokenRepository,
+	biometricKeyRepository postgres.BiometricKeyRepository,
+	tokenFamilyService tokenFamilySvc.TokenFamilyService,
+	deviceService deviceSvc.DeviceService,
+	consentService consentSvc.ConsentService,
+	loginEventService loginEventSvc.LoginEventService,
+	redis *redisRepos.Redis,
+	rootConfig *config.Root,
+	clogger *helpers.CustomLogger,
+	unitOfWork postgres.UnitOfWork,
 ) BiometricService {
 	return &biometricService{
-		userRepository:       userRepository,
-		userDetailRepository: userDetailRepository,
-		middleware:           middleware,
-		tokenBlacklist:       tokenBlacklist,
+		userRepository:         userRepository,
+		userDetailRepository:   userDetailRepository,
+		biometricKeyRepository: biometricKeyRepository,
+		tokenFamilyService:     tokenFamilyService,
+		deviceService:          deviceService,
+		consentService:         consentService,
+		loginEventService:      loginEventService,
+		redis:                  redis,
+		rootConfig:             rootConfig,
+		clogger:                clogger,
+		unitOfWork:             unitOfWork,
 	}
 }
 
 type BiometricService interface {
+	ChallengeService(ctx context.Context, request *request.BiometricChallengeRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse
 	VerifyBiometric(ctx context.Context, request *request.BiometricRequest, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse
+	RegisterDeviceKey(ctx context.Context, tx *gorm.DB, user *entity.User, deviceID string, algorithm enum.BiometricKeyAlgorithm, publicKey string) error
+	RevokeDeviceKeys(ctx context.Context, tx *gorm.DB, user *entity.User, deviceID string) (bool, error)
+	InquiryKeyService(ctx context.Context, userUUID string, currentDeviceID string, logData *dto.CustomLoggerRequest) *dto.BaseResponse
+	RevokeKeyService(ctx context.Context, userUUID string, keyID string, logData *dto.CustomLoggerRequest) *dto.BaseResponse
 	login(ctx context.Context, request *request.BiometricRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse
 }
 
+// ChallengeService hands out a nonce without looking the user up, an unknown
+// user or device only fails at login so the endpoint tells nothing about accounts.
+func (svc *biometricService) ChallengeService(ctx context.Context, request *request.BiometricChallengeRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	logData.UserUUID = request.UUID
+	nonce := make([]byte, biometricNonceBytes)
+	if _, err := rand.Read(nonce); err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	challenge := biometricChallenge{
+		UUID:     request.UUID,
+		DeviceID: request.DeviceID,
+		Nonce:    base64.RawURLEncoding.EncodeToString(nonce),
+	}
+	challengeID := uuid.New().String()
+	jsonChallenge, _ := json.Marshal(challenge)
+	if err := svc.redis.SetBiometricChallenge(ctx, challengeID, string(jsonChallenge), svc.rootConfig.App.BiometricChallenge); err != nil {
+		svc.clogger.ErrorLogger(ctx, "ChallengeService.redis.SetBiometricChallenge", err)
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	logData.Success = true
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+		Data: response.BiometricChallengeResponse{
+			ChallengeID: challengeID,
+			Nonce:       challenge.Nonce,
+			ExpireAt:    time.Now().Add(svc.rootConfig.App.BiometricChallenge),
+		},
+	}
+}
+
+func (svc *biometricService) takeChallenge(ctx context.Context, request *request.BiometricRequest) (*biometricChallenge, error) {
+	value, err := svc.redis.TakeBiometricChallenge(ctx, request.ChallengeID)
+	if err != nil {
+		return nil, err
+	}
+	if value == "" {
+		return nil, nil
+	}
+	var challenge biometricChallenge
+	if err = json.Unmarshal([]byte(value), &challenge); err != nil {
+		return nil, err
+	}
+	// a nonce is only good for the user and device that asked for it
+	if challenge.UUID != request.UUID || challenge.DeviceID != request.DeviceID {
+		return nil, nil
+	}
+	return &challenge, nil
+}
+
 func (svc *biometricService) login(ctx context.Context, request *request.BiometricRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
 	var (
-		user           *entity.User
-		userDt         *entity.UserDetail
-		token          *middleware.TokenData
-		err            error
-		ok             bool
-		blacklistError = errors.New("token is blacklisted")
+		user      *entity.User
+		userDt    *entity.UserDetail
+		key       *entity.BiometricKey
+		challenge *biometricChallenge
+		token     *middleware.TokenData
+		err       error
 	)
+	logData.UserUUID = request.UUID
 
-	g := errgroup.Group{}
-	g.Go(func() error {
-		var errtmp error
-		isExist, errtmp := svc.tokenBlacklist.IsBlaclistTokenActive(ctx, request.Token)
-		if err != nil {
-			return errtmp
+	// taken before anything else can fail, a signature never gets a second try on the same nonce
+	challenge, err = svc.takeChallenge(ctx, request)
+	if err != nil {
+		svc.clogger.ErrorLogger(ctx, "login.takeChallenge", err)
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
 		}
-		if isExist {
-			return blacklistError
+	}
+	if challenge == nil {
+		logData.Error = "biometric challenge invalid or expired"
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.BIOMETRIC_CHALLENGE_INVALID_CODE,
+			Message:    pkgErr.BIOMETRIC_CHALLENGE_INVALID_MSG,
 		}
-		return nil
-	})
+	}
+
+	g := errgroup.Group{}
 	g.Go(func() error {
 		var errtmp error
 		user, errtmp = svc.userRepository.SelectUserByUUID(ctx, request.UUID)
@@ -85,17 +204,9 @@ func (svc *biometricService) login(ctx context.Context, request *request.Biometr
 		}
 		return nil
 	})
-
 	err = g.Wait()
 	if err != nil {
 		logData.Error = err.Error()
-		if errors.Is(err, blacklistError) {
-			return &dto.BaseResponse{
-				StatusCode: pkgErr.AUTH_UNAUTHORIZED_CODE,
-				Message:    pkgErr.UNAUTHORIZED_MSG,
-				Error:      err.Error(),
-			}
-		}
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
 			Message:    pkgErr.SERVER_BUSY,
@@ -110,31 +221,92 @@ func (svc *biometricService) login(ctx context.Context, request *request.Biometr
 			Message:    pkgErr.USER_NOT_FOUND_MSG,
 		}
 	}
-	logData.UserUUID = user.UUID
 	logData.Email = user.Email
-	ok, token, err = svc.middleware.RefreshToken(ctx, request.Token, user)
+	attempt := loginEventSvc.Attempt{
+		Method:   enum.LOGIN_BIOMETRIC,
+		DeviceID: request.DeviceID,
+	}
+	// a pending deletion is only cancelled by a login with the pin
+	if user.Status == enum.USER_PENDING_DELETION {
+		logData.Error = "account pending deletion"
+		attempt.Failure = logData.Error
+		svc.loginEventService.Record(ctx, user, attempt)
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_PENDING_DELETION_CODE,
+			Message:    pkgErr.PENDING_DELETION_MSG,
+		}
+	}
+	if userDt.Biometric != enum.BIOMETRIC_ACTIVE {
+		logData.Error = "biometric inactive"
+		attempt.Failure = logData.Error
+		svc.loginEventService.Record(ctx, user, attempt)
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_BIOMETRC_INACTIVE_CODE,
+			Message:    pkgErr.BIOMETRIC_INACTIVE_MSG,
+		}
+	}
+	if !svc.deviceService.IsTrusted(ctx, user, request.DeviceID) {
+		logData.Error = "untrusted device"
+		attempt.Failure = logData.Error
+		svc.loginEventService.Record(ctx, user, attempt)
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_DEFERENCE_DEVICE_CODE,
+			Message:    pkgErr.DEFERENCE_DEVICE_MSG,
+		}
+	}
+	key, err = svc.biometricKeyRepository.SelectActiveBiometricKey(ctx, user.ID, request.DeviceID)
 	if err != nil {
 		logData.Error = err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			attempt.Failure = "biometric key not found"
+			svc.loginEventService.Record(ctx, user, attempt)
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.BIOMETRIC_KEY_NOT_FOUND_CODE,
+				Message:    pkgErr.BIOMETRIC_KEY_NOT_FOUND_MSG,
+			}
+		}
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
 			Message:    pkgErr.SERVER_BUSY,
 			Error:      err.Error(),
 		}
 	}
-	if !ok {
-		logData.Error = "unauthorized"
+	if err = verifyBiometricSignature(key.Algorithm, key.PublicKey, challenge.Nonce, request.Signature); err != nil {
+		logData.Error = err.Error()
+		logData.Data = map[string]interface{}{
+			"security_incident": "biometric signature invalid",
+			"device_id":         request.DeviceID,
+			"key_id":            key.KeyID,
+		}
+		attempt.Failure = "biometric signature invalid"
+		svc.loginEventService.Record(ctx, user, attempt)
 		return &dto.BaseResponse{
-			StatusCode: pkgErr.AUTH_UNAUTHORIZED_CODE,
-			Message:    pkgErr.UNAUTHORIZED_MSG,
+			StatusCode: pkgErr.BIOMETRIC_SIGNATURE_INVALID_CODE,
+			Message:    pkgErr.BIOMETRIC_SIGNATURE_INVALID_MSG,
 		}
 	}
-	if userDt.Biometric != enum.BIOMETRIC_ACTIVE {
-		logData.Error = "biometric inactive"
+	// usage is informative only, a failed update does not block the login
+	_ = svc.biometricKeyRepository.UpdateBiometricKeyLastUsed(ctx, key.ID)
+
+	mustReaccept, err := svc.consentService.MustReaccept(ctx, user)
+	if err != nil {
+		logData.Error = err.Error()
 		return &dto.BaseResponse{
-			StatusCode: pkgErr.AUTH_BIOMETRC_INACTIVE_CODE,
-			Message:    pkgErr.BIOMETRIC_INACTIVE_MSG,
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	token, err = svc.tokenFamilyService.IssueTokens(ctx, user, request.DeviceID)
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
 		}
 	}
+	svc.loginEventService.Record(ctx, user, attempt)
 	logData.Success = true
 	return &dto.BaseResponse{
 		StatusCode: pkgErr.SUCCESS_CODE,
@@ -146,7 +318,8 @@ func (svc *biometricService) login(ctx context.Context, request *request.Biometr
 				Email: user.Email,
 				Phone: user.PhoneNumber,
 			},
-			Token: *token,
+			Token:               *token,
+			MustReacceptConsent: mustReaccept,
 		},
 	}
 }
@@ -163,3 +336,189 @@ func (svc *biometricService) VerifyBiometric(ctx context.Context, request *reque
 		Data:       nil,
 	}
 }
+
+// RegisterDeviceKey replaces the key of the device inside the caller's transaction,
+// the key is returned as ErrBiometricKeyInvalid when it can not sign with the algorithm.
+func (svc *biometricService) RegisterDeviceKey(ctx context.Context, tx *gorm.DB, user *entity.User, deviceID string, algorithm enum.BiometricKeyAlgorithm, publicKey string) error {
+	parsedKey, err := parseBiometricPublicKey(algorithm, publicKey)
+	if err != nil {
+		return err
+	}
+	encodedKey, err := encodeBiometricPublicKey(parsedKey)
+	if err != nil {
+		return err
+	}
+	if _, err = svc.biometricKeyRepository.RevokeBiometricKeysByDevice(ctx, tx, user.ID, deviceID); err != nil {
+		return err
+	}
+	return svc.biometricKeyRepository.InsertBiometricKey(ctx, tx, &entity.BiometricKey{
+		KeyID:     uuid.New().String(),
+		UserID:    user.ID,
+		UserUUID:  user.UUID,
+		DeviceID:  deviceID,
+		Algorithm: algorithm,
+		PublicKey: encodedKey,
+	})
+}
+
+// RevokeDeviceKeys revokes the key of the device inside the caller's transaction and
+// reports whether another device still has a key, biometric stays active for it.
+func (svc *biometricService) RevokeDeviceKeys(ctx context.Context, tx *gorm.DB, user *entity.User, deviceID string) (bool, error) {
+	keys, err := svc.biometricKeyRepository.SelectActiveBiometricKeys(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+	if _, err = svc.biometricKeyRepository.RevokeBiometricKeysByDevice(ctx, tx, user.ID, deviceID); err != nil {
+		return false, err
+	}
+	for _, key := range keys {
+		if key.DeviceID != deviceID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (svc *biometricService) InquiryKeyService(ctx context.Context, userUUID string, currentDeviceID string, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	logData.UserUUID = userUUID
+	user, err := svc.userRepository.SelectUserByUUID(ctx, userUUID)
+	if err != nil {
+		logData.Error = err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.PROFILE_USER_NOT_FOUND_CODE,
+				Message:    pkgErr.USER_NOT_FOUND_MSG,
+			}
+		}
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	keys, err := svc.biometricKeyRepository.SelectActiveBiometricKeys(ctx, user.ID)
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	result := make([]response.BiometricKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, response.BiometricKeyResponse{
+			KeyID:      key.KeyID,
+			DeviceID:   key.DeviceID,
+			Algorithm:  key.Algorithm,
+			CreatedAt:  key.CreatedAt,
+			LastUsedAt: key.LastUsedAt,
+			Current:    key.DeviceID == currentDeviceID,
+		})
+	}
+	logData.Success = true
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+		Data:       result,
+	}
+}
+
+// RevokeKeyService removes biometric login from one device, biometric is switched
+// off for the user once no device has a key left.
+func (svc *biometricService) RevokeKeyService(ctx context.Context, userUUID string, keyID string, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	logData.UserUUID = userUUID
+	var (
+		user   *entity.User
+		userDt *entity.UserDetail
+	)
+	g := errgroup.Group{}
+	g.Go(func() error {
+		var errTmp error
+		user, errTmp = svc.userRepository.SelectUserByUUID(ctx, userUUID)
+		if errTmp != nil {
+			if errors.Is(errTmp, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return errTmp
+		}
+		return nil
+	})
+	g.Go(func() error {
+		var errTmp error
+		userDt, errTmp = svc.userDetailRepository.SelectUserDetailByUserUUID(ctx, &userUUID)
+		if errTmp != nil {
+			if errors.Is(errTmp, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return errTmp
+		}
+		return nil
+	})
+	if err := g.Wait(); err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	if user == nil || userDt == nil {
+		logData.Error = "user not found"
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.PROFILE_USER_NOT_FOUND_CODE,
+			Message:    pkgErr.USER_NOT_FOUND_MSG,
+		}
+	}
+	keys, err := svc.biometricKeyRepository.SelectActiveBiometricKeys(ctx, user.ID)
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+
+	remaining := false
+	for _, key := range keys {
+		if key.KeyID != keyID {
+			remaining = true
+			break
+		}
+	}
+	err = svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		// another user's key is reported as not found
+		affected, err := svc.biometricKeyRepository.RevokeBiometricKeyByKeyID(ctx, tx, user.ID, keyID)
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrBiometricKeyNotFound
+		}
+		if !remaining && userDt.Biometric == enum.BIOMETRIC_ACTIVE {
+			return svc.userDetailRepository.UpdateUserDetail(ctx, tx, userDt, &entity.UserDetail{Biometric: enum.BIOMETRIC_IN_ACTIVE})
+		}
+		return nil
+	})
+	if errors.Is(err, ErrBiometricKeyNotFound) {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.BIOMETRIC_KEY_NOT_FOUND_CODE,
+			Message:    pkgErr.BIOMETRIC_KEY_NOT_FOUND_MSG,
+		}
+	}
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	logData.Success = true
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+	}
+}
@@ -0,0 +1,277 @@
+package consentSvc
+
+import (
+	"backend-mobile-api/helpers"
+	"backend-mobile-api/internal/repository/postgres"
+	"backend-mobile-api/model/dto"
+	"backend-mobile-api/model/dto/request"
+	"backend-mobile-api/model/dto/response"
+	"backend-mobile-api/model/entity"
+	"backend-mobile-api/model/enum"
+	"backend-mobile-api/model/enum/pkgErr"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrConsentRequired        = errors.New("mandatory legal document not accepted")
+	ErrConsentDocumentInvalid = errors.New("legal document is not the current version")
+)
+
+type consentService struct {
+	consentRepository postgres.ConsentRepository
+	userRepository    postgres.UserRepository
+	unitOfWork        postgres.UnitOfWork
+	clogger           *helpers.CustomLogger
+}
+
+func NewConsentService(
+	consentRepository postgres.ConsentRepository,
+	userRepository postgres.UserRepository,
+	unitOfWork postgres.UnitOfWork,
+	clogger *helpers.CustomLogger,
+) ConsentService {
+	return &consentService{
+		consentRepository: consentRepository,
+		userRepository:    userRepository,
+		unitOfWork:        unitOfWork,
+		clogger:           clogger,
+	}
+}
+
+// ConsentService records which version of each legal document a user accepted.
+// Only the current versions can be accepted, a user who has not accepted the
+// current version of a mandatory document has to accept it again.
+type ConsentService interface {
+	InquiryDocumentService(ctx context.Context, logData *dto.CustomLoggerRequest) *dto.BaseResponse
+	InquiryConsentService(ctx context.Context, userUUID string, logData *dto.CustomLoggerRequest) *dto.BaseResponse
+	AcceptConsentService(ctx context.Context, req *request.AcceptConsentRequest, userUUID string, logData *dto.CustomLoggerRequest) *dto.BaseResponse
+	Record(ctx context.Context, user *entity.User, documentIDs []uint, requireMandatory bool) error
+	MustReaccept(ctx context.Context, user *entity.User) (bool, error)
+}
+
+func (svc *consentService) InquiryDocumentService(ctx context.Context, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	documents, err := svc.consentRepository.SelectCurrentLegalDocuments(ctx, time.Now())
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	data := make([]response.LegalDocumentResponse, 0, len(documents))
+	for _, document := range documents {
+		data = append(data, documentResponse(document))
+	}
+	logData.Success = true
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+		Data:       data,
+	}
+}
+
+func (svc *consentService) InquiryConsentService(ctx context.Context, userUUID string, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	logData.UserUUID = userUUID
+	user, resp := svc.selectUser(ctx, userUUID, logData)
+	if resp != nil {
+		return resp
+	}
+	consents, err := svc.consentRepository.SelectUserConsents(ctx, user.ID)
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	documents, err := svc.consentRepository.SelectCurrentLegalDocuments(ctx, time.Now())
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	data := response.ConsentResponse{
+		Accepted: make([]response.UserConsentResponse, 0, len(consents)),
+		Pending:  make([]response.LegalDocumentResponse, 0),
+	}
+	for _, consent := range consents {
+		data.Accepted = append(data.Accepted, response.UserConsentResponse{
+			Document:   documentResponse(consent.Document),
+			AcceptedAt: consent.AcceptedAt,
+			IPAddress:  consent.IPAddress,
+			AppVersion: consent.AppVersion,
+		})
+	}
+	for _, document := range pendingDocuments(documents, consents) {
+		data.Pending = append(data.Pending, documentResponse(document))
+		data.MustReaccept = data.MustReaccept || document.Mandatory
+	}
+	logData.Success = true
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+		Data:       data,
+	}
+}
+
+func (svc *consentService) AcceptConsentService(ctx context.Context, req *request.AcceptConsentRequest, userUUID string, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	logData.UserUUID = userUUID
+	user, resp := svc.selectUser(ctx, userUUID, logData)
+	if resp != nil {
+		return resp
+	}
+	if err := svc.Record(ctx, user, req.DocumentIDs, false); err != nil {
+		logData.Error = err.Error()
+		if resp = ErrorResponse(err); resp != nil {
+			return resp
+		}
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	return svc.InquiryConsentService(ctx, userUUID, logData)
+}
+
+// Record stores the acceptance of the given documents with the client details
+// of the current request. Every id has to be a current version, with
+// requireMandatory the current mandatory documents all have to be among them.
+func (svc *consentService) Record(ctx context.Context, user *entity.User, documentIDs []uint, requireMandatory bool) error {
+	documents, err := svc.consentRepository.SelectCurrentLegalDocuments(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	current := make(map[uint]entity.LegalDocument, len(documents))
+	for _, document := range documents {
+		current[document.ID] = document
+	}
+	accepted := make(map[uint]bool, len(documentIDs))
+	for _, id := range documentIDs {
+		if _, ok := current[id]; !ok {
+			return ErrConsentDocumentInvalid
+		}
+		accepted[id] = true
+	}
+	if requireMandatory {
+		for _, document := range documents {
+			if document.Mandatory && !accepted[document.ID] {
+				return ErrConsentRequired
+			}
+		}
+	}
+
+	// an app without the consent screen sends nothing, there is nothing to store then
+	if len(accepted) == 0 {
+		return nil
+	}
+	now := time.Now()
+	consents := make([]entity.UserConsent, 0, len(accepted))
+	for id := range accepted {
+		consents = append(consents, entity.UserConsent{
+			UserID:     user.ID,
+			DocumentID: id,
+			AcceptedAt: now,
+		})
+	}
+	if customResource, ok := ctx.Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue); ok {
+		for i := range consents {
+			consents[i].IPAddress = customResource.HeaderXRealIp
+			consents[i].AppVersion = customResource.HeaderXAppVersion
+		}
+	}
+	return svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		return svc.consentRepository.InsertUserConsents(ctx, tx, consents)
+	})
+}
+
+// MustReaccept reports whether a mandatory document was published after the
+// user last accepted its type.
+func (svc *consentService) MustReaccept(ctx context.Context, user *entity.User) (bool, error) {
+	documents, err := svc.consentRepository.SelectCurrentLegalDocuments(ctx, time.Now())
+	if err != nil {
+		return false, err
+	}
+	consents, err := svc.consentRepository.SelectUserConsents(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, document := range pendingDocuments(documents, consents) {
+		if document.Mandatory {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (svc *consentService) selectUser(ctx context.Context, userUUID string, logData *dto.CustomLoggerRequest) (*entity.User, *dto.BaseResponse) {
+	user, err := svc.userRepository.SelectUserByUUID(ctx, userUUID)
+	if err != nil {
+		logData.Error = err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &dto.BaseResponse{
+				StatusCode: pkgErr.PROFILE_USER_NOT_FOUND_CODE,
+				Message:    pkgErr.USER_NOT_FOUND_MSG,
+			}
+		}
+		return nil, &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	logData.Email = user.Email
+	return user, nil
+}
+
+// ErrorResponse maps a consent error to its response, nil for any other error.
+func ErrorResponse(err error) *dto.BaseResponse {
+	switch {
+	case errors.Is(err, ErrConsentRequired):
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.CONSENT_REQUIRED_CODE,
+			Message:    pkgErr.CONSENT_REQUIRED_MSG,
+		}
+	case errors.Is(err, ErrConsentDocumentInvalid):
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.CONSENT_DOCUMENT_INVALID_CODE,
+			Message:    pkgErr.CONSENT_DOCUMENT_INVALID_MSG,
+		}
+	}
+	return nil
+}
+
+func pendingDocuments(documents []entity.LegalDocument, consents []entity.UserConsent) []entity.LegalDocument {
+	accepted := make(map[uint]bool, len(consents))
+	for _, consent := range consents {
+		accepted[consent.DocumentID] = true
+	}
+	var pending []entity.LegalDocument
+	for _, document := range documents {
+		if !accepted[document.ID] {
+			pending = append(pending, document)
+		}
+	}
+	return pending
+}
+
+func documentResponse(document entity.LegalDocument) response.LegalDocumentResponse {
+	return response.LegalDocumentResponse{
+		ID:          document.ID,
+		Type:        document.Type,
+		Version:     document.Version,
+		Title:       document.Title,
+		URL:         document.URL,
+		Mandatory:   document.Mandatory,
+		PublishedAt: document.PublishedAt,
+	}
+}
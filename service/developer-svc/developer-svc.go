@@ -0,0 +1,72 @@
+package developerSvc
+
+import (
+	"backend-mobile-api/helpers"
+	redisRepos "backend-mobile-api/internal/repository/redis"
+	"backend-mobile-api/model/dto"
+	"backend-mobile-api/model/dto/request"
+	"backend-mobile-api/model/dto/response"
+	"backend-mobile-api/model/enum/pkgErr"
+	"context"
+	"encoding/json"
+)
+
+type developerService struct {
+	redis   *redisRepos.Redis
+	clogger *helpers.CustomLogger
+}
+
+func NewDeveloperService(redis *redisRepos.Redis, clogger *helpers.CustomLogger) DeveloperService {
+	return &developerService{
+		redis:   redis,
+		clogger: clogger,
+	}
+}
+
+// DeveloperService backs the tooling routes registered outside production only.
+type DeveloperService interface {
+	LastOtpService(ctx context.Context, req *request.LastOtpRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse
+}
+
+// LastOtpService returns the last code the fake otp provider sent to a destination,
+// codes sent through verihubs or by email never show up here.
+func (svc *developerService) LastOtpService(ctx context.Context, req *request.LastOtpRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	value, err := svc.redis.GetFakeOtp(ctx, req.Destination)
+	if err != nil {
+		svc.clogger.ErrorLogger(ctx, "LastOtpService.redis.GetFakeOtp", err)
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	if value == "" {
+		logData.Error = "otp not found"
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.OUTBOUND_RECORD_NOT_FOUND_CODE,
+			Message:    pkgErr.RECORD_NOT_FOUND_MSG,
+		}
+	}
+	var sent dto.FakeOtp
+	if err = json.Unmarshal([]byte(value), &sent); err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	logData.Success = true
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+		Data: response.LastOtpResponse{
+			Destination: req.Destination,
+			Otp:         sent.Otp,
+			OtpMethod:   sent.OtpMethod,
+			SessionId:   sent.SessionId,
+			SentAt:      sent.SentAt,
+		},
+	}
+}
@@ -0,0 +1,407 @@
+package deviceSvc
+
+import (
+	"backend-mobile-api/app/config"
+	"backend-mobile-api/helpers"
+	"backend-mobile-api/internal/outbond/smtp"
+	"backend-mobile-api/internal/repository/postgres"
+	redisRepos "backend-mobile-api/internal/repository/redis"
+	"backend-mobile-api/model/dto"
+	"backend-mobile-api/model/dto/request"
+	"backend-mobile-api/model/dto/response"
+	"backend-mobile-api/model/entity"
+	"backend-mobile-api/model/enum"
+	"backend-mobile-api/model/enum/pkgErr"
+	"backend-mobile-api/service/notification"
+	"backend-mobile-api/service/otp"
+	tokenFamilySvc "backend-mobile-api/service/token-family-svc"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrDeviceBindingInvalid = errors.New("device binding invalid or expired")
+
+// DeviceService keeps the list of devices a user has proven to own. Logging
+// in from a device outside the list needs an otp sent to the registered email
+// or phone number; once verified the device is trusted, becomes the user's
+// main device and the sessions of the previous main device are revoked. The
+// previous device stays trusted so its owner can log back in and remove the
+// new one when the binding was not theirs.
+type DeviceService interface {
+	IsTrusted(ctx context.Context, user *entity.User, deviceID string) bool
+	StartBinding(ctx context.Context, user *entity.User, deviceID string, method enum.OtpType) (*response.DeviceChallengeResponse, error)
+	ConfirmBinding(ctx context.Context, req *request.VerifyDeviceRequest) (*entity.User, error)
+	InquiryDeviceService(ctx context.Context, userUUID string, currentDeviceID string, logData *dto.CustomLoggerRequest) *dto.BaseResponse
+	RevokeDeviceService(ctx context.Context, userUUID string, currentDeviceID string, id string, logData *dto.CustomLoggerRequest) *dto.BaseResponse
+	NotifyCredentialChanged(ctx context.Context, user *entity.User, field enum.ProfileFieldUpdate, currentDeviceID string)
+}
+
+type deviceService struct {
+	deviceRepository       postgres.DeviceRepository
+	userRepository         postgres.UserRepository
+	tokenFamilyRepository  postgres.TokenFamilyRepository
+	biometricKeyRepository postgres.BiometricKeyRepository
+	tokenFamilyService     tokenFamilySvc.TokenFamilyService
+	otpService             otp.OtpService
+	redis                  *redisRepos.Redis
+	smtp                   *smtp.Smtp
+	notifier               *notification.FirebaseNotifier
+	rootConfig             *config.Root
+	clogger                *helpers.CustomLogger
+	unitOfWork             postgres.UnitOfWork
+}
+
+func NewDeviceService(
+	deviceRepository postgres.DeviceRepository,
+	userRepository postgres.UserRepository,
+	tokenFamilyRepository postgres.TokenFamilyRepository,
+	biometricKeyRepository postgres.BiometricKeyRepository,
+	tokenFamilyService tokenFamilySvc.TokenFamilyService,
+	otpService otp.OtpService,
+	redis *redisRepos.Redis,
+	smtp *smtp.Smtp,
+	notifier *notification.FirebaseNotifier,
+	rootConfig *config.Root,
+	clogger *helpers.CustomLogger,
+	unitOfWork postgres.UnitOfWork,
+) DeviceService {
+	return &deviceService{
+		deviceRepository:       deviceRepository,
+		userRepository:         userRepository,
+		tokenFamilyRepository:  tokenFamilyRepository,
+		biometricKeyRepository: biometricKeyRepository,
+		tokenFamilyService:     tokenFamilyService,
+		otpService:             otpService,
+		redis:                  redis,
+		smtp:                   smtp,
+		notifier:               notifier,
+		rootConfig:             rootConfig,
+		clogger:                clogger,
+		unitOfWork:             unitOfWork,
+	}
+}
+
+// IsTrusted fails closed, a lookup error is treated as an unknown device.
+func (svc *deviceService) IsTrusted(ctx context.Context, user *entity.User, deviceID string) bool {
+	if user == nil || deviceID == "" {
+		return false
+	}
+	device, err := svc.deviceRepository.SelectTrustedDevice(ctx, uint(user.ID), deviceID)
+	return err == nil && device != nil
+}
+
+// StartBinding sends the new-device otp and remembers which device asked for it,
+// so the otp can not be used to trust another device.
+func (svc *deviceService) StartBinding(ctx context.Context, user *entity.User, deviceID string, method enum.OtpType) (*response.DeviceChallengeResponse, error) {
+	destination := user.Email
+	if method == enum.TYPE_SMS || method == enum.TYPE_WHATSAPP {
+		destination = user.PhoneNumber
+	}
+	otpData, err := svc.otpService.SendOtp(ctx, &dto.SendOtp{
+		OtpPurpose:     enum.OTP_NEW_DEVICE,
+		OtpMethod:      method,
+		OtpDestination: destination,
+		UserId:         user.ID,
+		UserUUID:       user.UUID,
+		VerifyKey:      uuid.New().String(),
+		// the user owns both, a new-device otp may fall back to any of them
+		FallbackEmail:       user.Email,
+		FallbackPhoneNumber: user.PhoneNumber,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// outlives every fallback channel, each restarts the otp expiry
+	bindingExpire := svc.rootConfig.App.OtpExpire + svc.rootConfig.Otp.DeliveryTimeout*time.Duration(len(svc.rootConfig.Otp.Channels))
+	if err = svc.redis.SetDeviceBinding(ctx, otpData.VerifyKey, deviceID, bindingExpire); err != nil {
+		svc.clogger.ErrorLogger(ctx, "StartBinding.redis.SetDeviceBinding", err)
+		return nil, err
+	}
+	return &response.DeviceChallengeResponse{
+		VerifyId:    otpData.VerifyKey,
+		OtpMethod:   otpData.OtpMethod,
+		Destination: helpers.MaskDestination(otpData.OtpDestination),
+		ExpireAt:    otpData.ExpiredAt,
+	}, nil
+}
+
+// ConfirmBinding verifies the new-device otp, trusts the device and moves the
+// user to it. The previous main device is logged out and notified.
+func (svc *deviceService) ConfirmBinding(ctx context.Context, req *request.VerifyDeviceRequest) (*entity.User, error) {
+	stored, err := svc.redis.GetDeviceBinding(ctx, req.VerifyID)
+	if err != nil {
+		svc.clogger.ErrorLogger(ctx, "ConfirmBinding.redis.GetDeviceBinding", err)
+		return nil, err
+	}
+	if stored == "" || stored != req.DeviceID {
+		return nil, ErrDeviceBindingInvalid
+	}
+	otpData, err := svc.otpService.VerifyOtpCode(ctx, &request.VerifyOtpRequest{
+		Otp:      req.Otp,
+		VerifyID: req.VerifyID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDeviceBindingInvalid, err)
+	}
+	if otpData.OtpPurpose != enum.OTP_NEW_DEVICE {
+		return nil, ErrDeviceBindingInvalid
+	}
+	_ = svc.redis.DeleteDeviceBinding(ctx, req.VerifyID)
+
+	user, err := svc.userRepository.SelectUserByUUID(ctx, otpData.UserUUID)
+	if err != nil {
+		return nil, err
+	}
+	oldDeviceID := user.DeviceID
+
+	err = svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		err := svc.deviceRepository.TrustDevice(ctx, tx, &entity.Device{
+			UserID:         uint(user.ID),
+			UserUUID:       user.UUID,
+			DeviceID:       req.DeviceID,
+			AppVersionCode: req.DeviceInfo.AppVersionCode,
+			AppVersionName: req.DeviceInfo.AppVersionName,
+			Manufacturer:   req.DeviceInfo.Manufacturer,
+			Brand:          req.DeviceInfo.Brand,
+			DeviceModel:    req.DeviceInfo.Model,
+			Product:        req.DeviceInfo.Product,
+			VersionSdk:     req.DeviceInfo.VersionSdk,
+			VersionRelease: req.DeviceInfo.VersionRelease,
+		})
+		if err != nil {
+			return err
+		}
+		if oldDeviceID != req.DeviceID {
+			return svc.userRepository.UpdateUser(ctx, tx, user, &entity.User{DeviceID: req.DeviceID})
+		}
+		return nil
+	})
+	if err != nil {
+		svc.clogger.ErrorLogger(ctx, "ConfirmBinding.unitOfWork.Do", err)
+		return nil, err
+	}
+	user.DeviceID = req.DeviceID
+
+	if oldDeviceID != "" && oldDeviceID != req.DeviceID {
+		if err = svc.revokeDeviceSessions(ctx, user.UUID, oldDeviceID, tokenFamilySvc.REVOKE_REASON_REPLACED); err != nil {
+			return nil, err
+		}
+		svc.notifyDeviceReplaced(ctx, user, oldDeviceID, req.DeviceInfo)
+	}
+	return user, nil
+}
+
+func (svc *deviceService) InquiryDeviceService(ctx context.Context, userUUID string, currentDeviceID string, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	logData.UserUUID = userUUID
+	user, err := svc.userRepository.SelectUserByUUID(ctx, userUUID)
+	if err != nil {
+		logData.Error = err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.PROFILE_USER_NOT_FOUND_CODE,
+				Message:    pkgErr.USER_NOT_FOUND_MSG,
+			}
+		}
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	devices, err := svc.deviceRepository.SelectTrustedDevices(ctx, uint(user.ID))
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	// a device registered more than once has one row per registration, list it once
+	seen := make(map[string]bool, len(devices))
+	trusted := make([]response.TrustedDeviceResponse, 0, len(devices))
+	for _, device := range devices {
+		if seen[device.DeviceID] {
+			continue
+		}
+		seen[device.DeviceID] = true
+		trusted = append(trusted, response.TrustedDeviceResponse{
+			ID:             device.ID,
+			DeviceID:       device.DeviceID,
+			Manufacturer:   device.Manufacturer,
+			Brand:          device.Brand,
+			DeviceModel:    device.DeviceModel,
+			AppVersionName: device.AppVersionName,
+			TrustedAt:      *device.TrustedAt,
+			Current:        device.DeviceID == currentDeviceID,
+		})
+	}
+	logData.Success = true
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+		Data:       trusted,
+	}
+}
+
+func (svc *deviceService) RevokeDeviceService(ctx context.Context, userUUID string, currentDeviceID string, id string, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	logData.UserUUID = userUUID
+	deviceRowID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.DEVICE_NOT_FOUND_CODE,
+			Message:    pkgErr.DEVICE_NOT_FOUND_MSG,
+		}
+	}
+	user, err := svc.userRepository.SelectUserByUUID(ctx, userUUID)
+	if err != nil {
+		logData.Error = err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.PROFILE_USER_NOT_FOUND_CODE,
+				Message:    pkgErr.USER_NOT_FOUND_MSG,
+			}
+		}
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	// another user's device is reported as not found, not forbidden
+	device, err := svc.deviceRepository.SelectTrustedDeviceByID(ctx, uint(user.ID), uint(deviceRowID))
+	if err != nil {
+		logData.Error = err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.DEVICE_NOT_FOUND_CODE,
+				Message:    pkgErr.DEVICE_NOT_FOUND_MSG,
+			}
+		}
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	if device.DeviceID == currentDeviceID {
+		logData.Error = "revoking current device"
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.DEVICE_CURRENT_REVOKE_CODE,
+			Message:    pkgErr.DEVICE_CURRENT_REVOKE_MSG,
+		}
+	}
+	err = svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		if err := svc.deviceRepository.RevokeTrustedDevice(ctx, tx, uint(user.ID), device.DeviceID); err != nil {
+			return err
+		}
+		// an untrusted device can not log in with biometric, its key goes with it
+		_, err := svc.biometricKeyRepository.RevokeBiometricKeysByDevice(ctx, tx, user.ID, device.DeviceID)
+		return err
+	})
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	if err = svc.revokeDeviceSessions(ctx, user.UUID, device.DeviceID, tokenFamilySvc.REVOKE_REASON_UNTRUSTED); err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	logData.Success = true
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+	}
+}
+
+func (svc *deviceService) revokeDeviceSessions(ctx context.Context, userUUID string, deviceID string, reason string) error {
+	families, err := svc.tokenFamilyRepository.SelectActiveFamiliesByDevice(ctx, userUUID, deviceID)
+	if err != nil {
+		return err
+	}
+	for _, family := range families {
+		if err = svc.tokenFamilyService.RevokeFamily(ctx, family.FamilyID, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyDeviceReplaced tells the owner on the old device and by email, so a
+// binding they did not make is noticed quickly.
+func (svc *deviceService) notifyDeviceReplaced(ctx context.Context, user *entity.User, oldDeviceID string, newDevice request.DeviceData) {
+	body := fmt.Sprintf(
+		"Hello %s,\n\nYour account was just linked to a new device (%s %s) on %s and this device has been logged out.\n\nIf this was not you, log in again on this device, remove the new device from your trusted devices and change your PIN immediately.\n\nBest regards,\nBeyondTech",
+		user.FullName, newDevice.Brand, newDevice.Model, time.Now().Format("02 Jan 2006 15:04"),
+	)
+	email := user.Email
+	wrapContext := helpers.WrapContext(ctx)
+	go func() {
+		_ = svc.smtp.SendMail(wrapContext, []string{email}, enum.NEW_DEVICE_SUBJECT, body)
+	}()
+
+	if svc.notifier == nil {
+		return
+	}
+	devices, err := svc.deviceRepository.SelectDeviceByStruct(ctx, &entity.Device{UserID: uint(user.ID), DeviceID: oldDeviceID})
+	if err != nil || len(devices) == 0 || devices[0].FCMToken == "" {
+		return
+	}
+	token := devices[0].FCMToken
+	title := "Perangkat Baru Terhubung"
+	message := fmt.Sprintf("Akun kamu baru saja dipindahkan ke %s %s. Jika bukan kamu, segera login kembali dan ganti PIN.", newDevice.Brand, newDevice.Model)
+	go func() {
+		if err := svc.notifier.SendPushNotification(token, title, message, "device_replaced"); err != nil {
+			svc.clogger.ErrorLogger(wrapContext, "notifyDeviceReplaced.notifier.SendPushNotification", err)
+		}
+	}()
+}
+
+// NotifyCredentialChanged tells the other trusted devices that they were logged
+// out because the pin, email or phone number changed.
+func (svc *deviceService) NotifyCredentialChanged(ctx context.Context, user *entity.User, field enum.ProfileFieldUpdate, currentDeviceID string) {
+	if svc.notifier == nil {
+		return
+	}
+	devices, err := svc.deviceRepository.SelectTrustedDevices(ctx, uint(user.ID))
+	if err != nil {
+		return
+	}
+	credential := "PIN"
+	switch field {
+	case enum.PROFILE_UPDATE_EMAIL:
+		credential = "Email"
+	case enum.PROFILE_UPDATE_PHONE_NUMBER:
+		credential = "Nomor HP"
+	}
+	title := "Kamu Telah Logout"
+	message := fmt.Sprintf("%s akun kamu baru saja diubah, silakan login kembali. Jika bukan kamu, segera hubungi customer service.", credential)
+	wrapContext := helpers.WrapContext(ctx)
+	for _, device := range devices {
+		if device.DeviceID == currentDeviceID || device.FCMToken == "" {
+			continue
+		}
+		token := device.FCMToken
+		go func() {
+			if err := svc.notifier.SendPushNotification(token, title, message, "credential_changed"); err != nil {
+				svc.clogger.ErrorLogger(wrapContext, "NotifyCredentialChanged.notifier.SendPushNotification", err)
+			}
+		}()
+	}
+}
@@ -2,7 +2,7 @@ package kycservice
 
 import (
 	"backend-mobile-api/helpers"
-	"backend-mobile-api/internal/outbond/verihubs"
+	"backend-mobile-api/internal/outbond/provider"
 	"backend-mobile-api/internal/repository/minio"
 	"backend-mobile-api/internal/repository/postgres"
 	"backend-mobile-api/model/dto"
@@ -18,6 +18,8 @@ import (
 	"io"
 	"mime/multipart"
 	"net/http"
+
+	"gorm.io/gorm"
 )
 
 type KycService interface {
@@ -30,12 +32,13 @@ type KycService interface {
 
 type kycService struct {
 	clogger               *helpers.CustomLogger
-	outboundVeriHubsSvc   verihubs.OutboundVeriHubsService
+	kycProvider           provider.KycProvider
 	KycKtpRepository      postgres.KycKtpRepository
 	KycPassportRepository postgres.KycPassportRepository
 	UserRepository        postgres.UserRepository
 	UserDetailsRepository postgres.UserDetailRepository
 	MinioRepository       minio.MinioRepository
+	unitOfWork            postgres.UnitOfWork
 }
 
 func (k *kycService) VerifyPhotoSelfie(ctx context.Context, req request.VerifyKycSelfie) (*dto.BaseResponse, *dto.CustomLoggerRequest) {
@@ -56,7 +59,7 @@ func (k *kycService) VerifyPhotoSelfie(ctx context.Context, req request.VerifyKy
 		IsLiveness:  true,
 	}
 
-	verihubResponse, err = k.outboundVeriHubsSvc.SendVerifySelfie(ctx, &kycRequest)
+	verihubResponse, err = k.kycProvider.SendVerifySelfie(ctx, &kycRequest)
 	if err != nil {
 		logData.Error = err.Error()
 		return &dto.BaseResponse{
@@ -77,7 +80,6 @@ func (k *kycService) VerifyPhotoSelfie(ctx context.Context, req request.VerifyKy
 func (k *kycService) SaveKycPassport(ctx context.Context, req request.PassportRequest, userUUID string) (*dto.BaseResponse, *dto.CustomLoggerRequest) {
 	var (
 		err     error
-		tx      = k.KycKtpRepository.Tx(ctx)
 		logData = &dto.CustomLoggerRequest{Remarks: "kyc-save-passport", Success: false}
 	)
 
@@ -88,7 +90,6 @@ func (k *kycService) SaveKycPassport(ctx context.Context, req request.PassportRe
 	if err != nil {
 		k.clogger.ErrorLogger(ctx, "SaveKycKTP.GetUser.FailOnDb", err)
 		logData.Error = err.Error()
-		tx.Rollback()
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.INTERNAL_SERVER_ERROR_CODE,
 			Message:    pkgErr.INTERNAL_SERVER_MSG,
@@ -101,7 +102,6 @@ func (k *kycService) SaveKycPassport(ctx context.Context, req request.PassportRe
 	if err != nil {
 		k.clogger.ErrorLogger(ctx, "SaveKycKTP.SelectUserDetailByUserId.FailOnDb", err)
 		logData.Error = err.Error()
-		tx.Rollback()
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.INTERNAL_SERVER_ERROR_CODE,
 			Message:    pkgErr.INTERNAL_SERVER_MSG,
@@ -119,7 +119,6 @@ func (k *kycService) SaveKycPassport(ctx context.Context, req request.PassportRe
 	fileName := fmt.Sprintf("%s-%s", userData.UUID, "pasport")
 	fileHeader, err := Base64ToMultipartFileHeader(req.Image, fileName, "image/jpeg")
 	if err != nil {
-		tx.Rollback()
 		k.clogger.ErrorLogger(ctx, "KYCIdenityPassport.PutObject", err)
 		return nil, logData
 	}
@@ -130,23 +129,19 @@ func (k *kycService) SaveKycPassport(ctx context.Context, req request.PassportRe
 		return nil, logData
 	}
 
-	err = k.KycPassportRepository.SaveKYCPassport(tx, ctx, entity.EncapsulateRequestPassportToEntity(req, userData, *objectName))
-	if err != nil {
-		k.clogger.ErrorLogger(ctx, "SaveKycKTP.SaveKYCKtp.FailOnDb", err)
-		logData.Error = err.Error()
-		tx.Rollback()
-		return &dto.BaseResponse{
-			StatusCode: pkgErr.INTERNAL_SERVER_ERROR_CODE,
-			Message:    pkgErr.INTERNAL_SERVER_MSG,
-			Error:      err.Error(),
-		}, logData
-	}
-
-	err = k.UserDetailsRepository.UpdateUserDetail(ctx, tx, userDetails, newUserDetails)
+	err = k.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		if err := k.KycPassportRepository.SaveKYCPassport(tx, ctx, entity.EncapsulateRequestPassportToEntity(req, userData, *objectName)); err != nil {
+			k.clogger.ErrorLogger(ctx, "SaveKycKTP.SaveKYCKtp.FailOnDb", err)
+			return err
+		}
+		if err := k.UserDetailsRepository.UpdateUserDetail(ctx, tx, userDetails, newUserDetails); err != nil {
+			k.clogger.ErrorLogger(ctx, "SaveKycKTP.UpdateUserDetail.FailOnDb", err)
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		k.clogger.ErrorLogger(ctx, "SaveKycKTP.UpdateUserDetail.FailOnDb", err)
 		logData.Error = err.Error()
-		tx.Rollback()
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.INTERNAL_SERVER_ERROR_CODE,
 			Message:    pkgErr.INTERNAL_SERVER_MSG,
@@ -154,7 +149,6 @@ func (k *kycService) SaveKycPassport(ctx context.Context, req request.PassportRe
 		}, logData
 	}
 
-	tx.Commit()
 	return &dto.BaseResponse{
 		StatusCode: pkgErr.SUCCESS_CODE,
 		Message:    pkgErr.SUCCES_MSG,
@@ -220,7 +214,6 @@ func Base64ToMultipartFileHeader(base64Str, fileName, contentType string) (*mult
 func (k *kycService) SaveKycKTP(ctx context.Context, req request.KTPrequest, userUUID string) (*dto.BaseResponse, *dto.CustomLoggerRequest) {
 	var (
 		err     error
-		tx      = k.KycKtpRepository.Tx(ctx)
 		logData = &dto.CustomLoggerRequest{Remarks: "kyc-save-ktp", Success: false}
 	)
 
@@ -231,7 +224,6 @@ func (k *kycService) SaveKycKTP(ctx context.Context, req request.KTPrequest, use
 	if err != nil {
 		k.clogger.ErrorLogger(ctx, "SaveKycKTP.GetUser.FailOnDb", err)
 		logData.Error = err.Error()
-		tx.Rollback()
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.INTERNAL_SERVER_ERROR_CODE,
 			Message:    pkgErr.INTERNAL_SERVER_MSG,
@@ -244,7 +236,6 @@ func (k *kycService) SaveKycKTP(ctx context.Context, req request.KTPrequest, use
 	if err != nil {
 		k.clogger.ErrorLogger(ctx, "SaveKycKTP.SelectUserDetailByUserId.FailOnDb", err)
 		logData.Error = err.Error()
-		tx.Rollback()
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.INTERNAL_SERVER_ERROR_CODE,
 			Message:    pkgErr.INTERNAL_SERVER_MSG,
@@ -262,35 +253,29 @@ func (k *kycService) SaveKycKTP(ctx context.Context, req request.KTPrequest, use
 	fileName := fmt.Sprintf("%s-%s", userData.UUID, "ktp")
 	fileHeader, err := Base64ToMultipartFileHeader(req.Image, fileName, "image/jpeg")
 	if err != nil {
-		tx.Rollback()
 		k.clogger.ErrorLogger(ctx, "KYCIdenityKTP.PutObject", err)
 		return nil, logData
 	}
 
 	_, objectPath, err := k.MinioRepository.PutObject(ctx, fileHeader, "kyc/ktp", &fileName)
 	if err != nil {
-		tx.Rollback()
 		k.clogger.ErrorLogger(ctx, "KYCIdenityKTP.PutObject", err)
 		return nil, logData
 	}
 
-	err = k.KycKtpRepository.SaveKYCKtp(ctx, tx, entity.EncapsulateRequestKtpToEntity(req, userData, *objectPath))
-	if err != nil {
-		k.clogger.ErrorLogger(ctx, "SaveKycKTP.SaveKYCKtp.FailOnDb", err)
-		logData.Error = err.Error()
-		tx.Rollback()
-		return &dto.BaseResponse{
-			StatusCode: pkgErr.INTERNAL_SERVER_ERROR_CODE,
-			Message:    pkgErr.INTERNAL_SERVER_MSG,
-			Error:      err.Error(),
-		}, logData
-	}
-
-	err = k.UserDetailsRepository.UpdateUserDetail(ctx, tx, userDetails, newUserDetails)
+	err = k.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		if err := k.KycKtpRepository.SaveKYCKtp(ctx, tx, entity.EncapsulateRequestKtpToEntity(req, userData, *objectPath)); err != nil {
+			k.clogger.ErrorLogger(ctx, "SaveKycKTP.SaveKYCKtp.FailOnDb", err)
+			return err
+		}
+		if err := k.UserDetailsRepository.UpdateUserDetail(ctx, tx, userDetails, newUserDetails); err != nil {
+			k.clogger.ErrorLogger(ctx, "SaveKycKTP.UpdateUserDetail.FailOnDb", err)
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		k.clogger.ErrorLogger(ctx, "SaveKycKTP.UpdateUserDetail.FailOnDb", err)
 		logData.Error = err.Error()
-		tx.Rollback()
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.INTERNAL_SERVER_ERROR_CODE,
 			Message:    pkgErr.INTERNAL_SERVER_MSG,
@@ -298,7 +283,6 @@ func (k *kycService) SaveKycKTP(ctx context.Context, req request.KTPrequest, use
 		}, logData
 	}
 
-	tx.Commit()
 	return &dto.BaseResponse{
 		StatusCode: pkgErr.SUCCESS_CODE,
 		Message:    pkgErr.SUCCES_MSG,
@@ -319,7 +303,7 @@ func (k *kycService) VerifyKycKTP(ctx context.Context, req request.KycRequest) (
 		Image:           req.Image,
 	}
 
-	verihubResponse, err = k.outboundVeriHubsSvc.SendKYCIdenityKTP(ctx, &kycRequest)
+	verihubResponse, err = k.kycProvider.SendKYCIdenityKTP(ctx, &kycRequest)
 	if err != nil {
 		logData.Error = err.Error()
 		return &dto.BaseResponse{
@@ -350,7 +334,7 @@ func (k *kycService) VerifyKycPassport(ctx context.Context, req request.KycReque
 		Image:           req.Image,
 	}
 
-	verihubResponse, err = k.outboundVeriHubsSvc.SendKYCIdenityPassport(ctx, &kycRequest)
+	verihubResponse, err = k.kycProvider.SendKYCIdenityPassport(ctx, &kycRequest)
 	if err != nil {
 		logData.Error = err.Error()
 		return &dto.BaseResponse{
@@ -369,19 +353,21 @@ func (k *kycService) VerifyKycPassport(ctx context.Context, req request.KycReque
 
 func NewKycService(
 	clogger *helpers.CustomLogger,
-	outboundVeriHubsSvc verihubs.OutboundVeriHubsService,
+	kycProvider provider.KycProvider,
 	kycPassportRepository postgres.KycPassportRepository,
 	kycKtpRepository postgres.KycKtpRepository,
 	userRepository postgres.UserRepository,
 	userDetailsRepository postgres.UserDetailRepository,
-	minioRepository minio.MinioRepository) KycService {
+	minioRepository minio.MinioRepository,
+	unitOfWork postgres.UnitOfWork) KycService {
 	return &kycService{
 		clogger:               clogger,
-		outboundVeriHubsSvc:   outboundVeriHubsSvc,
+		kycProvider:           kycProvider,
 		KycKtpRepository:      kycKtpRepository,
 		KycPassportRepository: kycPassportRepository,
 		UserRepository:        userRepository,
 		UserDetailsRepository: userDetailsRepository,
 		MinioRepository:       minioRepository,
+		unitOfWork:            unitOfWork,
 	}
 }
@@ -0,0 +1,283 @@
+package loginEventSvc
+
+import (
+	"backend-mobile-api/app/config"
+	"backend-mobile-api/helpers"
+	"backend-mobile-api/internal/outbond/smtp"
+	"backend-mobile-api/internal/repository/postgres"
+	"backend-mobile-api/model/dto"
+	"backend-mobile-api/model/dto/response"
+	"backend-mobile-api/model/entity"
+	"backend-mobile-api/model/enum"
+	"backend-mobile-api/model/enum/pkgErr"
+	"backend-mobile-api/service/notification"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// FAILURE_DEVICE_VERIFICATION marks a login that stopped at the new-device otp,
+// the verification that follows completes it as a successful login.
+const FAILURE_DEVICE_VERIFICATION = "device verification required"
+
+// Attempt is what a login endpoint knows about the attempt, the client address
+// and app version are taken from the request context. Failure is empty for a
+// successful login.
+type Attempt struct {
+	Method    enum.LoginType
+	DeviceID  string
+	Place     string
+	Latitude  string
+	Longitude string
+	Failure   string
+}
+
+type loginEventService struct {
+	loginEventRepository postgres.LoginEventRepository
+	deviceRepository     postgres.DeviceRepository
+	userRepository       postgres.UserRepository
+	unitOfWork           postgres.UnitOfWork
+	smtp                 *smtp.Smtp
+	notifier             *notification.FirebaseNotifier
+	config               *config.LoginAlert
+	clogger              *helpers.CustomLogger
+}
+
+func NewLoginEventService(
+	loginEventRepository postgres.LoginEventRepository,
+	deviceRepository postgres.DeviceRepository,
+	userRepository postgres.UserRepository,
+	unitOfWork postgres.UnitOfWork,
+	smtp *smtp.Smtp,
+	notifier *notification.FirebaseNotifier,
+	config *config.LoginAlert,
+	clogger *helpers.CustomLogger,
+) LoginEventService {
+	return &loginEventService{
+		loginEventRepository: loginEventRepository,
+		deviceRepository:     deviceRepository,
+		userRepository:       userRepository,
+		unitOfWork:           unitOfWork,
+		smtp:                 smtp,
+		notifier:             notifier,
+		config:               config,
+		clogger:              clogger,
+	}
+}
+
+// LoginEventService keeps the login history of a user. A successful login from
+// a device the user never logged in from, or far from the previous login,
+// alerts the user by email and on their other trusted devices.
+type LoginEventService interface {
+	Record(ctx context.Context, user *entity.User, attempt Attempt)
+	RecordDeviceVerified(ctx context.Context, user *entity.User, deviceID string)
+	InquiryLoginHistoryService(ctx context.Context, userUUID string, page int, limit int, logData *dto.CustomLoggerRequest) *dto.BaseResponse
+}
+
+// Record stores the attempt. The history is informative, a failure is logged
+// and never blocks the login.
+func (svc *loginEventService) Record(ctx context.Context, user *entity.User, attempt Attempt) {
+	event := &entity.LoginEvent{
+		UserID:        user.ID,
+		UserUUID:      user.UUID,
+		Method:        attempt.Method,
+		Status:        enum.LOGIN_SUCCESS,
+		FailureReason: attempt.Failure,
+		DeviceID:      attempt.DeviceID,
+		Place:         attempt.Place,
+		Latitude:      helpers.CoarseLatitude(attempt.Latitude),
+		Longitude:     helpers.CoarseLongitude(attempt.Longitude),
+	}
+	if attempt.Failure != "" {
+		event.Status = enum.LOGIN_FAILED
+	}
+	if customResource, ok := ctx.Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue); ok {
+		event.IPAddress = customResource.HeaderXRealIp
+		event.AppVersion = customResource.HeaderXAppVersion
+		// every request carries the device location, a login without its own falls back to it
+		if event.Latitude == nil || event.Longitude == nil {
+			event.Latitude = helpers.CoarseLatitude(customResource.HeaderXLatitude)
+			event.Longitude = helpers.CoarseLongitude(customResource.HeaderXLongitude)
+		}
+	}
+	if event.Status == enum.LOGIN_SUCCESS {
+		if err := svc.flag(ctx, event); err != nil {
+			svc.clogger.ErrorLogger(ctx, "Record.flag", err)
+		}
+	}
+	err := svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		return svc.loginEventRepository.InsertLoginEvent(ctx, tx, event)
+	})
+	if err != nil {
+		svc.clogger.ErrorLogger(ctx, "Record.InsertLoginEvent", err)
+		return
+	}
+	if event.NewDevice || event.FarLocation {
+		svc.alert(ctx, user, event)
+	}
+}
+
+// RecordDeviceVerified completes the login that stopped at the new-device otp,
+// the method and place come from that attempt.
+func (svc *loginEventService) RecordDeviceVerified(ctx context.Context, user *entity.User, deviceID string) {
+	pending, err := svc.loginEventRepository.SelectLastLoginEvent(ctx, &entity.LoginEvent{
+		UserID:        user.ID,
+		DeviceID:      deviceID,
+		FailureReason: FAILURE_DEVICE_VERIFICATION,
+	})
+	if err != nil {
+		svc.clogger.ErrorLogger(ctx, "RecordDeviceVerified.SelectLastLoginEvent", err)
+		return
+	}
+	if pending == nil {
+		svc.clogger.ErrorLogger(ctx, "RecordDeviceVerified", errors.New("no login waiting for device verification"))
+		return
+	}
+	attempt := Attempt{
+		Method:   pending.Method,
+		DeviceID: deviceID,
+		Place:    pending.Place,
+	}
+	if pending.Latitude != nil && pending.Longitude != nil {
+		attempt.Latitude = fmt.Sprint(*pending.Latitude)
+		attempt.Longitude = fmt.Sprint(*pending.Longitude)
+	}
+	svc.Record(ctx, user, attempt)
+}
+
+// flag compares the login with the earlier successful ones. A user without
+// any is not alerted, the first login of an account is neither new nor far.
+func (svc *loginEventService) flag(ctx context.Context, event *entity.LoginEvent) error {
+	last, err := svc.loginEventRepository.SelectLastLoginEvent(ctx, &entity.LoginEvent{
+		UserID: event.UserID,
+		Status: enum.LOGIN_SUCCESS,
+	})
+	if err != nil || last == nil {
+		return err
+	}
+	seen, err := svc.loginEventRepository.CountLoginEvents(ctx, &entity.LoginEvent{
+		UserID:   event.UserID,
+		DeviceID: event.DeviceID,
+		Status:   enum.LOGIN_SUCCESS,
+	})
+	if err != nil {
+		return err
+	}
+	event.NewDevice = seen == 0
+	if last.Latitude != nil && last.Longitude != nil && event.Latitude != nil && event.Longitude != nil {
+		distance := helpers.DistanceKm(*last.Latitude, *last.Longitude, *event.Latitude, *event.Longitude)
+		event.FarLocation = distance > svc.config.FarDistanceKm
+	}
+	return nil
+}
+
+func (svc *loginEventService) alert(ctx context.Context, user *entity.User, event *entity.LoginEvent) {
+	place := event.Place
+	if place == "" {
+		place = "an unknown location"
+	}
+	var reasons []string
+	if event.NewDevice {
+		reasons = append(reasons, "from a device you have not used before")
+	}
+	if event.FarLocation {
+		reasons = append(reasons, "far from where you last logged in")
+	}
+	body := fmt.Sprintf(
+		"Hello %s,\n\nYour account was just logged in %s.\n\nTime: %s\nPlace: %s\nIP address: %s\n\nIf this was not you, change your PIN immediately and log out the session from your active sessions.\n\nBest regards,\nBeyondTech",
+		user.FullName, strings.Join(reasons, " and "), event.CreatedAt.Format("02 Jan 2006 15:04"), place, event.IPAddress,
+	)
+	email := user.Email
+	wrapContext := helpers.WrapContext(ctx)
+	go func() {
+		_ = svc.smtp.SendMail(wrapContext, []string{email}, enum.NEW_LOGIN_SUBJECT, body)
+	}()
+
+	if svc.notifier == nil {
+		return
+	}
+	devices, err := svc.deviceRepository.SelectTrustedDevices(ctx, uint(user.ID))
+	if err != nil {
+		return
+	}
+	title := "Login Baru Terdeteksi"
+	message := fmt.Sprintf("Akun kamu baru saja login di %s. Jika bukan kamu, segera ganti PIN.", place)
+	for _, device := range devices {
+		// the device that logged in already knows
+		if device.DeviceID == event.DeviceID || device.FCMToken == "" {
+			continue
+		}
+		token := device.FCMToken
+		go func() {
+			if err := svc.notifier.SendPushNotification(token, title, message, "new_login"); err != nil {
+				svc.clogger.ErrorLogger(wrapContext, "alert.notifier.SendPushNotification", err)
+			}
+		}()
+	}
+}
+
+func (svc *loginEventService) InquiryLoginHistoryService(ctx context.Context, userUUID string, page int, limit int, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	logData.UserUUID = userUUID
+	user, err := svc.userRepository.SelectUserByUUID(ctx, userUUID)
+	if err != nil {
+		logData.Error = err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.PROFILE_USER_NOT_FOUND_CODE,
+				Message:    pkgErr.USER_NOT_FOUND_MSG,
+			}
+		}
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	logData.Email = user.Email
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > svc.config.MaxHistoryLimit {
+		limit = svc.config.MaxHistoryLimit
+	}
+	events, total, err := svc.loginEventRepository.SelectLoginEvents(ctx, user.ID, limit, (page-1)*limit)
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	data := response.LoginHistoryResponse{
+		Page:   page,
+		Limit:  limit,
+		Total:  total,
+		Events: make([]response.LoginEventResponse, 0, len(events)),
+	}
+	for _, event := range events {
+		data.Events = append(data.Events, response.LoginEventResponse{
+			Method:        event.Method,
+			Status:        event.Status,
+			FailureReason: event.FailureReason,
+			DeviceID:      event.DeviceID,
+			IPAddress:     event.IPAddress,
+			AppVersion:    event.AppVersion,
+			Place:         event.Place,
+			Latitude:      event.Latitude,
+			Longitude:     event.Longitude,
+			NewDevice:     event.NewDevice,
+			FarLocation:   event.FarLocation,
+			CreatedAt:     event.CreatedAt,
+		})
+	}
+	logData.Success = true
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+		Data:       data,
+	}
+}
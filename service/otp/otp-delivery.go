@@ -0,0 +1,339 @@
+package otp
+
+import (
+	"backend-mobile-api/helpers"
+	"backend-mobile-api/model/dto"
+	"backend-mobile-api/model/entity"
+	"backend-mobile-api/model/enum"
+	verihubsDto "backend-mobile-api/model/outbond/verihubs-dto"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrOtpUndeliverable = errors.New("otp could not be sent on any channel")
+
+// deliveryChannels starts with the channel the client picked, followed by the
+// configured channels the user has a destination for.
+func (svc *otpService) deliveryChannels(req *dto.SendOtp) []dto.OtpDeliveryChannel {
+	channels := []dto.OtpDeliveryChannel{{OtpMethod: req.OtpMethod, OtpDestination: req.OtpDestination}}
+	for _, name := range svc.rootConfig.Otp.Channels {
+		method := enum.OtpType(strings.ToUpper(strings.TrimSpace(name)))
+		if method == req.OtpMethod {
+			continue
+		}
+		destination := otpDestination(req, method)
+		if destination == "" {
+			continue
+		}
+		channels = append(channels, dto.OtpDeliveryChannel{OtpMethod: method, OtpDestination: destination})
+	}
+	return channels
+}
+
+// otpDestination whatsapp and sms share the phone number, a channel of the other
+// kind needs the fallback destination of the request.
+func otpDestination(req *dto.SendOtp, method enum.OtpType) string {
+	phone := req.OtpMethod == enum.TYPE_SMS || req.OtpMethod == enum.TYPE_WHATSAPP
+	switch method {
+	case enum.TYPE_EMAIL:
+		if req.OtpMethod == enum.TYPE_EMAIL {
+			return req.OtpDestination
+		}
+		return req.FallbackEmail
+	case enum.TYPE_SMS, enum.TYPE_WHATSAPP:
+		if phone {
+			return req.OtpDestination
+		}
+		return req.FallbackPhoneNumber
+	}
+	return ""
+}
+
+// deliverNext sends on the channels after the current attempt until one accepts the otp.
+func (svc *otpService) deliverNext(ctx context.Context, delivery *dto.OtpDelivery) (*entity.OTP, error) {
+	lastErr := ErrOtpUndeliverable
+	for attempt := delivery.Attempt + 1; attempt < len(delivery.Channels); attempt++ {
+		otpData, err := svc.deliver(ctx, delivery, attempt)
+		if err != nil {
+			svc.clogger.ErrorLogger(ctx, fmt.Sprintf("deliverNext.%s", delivery.Channels[attempt].OtpMethod), err)
+			lastErr = err
+			continue
+		}
+		if err = svc.saveDelivery(ctx, delivery); err != nil {
+			svc.clogger.ErrorLogger(ctx, "deliverNext.saveDelivery", err)
+			return nil, err
+		}
+		if !delivery.Confirmed && attempt+1 < len(delivery.Channels) {
+			svc.watchDelivery(ctx, delivery.VerifyKey, attempt)
+		}
+		return otpData, nil
+	}
+	return nil, lastErr
+}
+
+// deliver sends the otp on one channel and records the attempt, a failed attempt
+// is stored too so the delivery timeline shows why the channel was left.
+func (svc *otpService) deliver(ctx context.Context, delivery *dto.OtpDelivery, attempt int) (*entity.OTP, error) {
+	var (
+		channel = delivery.Channels[attempt]
+		otpData = entity.OTP{
+			OtpCode:        delivery.Code,
+			OtpPurpose:     delivery.OtpPurpose,
+			OtpMethod:      channel.OtpMethod,
+			OtpDestination: channel.OtpDestination,
+			UserId:         delivery.UserId,
+			UserUUID:       delivery.UserUUID,
+			VerifyKey:      delivery.VerifyKey,
+			ExpiredAt:      time.Now().Add(svc.rootConfig.App.OtpExpire),
+			Status:         enum.OTP_REQUESTED,
+		}
+		// verihubs generates its own code when configured to, we keep ours for the other channels
+		otp     = &delivery.Code
+		sendErr error
+	)
+	if svc.rootConfig.Verihubs.VerihubsOTPCode {
+		otp = nil
+	}
+	switch channel.OtpMethod {
+	case enum.TYPE_EMAIL:
+		otpData.SessionId = delivery.VerifyKey
+		if sendErr = svc.smtp.SendMail(ctx, []string{channel.OtpDestination}, enum.VERIFY_OTP_SUBJECT, svc.smtp.RegisterOtpMsg(delivery.Code)); sendErr == nil {
+			// smtp has no delivery report, an accepted mail is as far as we can see
+			otpData.Status = enum.OTP_DELIVERED
+		}
+	case enum.TYPE_SMS:
+		resp, err := svc.otpProvider.SendSMSOtpService(ctx, &verihubsDto.SendOtpBaseRequest{
+			MSISDN:      channel.OtpDestination,
+			Otp:         otp,
+			Template:    svc.rootConfig.Verihubs.OTPSMSTemplate,
+			TimeLimit:   int64(svc.rootConfig.App.OtpExpire.Seconds()),
+			Challenge:   svc.rootConfig.Verihubs.OTPSMSChallenge,
+			CallbackUrl: svc.callbackUrl(delivery.VerifyKey),
+		})
+		sendErr = err
+		if err == nil && resp != nil {
+			otpData.OtpCode = resp.Otp
+			otpData.SessionId = resp.SessionID
+		}
+	case enum.TYPE_WHATSAPP:
+		resp, err := svc.otpProvider.SendWhatsappsService(ctx, &verihubsDto.SendWhatsappOtpBaseRequest{
+			MSISDN:       channel.OtpDestination,
+			Otp:          otp,
+			Challenge:    nil,
+			TimeLimit:    int64(svc.rootConfig.App.OtpExpire.Seconds()),
+			LangCode:     svc.rootConfig.Verihubs.OTPWhatsappLangCode,
+			TemplateName: svc.rootConfig.Verihubs.OTPWhatsappTemplateName,
+			OtpLength:    svc.rootConfig.Verihubs.OTPWhatsappOtpLength,
+			CallbackUrl:  svc.callbackUrl(delivery.VerifyKey),
+		})
+		sendErr = err
+		if err == nil && resp != nil {
+			otpData.OtpCode = resp.Otp
+			otpData.SessionId = resp.SessionID
+		}
+	default:
+		sendErr = fmt.Errorf("invalid otp method %s", channel.OtpMethod)
+	}
+	if sendErr != nil {
+		otpData.Status = enum.OTP_FAILED
+	}
+
+	err := svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		if err := svc.otpRepository.InsertOtpDataRepository(ctx, tx, &otpData); err != nil {
+			return err
+		}
+		return svc.otpRepository.InsertOtpDeliveryEvent(ctx, tx, &entity.OtpDeliveryEvent{
+			OtpID:     otpData.ID,
+			VerifyKey: otpData.VerifyKey,
+			SessionId: otpData.SessionId,
+			OtpMethod: otpData.OtpMethod,
+			Status:    otpData.Status,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if sendErr != nil {
+		return nil, sendErr
+	}
+
+	jsonOtp, _ := json.Marshal(otpData)
+	if err := svc.redis.SetOtp(ctx, otpData.OtpCode, delivery.VerifyKey, string(jsonOtp), svc.rootConfig.App.OtpExpire); err != nil {
+		return nil, err
+	}
+	delivery.Attempt = attempt
+	delivery.SessionId = otpData.SessionId
+	delivery.Status = otpData.Status
+	delivery.Confirmed = enum.OtpDeliveryConfirmed(otpData.Status)
+	delivery.Deadline = time.Now().Add(svc.rootConfig.Otp.DeliveryTimeout)
+	delivery.ExpiredAt = otpData.ExpiredAt
+	delivery.Codes = append(delivery.Codes, otpData.OtpCode)
+	return &otpData, nil
+}
+
+// watchDelivery moves the otp on when its channel has not confirmed delivery in
+// time. A restart loses the timer, DeliveryStatus checks the deadline as well.
+func (svc *otpService) watchDelivery(ctx context.Context, verifyKey string, attempt int) {
+	wrapContext := helpers.WrapContext(ctx)
+	time.AfterFunc(svc.rootConfig.Otp.DeliveryTimeout, func() {
+		delivery, err := svc.loadDelivery(wrapContext, verifyKey)
+		if err != nil {
+			svc.clogger.ErrorLogger(wrapContext, "watchDelivery.loadDelivery", err)
+			return
+		}
+		if delivery == nil || delivery.Confirmed || delivery.Attempt != attempt {
+			return
+		}
+		if err = svc.failover(wrapContext, delivery); err != nil {
+			svc.clogger.ErrorLogger(wrapContext, "watchDelivery.failover", err)
+		}
+	})
+}
+
+// failover sends on the next channel, the callback and the timeout may both ask
+// for the same attempt and only the first one is served.
+func (svc *otpService) failover(ctx context.Context, delivery *dto.OtpDelivery) error {
+	if delivery.Attempt+1 >= len(delivery.Channels) {
+		return nil
+	}
+	claimed, err := svc.redis.ClaimOtpFailover(ctx, delivery.VerifyKey, delivery.Attempt, svc.rootConfig.App.OtpExpire)
+	if err != nil || !claimed {
+		return err
+	}
+	_, err = svc.deliverNext(ctx, delivery)
+	return err
+}
+
+// HandleDeliveryStatus applies a verihubs callback to the delivery of the otp,
+// a failure on the channel in use moves the otp to the next channel.
+func (svc *otpService) HandleDeliveryStatus(ctx context.Context, otpData *entity.OTP, status enum.OTPStatus) error {
+	delivery, err := svc.loadDelivery(ctx, otpData.VerifyKey)
+	if err != nil || delivery == nil {
+		return err
+	}
+	// a late report of a channel already left behind changes nothing
+	if delivery.SessionId != otpData.SessionId {
+		return nil
+	}
+	delivery.Status = status
+	if enum.OtpDeliveryConfirmed(status) {
+		delivery.Confirmed = true
+	}
+	if err = svc.saveDelivery(ctx, delivery); err != nil {
+		return err
+	}
+	if enum.OtpDeliveryFailed(status) {
+		return svc.failover(ctx, delivery)
+	}
+	return nil
+}
+
+// DeliveryStatus returns the delivery of a verify key, nil once it is verified or expired.
+func (svc *otpService) DeliveryStatus(ctx context.Context, verifyKey string) (*dto.OtpDelivery, error) {
+	delivery, err := svc.loadDelivery(ctx, verifyKey)
+	if err != nil || delivery == nil {
+		return delivery, err
+	}
+	if !delivery.Confirmed && time.Now().After(delivery.Deadline) {
+		if err = svc.failover(ctx, delivery); err != nil {
+			svc.clogger.ErrorLogger(ctx, "DeliveryStatus.failover", err)
+		}
+		return svc.loadDelivery(ctx, verifyKey)
+	}
+	return delivery, nil
+}
+
+// CompleteDelivery ends every code sent under the verify key once one of them is
+// verified, the timeline of the verified otp gets its last event.
+func (svc *otpService) CompleteDelivery(ctx context.Context, verified *entity.OTP) error {
+	err := svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		if err := svc.otpRepository.InsertOtpDeliveryEvent(ctx, tx, &entity.OtpDeliveryEvent{
+			OtpID:     verified.ID,
+			VerifyKey: verified.VerifyKey,
+			SessionId: verified.SessionId,
+			OtpMethod: verified.OtpMethod,
+			Status:    enum.OTP_VERIFIED,
+		}); err != nil {
+			return err
+		}
+		return svc.invalidate(ctx, verified.VerifyKey)
+	})
+	if err != nil {
+		return err
+	}
+	return svc.redis.DeleteOtpVerifyAttempt(ctx, verified.VerifyKey)
+}
+
+// invalidate removes the cached codes and delivery of a verify key and expires its otp rows.
+func (svc *otpService) invalidate(ctx context.Context, verifyKey string) error {
+	delivery, err := svc.loadDelivery(ctx, verifyKey)
+	if err != nil {
+		return err
+	}
+	if delivery != nil {
+		for _, code := range delivery.Codes {
+			_ = svc.redis.DeleteOtp(ctx, code, verifyKey)
+		}
+		_ = svc.redis.DeleteOtpDelivery(ctx, verifyKey)
+	}
+	// joins the unit of work of the caller when there is one
+	return svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		return svc.otpRepository.ExpireOtpByVerifyKey(ctx, tx, verifyKey)
+	})
+}
+
+func (svc *otpService) loadDelivery(ctx context.Context, verifyKey string) (*dto.OtpDelivery, error) {
+	value, err := svc.redis.GetOtpDelivery(ctx, verifyKey)
+	if err != nil || value == "" {
+		return nil, err
+	}
+	var delivery dto.OtpDelivery
+	if err = json.Unmarshal([]byte(value), &delivery); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (svc *otpService) saveDelivery(ctx context.Context, delivery *dto.OtpDelivery) error {
+	ttl := time.Until(delivery.ExpiredAt)
+	if ttl <= 0 {
+		return nil
+	}
+	jsonDelivery, _ := json.Marshal(delivery)
+	return svc.redis.SetOtpDelivery(ctx, delivery.VerifyKey, string(jsonDelivery), ttl)
+}
+
+// callbackUrl adds the token of the verify key to the configured callback url,
+// verihubs appends the session and status of the report to it.
+func (svc *otpService) callbackUrl(verifyKey string) *string {
+	base := svc.rootConfig.Verihubs.OTPCallBackUrl
+	if base == nil || *base == "" {
+		return base
+	}
+	callback, err := url.Parse(*base)
+	if err != nil {
+		return base
+	}
+	query := callback.Query()
+	query.Set("token", CallbackToken(svc.rootConfig.Verihubs.OTPCallbackSecret, verifyKey))
+	callback.RawQuery = query.Encode()
+	signed := callback.String()
+	return &signed
+}
+
+// CallbackToken binds a delivery callback to the verify key of the otp it reports on.
+func CallbackToken(secret string, verifyKey string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(verifyKey))
+	return hex.EncodeToString(mac.Sum(nil))
+}
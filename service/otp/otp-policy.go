@@ -0,0 +1,193 @@
+package otp
+
+import (
+	redisRepos "backend-mobile-api/internal/repository/redis"
+	"backend-mobile-api/model/dto"
+	"backend-mobile-api/model/dto/response"
+	"backend-mobile-api/model/enum"
+	"backend-mobile-api/model/enum/pkgErr"
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+var (
+	ErrOtpInvalid        = errors.New("otp code is invalid")
+	ErrOtpResendCooldown = errors.New("otp resend cooldown running")
+	ErrOtpSendLimit      = errors.New("otp send limit reached")
+	ErrOtpVerifyLimit    = errors.New("otp verify attempts exhausted")
+)
+
+// PolicyError is returned when the otp policy turns a request down, it tells the
+// app how long to wait and how many codes may still be tried.
+type PolicyError struct {
+	Err               error
+	RetryAfter        time.Duration
+	RemainingAttempts int64
+}
+
+func (e *PolicyError) Error() string {
+	return e.Err.Error()
+}
+func (e *PolicyError) Unwrap() error {
+	return e.Err
+}
+
+type sendCap struct {
+	scope  string
+	id     string
+	window time.Duration
+	limit  int64
+}
+
+// allowSend enforces the resend cooldown of the destination and the hourly and
+// daily caps of the destination, device and ip before an otp is sent. Counters
+// only move once every check passed so a rejected request costs nothing.
+func (svc *otpService) allowSend(ctx context.Context, req *dto.SendOtp) error {
+	var deviceID, ipAddress string
+	if customResource, ok := ctx.Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue); ok {
+		deviceID = customResource.HeaderXDeviceID
+		ipAddress = customResource.HeaderXRealIp
+	}
+	cfg := svc.rootConfig.Otp
+	caps := []sendCap{
+		{redisRepos.OTP_SCOPE_DESTINATION, req.OtpDestination, time.Hour, cfg.DestinationHourlyLimit},
+		{redisRepos.OTP_SCOPE_DESTINATION, req.OtpDestination, 24 * time.Hour, cfg.DestinationDailyLimit},
+		{redisRepos.OTP_SCOPE_DEVICE, deviceID, time.Hour, cfg.DeviceHourlyLimit},
+		{redisRepos.OTP_SCOPE_DEVICE, deviceID, 24 * time.Hour, cfg.DeviceDailyLimit},
+		{redisRepos.OTP_SCOPE_IP, ipAddress, time.Hour, cfg.IpHourlyLimit},
+		{redisRepos.OTP_SCOPE_IP, ipAddress, 24 * time.Hour, cfg.IpDailyLimit},
+	}
+
+	if cfg.ResendCooldown > 0 {
+		wait, err := svc.redis.GetOtpCooldown(ctx, string(req.OtpPurpose), req.OtpDestination)
+		if err != nil {
+			svc.clogger.ErrorLogger(ctx, "SendOtp.redis.GetOtpCooldown", err)
+			return err
+		}
+		if wait > 0 {
+			return &PolicyError{Err: ErrOtpResendCooldown, RetryAfter: wait}
+		}
+	}
+	for _, c := range caps {
+		if c.id == "" || c.limit <= 0 {
+			continue
+		}
+		count, reset, err := svc.redis.GetOtpSend(ctx, c.scope, c.id, c.window)
+		if err != nil {
+			svc.clogger.ErrorLogger(ctx, "SendOtp.redis.GetOtpSend", err)
+			return err
+		}
+		if count >= c.limit {
+			svc.clogger.WarnLogger(ctx, "SendOtp: otp send limit reached for "+c.scope+" "+c.window.String())
+			return &PolicyError{Err: ErrOtpSendLimit, RetryAfter: reset}
+		}
+	}
+	if cfg.ResendCooldown > 0 {
+		// two requests racing past the check above are settled here
+		claimed, err := svc.redis.ClaimOtpCooldown(ctx, string(req.OtpPurpose), req.OtpDestination, cfg.ResendCooldown)
+		if err != nil {
+			svc.clogger.ErrorLogger(ctx, "SendOtp.redis.ClaimOtpCooldown", err)
+			return err
+		}
+		if !claimed {
+			return &PolicyError{Err: ErrOtpResendCooldown, RetryAfter: cfg.ResendCooldown}
+		}
+	}
+	for _, c := range caps {
+		if c.id == "" || c.limit <= 0 {
+			continue
+		}
+		if _, err := svc.redis.IncrOtpSend(ctx, c.scope, c.id, c.window); err != nil {
+			svc.clogger.ErrorLogger(ctx, "SendOtp.redis.IncrOtpSend", err)
+		}
+	}
+	return nil
+}
+
+// ReserveVerifyAttempt counts the attempt before the code is compared, so
+// parallel guesses can not all pass the limit check before the first wrong one
+// is recorded. It returns the number of the attempt for RejectOtp, a verify
+// key past its attempts is rejected outright.
+func (svc *otpService) ReserveVerifyAttempt(ctx context.Context, verifyKey string) (int64, error) {
+	maxAttempts := svc.rootConfig.Otp.MaxVerifyAttempts
+	if maxAttempts <= 0 {
+		return 0, nil
+	}
+	// the counter has to outlive every code the failover may still send
+	expiration := svc.rootConfig.App.OtpExpire + svc.rootConfig.Otp.DeliveryTimeout*time.Duration(len(svc.rootConfig.Otp.Channels))
+	attempt, err := svc.redis.IncrOtpVerifyAttempt(ctx, verifyKey, expiration)
+	if err != nil {
+		svc.clogger.ErrorLogger(ctx, "ReserveVerifyAttempt.redis.IncrOtpVerifyAttempt", err)
+		return 0, err
+	}
+	if attempt > maxAttempts {
+		return attempt, &PolicyError{Err: ErrOtpVerifyLimit}
+	}
+	return attempt, nil
+}
+
+// RejectOtp answers a wrong code of the reserved attempt, the last allowed
+// attempt invalidates every otp sent under the verify key so only a new otp
+// can be verified.
+func (svc *otpService) RejectOtp(ctx context.Context, verifyKey string, attempt int64) error {
+	maxAttempts := svc.rootConfig.Otp.MaxVerifyAttempts
+	if maxAttempts <= 0 {
+		return &PolicyError{Err: ErrOtpInvalid}
+	}
+	if attempt < maxAttempts {
+		return &PolicyError{Err: ErrOtpInvalid, RemainingAttempts: maxAttempts - attempt}
+	}
+	svc.clogger.WarnLogger(ctx, "RejectOtp: otp invalidated after too many wrong codes")
+	if err := svc.invalidate(ctx, verifyKey); err != nil {
+		svc.clogger.ErrorLogger(ctx, "RejectOtp.invalidate", err)
+		return err
+	}
+	return &PolicyError{Err: ErrOtpVerifyLimit}
+}
+
+func seconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
+
+// ErrorResponse maps a policy error of SendOtp, VerifyOtpCode, ReserveVerifyAttempt
+// or RejectOtp to the response of the calling service, a wrong code keeps the
+// caller's own code and message. Any other error returns nil.
+func ErrorResponse(err error, invalidCode pkgErr.Code, invalidMsg string) *dto.BaseResponse {
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) {
+		return nil
+	}
+	status := &response.OtpAttemptResponse{
+		RemainingAttempts: policyErr.RemainingAttempts,
+		RetryAfter:        seconds(policyErr.RetryAfter),
+	}
+	switch {
+	case errors.Is(err, ErrOtpInvalid):
+		return &dto.BaseResponse{
+			StatusCode: invalidCode,
+			Message:    invalidMsg,
+			Data:       status,
+		}
+	case errors.Is(err, ErrOtpResendCooldown):
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.OTP_RESEND_COOLDOWN_CODE,
+			Message:    pkgErr.OTP_RESEND_COOLDOWN_MSG,
+			Data:       status,
+		}
+	case errors.Is(err, ErrOtpSendLimit):
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.OTP_SEND_LIMIT_CODE,
+			Message:    pkgErr.OTP_SEND_LIMIT_MSG,
+			Data:       status,
+		}
+	case errors.Is(err, ErrOtpVerifyLimit):
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.OTP_VERIFY_LIMIT_CODE,
+			Message:    pkgErr.OTP_VERIFY_LIMIT_MSG,
+			Data:       status,
+		}
+	}
+	return nil
+}
@@ -3,8 +3,8 @@ package otp
 import (
 	"backend-mobile-api/app/config"
 	"backend-mobile-api/helpers"
+	"backend-mobile-api/internal/outbond/provider"
 	"backend-mobile-api/internal/outbond/smtp"
-	"backend-mobile-api/internal/outbond/verihubs"
 	"backend-mobile-api/internal/repository/postgres"
 	"backend-mobile-api/internal/repository/redis"
 	"backend-mobile-api/model/dto"
@@ -16,17 +16,19 @@ import (
 	"crypto/rand"
 	"encoding/json"
 	"errors"
+	"gorm.io/gorm"
 	"io"
 	"time"
 )
 
 type otpService struct {
-	otpRepository           postgres.OtpRepository
-	rootConfig              *config.Root
-	redis                   redis.Redis
-	clogger                 helpers.CustomLogger
-	outboundVerihubsService verihubs.OutboundVeriHubsService
-	smtp                    smtp.Smtp
+	otpRepository postgres.OtpRepository
+	rootConfig    *config.Root
+	redis         redis.Redis
+	clogger       helpers.CustomLogger
+	otpProvider   provider.OtpProvider
+	smtp          smtp.Smtp
+	unitOfWork    postgres.UnitOfWork
 }
 
 func NewOtpService(
@@ -34,16 +36,18 @@ func NewOtpService(
 	rootConfig *config.Root,
 	redis *redis.Redis,
 	clogger *helpers.CustomLogger,
-	outboundVerihubsService verihubs.OutboundVeriHubsService,
+	otpProvider provider.OtpProvider,
 	smtp *smtp.Smtp,
+	unitOfWork postgres.UnitOfWork,
 ) OtpService {
 	return &otpService{
-		otpRepository:           otpRepository,
-		rootConfig:              rootConfig,
-		redis:                   *redis,
-		clogger:                 *clogger,
-		outboundVerihubsService: outboundVerihubsService,
-		smtp:                    *smtp,
+		otpRepository: otpRepository,
+		rootConfig:    rootConfig,
+		redis:         *redis,
+		clogger:       *clogger,
+		otpProvider:   otpProvider,
+		smtp:          *smtp,
+		unitOfWork:    unitOfWork,
 	}
 }
 
@@ -51,6 +55,11 @@ type OtpService interface {
 	generateOtpCode(ctx context.Context, verifyKey string) (string, error)
 	SendOtp(ctx context.Context, req *dto.SendOtp) (*entity.OTP, error)
 	VerifyOtpCode(ctx context.Context, req *request.VerifyOtpRequest) (*entity.OTP, error)
+	HandleDeliveryStatus(ctx context.Context, otpData *entity.OTP, status enum.OTPStatus) error
+	DeliveryStatus(ctx context.Context, verifyKey string) (*dto.OtpDelivery, error)
+	CompleteDelivery(ctx context.Context, verified *entity.OTP) error
+	ReserveVerifyAttempt(ctx context.Context, verifyKey string) (int64, error)
+	RejectOtp(ctx context.Context, verifyKey string, attempt int64) error
 }
 
 func (svc *otpService) generateOtpCode(ctx context.Context, verifyKey string) (string, error) {
@@ -76,90 +85,30 @@ func (svc *otpService) generateOtpCode(ctx context.Context, verifyKey string) (s
 	}
 	return string(b), err
 }
+
+// SendOtp sends the otp on the channel the client picked and falls back to the
+// next channel of config.Otp when the send fails, the callback reports a failure
+// or delivery is not confirmed in time. The returned otp is the one of the channel
+// in use when SendOtp returns, DeliveryStatus tells the channel used later on.
+// A request over the resend cooldown or a send cap fails with a *PolicyError.
 func (svc *otpService) SendOtp(ctx context.Context, req *dto.SendOtp) (*entity.OTP, error) {
-	var (
-		otpData = entity.OTP{
-			OtpCode:        "",
-			OtpPurpose:     req.OtpPurpose,
-			OtpMethod:      req.OtpMethod,
-			OtpDestination: req.OtpDestination,
-			UserId:         req.UserId,
-			UserUUID:       req.UserUUID,
-			VerifyKey:      req.VerifyKey,
-			ExpiredAt:      time.Now().Add(svc.rootConfig.App.OtpExpire),
-			SessionId:      "",
-			Status:         "",
-		}
-	)
-	CustomOtp, err := svc.generateOtpCode(ctx, req.VerifyKey)
-	if err != nil {
+	if err := svc.allowSend(ctx, req); err != nil {
 		return nil, err
 	}
-	otpData.Status = enum.OTP_DELIVERED
-	switch req.OtpMethod {
-	case enum.TYPE_EMAIL:
-		if err := svc.smtp.SendMail(ctx, []string{req.OtpDestination}, enum.VERIFY_OTP_SUBJECT, svc.smtp.RegisterOtpMsg(CustomOtp)); err != nil {
-			svc.clogger.ErrorLogger(ctx, "sendOtp.smtp.SendMail", err)
-			otpData.Status = enum.OTP_FAILED
-		}
-		otpData.OtpCode = CustomOtp
-		otpData.SessionId = req.VerifyKey
-	case enum.TYPE_SMS:
-		var otp *string
-		if !svc.rootConfig.Verihubs.VerihubsOTPCode {
-			otp = &CustomOtp
-		}
-		resp, err := svc.outboundVerihubsService.SendSMSOtpService(ctx, &verihubsDto.SendOtpBaseRequest{
-			MSISDN:      req.OtpDestination,
-			Otp:         otp,
-			Template:    svc.rootConfig.Verihubs.OTPSMSTemplate,
-			TimeLimit:   int64(svc.rootConfig.App.OtpExpire.Seconds()),
-			Challenge:   svc.rootConfig.Verihubs.OTPSMSChallenge,
-			CallbackUrl: svc.rootConfig.Verihubs.OTPCallBackUrl,
-		})
-		if err != nil {
-			otpData.Status = enum.OTP_FAILED
-			svc.clogger.ErrorLogger(ctx, "sendOtp.outboundVeriHubsSvc.SendSMSOtpService", err)
-			return nil, err
-		}
-		if resp != nil {
-			otpData.OtpCode = resp.Otp
-			otpData.SessionId = resp.SessionID
-		}
-
-	case enum.TYPE_WHATSAPP:
-		var otp *string
-		if !svc.rootConfig.Verihubs.VerihubsOTPCode {
-			otp = &CustomOtp
-		}
-		resp, err := svc.outboundVerihubsService.SendWhatsappsService(ctx, &verihubsDto.SendWhatsappOtpBaseRequest{
-			MSISDN:       req.OtpDestination,
-			Otp:          otp,
-			Challenge:    nil,
-			TimeLimit:    int64(svc.rootConfig.App.OtpExpire.Seconds()),
-			LangCode:     svc.rootConfig.Verihubs.OTPWhatsappLangCode,
-			TemplateName: svc.rootConfig.Verihubs.OTPWhatsappTemplateName,
-			OtpLength:    svc.rootConfig.Verihubs.OTPWhatsappOtpLength,
-			CallbackUrl:  svc.rootConfig.Verihubs.OTPCallBackUrl,
-		})
-		if err != nil {
-			otpData.Status = enum.OTP_FAILED
-		}
-		if resp != nil {
-			otpData.OtpCode = resp.Otp
-			otpData.SessionId = resp.SessionID
-			otpData.UserUUID = req.UserUUID
-		}
-	}
-	tx := svc.otpRepository.Tx(ctx)
-	err = svc.otpRepository.InsertOtpDataRepository(ctx, tx, &otpData)
+	code, err := svc.generateOtpCode(ctx, req.VerifyKey)
 	if err != nil {
-		svc.clogger.ErrorLogger(ctx, "otpRepository.InsertOtpDataRepository", err)
-		tx.Rollback()
 		return nil, err
 	}
-	tx.Commit()
-	return svc.otpRepository.SelectOtpByVerifyKeyBeforeExpire(ctx, req.VerifyKey)
+	delivery := &dto.OtpDelivery{
+		VerifyKey:  req.VerifyKey,
+		OtpPurpose: req.OtpPurpose,
+		UserId:     req.UserId,
+		UserUUID:   req.UserUUID,
+		Channels:   svc.deliveryChannels(req),
+		Attempt:    -1,
+		Code:       code,
+	}
+	return svc.deliverNext(ctx, delivery)
 }
 
 func (svc *otpService) VerifyOtpCode(ctx context.Context, req *request.VerifyOtpRequest) (*entity.OTP, error) {
@@ -167,6 +116,10 @@ func (svc *otpService) VerifyOtpCode(ctx context.Context, req *request.VerifyOtp
 		otpData   *entity.OTP
 		otpUpdate *entity.OTP
 	)
+	attempt, err := svc.ReserveVerifyAttempt(ctx, req.VerifyID)
+	if err != nil {
+		return nil, err
+	}
 	strJson, err := svc.redis.GetOtp(ctx, req.Otp, req.VerifyID)
 	if err != nil {
 		svc.clogger.ErrorLogger(ctx, "VerifyOtpCode", err)
@@ -190,7 +143,7 @@ func (svc *otpService) VerifyOtpCode(ctx context.Context, req *request.VerifyOtp
 
 	}
 	if otpData.OtpCode != req.Otp {
-		return nil, errors.New("otp code is invalid")
+		return nil, svc.RejectOtp(ctx, req.VerifyID, attempt)
 	}
 	if otpData.Status == enum.OTP_BLOCKED {
 		return nil, errors.New("otp code is blocked")
@@ -206,7 +159,7 @@ func (svc *otpService) VerifyOtpCode(ctx context.Context, req *request.VerifyOtp
 	case enum.TYPE_EMAIL:
 		otpUpdate = &entity.OTP{Status: enum.OTP_VERIFIED}
 	case enum.TYPE_SMS:
-		if _, err := svc.outboundVerihubsService.VerifySMSOtpService(ctx, &verihubsDto.VerifyOtpBaseRequest{
+		if _, err := svc.otpProvider.VerifySMSOtpService(ctx, &verihubsDto.VerifyOtpBaseRequest{
 			MSISDN:    otpData.OtpDestination,
 			Otp:       otpData.OtpCode,
 			Challenge: nil,
@@ -216,7 +169,7 @@ func (svc *otpService) VerifyOtpCode(ctx context.Context, req *request.VerifyOtp
 		}
 		otpUpdate = &entity.OTP{Status: enum.OTP_VERIFIED}
 	case enum.TYPE_WHATSAPP:
-		if _, err = svc.outboundVerihubsService.VerifyWhatsappsOtpService(ctx, &verihubsDto.VerifyOtpBaseRequest{
+		if _, err = svc.otpProvider.VerifyWhatsappsOtpService(ctx, &verihubsDto.VerifyOtpBaseRequest{
 			MSISDN:    otpData.OtpDestination,
 			Otp:       otpData.OtpCode,
 			Challenge: nil,
@@ -230,14 +183,16 @@ func (svc *otpService) VerifyOtpCode(ctx context.Context, req *request.VerifyOtp
 
 	}
 	if otpUpdate != nil {
-		txOtp := svc.otpRepository.Tx(ctx)
-		err = svc.otpRepository.UpdateOtpDataRepository(ctx, txOtp, otpData, otpUpdate)
+		err = svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+			return svc.otpRepository.UpdateOtpDataRepository(ctx, tx, otpData, otpUpdate)
+		})
 		if err != nil {
 			svc.clogger.ErrorLogger(ctx, "otpRepository.UpdateOtpDataRepository", err)
-			txOtp.Rollback()
 			return nil, err
 		}
-		txOtp.Commit()
+		if err = svc.CompleteDelivery(ctx, otpData); err != nil {
+			svc.clogger.ErrorLogger(ctx, "VerifyOtpCode.CompleteDelivery", err)
+		}
 		return svc.otpRepository.SelectOtpByVerifyKey(ctx, req.VerifyID)
 	}
 	return otpData, nil
@@ -0,0 +1,699 @@
+package paymentRequestService
+
+import (
+	"backend-mobile-api/helpers"
+	"backend-mobile-api/internal/repository/postgres"
+	"backend-mobile-api/model/dto"
+	"backend-mobile-api/model/dto/request"
+	"backend-mobile-api/model/dto/response"
+	"backend-mobile-api/model/entity"
+	"backend-mobile-api/model/enum"
+	"backend-mobile-api/model/enum/pkgErr"
+	"backend-mobile-api/service/notification"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"golang.org/x/sync/errgroup"
+	"gorm.io/gorm"
+	"math"
+	"strings"
+	"time"
+)
+
+const defaultPaymentRequestExpiredHours = 72
+
+type paymentRequestService struct {
+	paymentRequestRepository postgres.PaymentRequestRepository
+	userRepository           postgres.UserRepository
+	transactionRepository    postgres.TransactionRepository
+	notifier                 *notification.FirebaseNotifier
+	clog                     *helpers.CustomLogger
+	unitOfWork               postgres.UnitOfWork
+}
+
+// errParticipantAnswered is returned inside a unit of work when the participant was answered concurrently
+var errParticipantAnswered = errors.New("participant already responded")
+
+func NewPaymentRequestService(
+	paymentRequestRepository postgres.PaymentRequestRepository,
+	userRepository postgres.UserRepository,
+	transactionRepository postgres.TransactionRepository,
+	notifier *notification.FirebaseNotifier,
+	clog *helpers.CustomLogger,
+	unitOfWork postgres.UnitOfWork,
+) PaymentRequestService {
+	return &paymentRequestService{
+		paymentRequestRepository: paymentRequestRepository,
+		userRepository:           userRepository,
+		transactionRepository:    transactionRepository,
+		notifier:                 notifier,
+		clog:                     clog,
+		unitOfWork:               unitOfWork,
+	}
+}
+
+type PaymentRequestService interface {
+	CreatePaymentRequestService(ctx context.Context, req *request.CreatePaymentRequest, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse
+	InquiryPaymentRequestService(ctx context.Context, requestID *string, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse
+	OutgoingPaymentRequestService(ctx context.Context, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse
+	IncomingPaymentRequestService(ctx context.Context, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse
+	PayPaymentRequestService(ctx context.Context, req *request.PayPaymentRequest, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse
+	DeclinePaymentRequestService(ctx context.Context, req *request.DeclinePaymentRequest, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse
+	SettleTransaction(ctx context.Context, transactionID string, status string) (func(ctx context.Context), error)
+}
+
+func (svc *paymentRequestService) CreatePaymentRequestService(ctx context.Context, req *request.CreatePaymentRequest, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	requester, err := svc.userRepository.SelectUserByUUID(ctx, *userUUID)
+	if err != nil {
+		logData.Error = err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.PAYMENT_REQUEST_USER_NOT_FOUND_CODE,
+				Message:    pkgErr.USER_NOT_FOUND_MSG,
+				Error:      err.Error(),
+			}
+		}
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	logData.UserUUID = requester.UUID
+	logData.Email = requester.Email
+
+	participants := make([]*entity.User, len(req.Participants))
+	g := errgroup.Group{}
+	for i, participant := range req.Participants {
+		i, participant := i, participant
+		g.Go(func() error {
+			user, errTmp := svc.userRepository.SelectUserByEmailOrPhoneNumber(ctx, strings.TrimSpace(participant.Identity))
+			if errTmp != nil {
+				if errors.Is(errTmp, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("participant %s is not registered: %w", participant.Identity, errTmp)
+				}
+				return errTmp
+			}
+			participants[i] = user
+			return nil
+		})
+	}
+	if err = g.Wait(); err != nil {
+		logData.Error = err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.PAYMENT_REQUEST_USER_NOT_FOUND_CODE,
+				Message:    pkgErr.USER_NOT_FOUND_MSG,
+				Error:      err.Error(),
+			}
+		}
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+
+	seen := make(map[int64]bool, len(participants))
+	for _, participant := range participants {
+		if participant.ID == requester.ID || seen[participant.ID] || participant.Status == enum.USER_INACTIVE {
+			err = fmt.Errorf("invalid participant %s", participant.UUID)
+			logData.Error = err.Error()
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.PAYMENT_REQUEST_INVALID_PAYLOAD_CODE,
+				Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+				Error:      err.Error(),
+			}
+		}
+		seen[participant.ID] = true
+	}
+
+	amounts, total, err := splitAmount(req)
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.PAYMENT_REQUEST_INVALID_AMOUNT_CODE,
+			Message:    pkgErr.INVALID_AMOUNT_MSG,
+			Error:      err.Error(),
+		}
+	}
+
+	expiredHours := req.ExpiredInHours
+	if expiredHours == 0 {
+		expiredHours = defaultPaymentRequestExpiredHours
+	}
+	paymentRequest := entity.PaymentRequest{
+		RequestID:     uuid.New().String(),
+		RequesterID:   requester.ID,
+		RequesterUUID: requester.UUID,
+		SplitType:     req.SplitType,
+		TotalAmount:   total,
+		Note:          req.Note,
+		Status:        enum.PAYMENT_REQUEST_OPEN,
+		ExpiredAt:     time.Now().Add(time.Duration(expiredHours) * time.Hour),
+	}
+	for i, participant := range participants {
+		paymentRequest.Participants = append(paymentRequest.Participants, entity.PaymentRequestParticipant{
+			UserID:   participant.ID,
+			UserUUID: participant.UUID,
+			Amount:   amounts[i],
+			Status:   enum.PARTICIPANT_PENDING,
+		})
+	}
+
+	err = svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		return svc.paymentRequestRepository.InsertPaymentRequest(ctx, tx, &paymentRequest)
+	})
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+
+	for i, participant := range participants {
+		svc.notify(ctx, participant.UUID, "Permintaan Pembayaran",
+			fmt.Sprintf("%s meminta pembayaran sebesar Rp%.0f", requester.FullName, amounts[i]),
+			string(enum.PARTICIPANT_PENDING))
+	}
+
+	users := map[int64]*entity.User{requester.ID: requester}
+	for _, participant := range participants {
+		users[participant.ID] = participant
+	}
+	logData.Success = true
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+		Data:       toPaymentRequestResponse(&paymentRequest, users),
+	}
+}
+
+func (svc *paymentRequestService) InquiryPaymentRequestService(ctx context.Context, requestID *string, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	logData.UserUUID = *userUUID
+	if err := svc.paymentRequestRepository.ExpirePaymentRequests(ctx); err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	paymentRequest, resp := svc.selectPaymentRequest(ctx, *requestID, logData)
+	if resp != nil {
+		return resp
+	}
+	if paymentRequest.RequesterUUID != *userUUID && findParticipant(paymentRequest, *userUUID) == nil {
+		logData.Error = "user is not part of payment request"
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.PAYMENT_REQUEST_NOT_FOUND_CODE,
+			Message:    pkgErr.PAYMENT_REQUEST_NOT_FOUND_MSG,
+		}
+	}
+	users, err := svc.selectUsers(ctx, []entity.PaymentRequest{*paymentRequest})
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	logData.Success = true
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+		Data:       toPaymentRequestResponse(paymentRequest, users),
+	}
+}
+
+func (svc *paymentRequestService) OutgoingPaymentRequestService(ctx context.Context, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	return svc.listPaymentRequest(ctx, userUUID, logData, svc.paymentRequestRepository.SelectPaymentRequestsByRequester)
+}
+
+func (svc *paymentRequestService) IncomingPaymentRequestService(ctx context.Context, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	return svc.listPaymentRequest(ctx, userUUID, logData, svc.paymentRequestRepository.SelectPaymentRequestsByParticipant)
+}
+
+func (svc *paymentRequestService) listPaymentRequest(ctx context.Context, userUUID *string, logData *dto.CustomLoggerRequest, selectFunc func(ctx context.Context, userUUID string) ([]entity.PaymentRequest, error)) *dto.BaseResponse {
+	logData.UserUUID = *userUUID
+	if err := svc.paymentRequestRepository.ExpirePaymentRequests(ctx); err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	paymentRequests, err := selectFunc(ctx, *userUUID)
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	users, err := svc.selectUsers(ctx, paymentRequests)
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	data := make([]response.PaymentRequestResponse, 0, len(paymentRequests))
+	for i := range paymentRequests {
+		data = append(data, toPaymentRequestResponse(&paymentRequests[i], users))
+	}
+	logData.Success = true
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+		Data:       data,
+	}
+}
+
+func (svc *paymentRequestService) PayPaymentRequestService(ctx context.Context, req *request.PayPaymentRequest, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	paymentRequest, participant, resp := svc.selectPendingParticipant(ctx, req.RequestID, *userUUID, logData)
+	if resp != nil {
+		return resp
+	}
+
+	transactionID, err := svc.transactionRepository.GenerateTransactionID(ctx)
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	transaction := entity.Transaction{
+		TransactionID: transactionID,
+		Type:          enum.TRANSACTION_TYPE_PAYMENT_REQUEST,
+		PaymentMethod: req.PaymentMethod,
+		Description:   fmt.Sprintf("payment request %s", paymentRequest.RequestID),
+		Nominal:       participant.Amount,
+		Total:         participant.Amount,
+		Status:        enum.TRANSACTION_STATUS_PENDING,
+	}
+	// the transaction and the participant answer commit together, a participant
+	// answered concurrently leaves no payable transaction behind
+	err = svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		if err := svc.transactionRepository.CreateTransaction(ctx, &transaction, *userUUID); err != nil {
+			return err
+		}
+		// the participant is paid once the transaction succeeds, see SettleTransaction
+		now := time.Now()
+		return svc.answerParticipant(ctx, tx, paymentRequest, participant, &entity.PaymentRequestParticipant{
+			Status:        enum.PARTICIPANT_AWAITING_PAYMENT,
+			TransactionID: &transactionID,
+			RespondedAt:   &now,
+		})
+	})
+	if err != nil {
+		logData.Error = err.Error()
+		return answerErrorResponse(err)
+	}
+
+	logData.Success = true
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+		Data: response.PayPaymentRequestResponse{
+			RequestID:     paymentRequest.RequestID,
+			TransactionID: transaction.TransactionID,
+			Amount:        transaction.Nominal,
+			UniqueCode:    transaction.UniqueCode,
+			Total:         transaction.Total + transaction.UniqueCode,
+			ExpiredAt:     transaction.ExpiredAt,
+		},
+	}
+}
+
+func (svc *paymentRequestService) DeclinePaymentRequestService(ctx context.Context, req *request.DeclinePaymentRequest, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	paymentRequest, participant, resp := svc.selectPendingParticipant(ctx, req.RequestID, *userUUID, logData)
+	if resp != nil {
+		return resp
+	}
+	now := time.Now()
+	err := svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		return svc.answerParticipant(ctx, tx, paymentRequest, participant, &entity.PaymentRequestParticipant{
+			Status:      enum.PARTICIPANT_DECLINED,
+			RespondedAt: &now,
+		})
+	})
+	if err != nil {
+		logData.Error = err.Error()
+		return answerErrorResponse(err)
+	}
+
+	svc.notify(ctx, paymentRequest.RequesterUUID, "Permintaan Pembayaran Ditolak",
+		fmt.Sprintf("Permintaan pembayaran Rp%.0f ditolak", participant.Amount),
+		string(enum.PARTICIPANT_DECLINED))
+
+	logData.Success = true
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+	}
+}
+
+// SettleTransaction follows the transaction a participant pays with. A successful
+// transaction marks the participant paid, a failed, canceled or expired one gives
+// the participant the pending answer back so the request can be paid again.
+// It runs in the unit of work of the caller, the returned notify is non nil when
+// the requester has to be told and is only called, with a ctx outside the unit of
+// work, once that unit of work committed.
+func (svc *paymentRequestService) SettleTransaction(ctx context.Context, transactionID string, status string) (func(ctx context.Context), error) {
+	paymentRequest, err := svc.paymentRequestRepository.SelectPaymentRequestByTransactionID(ctx, transactionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	participant := findParticipantByTransaction(paymentRequest, transactionID)
+	if participant == nil || participant.Status != enum.PARTICIPANT_AWAITING_PAYMENT {
+		return nil, nil
+	}
+	switch status {
+	case enum.TRANSACTION_STATUS_SUCCESS:
+		err = svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+			affected, err := svc.paymentRequestRepository.UpdateAwaitingParticipant(ctx, tx, participant, &entity.PaymentRequestParticipant{
+				Status: enum.PARTICIPANT_PAID,
+			})
+			if err != nil {
+				return err
+			}
+			if affected == 0 {
+				// settled by a concurrent status update
+				return errParticipantAnswered
+			}
+			return svc.closeIfSettled(ctx, tx, paymentRequest)
+		})
+		if errors.Is(err, errParticipantAnswered) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) {
+			svc.notify(ctx, paymentRequest.RequesterUUID, "Permintaan Pembayaran Dibayar",
+				fmt.Sprintf("Permintaan pembayaran Rp%.0f telah dibayar", participant.Amount),
+				string(enum.PARTICIPANT_PAID))
+		}, nil
+	case enum.TRANSACTION_STATUS_FAILED, enum.TRANSACTION_STATUS_CANCELED, enum.TRANSACTION_STATUS_EXPIRED:
+		return nil, svc.releaseParticipant(ctx, participant)
+	}
+	return nil, nil
+}
+
+// releaseParticipant reopens the answer of a participant whose transaction ended without payment.
+func (svc *paymentRequestService) releaseParticipant(ctx context.Context, participant *entity.PaymentRequestParticipant) error {
+	err := svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		_, err := svc.paymentRequestRepository.ReleaseAwaitingParticipant(ctx, tx, participant)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	participant.Status = enum.PARTICIPANT_PENDING
+	participant.TransactionID = nil
+	participant.RespondedAt = nil
+	return nil
+}
+
+func (svc *paymentRequestService) selectPaymentRequest(ctx context.Context, requestID string, logData *dto.CustomLoggerRequest) (*entity.PaymentRequest, *dto.BaseResponse) {
+	paymentRequest, err := svc.paymentRequestRepository.SelectPaymentRequestByRequestID(ctx, requestID)
+	if err != nil {
+		logData.Error = err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &dto.BaseResponse{
+				StatusCode: pkgErr.PAYMENT_REQUEST_NOT_FOUND_CODE,
+				Message:    pkgErr.PAYMENT_REQUEST_NOT_FOUND_MSG,
+				Error:      err.Error(),
+			}
+		}
+		return nil, &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	return paymentRequest, nil
+}
+
+func (svc *paymentRequestService) selectPendingParticipant(ctx context.Context, requestID string, userUUID string, logData *dto.CustomLoggerRequest) (*entity.PaymentRequest, *entity.PaymentRequestParticipant, *dto.BaseResponse) {
+	logData.UserUUID = userUUID
+	if err := svc.paymentRequestRepository.ExpirePaymentRequests(ctx); err != nil {
+		logData.Error = err.Error()
+		return nil, nil, &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	paymentRequest, resp := svc.selectPaymentRequest(ctx, requestID, logData)
+	if resp != nil {
+		return nil, nil, resp
+	}
+	participant := findParticipant(paymentRequest, userUUID)
+	if participant == nil {
+		logData.Error = "user is not participant of payment request"
+		return nil, nil, &dto.BaseResponse{
+			StatusCode: pkgErr.PAYMENT_REQUEST_NOT_FOUND_CODE,
+			Message:    pkgErr.PAYMENT_REQUEST_NOT_FOUND_MSG,
+		}
+	}
+	if paymentRequest.Status == enum.PAYMENT_REQUEST_EXPIRED || participant.Status == enum.PARTICIPANT_EXPIRED {
+		logData.Error = "payment request expired"
+		return nil, nil, &dto.BaseResponse{
+			StatusCode: pkgErr.PAYMENT_REQUEST_EXPIRED_CODE,
+			Message:    pkgErr.EXPIRED_TIME_MSG,
+		}
+	}
+	if participant.Status == enum.PARTICIPANT_AWAITING_PAYMENT {
+		// transactions also expire in bulk without a status update, the stale payment is released here
+		transaction, err := svc.transactionRepository.FindTransactionByID(ctx, *participant.TransactionID)
+		if err != nil {
+			logData.Error = err.Error()
+			return nil, nil, &dto.BaseResponse{
+				StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+				Message:    pkgErr.SERVER_BUSY,
+				Error:      err.Error(),
+			}
+		}
+		stale := transaction.Status != enum.TRANSACTION_STATUS_PENDING && transaction.Status != enum.TRANSACTION_STATUS_SUCCESS
+		if stale || (transaction.Status == enum.TRANSACTION_STATUS_PENDING && transaction.ExpiredAt.Before(time.Now())) {
+			if err = svc.releaseParticipant(ctx, participant); err != nil {
+				logData.Error = err.Error()
+				return nil, nil, &dto.BaseResponse{
+					StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+					Message:    pkgErr.SERVER_BUSY,
+					Error:      err.Error(),
+				}
+			}
+		}
+	}
+	if participant.Status != enum.PARTICIPANT_PENDING {
+		logData.Error = fmt.Sprintf("participant already %s", participant.Status)
+		return nil, nil, &dto.BaseResponse{
+			StatusCode: pkgErr.PAYMENT_REQUEST_ALREADY_RESPONDED_CODE,
+			Message:    pkgErr.ALREADY_RESPONDED_MSG,
+		}
+	}
+	return paymentRequest, participant, nil
+}
+
+// answerParticipant stores the participant answer and closes the request once nobody is pending anymore,
+// it runs in the unit of work of the caller.
+func (svc *paymentRequestService) answerParticipant(ctx context.Context, tx *gorm.DB, paymentRequest *entity.PaymentRequest, participant *entity.PaymentRequestParticipant, answer *entity.PaymentRequestParticipant) error {
+	affected, err := svc.paymentRequestRepository.UpdatePendingParticipant(ctx, tx, participant, answer)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errParticipantAnswered
+	}
+	return svc.closeIfSettled(ctx, tx, paymentRequest)
+}
+
+func answerErrorResponse(err error) *dto.BaseResponse {
+	if errors.Is(err, errParticipantAnswered) {
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.PAYMENT_REQUEST_ALREADY_RESPONDED_CODE,
+			Message:    pkgErr.ALREADY_RESPONDED_MSG,
+		}
+	}
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+		Message:    pkgErr.SERVER_BUSY,
+		Error:      err.Error(),
+	}
+}
+
+// closeIfSettled closes an open request once every participant answered and
+// every payment went through. The request row is locked first, so of two answers
+// settling at the same time the second one reads the participants the first committed.
+func (svc *paymentRequestService) closeIfSettled(ctx context.Context, tx *gorm.DB, paymentRequest *entity.PaymentRequest) error {
+	locked, err := svc.paymentRequestRepository.LockPaymentRequest(ctx, tx, paymentRequest.ID)
+	if err != nil || locked.Status != enum.PAYMENT_REQUEST_OPEN {
+		return err
+	}
+	participants, err := svc.paymentRequestRepository.SelectParticipants(ctx, tx, paymentRequest.ID)
+	if err != nil {
+		return err
+	}
+	closed := enum.PAYMENT_REQUEST_COMPLETED
+	for _, participant := range participants {
+		switch participant.Status {
+		case enum.PARTICIPANT_PENDING, enum.PARTICIPANT_AWAITING_PAYMENT:
+			return nil
+		case enum.PARTICIPANT_PAID:
+		default:
+			closed = enum.PAYMENT_REQUEST_CLOSED
+		}
+	}
+	return svc.paymentRequestRepository.UpdatePaymentRequest(ctx, tx, locked, &entity.PaymentRequest{Status: closed})
+}
+
+func (svc *paymentRequestService) selectUsers(ctx context.Context, paymentRequests []entity.PaymentRequest) (map[int64]*entity.User, error) {
+	ids := make(map[int64]bool)
+	for _, paymentRequest := range paymentRequests {
+		ids[paymentRequest.RequesterID] = true
+		for _, participant := range paymentRequest.Participants {
+			ids[participant.UserID] = true
+		}
+	}
+	users := make(map[int64]*entity.User, len(ids))
+	for id := range ids {
+		user, err := svc.userRepository.SelectUserByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		users[id] = user
+	}
+	return users, nil
+}
+
+func (svc *paymentRequestService) notify(ctx context.Context, userUUID string, title string, body string, status string) {
+	if svc.notifier == nil {
+		return
+	}
+	device, err := svc.transactionRepository.FindDeviceByUserUUID(ctx, userUUID)
+	if err != nil || device.FCMToken == "" {
+		return
+	}
+	go func() {
+		if err := svc.notifier.SendPushNotification(device.FCMToken, title, body, status); err != nil {
+			svc.clog.ErrorLogger(context.Background(), "paymentRequestService.notifier.SendPushNotification", err)
+		}
+	}()
+}
+
+func findParticipant(paymentRequest *entity.PaymentRequest, userUUID string) *entity.PaymentRequestParticipant {
+	for i := range paymentRequest.Participants {
+		if paymentRequest.Participants[i].UserUUID == userUUID {
+			return &paymentRequest.Participants[i]
+		}
+	}
+	return nil
+}
+
+func findParticipantByTransaction(paymentRequest *entity.PaymentRequest, transactionID string) *entity.PaymentRequestParticipant {
+	for i := range paymentRequest.Participants {
+		if id := paymentRequest.Participants[i].TransactionID; id != nil && *id == transactionID {
+			return &paymentRequest.Participants[i]
+		}
+	}
+	return nil
+}
+
+// splitAmount returns the amount owed by every participant in request order and the bill total.
+// Amounts are whole rupiah, an even split leaves the rounding remainder on the first participant
+// or, when the requester takes a share, on the requester.
+func splitAmount(req *request.CreatePaymentRequest) ([]float64, float64, error) {
+	amounts := make([]float64, len(req.Participants))
+	switch req.SplitType {
+	case enum.SPLIT_EVEN:
+		total := math.Round(req.TotalAmount)
+		if total <= 0 {
+			return nil, 0, errors.New("total_amount must be greater than 0")
+		}
+		shares := float64(len(req.Participants))
+		if req.IncludeRequester {
+			shares++
+		}
+		share := math.Floor(total / shares)
+		if share <= 0 {
+			return nil, 0, errors.New("total_amount is too small to split")
+		}
+		for i := range amounts {
+			amounts[i] = share
+		}
+		if !req.IncludeRequester {
+			amounts[0] += total - share*shares
+		}
+		return amounts, total, nil
+	case enum.SPLIT_CUSTOM:
+		var sum float64
+		for i, participant := range req.Participants {
+			if participant.Amount <= 0 || participant.Amount != math.Floor(participant.Amount) {
+				return nil, 0, fmt.Errorf("invalid amount for participant %s", participant.Identity)
+			}
+			amounts[i] = participant.Amount
+			sum += participant.Amount
+		}
+		if req.TotalAmount == 0 {
+			return amounts, sum, nil
+		}
+		if req.IncludeRequester && sum > req.TotalAmount {
+			return nil, 0, errors.New("participant amounts exceed total_amount")
+		}
+		if !req.IncludeRequester && sum != req.TotalAmount {
+			return nil, 0, errors.New("participant amounts must equal total_amount")
+		}
+		return amounts, req.TotalAmount, nil
+	}
+	return nil, 0, fmt.Errorf("invalid split type %s", req.SplitType)
+}
+
+func toPaymentRequestResponse(paymentRequest *entity.PaymentRequest, users map[int64]*entity.User) response.PaymentRequestResponse {
+	resp := response.PaymentRequestResponse{
+		RequestID:     paymentRequest.RequestID,
+		RequesterUUID: paymentRequest.RequesterUUID,
+		SplitType:     paymentRequest.SplitType,
+		TotalAmount:   paymentRequest.TotalAmount,
+		Note:          paymentRequest.Note,
+		Status:        paymentRequest.Status,
+		ExpiredAt:     paymentRequest.ExpiredAt,
+		CreatedAt:     paymentRequest.CreatedAt,
+		Participants:  make([]response.PaymentRequestParticipantData, 0, len(paymentRequest.Participants)),
+	}
+	if requester, ok := users[paymentRequest.RequesterID]; ok {
+		resp.RequesterName = requester.FullName
+	}
+	for _, participant := range paymentRequest.Participants {
+		data := response.PaymentRequestParticipantData{
+			UserUUID:      participant.UserUUID,
+			Amount:        participant.Amount,
+			Status:        participant.Status,
+			TransactionID: participant.TransactionID,
+			RespondedAt:   participant.RespondedAt,
+		}
+		if user, ok := users[participant.UserID]; ok {
+			data.Name = user.FullName
+		}
+		resp.Participants = append(resp.Participants, data)
+	}
+	return resp
+}
@@ -0,0 +1,250 @@
+package pinAttemptSvc
+
+import (
+	"backend-mobile-api/app/config"
+	"backend-mobile-api/helpers"
+	redisRepos "backend-mobile-api/internal/repository/redis"
+	"backend-mobile-api/model/dto"
+	"backend-mobile-api/model/dto/response"
+	"backend-mobile-api/model/entity"
+	"backend-mobile-api/model/enum/pkgErr"
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"math"
+	"time"
+)
+
+// pinInFlight holds the delay key while a claimed attempt is being compared
+const pinInFlight = 10 * time.Second
+
+var (
+	ErrPinInvalid         = errors.New("invalid pin")
+	ErrPinAttemptTooSoon  = errors.New("pin attempt before delay elapsed")
+	ErrPinTemporaryLocked = errors.New("pin temporarily locked")
+	ErrPinPermanentLocked = errors.New("pin locked until reset")
+)
+
+// PinAttemptService guards every pin check against brute force. Failures are
+// counted per user and per device in redis: each failure doubles the wait
+// before the next attempt, every TemporaryLockAfter failures lock the pin for
+// a while and PermanentLockAfter failures lock it until forgot-pin.
+type PinAttemptService interface {
+	VerifyPin(ctx context.Context, user *entity.User, deviceID string, pin string) (*response.PinAttemptResponse, error)
+	Reset(ctx context.Context, userUUID string) error
+}
+
+type pinAttemptService struct {
+	redis   *redisRepos.Redis
+	config  *config.PinAttempt
+	clogger *helpers.CustomLogger
+}
+
+func NewPinAttemptService(redis *redisRepos.Redis, config *config.PinAttempt, clogger *helpers.CustomLogger) PinAttemptService {
+	return &pinAttemptService{
+		redis:   redis,
+		config:  config,
+		clogger: clogger,
+	}
+}
+
+// VerifyPin compares the pin with the user's hash unless the user or device is
+// locked. On rejection the returned status tells how many attempts are left
+// before the permanent lock and how long to wait before the next one.
+func (s *pinAttemptService) VerifyPin(ctx context.Context, user *entity.User, deviceID string, pin string) (*response.PinAttemptResponse, error) {
+	locked, ttl, err := s.redis.GetPinLock(ctx, redisRepos.PIN_SCOPE_USER, user.UUID)
+	if err != nil {
+		s.clogger.ErrorLogger(ctx, "VerifyPin.redis.GetPinLock", err)
+		return nil, err
+	}
+	if locked {
+		if ttl < 0 {
+			return &response.PinAttemptResponse{}, ErrPinPermanentLocked
+		}
+		return &response.PinAttemptResponse{
+			RemainingAttempts: s.remaining(ctx, user.UUID),
+			RetryAfter:        seconds(ttl),
+		}, ErrPinTemporaryLocked
+	}
+	if deviceID != "" {
+		locked, ttl, err = s.redis.GetPinLock(ctx, redisRepos.PIN_SCOPE_DEVICE, deviceID)
+		if err != nil {
+			s.clogger.ErrorLogger(ctx, "VerifyPin.redis.GetPinLock", err)
+			return nil, err
+		}
+		if locked {
+			return &response.PinAttemptResponse{
+				RemainingAttempts: s.remaining(ctx, user.UUID),
+				RetryAfter:        seconds(ttl),
+			}, ErrPinTemporaryLocked
+		}
+	}
+	// the attempt is reserved before bcrypt runs: the delay key admits one attempt
+	// at a time and the counters already hold it, so parallel guesses cannot
+	// all pass the checks above before the first failure is recorded
+	claimed, err := s.redis.ClaimPinDelay(ctx, user.UUID, pinInFlight)
+	if err != nil {
+		s.clogger.ErrorLogger(ctx, "VerifyPin.redis.ClaimPinDelay", err)
+		return nil, err
+	}
+	if !claimed {
+		delay, err := s.redis.GetPinDelay(ctx, user.UUID)
+		if err != nil {
+			s.clogger.ErrorLogger(ctx, "VerifyPin.redis.GetPinDelay", err)
+			return nil, err
+		}
+		return &response.PinAttemptResponse{
+			RemainingAttempts: s.remaining(ctx, user.UUID),
+			RetryAfter:        seconds(delay),
+		}, ErrPinAttemptTooSoon
+	}
+	failures, err := s.redis.IncrPinAttempt(ctx, redisRepos.PIN_SCOPE_USER, user.UUID, s.config.Window)
+	if err != nil {
+		s.clogger.ErrorLogger(ctx, "VerifyPin.redis.IncrPinAttempt", err)
+		return nil, err
+	}
+	var deviceFailures int64
+	if deviceID != "" {
+		deviceFailures, err = s.redis.IncrPinAttempt(ctx, redisRepos.PIN_SCOPE_DEVICE, deviceID, s.config.Window)
+		if err != nil {
+			s.clogger.ErrorLogger(ctx, "VerifyPin.redis.IncrPinAttempt", err)
+			return nil, err
+		}
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.Pin), []byte(pin)) == nil {
+		s.release(ctx, user.UUID, deviceID)
+		return nil, nil
+	}
+	return s.fail(ctx, user.UUID, deviceID, failures, deviceFailures)
+}
+
+// release hands back the reserved attempt of a correct pin. The device counter
+// only loses this attempt, a correct pin on one account must not reset tries on others.
+func (s *pinAttemptService) release(ctx context.Context, userUUID string, deviceID string) {
+	if err := s.redis.DeletePinAttempt(ctx, redisRepos.PIN_SCOPE_USER, userUUID); err != nil {
+		s.clogger.ErrorLogger(ctx, "VerifyPin.redis.DeletePinAttempt", err)
+	}
+	if deviceID != "" {
+		if err := s.redis.DecrPinAttempt(ctx, redisRepos.PIN_SCOPE_DEVICE, deviceID); err != nil {
+			s.clogger.ErrorLogger(ctx, "VerifyPin.redis.DecrPinAttempt", err)
+		}
+	}
+	if err := s.redis.DeletePinDelay(ctx, userUUID); err != nil {
+		s.clogger.ErrorLogger(ctx, "VerifyPin.redis.DeletePinDelay", err)
+	}
+}
+
+// Reset clears the counters and locks of a user, called once the pin was changed.
+func (s *pinAttemptService) Reset(ctx context.Context, userUUID string) error {
+	if err := s.redis.DeletePinAttempt(ctx, redisRepos.PIN_SCOPE_USER, userUUID); err != nil {
+		s.clogger.ErrorLogger(ctx, "Reset.redis.DeletePinAttempt", err)
+		return err
+	}
+	if err := s.redis.DeletePinLock(ctx, redisRepos.PIN_SCOPE_USER, userUUID); err != nil {
+		s.clogger.ErrorLogger(ctx, "Reset.redis.DeletePinLock", err)
+		return err
+	}
+	if err := s.redis.DeletePinDelay(ctx, userUUID); err != nil {
+		s.clogger.ErrorLogger(ctx, "Reset.redis.DeletePinDelay", err)
+		return err
+	}
+	return nil
+}
+
+func (s *pinAttemptService) fail(ctx context.Context, userUUID string, deviceID string, failures int64, deviceFailures int64) (*response.PinAttemptResponse, error) {
+	if deviceID != "" && deviceFailures >= s.config.DeviceLockAfter {
+		s.clogger.WarnLogger(ctx, fmt.Sprintf("VerifyPin: device %s locked after %d wrong pin", deviceID, deviceFailures))
+		if err := s.redis.SetPinLock(ctx, redisRepos.PIN_SCOPE_DEVICE, deviceID, s.config.TemporaryLockDuration); err != nil {
+			s.clogger.ErrorLogger(ctx, "VerifyPin.redis.SetPinLock", err)
+			return nil, err
+		}
+	}
+
+	remaining := s.config.PermanentLockAfter - failures
+	if remaining <= 0 {
+		s.clogger.WarnLogger(ctx, fmt.Sprintf("VerifyPin: user %s pin locked after %d wrong pin", userUUID, failures))
+		if err := s.redis.SetPinLock(ctx, redisRepos.PIN_SCOPE_USER, userUUID, 0); err != nil {
+			s.clogger.ErrorLogger(ctx, "VerifyPin.redis.SetPinLock", err)
+			return nil, err
+		}
+		return &response.PinAttemptResponse{}, ErrPinPermanentLocked
+	}
+	if s.config.TemporaryLockAfter > 0 && failures%s.config.TemporaryLockAfter == 0 {
+		if err := s.redis.SetPinLock(ctx, redisRepos.PIN_SCOPE_USER, userUUID, s.config.TemporaryLockDuration); err != nil {
+			s.clogger.ErrorLogger(ctx, "VerifyPin.redis.SetPinLock", err)
+			return nil, err
+		}
+		return &response.PinAttemptResponse{
+			RemainingAttempts: remaining,
+			RetryAfter:        seconds(s.config.TemporaryLockDuration),
+		}, ErrPinTemporaryLocked
+	}
+
+	delay := s.config.MaxDelay
+	if shift := failures - 1; shift < 32 {
+		if d := s.config.BaseDelay << shift; d > 0 && d < delay {
+			delay = d
+		}
+	}
+	if err := s.redis.SetPinDelay(ctx, userUUID, delay); err != nil {
+		s.clogger.ErrorLogger(ctx, "VerifyPin.redis.SetPinDelay", err)
+		return nil, err
+	}
+	return &response.PinAttemptResponse{
+		RemainingAttempts: remaining,
+		RetryAfter:        seconds(delay),
+	}, ErrPinInvalid
+}
+
+func (s *pinAttemptService) remaining(ctx context.Context, userUUID string) int64 {
+	failures, err := s.redis.GetPinAttempt(ctx, redisRepos.PIN_SCOPE_USER, userUUID)
+	if err != nil {
+		s.clogger.ErrorLogger(ctx, "VerifyPin.redis.GetPinAttempt", err)
+	}
+	if remaining := s.config.PermanentLockAfter - failures; remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+func seconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
+
+// ErrorResponse maps a VerifyPin error to the response of the calling service,
+// a wrong pin keeps the caller's own code and message.
+func ErrorResponse(err error, status *response.PinAttemptResponse, invalidCode pkgErr.Code, invalidMsg string) *dto.BaseResponse {
+	switch {
+	case errors.Is(err, ErrPinInvalid):
+		return &dto.BaseResponse{
+			StatusCode: invalidCode,
+			Message:    invalidMsg,
+			Data:       status,
+		}
+	case errors.Is(err, ErrPinAttemptTooSoon):
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.PIN_ATTEMPT_TOO_SOON_CODE,
+			Message:    pkgErr.PIN_ATTEMPT_TOO_SOON_MSG,
+			Data:       status,
+		}
+	case errors.Is(err, ErrPinTemporaryLocked):
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.PIN_TEMPORARY_LOCKED_CODE,
+			Message:    pkgErr.PIN_TEMPORARY_LOCKED_MSG,
+			Data:       status,
+		}
+	case errors.Is(err, ErrPinPermanentLocked):
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.PIN_PERMANENT_LOCKED_CODE,
+			Message:    pkgErr.PIN_PERMANENT_LOCKED_MSG,
+			Data:       status,
+		}
+	}
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+		Message:    pkgErr.SERVER_BUSY,
+		Error:      err.Error(),
+	}
+}
@@ -1,50 +1,309 @@
 package recipientsvc
 
 import (
+	"backend-mobile-api/app/config"
+	"backend-mobile-api/helpers"
 	"backend-mobile-api/internal/repository/postgres"
 	"backend-mobile-api/model/entity"
+	accountinquirysvc "backend-mobile-api/service/account-inquiry-svc"
+	"backend-mobile-api/service/notification"
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRecipientNotFound      = errors.New("recipient not found")
+	ErrRecipientAlreadyExists = errors.New("recipient already exists")
+	ErrBankNotFound           = errors.New("bank not found")
+	ErrBankCodeMissing        = errors.New("bank has no bank code, account inquiry is not available")
+	ErrRecipientNameMismatch  = errors.New("recipient name does not match account inquiry")
 )
 
 type RecipientService interface {
-	GetRecipients(ctx context.Context) ([]entity.RecipientWithBank, error)
-	SearchRecipients(ctx context.Context, keyword string) ([]entity.RecipientWithBank, error)
-	AddRecipient(ctx context.Context, recipient *entity.Recipient) error
+	GetRecipients(ctx context.Context, userUUID string) ([]entity.RecipientWithBank, error)
+	SearchRecipients(ctx context.Context, userUUID string, keyword string) ([]entity.RecipientWithBank, error)
+	AddRecipient(ctx context.Context, userUUID string, recipient *entity.Recipient) error
+	UpdateRecipient(ctx context.Context, userUUID string, recipientID uint, update *RecipientUpdate) (*entity.Recipient, error)
+	DeleteRecipient(ctx context.Context, userUUID string, recipientID uint) error
+	SetFavoriteRecipient(ctx context.Context, userUUID string, recipientID uint, favorite bool) (*entity.Recipient, error)
+	GetRecipientSuggestions(ctx context.Context, userUUID string, limit int) ([]entity.RecipientSuggestion, error)
 	GetUserByUUID(ctx context.Context, uuid string) (*entity.User, error)
 }
 
+const (
+	suggestionLookback     = 180 * 24 * time.Hour // riwayat yang dihitung untuk suggestion
+	suggestionHalfLifeDays = 30                   // bobot transaksi turun setengah tiap 30 hari
+	suggestionDefaultLimit = 10
+	suggestionMaxLimit     = 50
+)
+
+// RecipientUpdate berisi field yang boleh diubah, nil berarti tidak diubah.
+// nama_penerima tidak bisa diubah langsung, selalu diambil dari hasil inquiry rekening.
+type RecipientUpdate struct {
+	Bank       *int
+	NoRekening *string
+	Alias      *string
+}
+
 type recipientService struct {
-	repo postgres.RecipientRepository
+	repo           postgres.RecipientRepository
+	bankRepo       postgres.BankListRepository
+	deviceRepo     postgres.DeviceRepository
+	accountInquiry accountinquirysvc.AccountInquiryService
+	notifier       *notification.FirebaseNotifier
+	policy         *config.RecipientPolicy
+	clogger        *helpers.CustomLogger
 }
 
-func NewRecipientService(repo postgres.RecipientRepository) RecipientService {
+func NewRecipientService(
+	repo postgres.RecipientRepository,
+	bankRepo postgres.BankListRepository,
+	deviceRepo postgres.DeviceRepository,
+	accountInquiry accountinquirysvc.AccountInquiryService,
+	notifier *notification.FirebaseNotifier,
+	policy *config.RecipientPolicy,
+	clogger *helpers.CustomLogger,
+) RecipientService {
 	if repo == nil {
 		log.Println("[ERROR] repo nil saat init service")
 	}
-	return &recipientService{repo: repo}
+	return &recipientService{
+		repo:           repo,
+		bankRepo:       bankRepo,
+		deviceRepo:     deviceRepo,
+		accountInquiry: accountInquiry,
+		notifier:       notifier,
+		policy:         policy,
+		clogger:        clogger,
+	}
 }
 
-// ✅ ambil semua recipient beserta url_image bank
-func (s *recipientService) GetRecipients(ctx context.Context) ([]entity.RecipientWithBank, error) {
-	if s == nil {
-		log.Println("[ERROR] service struct nil")
+// ✅ ambil semua recipient milik user beserta url_image bank
+func (s *recipientService) GetRecipients(ctx context.Context, userUUID string) ([]entity.RecipientWithBank, error) {
+	user, err := s.repo.FindUserByUUID(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetAllRecipients(ctx, user.ID)
+}
+
+// ✅ search recipient milik user berdasarkan nama / alias / no rekening
+func (s *recipientService) SearchRecipients(ctx context.Context, userUUID string, keyword string) ([]entity.RecipientWithBank, error) {
+	user, err := s.repo.FindUserByUUID(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.SearchRecipients(ctx, user.ID, keyword)
+}
+
+// ✅ insert recipient baru, owner diambil dari uuid JWT
+func (s *recipientService) AddRecipient(ctx context.Context, userUUID string, recipient *entity.Recipient) error {
+	user, err := s.repo.FindUserByUUID(ctx, userUUID)
+	if err != nil {
+		return err
+	}
+	recipient.RecipientID = 0
+	recipient.User = user.ID
+	recipient.UserUUID = user.UUID
+	if err := s.ensureUniqueAccount(ctx, user.ID, recipient.Bank, recipient.NoRekening, 0); err != nil {
+		return err
+	}
+	name, err := s.confirmedName(ctx, user.UUID, recipient.Bank, recipient.NoRekening)
+	if err != nil {
+		return err
 	}
-	// if s.repo == nil {
-	// 	log.Println("[ERROR] service.repo nil sebelum dipakai")
-	// }
-	return s.repo.GetAllRecipients(ctx)
+	if recipient.NamaPenerima != "" && !strings.EqualFold(strings.TrimSpace(recipient.NamaPenerima), strings.TrimSpace(name)) {
+		return ErrRecipientNameMismatch
+	}
+	recipient.NamaPenerima = name
+	if err := s.repo.InsertRecipient(ctx, recipient); err != nil {
+		return duplicateRecipient(err)
+	}
+	s.notifyRecipientAdded(ctx, user, recipient)
+	return nil
 }
 
-// ✅ search recipient berdasarkan nama / no rekening
-func (s *recipientService) SearchRecipients(ctx context.Context, keyword string) ([]entity.RecipientWithBank, error) {
-	return s.repo.SearchRecipients(ctx, keyword)
+// ✅ update recipient milik user
+func (s *recipientService) UpdateRecipient(ctx context.Context, userUUID string, recipientID uint, update *RecipientUpdate) (*entity.Recipient, error) {
+	user, recipient, err := s.findOwnedRecipient(ctx, userUUID, recipientID)
+	if err != nil {
+		return nil, err
+	}
+
+	accountChanged := (update.Bank != nil && *update.Bank != recipient.Bank) ||
+		(update.NoRekening != nil && *update.NoRekening != recipient.NoRekening)
+	updates := map[string]interface{}{}
+	if update.Bank != nil {
+		updates["bank_id"] = *update.Bank
+		recipient.Bank = *update.Bank
+	}
+	if update.NoRekening != nil {
+		updates["no_rekening"] = *update.NoRekening
+		recipient.NoRekening = *update.NoRekening
+	}
+	if update.Alias != nil {
+		updates["alias"] = *update.Alias
+		recipient.Alias = *update.Alias
+	}
+	if len(updates) == 0 {
+		return recipient, nil
+	}
+	if update.Bank != nil || update.NoRekening != nil {
+		if err := s.ensureUniqueAccount(ctx, recipient.User, recipient.Bank, recipient.NoRekening, recipient.RecipientID); err != nil {
+			return nil, err
+		}
+		name, err := s.confirmedName(ctx, userUUID, recipient.Bank, recipient.NoRekening)
+		if err != nil {
+			return nil, err
+		}
+		updates["nama_penerima"] = name
+		recipient.NamaPenerima = name
+	}
+	// rekening baru dianggap penerima baru, cooling-off dihitung ulang dari sekarang
+	if accountChanged {
+		recipient.CreatedAt = time.Now()
+		updates["created_at"] = recipient.CreatedAt
+	}
+	if err := s.repo.UpdateRecipient(ctx, recipient, updates); err != nil {
+		return nil, duplicateRecipient(err)
+	}
+	if accountChanged {
+		s.notifyRecipientAdded(ctx, user, recipient)
+	}
+	return recipient, nil
 }
 
-// ✅ insert recipient baru
-func (s *recipientService) AddRecipient(ctx context.Context, recipient *entity.Recipient) error {
-	return s.repo.InsertRecipient(ctx, recipient)
+// ✅ hapus recipient milik user
+func (s *recipientService) DeleteRecipient(ctx context.Context, userUUID string, recipientID uint) error {
+	_, recipient, err := s.findOwnedRecipient(ctx, userUUID, recipientID)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteRecipient(ctx, recipient)
 }
+
+// ✅ tandai / lepas recipient favorit (pin)
+func (s *recipientService) SetFavoriteRecipient(ctx context.Context, userUUID string, recipientID uint, favorite bool) (*entity.Recipient, error) {
+	_, recipient, err := s.findOwnedRecipient(ctx, userUUID, recipientID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateRecipient(ctx, recipient, map[string]interface{}{"is_favorite": favorite}); err != nil {
+		return nil, err
+	}
+	recipient.IsFavorite = favorite
+	return recipient, nil
+}
+
 func (s *recipientService) GetUserByUUID(ctx context.Context, uuid string) (*entity.User, error) {
 	return s.repo.FindUserByUUID(ctx, uuid) // panggil repo
 }
+
+func (s *recipientService) findOwnedRecipient(ctx context.Context, userUUID string, recipientID uint) (*entity.User, *entity.Recipient, error) {
+	user, err := s.repo.FindUserByUUID(ctx, userUUID)
+	if err != nil {
+		return nil, nil, err
+	}
+	recipient, err := s.repo.FindRecipientByID(ctx, user.ID, recipientID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrRecipientNotFound
+		}
+		return nil, nil, err
+	}
+	recipient.UserUUID = user.UUID
+	return user, recipient, nil
+}
+
+// duplicateRecipient memetakan unique violation dari insert/update bersamaan ke ErrRecipientAlreadyExists
+func duplicateRecipient(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrRecipientAlreadyExists
+	}
+	return err
+}
+
+// ensureUniqueAccount menolak (user, bank, no rekening) yang sudah tersimpan di recipient lain
+func (s *recipientService) ensureUniqueAccount(ctx context.Context, userID int64, bankID int, noRekening string, exceptID uint) error {
+	existing, err := s.repo.FindRecipientByAccount(ctx, userID, bankID, noRekening)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if existing.RecipientID != exceptID {
+		return ErrRecipientAlreadyExists
+	}
+	return nil
+}
+
+// confirmedName mengambil nama rekening yang sudah dikonfirmasi lewat check-account
+func (s *recipientService) confirmedName(ctx context.Context, userUUID string, bankID int, noRekening string) (string, error) {
+	bank, err := s.bankRepo.FindBankByID(ctx, bankID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrBankNotFound
+		}
+		return "", err
+	}
+	if bank.BankCode == "" {
+		return "", fmt.Errorf("%w: bank %d", ErrBankCodeMissing, bankID)
+	}
+	return s.accountInquiry.ConfirmedAccountName(ctx, userUUID, bank.BankCode, noRekening)
+}
+
+// ✅ tujuan transaksi yang paling sering & terakhir dipakai user, termasuk nominal terakhir
+func (s *recipientService) GetRecipientSuggestions(ctx context.Context, userUUID string, limit int) ([]entity.RecipientSuggestion, error) {
+	user, err := s.repo.FindUserByUUID(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = suggestionDefaultLimit
+	}
+	if limit > suggestionMaxLimit {
+		limit = suggestionMaxLimit
+	}
+	return s.repo.GetRecipientSuggestions(ctx, user.ID, time.Now().Add(-suggestionLookback), suggestionHalfLifeDays, limit)
+}
+
+// notifyRecipientAdded kirim push notif ke device user, supaya penambahan recipient oleh orang lain cepat ketahuan
+func (s *recipientService) notifyRecipientAdded(ctx context.Context, user *entity.User, recipient *entity.Recipient) {
+	if s.notifier == nil || s.policy == nil || !s.policy.NotifyOnAdd {
+		return
+	}
+	devices, err := s.deviceRepo.SelectTrustedDevices(ctx, uint(user.ID))
+	if err != nil {
+		s.clogger.ErrorLogger(ctx, "notifyRecipientAdded.deviceRepo.SelectTrustedDevices", err)
+		return
+	}
+	body := fmt.Sprintf("Penerima baru %s (%s) ditambahkan. Jika bukan kamu, segera ganti PIN.", recipient.NamaPenerima, maskAccount(recipient.NoRekening))
+	if s.policy.CoolingOffPeriod > 0 {
+		body += fmt.Sprintf(" Transfer ke penerima ini dibatasi selama %s.", s.policy.CoolingOffPeriod)
+	}
+	bgCtx := helpers.WrapContext(ctx)
+	for _, device := range devices {
+		if device.FCMToken == "" {
+			continue
+		}
+		go func() {
+			if err := s.notifier.SendPushNotification(device.FCMToken, "Penerima Baru Ditambahkan", body, "recipient_added"); err != nil {
+				s.clogger.ErrorLogger(bgCtx, "notifyRecipientAdded.notifier.SendPushNotification", err)
+			}
+		}()
+	}
+}
+
+func maskAccount(noRekening string) string {
+	if len(noRekening) <= 4 {
+		return noRekening
+	}
+	return strings.Repeat("*", len(noRekening)-4) + noRekening[len(noRekening)-4:]
+}
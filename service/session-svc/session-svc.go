@@ -0,0 +1,154 @@
+package sessionSvc
+
+import (
+	"backend-mobile-api/helpers"
+	"backend-mobile-api/internal/repository/postgres"
+	"backend-mobile-api/model/dto"
+	"backend-mobile-api/model/dto/response"
+	"backend-mobile-api/model/enum/pkgErr"
+	tokenFamilySvc "backend-mobile-api/service/token-family-svc"
+	"context"
+	"errors"
+	"gorm.io/gorm"
+)
+
+type sessionService struct {
+	tokenFamilyRepository postgres.TokenFamilyRepository
+	tokenFamilyService    tokenFamilySvc.TokenFamilyService
+	clogger               *helpers.CustomLogger
+}
+
+func NewSessionService(
+	tokenFamilyRepository postgres.TokenFamilyRepository,
+	tokenFamilyService tokenFamilySvc.TokenFamilyService,
+	clogger *helpers.CustomLogger,
+) SessionService {
+	return &sessionService{
+		tokenFamilyRepository: tokenFamilyRepository,
+		tokenFamilyService:    tokenFamilyService,
+		clogger:               clogger,
+	}
+}
+
+// SessionService exposes the token families of a user as login sessions.
+// Revoking a session blacklists every token still valid in its family.
+type SessionService interface {
+	InquirySessionService(ctx context.Context, userUUID string, currentSessionID string, logData *dto.CustomLoggerRequest) *dto.BaseResponse
+	RevokeSessionService(ctx context.Context, userUUID string, sessionID string, logData *dto.CustomLoggerRequest) *dto.BaseResponse
+	RevokeOtherSessionService(ctx context.Context, userUUID string, currentSessionID string, logData *dto.CustomLoggerRequest) *dto.BaseResponse
+}
+
+func (svc *sessionService) InquirySessionService(ctx context.Context, userUUID string, currentSessionID string, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	logData.UserUUID = userUUID
+	families, err := svc.tokenFamilyRepository.SelectActiveFamiliesByUser(ctx, userUUID)
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	sessions := make([]response.SessionResponse, 0, len(families))
+	for _, family := range families {
+		sessions = append(sessions, response.SessionResponse{
+			ID:         family.FamilyID,
+			DeviceID:   family.DeviceID,
+			AppVersion: family.AppVersion,
+			IPAddress:  family.IPAddress,
+			Latitude:   family.Latitude,
+			Longitude:  family.Longitude,
+			CreatedAt:  family.CreatedAt,
+			LastSeenAt: family.LastSeenAt,
+			Current:    family.FamilyID == currentSessionID,
+		})
+	}
+	logData.Success = true
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+		Data:       sessions,
+	}
+}
+
+func (svc *sessionService) RevokeSessionService(ctx context.Context, userUUID string, sessionID string, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	logData.UserUUID = userUUID
+	family, err := svc.tokenFamilyRepository.SelectTokenFamilyByFamilyID(ctx, sessionID)
+	if err != nil {
+		logData.Error = err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.SESSION_NOT_FOUND_CODE,
+				Message:    pkgErr.SESSION_NOT_FOUND_MSG,
+			}
+		}
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	// another user's session is reported as not found, not forbidden
+	if family.UserUUID != userUUID || family.RevokedAt != nil {
+		logData.Error = "session not found"
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.SESSION_NOT_FOUND_CODE,
+			Message:    pkgErr.SESSION_NOT_FOUND_MSG,
+		}
+	}
+	if err = svc.tokenFamilyService.RevokeFamily(ctx, family.FamilyID, tokenFamilySvc.REVOKE_REASON_SESSION); err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	logData.Success = true
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+	}
+}
+
+func (svc *sessionService) RevokeOtherSessionService(ctx context.Context, userUUID string, currentSessionID string, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	logData.UserUUID = userUUID
+	if currentSessionID == "" {
+		// token issued before sessions existed, refresh first so the current session is known
+		logData.Error = "current session unknown"
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.SESSION_NOT_FOUND_CODE,
+			Message:    pkgErr.SESSION_NOT_FOUND_MSG,
+		}
+	}
+	families, err := svc.tokenFamilyRepository.SelectActiveFamiliesByUser(ctx, userUUID)
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	revoked := 0
+	for _, family := range families {
+		if family.FamilyID == currentSessionID {
+			continue
+		}
+		if err = svc.tokenFamilyService.RevokeFamily(ctx, family.FamilyID, tokenFamilySvc.REVOKE_REASON_OTHERS); err != nil {
+			logData.Error = err.Error()
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+				Message:    pkgErr.SERVER_BUSY,
+				Error:      err.Error(),
+			}
+		}
+		revoked++
+	}
+	logData.Success = true
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+		Data:       response.RevokeSessionsResponse{Revoked: revoked},
+	}
+}
@@ -0,0 +1,438 @@
+package tokenFamilySvc
+
+import (
+	"backend-mobile-api/app/config"
+	"backend-mobile-api/helpers"
+	"backend-mobile-api/internal/middleware"
+	"backend-mobile-api/internal/repository/postgres"
+	redisRepos "backend-mobile-api/internal/repository/redis"
+	"backend-mobile-api/model/dto"
+	"backend-mobile-api/model/entity"
+	"backend-mobile-api/model/enum"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid")
+	ErrRefreshTokenReused  = errors.New("refresh token already used, token family revoked")
+
+	// errFamilyTokenUsed rolls a rotation back when the token was rotated concurrently
+	errFamilyTokenUsed = errors.New("refresh token rotated concurrently")
+)
+
+const (
+	REVOKE_REASON_REUSE      = "refresh-token reuse"
+	REVOKE_REASON_NEW_LOGIN  = "new login on device"
+	REVOKE_REASON_LOGOUT     = "log-out"
+	REVOKE_REASON_LEGACY     = "legacy refresh-token rotated"
+	REVOKE_REASON_SESSION    = "session revoked by user"
+	REVOKE_REASON_OTHERS     = "logged out from other device"
+	REVOKE_REASON_REPLACED   = "device replaced by new device"
+	REVOKE_REASON_UNTRUSTED  = "device removed from trusted devices"
+	REVOKE_REASON_CREDENTIAL = "pin, email or phone number changed"
+	REVOKE_REASON_DELETION   = "account deletion requested"
+)
+
+type TokenFamilyService interface {
+	IssueTokens(ctx context.Context, user *entity.User, deviceID string) (*middleware.TokenData, error)
+	RotateTokens(ctx context.Context, refreshToken string, user *entity.User, deviceID string) (*middleware.TokenData, error)
+	RevokeFamily(ctx context.Context, familyID string, reason string) error
+	RetireTokenVersion(ctx context.Context, user *entity.User, currentFamilyID string, deviceID string, reason string) (*middleware.TokenData, error)
+	SessionDevice(ctx context.Context, userUUID string, familyID string) (string, error)
+}
+
+type tokenFamilyService struct {
+	repo           postgres.TokenFamilyRepository
+	tokenBlacklist postgres.TokenBlacklistTokenRepository
+	roleRepository postgres.RoleRepository
+	middleware     middleware.CustomMiddleware
+	redis          *redisRepos.Redis
+	jwtConfig      *config.Jwt
+	clogger        *helpers.CustomLogger
+	unitOfWork     postgres.UnitOfWork
+}
+
+func NewTokenFamilyService(
+	repo postgres.TokenFamilyRepository,
+	tokenBlacklist postgres.TokenBlacklistTokenRepository,
+	roleRepository postgres.RoleRepository,
+	middleware middleware.CustomMiddleware,
+	redis *redisRepos.Redis,
+	jwtConfig *config.Jwt,
+	clogger *helpers.CustomLogger,
+	unitOfWork postgres.UnitOfWork,
+) TokenFamilyService {
+	return &tokenFamilyService{
+		repo:           repo,
+		tokenBlacklist: tokenBlacklist,
+		roleRepository: roleRepository,
+		middleware:     middleware,
+		redis:          redis,
+		jwtConfig:      jwtConfig,
+		clogger:        clogger,
+		unitOfWork:     unitOfWork,
+	}
+}
+
+// IssueTokens starts a new token family for the device. Any family still
+// active on the same device is revoked, so one device holds one session.
+func (s *tokenFamilyService) IssueTokens(ctx context.Context, user *entity.User, deviceID string) (*middleware.TokenData, error) {
+	families, err := s.repo.SelectActiveFamiliesByDevice(ctx, user.UUID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	for _, family := range families {
+		if err = s.RevokeFamily(ctx, family.FamilyID, REVOKE_REASON_NEW_LOGIN); err != nil {
+			return nil, err
+		}
+	}
+
+	family := &entity.TokenFamily{
+		FamilyID: uuid.NewString(),
+		UserID:   user.ID,
+		UserUUID: user.UUID,
+		DeviceID: deviceID,
+	}
+	applySessionMetadata(ctx, family)
+	var tokenData *middleware.TokenData
+	err = s.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		if err := s.repo.InsertTokenFamily(ctx, tx, family); err != nil {
+			return err
+		}
+		pair, err := s.issuePair(ctx, tx, family.FamilyID, user)
+		tokenData = pair
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = s.attachSigningSecret(ctx, tokenData, family.FamilyID, true); err != nil {
+		return nil, err
+	}
+	return tokenData, nil
+}
+
+// RotateTokens exchanges a refresh token for a new pair in the same family.
+// A refresh token can be exchanged once; presenting it again means it was
+// copied, so the whole family is revoked and ErrRefreshTokenReused returned.
+func (s *tokenFamilyService) RotateTokens(ctx context.Context, refreshToken string, user *entity.User, deviceID string) (*middleware.TokenData, error) {
+	claims, err := s.middleware.ParseRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if claims.Uuid != user.UUID || claims.Version < user.TokenVersion {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	// token issued before families existed: retire it and start a family
+	if claims.FamilyID == "" || claims.TokenID == "" {
+		now := time.Now()
+		err = s.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+			return s.tokenBlacklist.InsertBlaclistToken(ctx, tx, &entity.TokenBlacklist{
+				Token:       helpers.HashToken(refreshToken),
+				BlacklistAt: now,
+				ExpiredAt:   now.Add(s.jwtConfig.RefreshExpiration),
+				Description: "refresh-token " + REVOKE_REASON_LEGACY,
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+		return s.IssueTokens(ctx, user, deviceID)
+	}
+
+	family, err := s.repo.SelectTokenFamilyByFamilyID(ctx, claims.FamilyID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+	if family.RevokedAt != nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if family.UserUUID != user.UUID || family.DeviceID != deviceID {
+		return nil, s.reportReuse(ctx, family, claims.TokenID, "presented from another device")
+	}
+
+	token, err := s.repo.SelectFamilyTokenByRefreshHash(ctx, helpers.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+	if token.FamilyID != family.FamilyID || token.TokenID != claims.TokenID {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if token.UsedAt != nil {
+		return nil, s.reportReuse(ctx, family, claims.TokenID, "already rotated")
+	}
+
+	var tokenData *middleware.TokenData
+	err = s.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		affected, err := s.repo.MarkFamilyTokenUsed(ctx, tx, claims.TokenID)
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			// lost the race against another refresh with the same token
+			return errFamilyTokenUsed
+		}
+		applySessionMetadata(ctx, family)
+		if err = s.repo.UpdateTokenFamilySession(ctx, tx, family); err != nil {
+			return err
+		}
+		tokenData, err = s.issuePair(ctx, tx, family.FamilyID, user)
+		return err
+	})
+	if errors.Is(err, errFamilyTokenUsed) {
+		return nil, s.reportReuse(ctx, family, claims.TokenID, "rotated concurrently")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = s.attachSigningSecret(ctx, tokenData, family.FamilyID, false); err != nil {
+		return nil, err
+	}
+	return tokenData, nil
+}
+
+// RevokeFamily closes the family, drops its signing secret and blacklists the
+// tokens in it that have not expired yet. The blacklist is kept in postgres so it
+// outlives redis, redis only answers the auth middleware without a query.
+func (s *tokenFamilyService) RevokeFamily(ctx context.Context, familyID string, reason string) error {
+	tokens, err := s.repo.SelectUnexpiredFamilyTokens(ctx, familyID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	err = s.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		if err := s.repo.RevokeTokenFamily(ctx, tx, familyID, reason); err != nil {
+			return err
+		}
+		for _, token := range tokens {
+			blacklist := []entity.TokenBlacklist{{
+				Token:       token.RefreshTokenHash,
+				BlacklistAt: now,
+				ExpiredAt:   token.RefreshExpiredAt,
+				Description: "refresh-token " + reason,
+			}}
+			if token.AccessExpiredAt.After(now) {
+				blacklist = append(blacklist, entity.TokenBlacklist{
+					Token:       token.AccessTokenHash,
+					BlacklistAt: now,
+					ExpiredAt:   token.AccessExpiredAt,
+					Description: "access-token " + reason,
+				})
+			}
+			for i := range blacklist {
+				if err := s.tokenBlacklist.InsertBlaclistToken(ctx, tx, &blacklist[i]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		ttl := token.AccessExpiredAt.Sub(now)
+		if ttl <= 0 {
+			continue
+		}
+		if err = s.redis.SetBlaclistJwt(ctx, token.AccessTokenHash, ttl); err != nil {
+			s.clogger.ErrorLogger(ctx, "RevokeFamily.redis.SetBlaclistJwt", err)
+		}
+	}
+	if err = s.redis.DeleteSigningSecret(ctx, familyID); err != nil {
+		s.clogger.ErrorLogger(ctx, "RevokeFamily.redis.DeleteSigningSecret", err)
+	}
+	return nil
+}
+
+// RetireTokenVersion is called once user.TokenVersion was bumped. The version
+// is published to the auth middleware so every older access token is refused,
+// the other sessions of the user are revoked and the current one, when given,
+// gets a new pair so the device that made the change stays logged in.
+func (s *tokenFamilyService) RetireTokenVersion(ctx context.Context, user *entity.User, currentFamilyID string, deviceID string, reason string) (*middleware.TokenData, error) {
+	if err := s.redis.SetTokenVersion(ctx, user.UUID, user.TokenVersion, s.jwtConfig.RefreshExpiration); err != nil {
+		s.clogger.ErrorLogger(ctx, "RetireTokenVersion.redis.SetTokenVersion", err)
+		return nil, err
+	}
+	families, err := s.repo.SelectActiveFamiliesByUser(ctx, user.UUID)
+	if err != nil {
+		return nil, err
+	}
+	var current *entity.TokenFamily
+	for _, family := range families {
+		if currentFamilyID != "" && family.FamilyID == currentFamilyID && family.DeviceID == deviceID {
+			current = &family
+			continue
+		}
+		if err = s.RevokeFamily(ctx, family.FamilyID, reason); err != nil {
+			return nil, err
+		}
+	}
+	if current == nil {
+		return nil, nil
+	}
+
+	applySessionMetadata(ctx, current)
+	var tokenData *middleware.TokenData
+	err = s.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		if err := s.repo.UpdateTokenFamilySession(ctx, tx, current); err != nil {
+			return err
+		}
+		pair, err := s.issuePair(ctx, tx, current.FamilyID, user)
+		tokenData = pair
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = s.attachSigningSecret(ctx, tokenData, current.FamilyID, false); err != nil {
+		return nil, err
+	}
+	return tokenData, nil
+}
+
+// SessionDevice returns the device an active family of the user was issued
+// to. The family id is taken from a validated token, unlike X-DEVICE-ID.
+func (s *tokenFamilyService) SessionDevice(ctx context.Context, userUUID string, familyID string) (string, error) {
+	family, err := s.repo.SelectTokenFamilyByFamilyID(ctx, familyID)
+	if err != nil {
+		return "", err
+	}
+	if family.UserUUID != userUUID || family.RevokedAt != nil {
+		return "", ErrRefreshTokenInvalid
+	}
+	return family.DeviceID, nil
+}
+
+func (s *tokenFamilyService) issuePair(ctx context.Context, tx *gorm.DB, familyID string, user *entity.User) (*middleware.TokenData, error) {
+	roles, permissions, err := s.userAccess(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	tokenID := uuid.NewString()
+	tokenData, err := s.middleware.CreateTokens(ctx, &middleware.Claims{
+		Uuid:        user.UUID,
+		Username:    user.Email,
+		Role:        roles[0],
+		FamilyID:    familyID,
+		TokenID:     tokenID,
+		Version:     user.TokenVersion,
+		Roles:       roles,
+		Permissions: permissions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.repo.InsertFamilyToken(ctx, tx, &entity.TokenFamilyToken{
+		FamilyID:         familyID,
+		TokenID:          tokenID,
+		AccessTokenHash:  helpers.HashToken(tokenData.AccessToken),
+		RefreshTokenHash: helpers.HashToken(tokenData.RefreshToken),
+		AccessExpiredAt:  tokenData.ExpiredAccess,
+		RefreshExpiredAt: tokenData.ExpiredRefresh,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &tokenData, nil
+}
+
+// attachSigningSecret hands the session its request signing secret, kept per
+// token family. A login starts a new secret, a refresh keeps the current one so
+// requests signed in flight stay valid, and both extend it to the lifetime of
+// the refresh token.
+func (s *tokenFamilyService) attachSigningSecret(ctx context.Context, tokenData *middleware.TokenData, familyID string, renew bool) error {
+	secret := ""
+	if !renew {
+		current, err := s.redis.GetSigningSecret(ctx, familyID)
+		if err != nil {
+			s.clogger.ErrorLogger(ctx, "attachSigningSecret.redis.GetSigningSecret", err)
+			return err
+		}
+		secret = current
+	}
+	if secret == "" {
+		generated, err := middleware.GenerateSigningSecret()
+		if err != nil {
+			s.clogger.ErrorLogger(ctx, "attachSigningSecret.GenerateSigningSecret", err)
+			return err
+		}
+		secret = generated
+	}
+	if err := s.redis.SetSigningSecret(ctx, familyID, secret, s.jwtConfig.RefreshExpiration); err != nil {
+		s.clogger.ErrorLogger(ctx, "attachSigningSecret.redis.SetSigningSecret", err)
+		return err
+	}
+	tokenData.SigningSecret = secret
+	return nil
+}
+
+// userAccess collects the roles of a user and the permissions they grant, a
+// user without any row in user_roles is a customer.
+func (s *tokenFamilyService) userAccess(ctx context.Context, user *entity.User) ([]string, []string, error) {
+	userRoles, err := s.roleRepository.SelectRolesByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(userRoles) == 0 {
+		return []string{string(enum.ROLE_USER)}, []string{}, nil
+	}
+	var (
+		roles       = make([]string, 0, len(userRoles))
+		permissions = []string{}
+		seen        = map[enum.PermissionEnum]bool{}
+	)
+	for _, role := range userRoles {
+		roles = append(roles, string(role.Name))
+		for _, permission := range role.Permissions {
+			if seen[permission.Name] {
+				continue
+			}
+			seen[permission.Name] = true
+			permissions = append(permissions, string(permission.Name))
+		}
+	}
+	return roles, permissions, nil
+}
+
+// reportReuse treats a replayed refresh token as stolen: the incident is
+// logged and the family it belongs to is revoked for every holder.
+func (s *tokenFamilyService) reportReuse(ctx context.Context, family *entity.TokenFamily, tokenID string, detail string) error {
+	s.clogger.ErrorLogger(ctx, "SECURITY_INCIDENT.RotateTokens.refresh-token-reuse", fmt.Errorf(
+		"refresh token %s %s, revoking family %s of user %s on device %s",
+		tokenID, detail, family.FamilyID, family.UserUUID, family.DeviceID,
+	))
+	if err := s.RevokeFamily(ctx, family.FamilyID, REVOKE_REASON_REUSE); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// applySessionMetadata copies the client details of the current request onto
+// the session. Location is rounded to two decimals (~1 km), it is only shown
+// back to the user to recognise a session.
+func applySessionMetadata(ctx context.Context, family *entity.TokenFamily) {
+	family.LastSeenAt = time.Now()
+	customResource, ok := ctx.Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue)
+	if !ok {
+		return
+	}
+	family.AppVersion = customResource.HeaderXAppVersion
+	family.IPAddress = customResource.HeaderXRealIp
+	family.Latitude = helpers.CoarseLatitude(customResource.HeaderXLatitude)
+	family.Longitude = helpers.CoarseLongitude(customResource.HeaderXLongitude)
+}
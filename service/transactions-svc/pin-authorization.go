@@ -0,0 +1,27 @@
+package transactionsvc
+
+import (
+	"backend-mobile-api/model/dto/response"
+	"context"
+	"errors"
+	"log"
+)
+
+var ErrPinRequired = errors.New("pin is required")
+
+// AuthorizePin memastikan transaksi disetujui dengan pin user, percobaan salah dihitung oleh pin attempt service.
+// Selama PIN_ATTEMPT_REQUIRED_FOR_TRANSACTION belum aktif, app versi lama yang belum mengirim pin masih dilewatkan
+func (s *transactionService) AuthorizePin(ctx context.Context, userUUID string, deviceID string, pin string) (*response.PinAttemptResponse, error) {
+	if pin == "" {
+		if s.rootConfig.PinAttempt.RequiredForTransaction {
+			return nil, ErrPinRequired
+		}
+		log.Printf("[WARN] transaksi user %s tanpa pin, app belum mengirim pin", userUUID)
+		return nil, nil
+	}
+	user, err := s.userRepo.SelectUserByUUID(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	return s.pinAttemptService.VerifyPin(ctx, user, deviceID, pin)
+}
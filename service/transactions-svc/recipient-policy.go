@@ -0,0 +1,178 @@
+package transactionsvc
+
+import (
+	"backend-mobile-api/model/dto"
+	"backend-mobile-api/model/dto/request"
+	"backend-mobile-api/model/entity"
+	"backend-mobile-api/model/enum"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCoolingOffLimitExceeded = errors.New("transfer to new recipient exceeds cooling-off limit")
+	ErrStepUpRequired          = errors.New("first transfer to this account requires otp verification")
+	ErrStepUpInvalid           = errors.New("invalid step-up verification")
+)
+
+// StepUpVerification dikirim ulang oleh app setelah user memasukkan otp dari StepUpChallenge
+type StepUpVerification struct {
+	VerifyKey string `json:"verify_key"`
+	Otp       string `json:"otp"`
+}
+
+type StepUpChallenge struct {
+	VerifyKey string       `json:"verify_key"`
+	OtpMethod enum.OtpType `json:"otp_method"`
+	ExpiredAt time.Time    `json:"expired_at"`
+}
+
+// AuthorizeBankTransfer menjalankan policy recipient baru sebelum transfer dibuat:
+//   - total transfer ke recipient yang baru ditambahkan (atau rekening yang belum disimpan) dibatasi selama cooling-off
+//   - transfer pertama ke sebuah rekening wajib verifikasi otp, tanpa stepUp akan dikirim otp dan dikembalikan challenge
+func (s *transactionService) AuthorizeBankTransfer(ctx context.Context, userUUID string, detail *entity.TransactionBankTransfer, nominal float64, stepUp *StepUpVerification) (*StepUpChallenge, error) {
+	policy := s.rootConfig.RecipientPolicy
+	user, err := s.repo.FindUserByUUID(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	destination := transferDestination(user.UUID, detail)
+	coolingOff, err := s.coolingOffReservation(ctx, user, detail, destination, nominal)
+	if err != nil {
+		return nil, err
+	}
+
+	if !policy.StepUpFirstTransfer {
+		return nil, coolingOff()
+	}
+	count, _, err := s.repo.SummarizeTransfersToAccount(ctx, user.ID, detail.BankName, detail.AccountNumber, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, coolingOff()
+	}
+
+	if stepUp == nil || stepUp.VerifyKey == "" || stepUp.Otp == "" {
+		challenge, err := s.sendStepUpOtp(ctx, user, destination)
+		if err != nil {
+			return nil, err
+		}
+		return challenge, ErrStepUpRequired
+	}
+
+	stored, err := s.redis.GetTransferStepUp(ctx, stepUp.VerifyKey)
+	if err != nil {
+		return nil, err
+	}
+	if stored == "" || stored != destination {
+		return nil, ErrStepUpInvalid
+	}
+	otpData, err := s.otpService.VerifyOtpCode(ctx, &request.VerifyOtpRequest{
+		Otp:      stepUp.Otp,
+		VerifyID: stepUp.VerifyKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrStepUpInvalid, err)
+	}
+	if otpData.OtpPurpose != enum.OTP_TRANSFER_STEP_UP || otpData.UserUUID != user.UUID {
+		return nil, ErrStepUpInvalid
+	}
+	_ = s.redis.DeleteTransferStepUp(ctx, stepUp.VerifyKey)
+	return nil, coolingOff()
+}
+
+// coolingOffReservation mengecek batas cooling-off lebih awal (supaya otp tidak dikirim untuk transfer yang pasti ditolak)
+// dan mengembalikan fungsi yang me-reserve nominal secara atomic di redis setelah step-up lolos
+func (s *transactionService) coolingOffReservation(ctx context.Context, user *entity.User, detail *entity.TransactionBankTransfer, destination string, nominal float64) (func() error, error) {
+	policy := s.rootConfig.RecipientPolicy
+	noop := func() error { return nil }
+	if policy.CoolingOffPeriod <= 0 {
+		return noop, nil
+	}
+	since := time.Now().Add(-policy.CoolingOffPeriod)
+	window := policy.CoolingOffPeriod
+	recipient, err := s.recipientRepo.FindRecipientByBankName(ctx, user.ID, detail.BankName, detail.AccountNumber)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if recipient != nil {
+		window = time.Until(recipient.CreatedAt.Add(policy.CoolingOffPeriod))
+		if window <= 0 {
+			return noop, nil
+		}
+		since = recipient.CreatedAt
+	}
+	_, total, err := s.repo.SummarizeTransfersToAccount(ctx, user.ID, detail.BankName, detail.AccountNumber, since)
+	if err != nil {
+		return nil, err
+	}
+	if total+nominal > policy.CoolingOffMaxAmount {
+		return nil, ErrCoolingOffLimitExceeded
+	}
+	return func() error {
+		ok, err := s.redis.ReserveCoolingOff(ctx, destination, total, nominal, policy.CoolingOffMaxAmount, window)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrCoolingOffLimitExceeded
+		}
+		return nil
+	}, nil
+}
+
+// ReleaseBankTransfer mengembalikan reservasi cooling-off ketika transaksi gagal dibuat
+func (s *transactionService) ReleaseBankTransfer(ctx context.Context, userUUID string, detail *entity.TransactionBankTransfer, nominal float64) {
+	if s.rootConfig.RecipientPolicy.CoolingOffPeriod <= 0 {
+		return
+	}
+	if err := s.redis.ReleaseCoolingOff(ctx, transferDestination(userUUID, detail), nominal); err != nil {
+		log.Printf("[ERROR] gagal release cooling-off %s: %v", userUUID, err)
+	}
+}
+
+func (s *transactionService) sendStepUpOtp(ctx context.Context, user *entity.User, destination string) (*StepUpChallenge, error) {
+	method := enum.OtpType(strings.ToUpper(s.rootConfig.RecipientPolicy.StepUpOtpMethod))
+	otpDestination := user.Email
+	if method == enum.TYPE_SMS || method == enum.TYPE_WHATSAPP {
+		otpDestination = user.PhoneNumber
+	}
+	otpData, err := s.otpService.SendOtp(ctx, &dto.SendOtp{
+		OtpPurpose:          enum.OTP_TRANSFER_STEP_UP,
+		OtpMethod:           method,
+		OtpDestination:      otpDestination,
+		UserId:              user.ID,
+		UserUUID:            user.UUID,
+		VerifyKey:           uuid.New().String(),
+		FallbackEmail:       user.Email,
+		FallbackPhoneNumber: user.PhoneNumber,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// otp fallback restarts the expiry on every channel, the step up has to outlive them
+	stepUpExpire := s.rootConfig.App.OtpExpire + s.rootConfig.Otp.DeliveryTimeout*time.Duration(len(s.rootConfig.Otp.Channels))
+	if err = s.redis.SetTransferStepUp(ctx, otpData.VerifyKey, destination, stepUpExpire); err != nil {
+		return nil, err
+	}
+	return &StepUpChallenge{
+		VerifyKey: otpData.VerifyKey,
+		OtpMethod: otpData.OtpMethod,
+		ExpiredAt: otpData.ExpiredAt,
+	}, nil
+}
+
+// transferDestination mengikat otp ke user & rekening tujuan, otp tidak bisa dipakai untuk rekening lain
+func transferDestination(userUUID string, detail *entity.TransactionBankTransfer) string {
+	return fmt.Sprintf("%s:%s:%s", userUUID, strings.ToLower(detail.BankName), detail.AccountNumber)
+}
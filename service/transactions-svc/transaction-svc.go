@@ -1,16 +1,25 @@
 package transactionsvc
 
 import (
+	"backend-mobile-api/app/config"
 	"backend-mobile-api/helpers"
 	"backend-mobile-api/internal/outbond/smtp"
 	"backend-mobile-api/internal/repository/postgres"
+	redisRepos "backend-mobile-api/internal/repository/redis"
 	"backend-mobile-api/model/dto"
+	"backend-mobile-api/model/dto/response"
 	"backend-mobile-api/model/entity"
 	"backend-mobile-api/model/enum"
 	"backend-mobile-api/service/notification"
+	"backend-mobile-api/service/otp"
+	paymentRequestSvc "backend-mobile-api/service/payment-request-svc"
+	pinAttemptSvc "backend-mobile-api/service/pin-attempt-svc"
 	"context"
+	"errors"
 	"fmt"
 	"log"
+
+	"gorm.io/gorm"
 )
 
 type TransactionService interface {
@@ -34,13 +43,27 @@ type TransactionService interface {
 
 	GenerateTransactionCode(ctx context.Context, txType string) (*CodeResponse, error)
 	UpdateTransactionStatus(ctx context.Context, transactionID, status string) error
+	UpdateOwnTransactionStatus(ctx context.Context, userUUID, transactionID, status string) error
+
+	// policy recipient baru
+	AuthorizeBankTransfer(ctx context.Context, userUUID string, detail *entity.TransactionBankTransfer, nominal float64, stepUp *StepUpVerification) (*StepUpChallenge, error)
+	ReleaseBankTransfer(ctx context.Context, userUUID string, detail *entity.TransactionBankTransfer, nominal float64)
+	AuthorizePin(ctx context.Context, userUUID string, deviceID string, pin string) (*response.PinAttemptResponse, error)
 }
 
 type transactionService struct {
-	repo     postgres.TransactionRepository
-	userRepo postgres.UserRepository
-	notifier *notification.FirebaseNotifier
-	smtp     *smtp.Smtp
+	repo          postgres.TransactionRepository
+	userRepo      postgres.UserRepository
+	recipientRepo postgres.RecipientRepository
+	notifier      *notification.FirebaseNotifier
+	smtp          *smtp.Smtp
+	otpService    otp.OtpService
+	redis         *redisRepos.Redis
+	rootConfig    *config.Root
+	unitOfWork    postgres.UnitOfWork
+
+	pinAttemptService     pinAttemptSvc.PinAttemptService
+	paymentRequestService paymentRequestSvc.PaymentRequestService
 }
 type CodeResponse struct {
 	TransactionID string   `json:"transaction_id"`
@@ -76,14 +99,34 @@ func (s *transactionService) GenerateTransactionCode(ctx context.Context, paymen
 	}, nil
 }
 
-func NewTransactionService(repo postgres.TransactionRepository, notifier *notification.FirebaseNotifier, smtp *smtp.Smtp, userRepo postgres.UserRepository) TransactionService {
+func NewTransactionService(
+	repo postgres.TransactionRepository,
+	notifier *notification.FirebaseNotifier,
+	smtp *smtp.Smtp,
+	userRepo postgres.UserRepository,
+	recipientRepo postgres.RecipientRepository,
+	otpService otp.OtpService,
+	redis *redisRepos.Redis,
+	rootConfig *config.Root,
+	pinAttemptService pinAttemptSvc.PinAttemptService,
+	paymentRequestService paymentRequestSvc.PaymentRequestService,
+	unitOfWork postgres.UnitOfWork,
+) TransactionService {
 	if repo == nil {
 		log.Println("[ERROR] repo nil saat init transaction service")
 	}
 	return &transactionService{repo: repo,
-		notifier: notifier,
-		smtp:     smtp,
-		userRepo: userRepo,
+		notifier:      notifier,
+		smtp:          smtp,
+		userRepo:      userRepo,
+		recipientRepo: recipientRepo,
+		otpService:    otpService,
+		redis:         redis,
+		rootConfig:    rootConfig,
+		unitOfWork:    unitOfWork,
+
+		pinAttemptService:     pinAttemptService,
+		paymentRequestService: paymentRequestService,
 	}
 }
 
@@ -121,30 +164,100 @@ type TransactionStatusUpdatePayload struct {
 	Status        string `json:"status"`
 }
 
-// TransactionService.go
-func (s *transactionService) UpdateTransactionStatus(ctx context.Context, transactionID, status string) error {
-	// 1. Ambil transaksi
-	tx, err := s.repo.FindTransactionByID(ctx, transactionID)
+var (
+	ErrTransactionNotFound     = errors.New("transaction not found")
+	ErrTransactionStatusChange = errors.New("transaction status change is not allowed")
+)
 
+// UpdateTransactionStatus settles a transaction, it is only reachable from the
+// internal route the payment callback calls with the transaction:settle permission.
+func (s *transactionService) UpdateTransactionStatus(ctx context.Context, transactionID, status string) error {
+	tx, err := s.findTransaction(ctx, transactionID)
 	if err != nil {
 		return err
 	}
+	return s.changeStatus(ctx, tx, status)
+}
 
-	// 2. Update status transaksi di DB
-	if err := s.repo.UpdateStatus(ctx, transactionID, status); err != nil {
+// UpdateOwnTransactionStatus lets a user cancel their own pending transaction,
+// every other status is set by the payment callback only.
+func (s *transactionService) UpdateOwnTransactionStatus(ctx context.Context, userUUID, transactionID, status string) error {
+	tx, err := s.findTransaction(ctx, transactionID)
+	if err != nil {
 		return err
 	}
+	user, err := s.repo.FindUserByUUID(ctx, userUUID)
+	if err != nil {
+		return err
+	}
+	// a transaction of someone else is reported as missing, its id does not leak
+	if tx.UserID != user.ID {
+		return ErrTransactionNotFound
+	}
+	if status != enum.TRANSACTION_STATUS_CANCELED {
+		return fmt.Errorf("%w: users can only cancel a transaction", ErrTransactionStatusChange)
+	}
+	return s.changeStatus(ctx, tx, status)
+}
 
-	// 3. Cari device FCM token user
-	fcmToken, err := s.repo.GetUserFcmToken(ctx, uint(tx.UserID))
+func (s *transactionService) findTransaction(ctx context.Context, transactionID string) (*entity.Transaction, error) {
+	tx, err := s.repo.FindTransactionByID(ctx, transactionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTransactionNotFound
+	}
+	return tx, err
+}
+
+// changeStatus moves a pending transaction to its final status. The status and
+// the payment request participant it pays are committed together.
+func (s *transactionService) changeStatus(ctx context.Context, tx *entity.Transaction, status string) error {
+	if tx.Status != enum.TRANSACTION_STATUS_PENDING || status == enum.TRANSACTION_STATUS_PENDING {
+		return fmt.Errorf("%w: %s to %s", ErrTransactionStatusChange, tx.Status, status)
+	}
+	var notifyPaymentRequest func(ctx context.Context)
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context, _ *gorm.DB) error {
+		affected, err := s.repo.UpdatePendingStatus(ctx, tx.TransactionID, status)
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			// settled by a concurrent status update
+			return fmt.Errorf("%w: %s is no longer pending", ErrTransactionStatusChange, tx.TransactionID)
+		}
+		// participant of a payment request is only paid once the transaction succeeds
+		if tx.Type == enum.TRANSACTION_TYPE_PAYMENT_REQUEST {
+			notifyPaymentRequest, err = s.paymentRequestService.SettleTransaction(ctx, tx.TransactionID, status)
+			return err
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	if s.notifier != nil {
+	tx.Status = status
+	if notifyPaymentRequest != nil {
+		notifyPaymentRequest(ctx)
+	}
+
+	// the status is committed, a failed notification does not fail the update
+	fcmToken, err := s.repo.GetUserFcmToken(ctx, uint(tx.UserID))
+	if err != nil {
+		helpers.CustomeLogger(ctx, &dto.CustomLoggerRequest{
+			Error:   err.Error(),
+			Remarks: "[Service][UpdateTransactionStatus] gagal ambil fcm token",
+		})
+	} else if s.notifier != nil {
 		_ = s.notifier.SendTransactionNotification(ctx, fcmToken, tx.TransactionID, status)
 	}
 	user, err := s.userRepo.SelectUserByID(ctx, tx.UserID)
-	// 3. Kirim Email
+	if err != nil {
+		helpers.CustomeLogger(ctx, &dto.CustomLoggerRequest{
+			Error:   err.Error(),
+			Remarks: "[Service][UpdateTransactionStatus] gagal ambil user",
+		})
+		return nil
+	}
+	// Kirim Email
 	if s.smtp != nil {
 		body := fmt.Sprintf(
 			"Halo %s,\n\nStatus transaksi kamu dengan ID %s sekarang adalah: %s.\n\nTerima kasih sudah menggunakan layanan kami.",
@@ -152,7 +265,6 @@ func (s *transactionService) UpdateTransactionStatus(ctx context.Context, transa
 			tx.TransactionID,
 			status,
 		)
-		fmt.Println(user.ID)
 		to := []string{user.Email} // alamat email penerima
 
 		if err := s.smtp.SendMail(ctx, to, enum.EmailSubject("Notifikasi Transaksi"), body); err != nil {
@@ -0,0 +1,72 @@
+package userAuthSvc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrResetTokenInvalid = errors.New("reset token invalid or expired")
+	errAccessKeyUsed     = errors.New("access key already used")
+)
+
+// resetToken binds the access key to its expiry, the mail only ever carries
+// this form so a leaked bare access key can not reset a pin.
+func resetToken(secret string, accessKey string, expireAt time.Time) string {
+	payload := fmt.Sprintf("%s.%d", accessKey, expireAt.Unix())
+	return fmt.Sprintf("%s.%s", payload, resetTokenSign(secret, payload))
+}
+
+func resetTokenSign(secret string, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// isResetToken tells a signed reset token from the bare access key handed out
+// after an otp verification.
+func isResetToken(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// parseResetToken returns the access key of a token signed with secret that
+// has not expired yet.
+func parseResetToken(secret string, token string, now time.Time) (string, error) {
+	if secret == "" {
+		return "", ErrResetTokenInvalid
+	}
+	idx := strings.LastIndex(token, ".")
+	if idx < 0 {
+		return "", ErrResetTokenInvalid
+	}
+	payload, sign := token[:idx], token[idx+1:]
+	if !hmac.Equal([]byte(sign), []byte(resetTokenSign(secret, payload))) {
+		return "", ErrResetTokenInvalid
+	}
+	accessKey, rawExpire, ok := strings.Cut(payload, ".")
+	if !ok {
+		return "", ErrResetTokenInvalid
+	}
+	expire, err := strconv.ParseInt(rawExpire, 10, 64)
+	if err != nil || now.After(time.Unix(expire, 0)) {
+		return "", ErrResetTokenInvalid
+	}
+	return accessKey, nil
+}
+
+// resetLink appends the token to an allowlisted callback, query params the
+// client already put on it are kept.
+func resetLink(callback *url.URL, token string) string {
+	link := *callback
+	query := link.Query()
+	query.Set("acces_key", token)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
@@ -4,8 +4,8 @@ import (
 	"backend-mobile-api/app/config"
 	"backend-mobile-api/helpers"
 	"backend-mobile-api/internal/middleware"
+	"backend-mobile-api/internal/outbond/provider"
 	"backend-mobile-api/internal/outbond/smtp"
-	"backend-mobile-api/internal/outbond/verihubs"
 	"backend-mobile-api/internal/repository/postgres"
 	"backend-mobile-api/internal/repository/redis"
 	"backend-mobile-api/model/dto"
@@ -15,12 +15,19 @@ import (
 	"backend-mobile-api/model/enum"
 	"backend-mobile-api/model/enum/pkgErr"
 	verihubsDto "backend-mobile-api/model/outbond/verihubs-dto"
+	accountSvc "backend-mobile-api/service/account-svc"
+	consentSvc "backend-mobile-api/service/consent-svc"
+	deviceSvc "backend-mobile-api/service/device-svc"
+	loginEventSvc "backend-mobile-api/service/login-event-svc"
+	"backend-mobile-api/service/otp"
+	pinAttemptSvc "backend-mobile-api/service/pin-attempt-svc"
+	tokenFamilySvc "backend-mobile-api/service/token-family-svc"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/labstack/gommon/log"
-	mathRand "math/rand"
+	"net/url"
 	"strings"
 	"time"
 
@@ -40,9 +47,17 @@ type userAuthService struct {
 	AccessStateRepository postgres.AccessStateRepository
 	otpRepository         postgres.OtpRepository
 	tokenBlacklist        postgres.TokenBlacklistTokenRepository
-	outboundVeriHubsSvc   verihubs.OutboundVeriHubsService
+	otpProvider           provider.OtpProvider
 	userDetailRepository  postgres.UserDetailRepository
 	deviceRepository      postgres.DeviceRepository
+	tokenFamilyService    tokenFamilySvc.TokenFamilyService
+	pinAttemptService     pinAttemptSvc.PinAttemptService
+	deviceService         deviceSvc.DeviceService
+	otpService            otp.OtpService
+	unitOfWork            postgres.UnitOfWork
+	accountService        accountSvc.AccountService
+	consentService        consentSvc.ConsentService
+	loginEventService     loginEventSvc.LoginEventService
 }
 
 func NewUserAuthService(
@@ -56,9 +71,17 @@ func NewUserAuthService(
 	otpRepository postgres.OtpRepository,
 	tokenBlacklist postgres.TokenBlacklistTokenRepository,
 	//	loginLogRepository postgres.LoginLogRepository,
-	outboundVeriHubsSvc verihubs.OutboundVeriHubsService,
+	otpProvider provider.OtpProvider,
 	userDetilRepository postgres.UserDetailRepository,
 	deviceRepository postgres.DeviceRepository,
+	tokenFamilyService tokenFamilySvc.TokenFamilyService,
+	pinAttemptService pinAttemptSvc.PinAttemptService,
+	deviceService deviceSvc.DeviceService,
+	otpService otp.OtpService,
+	unitOfWork postgres.UnitOfWork,
+	accountService accountSvc.AccountService,
+	consentService consentSvc.ConsentService,
+	loginEventService loginEventSvc.LoginEventService,
 ) UserAuthService {
 	return &userAuthService{
 		userRespository:       userRespository,
@@ -71,24 +94,32 @@ func NewUserAuthService(
 		otpRepository:         otpRepository,
 		tokenBlacklist:        tokenBlacklist,
 		//		loginLogRepository:   loginLogRepository,
-		outboundVeriHubsSvc:  outboundVeriHubsSvc,
+		otpProvider:          otpProvider,
 		userDetailRepository: userDetilRepository,
 		deviceRepository:     deviceRepository,
+		tokenFamilyService:   tokenFamilyService,
+		pinAttemptService:    pinAttemptService,
+		deviceService:        deviceService,
+		otpService:           otpService,
+		unitOfWork:           unitOfWork,
+		accountService:       accountService,
+		consentService:       consentService,
+		loginEventService:    loginEventService,
 	}
 }
 
 type UserAuthService interface {
 	RegisterService(c context.Context, req *request.RegisterRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse
-	sendOtp(c context.Context, req *dto.SendOtp) (*entity.OTP, error)
 	LoginByEmailService(C context.Context, req *request.LoginEmailRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse
 	LoginByPhoneNumberService(C context.Context, req *request.LoginPhoneNumberRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse
 	LogoutService(c context.Context, req *request.LogoutRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse
 	RefreshService(c context.Context, req request.RefreshTokenRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse
 	VerifyOtpService(c context.Context, otpRequest *request.VerifyOtpRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse
-	GenerateOtp(c context.Context, value string, uuidKey string) (string, error)
 	SendOtpService(c context.Context, req *request.SendOtpRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse
+	OtpStatusService(ctx context.Context, req *request.OtpStatusRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse
 	SetPinService(c context.Context, req *request.SetPinRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse
 	ForgotPinService(ctx context.Context, req *request.ForgotPinRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse
+	VerifyDeviceService(ctx context.Context, req *request.VerifyDeviceRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse
 }
 
 func (svc *userAuthService) RegisterService(c context.Context, req *request.RegisterRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
@@ -144,28 +175,30 @@ func (svc *userAuthService) RegisterService(c context.Context, req *request.Regi
 			}
 		}
 	}
-	tx := svc.userRespository.Tx(c)
-	err = svc.userRespository.InsertUser(
-		c,
-		tx,
-		&entity.User{
+	err = svc.unitOfWork.Do(c, func(c context.Context, tx *gorm.DB) error {
+		user := &entity.User{
 			UUID:        uuid.New().String(),
 			FullName:    req.FullName,
 			Email:       req.Email,
 			PhoneNumber: req.PhoneNumber,
 			Status:      enum.VERIFICATION_STATUS_UNVERIFIED,
-		})
-
+		}
+		if err := svc.userRespository.InsertUser(c, tx, user); err != nil {
+			return err
+		}
+		return svc.consentService.Record(c, user, req.Consents, true)
+	})
 	if err != nil {
-		tx.Rollback()
 		logData.Error = err.Error()
+		if resp := consentSvc.ErrorResponse(err); resp != nil {
+			return resp
+		}
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
 			Message:    pkgErr.SERVER_BUSY,
 			Error:      err.Error(),
 		}
 	}
-	tx.Commit()
 	logData.Success = true
 	return &dto.BaseResponse{
 		StatusCode: pkgErr.SUCCESS_CODE,
@@ -202,26 +235,45 @@ func (svc *userAuthService) LoginByEmailService(c context.Context, req *request.
 	}
 	logData.UserUUID = user.UUID
 	logData.Email = user.Email
+	attempt := loginEventSvc.Attempt{
+		Method:    enum.LOGIN_EMAIL,
+		DeviceID:  req.DeviceID,
+		Place:     req.Place,
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+	}
 	if user.Status == enum.VERIFICATION_STATUS_UNVERIFIED {
 		logData.Error = "user is not verified"
+		attempt.Failure = logData.Error
+		svc.loginEventService.Record(c, user, attempt)
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.AUTH_UNVERIFIED_CODE,
 			Message:    pkgErr.UNVERIFIED_MSG,
 		}
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Pin), []byte(req.Pin))
+	pinStatus, err := svc.pinAttemptService.VerifyPin(c, user, req.DeviceID, req.Pin)
 	if err != nil {
+		logData.Error = err.Error()
+		attempt.Failure = err.Error()
+		svc.loginEventService.Record(c, user, attempt)
+		return pinAttemptSvc.ErrorResponse(err, pinStatus, pkgErr.AUTH_UNAUTHORIZED_CODE, pkgErr.WRONG_EMAIL_OR_PIN_MSG)
+	}
+	if !svc.deviceService.IsTrusted(c, user, req.DeviceID) {
+		attempt.Failure = loginEventSvc.FAILURE_DEVICE_VERIFICATION
+		svc.loginEventService.Record(c, user, attempt)
+		return svc.deviceChallenge(c, user, req.DeviceID, enum.TYPE_EMAIL, logData)
+	}
+	// logging in again inside the grace period takes the deletion request back
+	if err = svc.accountService.CancelDeletion(c, user); err != nil {
 		logData.Error = err.Error()
 		return &dto.BaseResponse{
-			StatusCode: pkgErr.AUTH_UNAUTHORIZED_CODE,
-			Message:    pkgErr.WRONG_EMAIL_OR_PIN_MSG,
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
 		}
 	}
-	token, err := svc.authService.CreateTokens(c, &middleware.Claims{
-		Uuid:     user.UUID,
-		Username: user.Email,
-	})
+	mustReaccept, err := svc.consentService.MustReaccept(c, user)
 	if err != nil {
 		logData.Error = err.Error()
 		return &dto.BaseResponse{
@@ -230,6 +282,16 @@ func (svc *userAuthService) LoginByEmailService(c context.Context, req *request.
 			Error:      err.Error(),
 		}
 	}
+	token, err := svc.tokenFamilyService.IssueTokens(c, user, req.DeviceID)
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	svc.loginEventService.Record(c, user, attempt)
 	logData.Success = true
 	return &dto.BaseResponse{
 		StatusCode: pkgErr.SUCCESS_CODE,
@@ -241,7 +303,8 @@ func (svc *userAuthService) LoginByEmailService(c context.Context, req *request.
 				Email: user.Email,
 				Phone: user.PhoneNumber,
 			},
-			Token: token,
+			Token:               *token,
+			MustReacceptConsent: mustReaccept,
 		},
 	}
 }
@@ -274,25 +337,44 @@ func (svc *userAuthService) LoginByPhoneNumberService(c context.Context, req *re
 	}
 	logData.Email = user.Email
 	logData.UserUUID = user.UUID
-	if user.DeviceID != req.DeviceID {
-		logData.Error = "device id mismatch"
+	attempt := loginEventSvc.Attempt{
+		Method:    enum.LOGIN_PHONE_NUMBER,
+		DeviceID:  req.DeviceID,
+		Place:     req.Place,
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+	}
+	pinStatus, err := svc.pinAttemptService.VerifyPin(c, user, req.DeviceID, req.Pin)
+	if err != nil {
+		logData.Error = err.Error()
+		attempt.Failure = err.Error()
+		svc.loginEventService.Record(c, user, attempt)
+		return pinAttemptSvc.ErrorResponse(err, pinStatus, pkgErr.AUTH_UNAUTHORIZED_CODE, pkgErr.WRONG_PHONE_NUMBER_OR_PIN_MSG)
+	}
+	if !svc.deviceService.IsTrusted(c, user, req.DeviceID) {
+		attempt.Failure = loginEventSvc.FAILURE_DEVICE_VERIFICATION
+		svc.loginEventService.Record(c, user, attempt)
+		return svc.deviceChallenge(c, user, req.DeviceID, enum.TYPE_SMS, logData)
+	}
+	// logging in again inside the grace period takes the deletion request back
+	if err = svc.accountService.CancelDeletion(c, user); err != nil {
+		logData.Error = err.Error()
 		return &dto.BaseResponse{
-			StatusCode: pkgErr.AUTH_UNAUTHORIZED_CODE,
-			Message:    pkgErr.UNAUTHORIZED_MSG,
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
 		}
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(user.Pin), []byte(req.Pin))
+	mustReaccept, err := svc.consentService.MustReaccept(c, user)
 	if err != nil {
 		logData.Error = err.Error()
 		return &dto.BaseResponse{
-			StatusCode: pkgErr.AUTH_UNAUTHORIZED_CODE,
-			Message:    pkgErr.WRONG_PHONE_NUMBER_OR_PIN_MSG,
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
 		}
 	}
-	token, err := svc.authService.CreateTokens(c, &middleware.Claims{
-		Uuid:     user.UUID,
-		Username: user.Email,
-	})
+	token, err := svc.tokenFamilyService.IssueTokens(c, user, req.DeviceID)
 	if err != nil {
 		logData.Error = err.Error()
 		return &dto.BaseResponse{
@@ -301,6 +383,7 @@ func (svc *userAuthService) LoginByPhoneNumberService(c context.Context, req *re
 			Error:      err.Error(),
 		}
 	}
+	svc.loginEventService.Record(c, user, attempt)
 	logData.Success = true
 	return &dto.BaseResponse{
 		StatusCode: pkgErr.SUCCESS_CODE,
@@ -312,7 +395,8 @@ func (svc *userAuthService) LoginByPhoneNumberService(c context.Context, req *re
 				Email: user.Email,
 				Phone: user.PhoneNumber,
 			},
-			Token: token,
+			Token:               *token,
+			MustReacceptConsent: mustReaccept,
 		},
 	}
 }
@@ -367,8 +451,15 @@ func (svc *userAuthService) RefreshService(c context.Context, req request.Refres
 	userData := (*users)[0]
 	logData.UserUUID = userData.UUID
 	logData.Email = userData.Email
+	if userData.Status == enum.USER_PENDING_DELETION {
+		logData.Error = "account pending deletion"
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_PENDING_DELETION_CODE,
+			Message:    pkgErr.PENDING_DELETION_MSG,
+		}
+	}
 
-	if userData.DeviceID != req.DeviceID {
+	if !svc.deviceService.IsTrusted(c, &userData, req.DeviceID) {
 		logData.Error = "deference device"
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.AUTH_DEFERENCE_DEVICE_CODE,
@@ -376,22 +467,31 @@ func (svc *userAuthService) RefreshService(c context.Context, req request.Refres
 		}
 	}
 
-	ok, tokenData, err := svc.authService.RefreshToken(c, req.RefreshToken, &userData)
+	tokenData, err := svc.tokenFamilyService.RotateTokens(c, req.RefreshToken, &userData, req.DeviceID)
 	if err != nil {
 		logData.Error = err.Error()
+		if errors.Is(err, tokenFamilySvc.ErrRefreshTokenReused) {
+			logData.Data = map[string]interface{}{
+				"security_incident": "refresh-token reuse",
+				"device_id":         req.DeviceID,
+			}
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.AUTH_TOKEN_REUSED_CODE,
+				Message:    pkgErr.TOKEN_REUSED_MSG,
+			}
+		}
+		if errors.Is(err, tokenFamilySvc.ErrRefreshTokenInvalid) {
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.AUTH_UNAUTHORIZED_CODE,
+				Message:    pkgErr.UNAUTHORIZED_MSG,
+			}
+		}
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
 			Message:    pkgErr.SERVER_BUSY,
 			Error:      err.Error(),
 		}
 	}
-	if !ok {
-		logData.Error = "unauthorized"
-		return &dto.BaseResponse{
-			StatusCode: pkgErr.AUTH_UNAUTHORIZED_CODE,
-			Message:    pkgErr.UNAUTHORIZED_MSG,
-		}
-	}
 	logData.Success = true
 	return &dto.BaseResponse{
 		StatusCode: pkgErr.SUCCESS_CODE,
@@ -449,13 +549,10 @@ func (svc *userAuthService) LogoutService(c context.Context, req *request.Logout
 		g             = errgroup.Group{}
 		accessExpire  int64
 		refreshExpire int64
+		familyID      string
 	)
 	g.Go(func() error {
-		rsa, err := svc.authService.EncodePublicKeyRSA(c, svc.rootConfig.Jwt.PublicKey)
-		if err != nil {
-			return err
-		}
-		token, err := svc.authService.ParseJwtToken(c, fmt.Sprint(tokenString), rsa)
+		token, err := svc.authService.ParseAccessToken(c, fmt.Sprint(tokenString))
 		if err != nil {
 			return err
 		}
@@ -467,11 +564,7 @@ func (svc *userAuthService) LogoutService(c context.Context, req *request.Logout
 		return nil
 	})
 	g.Go(func() error {
-		rsa, err := svc.authService.EncodePublicKeyRSA(c, svc.rootConfig.Jwt.RefreshPublicKey)
-		if err != nil {
-			return err
-		}
-		token, err := svc.authService.ParseJwtToken(c, req.RefreshToken, rsa)
+		token, err := svc.authService.ParseRefreshJwtToken(c, req.RefreshToken)
 		if err != nil {
 			return err
 		}
@@ -480,6 +573,7 @@ func (svc *userAuthService) LogoutService(c context.Context, req *request.Logout
 			return err
 		}
 		refreshExpire = int64((*mapRefreshClaim)["exp"].(float64))
+		familyID, _ = (*mapRefreshClaim)["fid"].(string)
 		return nil
 	})
 	if err := g.Wait(); err != nil {
@@ -495,24 +589,29 @@ func (svc *userAuthService) LogoutService(c context.Context, req *request.Logout
 	now := time.Now()
 	nowUnix := now.Unix()
 	if accessExpire-nowUnix > 0 {
-		_ = svc.redis.SetBlaclistJwt(c, fmt.Sprint(tokenString), time.Duration(accessExpire-nowUnix)*time.Second)
-	}
-	tx := svc.tokenBlacklist.Tx(c)
-	err := svc.tokenBlacklist.InsertBlaclistToken(c, tx, &entity.TokenBlacklist{
-		Token:       req.RefreshToken,
-		BlacklistAt: now,
-		ExpiredAt:   now.Add(time.Duration(refreshExpire)),
-		Description: "refresh-token log-out",
+		_ = svc.redis.SetBlaclistJwt(c, helpers.HashToken(fmt.Sprint(tokenString)), time.Duration(accessExpire-nowUnix)*time.Second)
+	}
+	err := svc.unitOfWork.Do(c, func(c context.Context, tx *gorm.DB) error {
+		return svc.tokenBlacklist.InsertBlaclistToken(c, tx, &entity.TokenBlacklist{
+			Token:       helpers.HashToken(req.RefreshToken),
+			BlacklistAt: now,
+			ExpiredAt:   time.Unix(refreshExpire, 0),
+			Description: "refresh-token log-out",
+		})
 	})
 	if err != nil {
-		tx.Rollback()
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
 			Message:    pkgErr.SERVER_BUSY,
 			Error:      err.Error(),
 		}
 	}
-	tx.Commit()
+	if familyID != "" {
+		// the tokens presented are already blacklisted, revoking the rest of the family is best effort
+		if err = svc.tokenFamilyService.RevokeFamily(c, familyID, tokenFamilySvc.REVOKE_REASON_LOGOUT); err != nil {
+			svc.clogger.ErrorLogger(c, "Logout.tokenFamilyService.RevokeFamily", err)
+		}
+	}
 
 	logData.Success = true
 	return &dto.BaseResponse{
@@ -531,6 +630,18 @@ func (svc *userAuthService) VerifyOtpService(c context.Context, req *request.Ver
 		resetPin      *entity.AccessState
 		newUUID       = uuid.New().String()
 	)
+	attempt, err := svc.otpService.ReserveVerifyAttempt(c, req.VerifyID)
+	if err != nil {
+		logData.Error = err.Error()
+		if resp := otp.ErrorResponse(err, pkgErr.AUTH_INVALID_OTP_CODE, pkgErr.INVALID_OTP_MSG); resp != nil {
+			return resp
+		}
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
 	strJson, err := svc.redis.GetOtp(c, req.Otp, req.VerifyID)
 	if err != nil {
 		logData.Error = err.Error()
@@ -579,9 +690,14 @@ func (svc *userAuthService) VerifyOtpService(c context.Context, req *request.Ver
 	}
 	if otpData.OtpCode != req.Otp {
 		logData.Error = "invalid otp"
+		err = svc.otpService.RejectOtp(c, req.VerifyID, attempt)
+		if resp := otp.ErrorResponse(err, pkgErr.AUTH_INVALID_OTP_CODE, pkgErr.INVALID_OTP_MSG); resp != nil {
+			return resp
+		}
 		return &dto.BaseResponse{
-			StatusCode: pkgErr.AUTH_INVALID_OTP_CODE,
-			Message:    pkgErr.INVALID_OTP_MSG,
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
 		}
 	}
 	users, err := svc.userRespository.SelectUserByStruct(c, &entity.User{ID: otpData.UserId})
@@ -614,7 +730,7 @@ func (svc *userAuthService) VerifyOtpService(c context.Context, req *request.Ver
 	case enum.TYPE_EMAIL:
 		otpUpdate = &entity.OTP{Status: enum.OTP_VERIFIED}
 	case enum.TYPE_SMS:
-		if _, err := svc.outboundVeriHubsSvc.VerifySMSOtpService(c, &verihubsDto.VerifyOtpBaseRequest{
+		if _, err := svc.otpProvider.VerifySMSOtpService(c, &verihubsDto.VerifyOtpBaseRequest{
 			MSISDN:    otpData.OtpDestination,
 			Otp:       otpData.OtpCode,
 			Challenge: nil,
@@ -628,7 +744,7 @@ func (svc *userAuthService) VerifyOtpService(c context.Context, req *request.Ver
 		}
 		otpUpdate = &entity.OTP{Status: enum.OTP_VERIFIED}
 	case enum.TYPE_WHATSAPP:
-		if _, err = svc.outboundVeriHubsSvc.VerifyWhatsappsOtpService(c, &verihubsDto.VerifyOtpBaseRequest{
+		if _, err = svc.otpProvider.VerifyWhatsappsOtpService(c, &verihubsDto.VerifyOtpBaseRequest{
 			MSISDN:    otpData.OtpDestination,
 			Otp:       otpData.OtpCode,
 			Challenge: nil,
@@ -687,74 +803,43 @@ func (svc *userAuthService) VerifyOtpService(c context.Context, req *request.Ver
 		}
 	}
 
-	//tx update
-	txOtp := svc.otpRepository.Tx(c)
-	txUser := svc.userRespository.Tx(c)
-	txUserDt := svc.userDetailRepository.Tx(c)
-	txDevice := svc.deviceRepository.Tx(c)
-	txResetPin := svc.AccessStateRepository.Tx(c)
-
-	g := errgroup.Group{}
-	//otp
-	g.Go(func() error {
-		var errData error
+	err = svc.unitOfWork.Do(c, func(c context.Context, tx *gorm.DB) error {
 		if otpUpdate != nil {
-			errData = svc.otpRepository.UpdateOtpDataRepository(c, txOtp, otpData, otpUpdate)
+			if err := svc.otpRepository.UpdateOtpDataRepository(c, tx, otpData, otpUpdate); err != nil {
+				return err
+			}
 		}
-		return errData
-	})
-	//user
-	g.Go(func() error {
-		var errData error
 		if userUpdate != nil {
-			errData = svc.userRespository.UpdateUser(c, txUser, &userData, userUpdate)
+			if err := svc.userRespository.UpdateUser(c, tx, &userData, userUpdate); err != nil {
+				return err
+			}
 		}
-		return errData
-	})
-	//userDetail
-	g.Go(func() error {
-		var errData error
 		if newUserDetail != nil {
-			errData = svc.userDetailRepository.InsertUserDetail(c, txUserDt, newUserDetail)
+			if err := svc.userDetailRepository.InsertUserDetail(c, tx, newUserDetail); err != nil {
+				return err
+			}
 		}
-		return errData
-	})
-	g.Go(func() error {
-		var errData error
 		if newDevice != nil {
-			errData = svc.deviceRepository.InsertDevice(c, txDevice, newDevice)
+			if err := svc.deviceRepository.TrustDevice(c, tx, newDevice); err != nil {
+				return err
+			}
 		}
-		return errData
-	})
-	g.Go(func() error {
-		var errData error
 		if resetPin != nil {
-			errData = svc.AccessStateRepository.InsertAccessStateRepository(c, txResetPin, resetPin)
+			return svc.AccessStateRepository.InsertAccessStateRepository(c, tx, resetPin)
 		}
-		return errData
+		return nil
 	})
-
-	err = g.Wait()
 	if err != nil {
-		txOtp.Rollback()
-		txUser.Rollback()
-		txUserDt.Rollback()
-		txDevice.Rollback()
-		txResetPin.Rollback()
-
 		logData.Error = err.Error()
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
 			Message:    pkgErr.SERVER_BUSY,
 			Error:      err.Error(),
 		}
-
 	}
-	txOtp.Commit()
-	txUser.Commit()
-	txUserDt.Commit()
-	txDevice.Commit()
-	txResetPin.Commit()
+	if err = svc.otpService.CompleteDelivery(c, otpData); err != nil {
+		svc.clogger.ErrorLogger(c, "VerifyOtpService.otpService.CompleteDelivery", err)
+	}
 
 	jsonUser, _ := json.Marshal(userData)
 	expireAcc := time.Now().Add(svc.rootConfig.App.AccessKeyExpire)
@@ -774,23 +859,6 @@ func (svc *userAuthService) VerifyOtpService(c context.Context, req *request.Ver
 		Data:       response.VerifyOtp{AccessKey: newUUID, ExpireAt: expireAcc},
 	}
 }
-func (svc *userAuthService) GenerateOtp(c context.Context, value string, uuidKey string) (string, error) {
-	var (
-		exist = false
-		err   error
-	)
-	mathRand.Seed(time.Now().UnixNano())
-	otp := fmt.Sprintf("%06d", mathRand.Intn(1000000))
-
-	if exist, err = svc.redis.OtpIsExist(c, otp, uuidKey); err != nil {
-		svc.clogger.ErrorLogger(c, "GenerateOtp.svc.redis.OtpIsExist", err)
-		return "", err
-	}
-	if exist {
-		return svc.GenerateOtp(c, value, uuidKey)
-	}
-	return otp, err
-}
 func (svc *userAuthService) SendOtpService(ctx context.Context, req *request.SendOtpRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
 	logData.Remarks = fmt.Sprintf("send-otp:%s:%s", req.OtpMethod, req.ServiceType)
 	user, err := svc.userRespository.SelectUserByEmailOrPhoneNumber(ctx, req.VerifyTo)
@@ -819,6 +887,17 @@ func (svc *userAuthService) SendOtpService(ctx context.Context, req *request.Sen
 	logData.Email = user.Email
 	aksesKey := uuid.New().String()
 	wrapContext := helpers.WrapContext(ctx)
+	sendOtp := &dto.SendOtp{
+		OtpPurpose:          req.ServiceType,
+		OtpMethod:           req.OtpMethod,
+		OtpDestination:      req.VerifyTo,
+		UserId:              user.ID,
+		UserUUID:            user.UUID,
+		VerifyKey:           aksesKey,
+		FallbackEmail:       user.Email,
+		FallbackPhoneNumber: user.PhoneNumber,
+	}
+	var otpData *entity.OTP
 	switch req.ServiceType {
 	case enum.OTP_VERIFY_ACCOUNT:
 		if user.Status != enum.VERIFICATION_STATUS_UNVERIFIED {
@@ -828,26 +907,12 @@ func (svc *userAuthService) SendOtpService(ctx context.Context, req *request.Sen
 			}
 		}
 
-		otpData, err := svc.sendOtp(wrapContext, &dto.SendOtp{
-			OtpPurpose:     req.ServiceType,
-			OtpMethod:      req.OtpMethod,
-			OtpDestination: req.VerifyTo,
-			UserId:         user.ID,
-			UserUUID:       user.UUID,
-			VerifyKey:      aksesKey,
-		})
+		otpData, err = svc.otpService.SendOtp(wrapContext, sendOtp)
 		if err != nil {
 			logData.Error = err.Error()
-			return &dto.BaseResponse{
-				StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
-				Message:    pkgErr.SERVER_BUSY,
-				Error:      err.Error(),
+			if resp := otp.ErrorResponse(err, pkgErr.AUTH_INVALID_OTP_CODE, pkgErr.INVALID_OTP_MSG); resp != nil {
+				return resp
 			}
-		}
-		jsonOtp, _ := json.Marshal(otpData)
-		if err = svc.redis.SetOtp(ctx, otpData.OtpCode, aksesKey, string(jsonOtp), svc.rootConfig.App.OtpExpire); err != nil {
-			logData.Error = err.Error()
-			svc.clogger.ErrorLogger(ctx, "GenerateOtp.redis.SetOtp", err)
 			return &dto.BaseResponse{
 				StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
 				Message:    pkgErr.SERVER_BUSY,
@@ -863,26 +928,12 @@ func (svc *userAuthService) SendOtpService(ctx context.Context, req *request.Sen
 			}
 		}
 
-		otpData, err := svc.sendOtp(ctx, &dto.SendOtp{
-			OtpPurpose:     req.ServiceType,
-			OtpMethod:      req.OtpMethod,
-			OtpDestination: req.VerifyTo,
-			UserId:         user.ID,
-			UserUUID:       user.UUID,
-			VerifyKey:      aksesKey,
-		})
+		otpData, err = svc.otpService.SendOtp(ctx, sendOtp)
 		if err != nil {
 			logData.Error = err.Error()
-			return &dto.BaseResponse{
-				StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
-				Message:    pkgErr.SERVER_BUSY,
-				Error:      err.Error(),
+			if resp := otp.ErrorResponse(err, pkgErr.AUTH_INVALID_OTP_CODE, pkgErr.INVALID_OTP_MSG); resp != nil {
+				return resp
 			}
-		}
-		jsonOtp, _ := json.Marshal(otpData)
-		if err = svc.redis.SetOtp(ctx, otpData.OtpCode, aksesKey, string(jsonOtp), svc.rootConfig.App.OtpExpire); err != nil {
-			svc.clogger.ErrorLogger(ctx, "SendOtpService.ForgotPin.redis.SetOtp", err)
-			logData.Error = err.Error()
 			return &dto.BaseResponse{
 				StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
 				Message:    pkgErr.SERVER_BUSY,
@@ -904,104 +955,49 @@ func (svc *userAuthService) SendOtpService(ctx context.Context, req *request.Sen
 		Message:    pkgErr.SUCCES_MSG,
 		Error:      "",
 		Data: response.SendOtpResponse{
-			VerifyId: aksesKey,
-			ExpireAt: time.Now().Add(svc.rootConfig.App.OtpExpire),
+			VerifyId:    aksesKey,
+			OtpMethod:   otpData.OtpMethod,
+			Destination: helpers.MaskDestination(otpData.OtpDestination),
+			ExpireAt:    otpData.ExpiredAt,
 		},
 	}
 }
-func (svc *userAuthService) sendOtp(ctx context.Context, req *dto.SendOtp) (*entity.OTP, error) {
-	var (
-		jsonData, _ = json.Marshal(req)
-		otpData     = entity.OTP{
-			OtpCode:        "",
-			OtpPurpose:     req.OtpPurpose,
-			OtpMethod:      req.OtpMethod,
-			OtpDestination: req.OtpDestination,
-			UserId:         req.UserId,
-			UserUUID:       req.UserUUID,
-			VerifyKey:      req.VerifyKey,
-			ExpiredAt:      time.Now().Add(svc.rootConfig.App.OtpExpire),
-			SessionId:      "",
-			Status:         "",
-		}
-	)
 
-	switch req.OtpMethod {
-	case enum.TYPE_EMAIL:
-		otpData.Status = enum.OTP_DELIVERED
-		otp, err := svc.GenerateOtp(ctx, string(jsonData), req.VerifyKey)
-		if err != nil {
-			return nil, err
-		}
-		if err := svc.smtp.SendMail(ctx, []string{req.OtpDestination}, enum.VERIFY_OTP_SUBJECT, svc.smtp.RegisterOtpMsg(otp)); err != nil {
-			svc.clogger.ErrorLogger(ctx, "sendOtp.smtp.SendMail", err)
-			otpData.Status = enum.OTP_FAILED
-		}
-		otpData.OtpCode = otp
-		otpData.Status = enum.OTP_DELIVERED
-		otpData.SessionId = req.VerifyKey
-	case enum.TYPE_SMS:
-		var otp *string
-		if !svc.rootConfig.Verihubs.VerihubsOTPCode {
-			otpStr, err := svc.GenerateOtp(ctx, string(jsonData), req.VerifyKey)
-			if err != nil {
-				return nil, err
-			}
-			otp = &otpStr
-		}
-		resp, err := svc.outboundVeriHubsSvc.SendSMSOtpService(ctx, &verihubsDto.SendOtpBaseRequest{
-			MSISDN:      req.OtpDestination,
-			Otp:         otp,
-			Template:    svc.rootConfig.Verihubs.OTPSMSTemplate,
-			TimeLimit:   int64(svc.rootConfig.App.OtpExpire.Seconds()),
-			Challenge:   svc.rootConfig.Verihubs.OTPSMSChallenge,
-			CallbackUrl: svc.rootConfig.Verihubs.OTPCallBackUrl,
-		})
-		if err != nil {
-			otpData.Status = enum.OTP_FAILED
-			svc.clogger.ErrorLogger(ctx, "sendOtp.outboundVeriHubsSvc.SendSMSOtpService", err)
-			return nil, err
+// OtpStatusService tells the app which channel the otp ended up on, it may have
+// moved to another channel after SendOtpService answered.
+func (svc *userAuthService) OtpStatusService(ctx context.Context, req *request.OtpStatusRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	delivery, err := svc.otpService.DeliveryStatus(ctx, req.VerifyID)
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
 		}
-		otpData.OtpCode = resp.Otp
-		otpData.SessionId = resp.SessionID
-		otpData.Status = enum.OTP_DELIVERED
-	case enum.TYPE_WHATSAPP:
-		var otp *string
-		if !svc.rootConfig.Verihubs.VerihubsOTPCode {
-			otpStr, err := svc.GenerateOtp(ctx, string(jsonData), req.VerifyKey)
-			if err != nil {
-				return nil, err
-			}
-			otp = &otpStr
-		}
-		resp, err := svc.outboundVeriHubsSvc.SendWhatsappsService(ctx, &verihubsDto.SendWhatsappOtpBaseRequest{
-			MSISDN:       req.OtpDestination,
-			Otp:          otp,
-			Challenge:    nil,
-			TimeLimit:    int64(svc.rootConfig.App.OtpExpire.Seconds()),
-			LangCode:     svc.rootConfig.Verihubs.OTPWhatsappLangCode,
-			TemplateName: svc.rootConfig.Verihubs.OTPWhatsappTemplateName,
-			OtpLength:    svc.rootConfig.Verihubs.OTPWhatsappOtpLength,
-			CallbackUrl:  svc.rootConfig.Verihubs.OTPCallBackUrl,
-		})
-		if err != nil {
-			otpData.Status = enum.OTP_FAILED
+	}
+	if delivery == nil || delivery.Attempt < 0 {
+		logData.Error = "otp delivery not found"
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_RECORD_NOT_FOUND_CODE,
+			Message:    pkgErr.RECORD_NOT_FOUND_MSG,
 		}
-		otpData.OtpCode = resp.Otp
-		otpData.SessionId = resp.SessionID
-		otpData.Status = enum.OTP_DELIVERED
-		otpData.UserUUID = req.UserUUID
-	default:
-
 	}
-	tx := svc.otpRepository.Tx(ctx)
-	err := svc.otpRepository.InsertOtpDataRepository(ctx, tx, &otpData)
-	if err != nil {
-		tx.Rollback()
-		return nil, err
+	logData.UserUUID = delivery.UserUUID
+	channel := delivery.Channels[delivery.Attempt]
+	logData.Success = true
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+		Data: response.OtpStatusResponse{
+			VerifyId:    delivery.VerifyKey,
+			OtpMethod:   channel.OtpMethod,
+			Destination: helpers.MaskDestination(channel.OtpDestination),
+			Status:      delivery.Status,
+			Delivered:   delivery.Confirmed,
+			Fallback:    delivery.Attempt > 0,
+			ExpireAt:    delivery.ExpiredAt,
+		},
 	}
-	tx.Commit()
-	return svc.otpRepository.SelectOtpByVerifyKeyBeforeExpire(ctx, req.VerifyKey)
 }
 func (svc *userAuthService) SetPinService(c context.Context, req *request.SetPinRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
 	var (
@@ -1017,15 +1013,27 @@ func (svc *userAuthService) SetPinService(c context.Context, req *request.SetPin
 			Error:      "deference confirmed pin",
 		}
 	}
+	accessKey := req.AccountToken
+	signed := isResetToken(req.AccountToken)
+	if signed {
+		accessKey, err = parseResetToken(svc.rootConfig.ResetLink.Secret, req.AccountToken, time.Now())
+		if err != nil {
+			logData.Error = err.Error()
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.AUTH_INVALID_ACCESS_CODE,
+				Message:    pkgErr.INVALID_ACCESS_KEY_MSG,
+			}
+		}
+	}
 	g := errgroup.Group{}
 	g.Go(func() error {
 		var errTmp error
-		pinData, errTmp = svc.AccessStateRepository.SelectAccessByAccessTokenRepository(c, req.AccountToken)
+		pinData, errTmp = svc.AccessStateRepository.SelectAccessByAccessTokenRepository(c, accessKey)
 		return errTmp
 	})
 	g.Go(func() error {
 		var errTmp error
-		userJson, errTmp := svc.redis.GetAccessKey(c, req.AccountToken)
+		userJson, errTmp := svc.redis.GetAccessKey(c, accessKey)
 		if errTmp != nil {
 			return errTmp
 		}
@@ -1033,7 +1041,7 @@ func (svc *userAuthService) SetPinService(c context.Context, req *request.SetPin
 			userData, errTmp = svc.userRespository.SelectUserByEmailOrPhoneNumber(c, req.EmailOrPhoneNumber)
 			return errTmp
 		}
-		_ = svc.redis.DeleteAccessKey(c, req.AccountToken)
+		_ = svc.redis.DeleteAccessKey(c, accessKey)
 		var user entity.User
 		errTmp = json.Unmarshal([]byte(userJson), &user)
 		if errTmp != nil {
@@ -1067,12 +1075,38 @@ func (svc *userAuthService) SetPinService(c context.Context, req *request.SetPin
 			Message:    pkgErr.INVALID_ACCESS_KEY_MSG,
 		}
 	}
+	// a forgot pin access is only good through the signed link, and only for
+	// the user it was issued to
+	if (pinData.AccessType == enum.ACCESS_FORGOT_PIN && !signed) || pinData.UserUUID != userData.UUID || time.Now().After(pinData.ExpiredAt) {
+		logData.Error = "access key not valid for this request"
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_INVALID_ACCESS_CODE,
+			Message:    pkgErr.INVALID_ACCESS_KEY_MSG,
+		}
+	}
 
-	tx := svc.userRespository.Tx(c)
 	hashPin, _ := bcrypt.GenerateFromPassword([]byte(req.Pin), bcrypt.DefaultCost)
-	err = svc.userRespository.UpdateUser(c, tx, userData, &entity.User{Pin: string(hashPin)})
+	err = svc.unitOfWork.Do(c, func(c context.Context, tx *gorm.DB) error {
+		consumed, err := svc.AccessStateRepository.ConsumeAccessState(c, tx, pinData)
+		if err != nil {
+			return err
+		}
+		if !consumed {
+			return errAccessKeyUsed
+		}
+		if err = svc.userRespository.UpdateUser(c, tx, userData, &entity.User{Pin: string(hashPin)}); err != nil {
+			return err
+		}
+		return svc.userRespository.BumpTokenVersion(c, tx, userData)
+	})
+	if errors.Is(err, errAccessKeyUsed) {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.AUTH_INVALID_ACCESS_CODE,
+			Message:    pkgErr.INVALID_ACCESS_KEY_MSG,
+		}
+	}
 	if err != nil {
-		tx.Rollback()
 		logData.Error = err.Error()
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
@@ -1080,12 +1114,18 @@ func (svc *userAuthService) SetPinService(c context.Context, req *request.SetPin
 			Error:      err.Error(),
 		}
 	}
-	tx.Commit()
-	tx = svc.AccessStateRepository.Tx(c)
-	_ = svc.AccessStateRepository.UpdateAccessByStruct(c, tx, pinData, &entity.AccessState{
-		Used: true,
-	})
-	tx.Commit()
+	// new pin lifts the brute-force lock, including the permanent one
+	_ = svc.pinAttemptService.Reset(c, userData.UUID)
+	// the pin is set without a session, every session of the user is logged out
+	if _, err = svc.tokenFamilyService.RetireTokenVersion(c, userData, "", req.DeviceID, tokenFamilySvc.REVOKE_REASON_CREDENTIAL); err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	svc.deviceService.NotifyCredentialChanged(c, userData, enum.PROFILE_UPDATE_PIN, req.DeviceID)
 	logData.Success = true
 	return &dto.BaseResponse{
 		StatusCode: pkgErr.SUCCESS_CODE,
@@ -1099,7 +1139,15 @@ func (svc *userAuthService) ForgotPinService(ctx context.Context, req *request.F
 		updaterUser *entity.User
 		newDevice   *entity.Device
 	)
-	user, err := svc.userRespository.SelectUserByEmailOrPhoneNumber(ctx, req.Email)
+	callback, err := url.Parse(req.CallbackUrl)
+	if err != nil || !svc.rootConfig.ResetLink.Allows(callback) || svc.rootConfig.ResetLink.Secret == "" {
+		logData.Error = fmt.Sprintf("callback url not allowed: %s", req.CallbackUrl)
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.RESET_LINK_NOT_ALLOWED_CODE,
+			Message:    pkgErr.RESET_LINK_NOT_ALLOWED_MSG,
+		}
+	}
+	user, err = svc.userRespository.SelectUserByEmailOrPhoneNumber(ctx, req.Email)
 	if err != nil {
 		logData.Error = err.Error()
 		return &dto.BaseResponse{
@@ -1127,42 +1175,34 @@ func (svc *userAuthService) ForgotPinService(ctx context.Context, req *request.F
 	user.DeviceID = req.DeviceID
 	jsonUser, _ := json.Marshal(user)
 
-	txUser := svc.userRespository.Tx(ctx)
-	txDevice := svc.userRespository.Tx(ctx)
-	txPin := svc.AccessStateRepository.Tx(ctx)
-	g, _ := errgroup.WithContext(ctx)
 	accessKey := uuid.New().String()
-	g.Go(func() error {
+	expireAt := time.Now().Add(svc.rootConfig.App.AccessKeyExpire)
+	err = svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
 		if updaterUser != nil {
-			return svc.userRespository.UpdateUser(ctx, txUser, user, updaterUser)
+			if err := svc.userRespository.UpdateUser(ctx, tx, user, updaterUser); err != nil {
+				return err
+			}
 		}
-		return nil
-	})
-	g.Go(func() error {
 		if newDevice != nil {
-			return svc.deviceRepository.InsertDevice(ctx, txDevice, newDevice)
+			if err := svc.deviceRepository.InsertDevice(ctx, tx, newDevice); err != nil {
+				return err
+			}
 		}
-		return nil
-	})
-	g.Go(func() error {
-		return svc.redis.SetAccessKey(ctx, accessKey, string(jsonUser), svc.rootConfig.App.AccessKeyExpire)
-	})
-	g.Go(func() error {
-		return svc.AccessStateRepository.InsertAccessStateRepository(ctx, txPin, &entity.AccessState{
+		if err := svc.AccessStateRepository.InsertAccessStateRepository(ctx, tx, &entity.AccessState{
 			AccessType:  enum.ACCESS_FORGOT_PIN,
 			UserId:      user.ID,
 			UserUUID:    user.UUID,
 			DeviceId:    req.DeviceID,
 			AccessToken: accessKey,
-			ExpiredAt:   time.Now().Add(svc.rootConfig.App.AccessKeyExpire),
+			ExpiredAt:   expireAt,
 			Used:        false,
-		})
+		}); err != nil {
+			return err
+		}
+		// last, a key left behind by a failed commit has no access state to unlock
+		return svc.redis.SetAccessKey(ctx, accessKey, string(jsonUser), svc.rootConfig.App.AccessKeyExpire)
 	})
-	err = g.Wait()
 	if err != nil {
-		txUser.Rollback()
-		txDevice.Rollback()
-		txPin.Rollback()
 		logData.Error = err.Error()
 
 		return &dto.BaseResponse{
@@ -1171,14 +1211,110 @@ func (svc *userAuthService) ForgotPinService(ctx context.Context, req *request.F
 			Error:      err.Error(),
 		}
 	}
-	txUser.Commit()
-	txDevice.Commit()
-	txPin.Commit()
-	url := fmt.Sprintf("%s%s", req.CallbackUrl, fmt.Sprintf("?acces_key=%s", accessKey))
-	svc.smtp.SendMail(ctx, []string{user.Email}, enum.ACCESS_RESET_PIN_SUBJECT, url)
+	link := resetLink(callback, resetToken(svc.rootConfig.ResetLink.Secret, accessKey, expireAt))
+	body, err := svc.smtp.ResetPinMsg(link, expireAt)
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	svc.smtp.SendHTMLMail(ctx, []string{user.Email}, enum.ACCESS_RESET_PIN_SUBJECT, body)
+	logData.Success = true
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+	}
+}
+
+// deviceChallenge answers a login from a device the user has not trusted yet:
+// no token is issued, an otp is sent instead to bind the device.
+func (svc *userAuthService) deviceChallenge(ctx context.Context, user *entity.User, deviceID string, method enum.OtpType, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	logData.Remarks = fmt.Sprintf("%s:new-device", logData.Remarks)
+	challenge, err := svc.deviceService.StartBinding(ctx, user, deviceID, method)
+	if err != nil {
+		logData.Error = err.Error()
+		if resp := otp.ErrorResponse(err, pkgErr.DEVICE_VERIFICATION_INVALID_CODE, pkgErr.DEVICE_VERIFICATION_INVALID_MSG); resp != nil {
+			return resp
+		}
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	logData.Error = "device verification required"
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.DEVICE_VERIFICATION_REQUIRED_CODE,
+		Message:    pkgErr.DEVICE_VERIFICATION_REQUIRED_MSG,
+		Data:       challenge,
+	}
+}
+
+func (svc *userAuthService) VerifyDeviceService(ctx context.Context, req *request.VerifyDeviceRequest, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	user, err := svc.deviceService.ConfirmBinding(ctx, req)
+	if err != nil {
+		logData.Error = err.Error()
+		if resp := otp.ErrorResponse(err, pkgErr.DEVICE_VERIFICATION_INVALID_CODE, pkgErr.DEVICE_VERIFICATION_INVALID_MSG); resp != nil {
+			return resp
+		}
+		if errors.Is(err, deviceSvc.ErrDeviceBindingInvalid) {
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.DEVICE_VERIFICATION_INVALID_CODE,
+				Message:    pkgErr.DEVICE_VERIFICATION_INVALID_MSG,
+			}
+		}
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	logData.UserUUID = user.UUID
+	logData.Email = user.Email
+	// logging in again inside the grace period takes the deletion request back
+	if err = svc.accountService.CancelDeletion(ctx, user); err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	mustReaccept, err := svc.consentService.MustReaccept(ctx, user)
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	token, err := svc.tokenFamilyService.IssueTokens(ctx, user, req.DeviceID)
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	svc.loginEventService.RecordDeviceVerified(ctx, user, req.DeviceID)
 	logData.Success = true
 	return &dto.BaseResponse{
 		StatusCode: pkgErr.SUCCESS_CODE,
 		Message:    pkgErr.SUCCES_MSG,
+		Data: response.LoginResponse{
+			User: response.UserData{
+				UUID:  user.UUID,
+				Name:  user.FullName,
+				Email: user.Email,
+				Phone: user.PhoneNumber,
+			},
+			Token:               *token,
+			MustReacceptConsent: mustReaccept,
+		},
 	}
 }
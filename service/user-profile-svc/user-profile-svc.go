@@ -3,8 +3,8 @@ package userProfileService
 import (
 	"backend-mobile-api/app/config"
 	"backend-mobile-api/helpers"
+	"backend-mobile-api/internal/outbond/provider"
 	"backend-mobile-api/internal/outbond/smtp"
-	"backend-mobile-api/internal/outbond/verihubs"
 	"backend-mobile-api/internal/repository/minio"
 	"backend-mobile-api/internal/repository/postgres"
 	"backend-mobile-api/internal/repository/redis"
@@ -14,7 +14,12 @@ import (
 	"backend-mobile-api/model/entity"
 	"backend-mobile-api/model/enum"
 	"backend-mobile-api/model/enum/pkgErr"
+	accountSvc "backend-mobile-api/service/account-svc"
+	"backend-mobile-api/service/biometricSvc"
+	deviceSvc "backend-mobile-api/service/device-svc"
 	"backend-mobile-api/service/otp"
+	pinAttemptSvc "backend-mobile-api/service/pin-attempt-svc"
+	tokenFamilySvc "backend-mobile-api/service/token-family-svc"
 	"context"
 	"encoding/json"
 	"errors"
@@ -34,10 +39,16 @@ type userProfileService struct {
 	clogger               *helpers.CustomLogger
 	AccessStateRepository postgres.AccessStateRepository
 	otpRepository         postgres.OtpRepository
-	outboundVeriHubsSvc   verihubs.OutboundVeriHubsService
+	otpProvider           provider.OtpProvider
 	userDetailRepository  postgres.UserDetailRepository
 	otpService            otp.OtpService
 	minioRepository       minio.MinioRepository
+	pinAttemptService     pinAttemptSvc.PinAttemptService
+	deviceService         deviceSvc.DeviceService
+	biometricService      biometricSvc.BiometricService
+	unitOfWork            postgres.UnitOfWork
+	accountService        accountSvc.AccountService
+	tokenFamilyService    tokenFamilySvc.TokenFamilyService
 }
 
 func NewUserProfileService(
@@ -48,10 +59,16 @@ func NewUserProfileService(
 	clogger *helpers.CustomLogger,
 	AccessStateRepository postgres.AccessStateRepository,
 	otpRepository postgres.OtpRepository,
-	outboundVeriHubsSvc verihubs.OutboundVeriHubsService,
+	otpProvider provider.OtpProvider,
 	userDetailRepository postgres.UserDetailRepository,
 	otpService otp.OtpService,
-	minioRepository minio.MinioRepository) UserProfileService {
+	minioRepository minio.MinioRepository,
+	pinAttemptService pinAttemptSvc.PinAttemptService,
+	deviceService deviceSvc.DeviceService,
+	biometricService biometricSvc.BiometricService,
+	unitOfWork postgres.UnitOfWork,
+	accountService accountSvc.AccountService,
+	tokenFamilyService tokenFamilySvc.TokenFamilyService) UserProfileService {
 	return &userProfileService{
 		userRepository:        userRepository,
 		redis:                 redis,
@@ -60,10 +77,16 @@ func NewUserProfileService(
 		clogger:               clogger,
 		AccessStateRepository: AccessStateRepository,
 		otpRepository:         otpRepository,
-		outboundVeriHubsSvc:   outboundVeriHubsSvc,
+		otpProvider:           otpProvider,
 		userDetailRepository:  userDetailRepository,
 		otpService:            otpService,
 		minioRepository:       minioRepository,
+		pinAttemptService:     pinAttemptService,
+		deviceService:         deviceService,
+		biometricService:      biometricService,
+		unitOfWork:            unitOfWork,
+		accountService:        accountService,
+		tokenFamilyService:    tokenFamilyService,
 	}
 }
 
@@ -80,6 +103,7 @@ type UserProfileService interface {
 	BiometricStatusService(ctx context.Context, req *request.BiometrictStatusRequest, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse
 	ResetProfilePictureService(ctx context.Context, req *request.ResetProfilePictureRequest, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse
 	GetProfilePictureController(ctx context.Context, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse
+	ExportDataService(ctx context.Context, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse
 }
 
 func (svc *userProfileService) AccessTokenService(ctx context.Context, req *request.AccessTokenRequest, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
@@ -121,28 +145,22 @@ func (svc *userProfileService) AccessTokenService(ctx context.Context, req *requ
 	logData.UserUUID = user.UUID
 	logData.Email = user.Email
 
-	if user.DeviceID != req.DeviceID || user.DeviceID != customResource.HeaderXDeviceID {
+	if req.DeviceID != customResource.HeaderXDeviceID || !svc.deviceService.IsTrusted(ctx, user, req.DeviceID) {
 		logData.Error = "deference device found"
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.PROFILE_DEFERENCE_DEVICE_CODE,
 			Message:    pkgErr.DEFERENCE_DEVICE_MSG,
 		}
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(user.Pin), []byte(req.Pin))
+	pinStatus, err := svc.pinAttemptService.VerifyPin(ctx, user, customResource.HeaderXDeviceID, req.Pin)
 	if err != nil {
 		logData.Error = err.Error()
-		return &dto.BaseResponse{
-			StatusCode: pkgErr.AUTH_UNAUTHORIZED_CODE,
-			Message:    pkgErr.INVALID_PIN,
-		}
+		return pinAttemptSvc.ErrorResponse(err, pinStatus, pkgErr.AUTH_UNAUTHORIZED_CODE, pkgErr.INVALID_PIN)
 	}
 	accessKey := uuid.New().String()
 	expired := time.Now().Add(svc.rootConfig.App.AccessKeyExpire)
-	txAccess := svc.AccessStateRepository.Tx(ctx)
-
-	g := errgroup.Group{}
-	g.Go(func() error {
-		return svc.AccessStateRepository.InsertAccessStateRepository(ctx, txAccess, &entity.AccessState{
+	err = svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		if err := svc.AccessStateRepository.InsertAccessStateRepository(ctx, tx, &entity.AccessState{
 			AccessType:  req.AccessType,
 			UserId:      user.ID,
 			UserUUID:    user.UUID,
@@ -150,16 +168,14 @@ func (svc *userProfileService) AccessTokenService(ctx context.Context, req *requ
 			AccessToken: accessKey,
 			ExpiredAt:   expired,
 			Used:        false,
-		})
-	})
-	g.Go(func() error {
+		}); err != nil {
+			return err
+		}
 		var jsonUser, _ = json.Marshal(user)
 
 		return svc.redis.SetAccessKey(ctx, accessKey, string(jsonUser), svc.rootConfig.App.AccessKeyExpire)
 	})
-
-	if err = g.Wait(); err != nil {
-		txAccess.Rollback()
+	if err != nil {
 		logData.Error = err.Error()
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
@@ -167,7 +183,6 @@ func (svc *userProfileService) AccessTokenService(ctx context.Context, req *requ
 			Error:      err.Error(),
 		}
 	}
-	txAccess.Commit()
 	logData.Success = true
 	return &dto.BaseResponse{
 		StatusCode: pkgErr.SUCCESS_CODE,
@@ -195,17 +210,14 @@ func (svc *userProfileService) sendOtpUpdateProfile(ctx context.Context, req *en
 	})
 	if err != nil {
 		logData.Error = err.Error()
+		if resp := otp.ErrorResponse(err, pkgErr.PROFILE_INVALID_OTP_CODE, pkgErr.INVALID_OTP_MSG); resp != nil {
+			return resp
+		}
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
 			Message:    pkgErr.SERVER_BUSY,
 		}
 	}
-	JsonOtp, _ := json.Marshal(otpData)
-	err = svc.redis.SetOtp(ctx, otpData.OtpCode, otpData.VerifyKey, string(JsonOtp), svc.rootConfig.App.OtpExpire)
-	if err != nil {
-		logData.Error = err.Error()
-		return &dto.BaseResponse{StatusCode: pkgErr.UNDEFINED_ERROR_CODE, Message: pkgErr.SERVER_BUSY}
-	}
 	err = svc.redis.SetDataUpdateUserProfile(ctx, otpKey, newProfileRequest, svc.rootConfig.App.AccessKeyExpire)
 	if err != nil {
 		svc.clogger.ErrorLogger(ctx, "SetDataUpdateUserProfile", err)
@@ -279,7 +291,7 @@ func (svc *userProfileService) InquiryUserProfileService(ctx context.Context, us
 			Message:    pkgErr.USER_NOT_FOUND_MSG,
 		}
 	}
-	if customResource.HeaderXDeviceID != user.DeviceID {
+	if !svc.deviceService.IsTrusted(ctx, user, customResource.HeaderXDeviceID) {
 		logData.Error = "invalid device id"
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.PROFILE_DEFERENCE_DEVICE_CODE,
@@ -379,7 +391,7 @@ func (svc *userProfileService) ResetPinService(ctx context.Context, req *request
 			Message:    pkgErr.EXPIRED_TIME_MSG,
 		}
 	}
-	if req.DeviceID != user.DeviceID {
+	if !svc.deviceService.IsTrusted(ctx, user, req.DeviceID) {
 		logData.Error = "invalid device id"
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.AUTH_DEFERENCE_DEVICE_CODE,
@@ -648,6 +660,9 @@ func (svc *userProfileService) VerifyOtpService(ctx context.Context, req *reques
 	})
 	if err != nil {
 		logData.Error = err.Error()
+		if resp := otp.ErrorResponse(err, pkgErr.PROFILE_INVALID_OTP_CODE, pkgErr.INVALID_OTP_MSG); resp != nil {
+			return resp
+		}
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.PROFILE_INVALID_OTP_CODE,
 			Message:    pkgErr.INVALID_OTP_MSG,
@@ -677,7 +692,7 @@ func (svc *userProfileService) VerifyOtpService(ctx context.Context, req *reques
 		"update_data": updateProfileData.Field,
 		"value":       updateProfileData.Value,
 	}
-	if user.DeviceID != req.DeviceID {
+	if !svc.deviceService.IsTrusted(ctx, user, req.DeviceID) {
 		logData.Error = "invalid device id"
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.PROFILE_DEFERENCE_DEVICE_CODE,
@@ -693,20 +708,42 @@ func (svc *userProfileService) VerifyOtpService(ctx context.Context, req *reques
 	case enum.PROFILE_UPDATE_PHONE_NUMBER:
 		updateUser.PhoneNumber = updateProfileData.Value
 	}
-	userTx := svc.userRepository.Tx(ctx)
-	err = svc.userRepository.UpdateUser(ctx, userTx, user, &updateUser)
+	// every field here is a credential, the tokens issued before the change stop working
+	err = svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		if err := svc.userRepository.UpdateUser(ctx, tx, user, &updateUser); err != nil {
+			return err
+		}
+		return svc.userRepository.BumpTokenVersion(ctx, tx, user)
+	})
 	if err != nil {
 		logData.Error = err.Error()
-		userTx.Rollback()
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
 			Message:    pkgErr.SERVER_BUSY}
 	}
-	userTx.Commit()
+	if updateProfileData.Field == enum.PROFILE_UPDATE_PIN {
+		_ = svc.pinAttemptService.Reset(ctx, user.UUID)
+	}
+	currentFamilyID := ""
+	if customResource, ok := ctx.Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue); ok {
+		currentFamilyID = customResource.AuthFamilyID
+	}
+	// a token issued before sessions existed has no family, that device logs in again as well
+	tokenData, err := svc.tokenFamilyService.RetireTokenVersion(ctx, user, currentFamilyID, req.DeviceID, tokenFamilySvc.REVOKE_REASON_CREDENTIAL)
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	svc.deviceService.NotifyCredentialChanged(ctx, user, updateProfileData.Field, req.DeviceID)
 	logData.Success = true
 	return &dto.BaseResponse{
 		StatusCode: pkgErr.SUCCESS_CODE,
 		Message:    pkgErr.SUCCES_MSG,
+		Data:       response.CredentialChangedResponse{Token: tokenData},
 	}
 }
 func (svc *userProfileService) ResetFullNameService(ctx context.Context, req *request.ResetFullNameRequest, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
@@ -732,7 +769,7 @@ func (svc *userProfileService) ResetFullNameService(ctx context.Context, req *re
 	logData.UserUUID = user.UUID
 	logData.Email = user.Email
 
-	if req.DeviceID != user.DeviceID {
+	if !svc.deviceService.IsTrusted(ctx, user, req.DeviceID) {
 		logData.Error = "invalid device id"
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.PROFILE_DEFERENCE_DEVICE_CODE,
@@ -740,10 +777,10 @@ func (svc *userProfileService) ResetFullNameService(ctx context.Context, req *re
 		}
 
 	}
-	txuser := svc.userRepository.Tx(ctx)
-	err = svc.userRepository.UpdateUser(ctx, txuser, user, &entity.User{FullName: req.FullName})
+	err = svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		return svc.userRepository.UpdateUser(ctx, tx, user, &entity.User{FullName: req.FullName})
+	})
 	if err != nil {
-		txuser.Rollback()
 		logData.Error = err.Error()
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
@@ -751,7 +788,6 @@ func (svc *userProfileService) ResetFullNameService(ctx context.Context, req *re
 			Error:      err.Error(),
 		}
 	}
-	txuser.Commit()
 	logData.Success = true
 	return &dto.BaseResponse{
 		StatusCode: pkgErr.SUCCESS_CODE,
@@ -809,7 +845,7 @@ func (svc *userProfileService) DeleteAccountService(ctx context.Context, req *re
 	}
 	logData.UserUUID = user.UUID
 	logData.Email = user.Email
-	if user.DeviceID != req.DeviceID {
+	if !svc.deviceService.IsTrusted(ctx, user, req.DeviceID) {
 		logData.Error = "invalid device id"
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.PROFILE_DEFERENCE_DEVICE_CODE,
@@ -837,43 +873,40 @@ func (svc *userProfileService) DeleteAccountService(ctx context.Context, req *re
 			Message:    pkgErr.EXPIRED_TIME_MSG,
 		}
 	}
-	txuser := svc.userRepository.Tx(ctx)
-	txAccess := svc.AccessStateRepository.Tx(ctx)
-	g = errgroup.Group{}
-	g.Go(func() error {
-		return svc.AccessStateRepository.UpdateAccessByStruct(ctx, txAccess, accessState, &entity.AccessState{Used: true})
-	})
-	g.Go(func() error {
-		return svc.userRepository.UpdateUser(ctx, txuser, user, &entity.User{Status: enum.USER_INACTIVE})
+	var deletion *entity.AccountDeletion
+	err = svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		if err := svc.AccessStateRepository.UpdateAccessByStruct(ctx, tx, accessState, &entity.AccessState{Used: true}); err != nil {
+			return err
+		}
+		var err error
+		deletion, err = svc.accountService.RequestDeletion(ctx, user)
+		return err
 	})
-	err = g.Wait()
 	if err != nil {
 		logData.Error = err.Error()
-		txuser.Rollback()
-		txAccess.Rollback()
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
 			Message:    pkgErr.SERVER_BUSY,
 			Error:      err.Error(),
 		}
 	}
-	err = svc.userRepository.DeleteUser(ctx, txuser, user)
-	if err != nil {
+	// every session ends with the request, including the one that made it
+	if _, err = svc.tokenFamilyService.RetireTokenVersion(ctx, user, "", "", tokenFamilySvc.REVOKE_REASON_DELETION); err != nil {
 		logData.Error = err.Error()
-		txuser.Rollback()
-		txAccess.Rollback()
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
 			Message:    pkgErr.SERVER_BUSY,
 			Error:      err.Error(),
 		}
 	}
-	txuser.Commit()
-	txAccess.Commit()
 	logData.Success = true
 	return &dto.BaseResponse{
 		StatusCode: pkgErr.SUCCESS_CODE,
 		Message:    pkgErr.SUCCES_MSG,
+		Data: response.AccountDeletionResponse{
+			Status:  deletion.Status,
+			PurgeAt: deletion.PurgeAt,
+		},
 	}
 
 }
@@ -886,7 +919,7 @@ func (svc *userProfileService) BiometricStatusService(ctx context.Context, req *
 	g := errgroup.Group{}
 	g.Go(func() error {
 		var errTmp error
-		userDetail, err = svc.userDetailRepository.SelectUserDetailByUserUUID(ctx, userUUID)
+		userDetail, errTmp = svc.userDetailRepository.SelectUserDetailByUserUUID(ctx, userUUID)
 		if errTmp != nil {
 			errors.Is(errTmp, gorm.ErrRecordNotFound)
 			return nil
@@ -917,28 +950,55 @@ func (svc *userProfileService) BiometricStatusService(ctx context.Context, req *
 			Message:    pkgErr.USER_NOT_FOUND_MSG,
 		}
 	}
-	if req.DeviceID != user.DeviceID {
+	// a key is only managed for the device of the calling session
+	if !svc.isSessionDevice(ctx, user, req.DeviceID) || !svc.deviceService.IsTrusted(ctx, user, req.DeviceID) {
 		logData.Error = "invalid device id"
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.PROFILE_DEFERENCE_DEVICE_CODE,
 			Message:    pkgErr.DEFERENCE_DEVICE_MSG,
 		}
 	}
-	biometrictStatus := enum.BIOMETRIC_ACTIVE
-	if !req.Active {
-		biometrictStatus = enum.BIOMETRIC_IN_ACTIVE
+	if req.Active {
+		// an access token alone must not be enough to add a way to log in
+		pinStatus, err := svc.pinAttemptService.VerifyPin(ctx, user, req.DeviceID, req.Pin)
+		if err != nil {
+			logData.Error = err.Error()
+			return pinAttemptSvc.ErrorResponse(err, pinStatus, pkgErr.AUTH_UNAUTHORIZED_CODE, pkgErr.INVALID_PIN)
+		}
 	}
-	txUserDetail := svc.userRepository.Tx(ctx)
-	if err = svc.userDetailRepository.UpdateUserDetail(ctx, txUserDetail, userDetail, &entity.UserDetail{Biometric: biometrictStatus}); err != nil {
+	biometrictStatus := enum.BIOMETRIC_ACTIVE
+	err = svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		if req.Active {
+			if err := svc.biometricService.RegisterDeviceKey(ctx, tx, user, req.DeviceID, req.Algorithm, req.PublicKey); err != nil {
+				return err
+			}
+		} else {
+			// biometric stays on while another device still has a key
+			otherDevice, err := svc.biometricService.RevokeDeviceKeys(ctx, tx, user, req.DeviceID)
+			if err != nil {
+				return err
+			}
+			if !otherDevice {
+				biometrictStatus = enum.BIOMETRIC_IN_ACTIVE
+			}
+		}
+		return svc.userDetailRepository.UpdateUserDetail(ctx, tx, userDetail, &entity.UserDetail{Biometric: biometrictStatus})
+	})
+	if err != nil {
 		logData.Error = err.Error()
-		txUserDetail.Rollback()
+		if errors.Is(err, biometricSvc.ErrBiometricKeyInvalid) {
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.BIOMETRIC_KEY_INVALID_CODE,
+				Message:    pkgErr.BIOMETRIC_KEY_INVALID_MSG,
+				Error:      err.Error(),
+			}
+		}
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
 			Message:    pkgErr.SERVER_BUSY,
 			Error:      err.Error(),
 		}
 	}
-	txUserDetail.Commit()
 	logData.Success = true
 	return &dto.BaseResponse{
 		StatusCode: pkgErr.SUCCESS_CODE,
@@ -946,6 +1006,24 @@ func (svc *userProfileService) BiometricStatusService(ctx context.Context, req *
 	}
 }
 
+// isSessionDevice tells whether deviceID is the device the request comes from,
+// the device the token family was issued to when the token has one.
+func (svc *userProfileService) isSessionDevice(ctx context.Context, user *entity.User, deviceID string) bool {
+	customResource, ok := ctx.Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue)
+	if !ok || customResource.HeaderXDeviceID != deviceID {
+		return false
+	}
+	if customResource.AuthFamilyID == "" {
+		return true
+	}
+	sessionDevice, err := svc.tokenFamilyService.SessionDevice(ctx, user.UUID, customResource.AuthFamilyID)
+	if err != nil {
+		svc.clogger.ErrorLogger(ctx, "isSessionDevice.tokenFamilyService.SessionDevice", err)
+		return false
+	}
+	return sessionDevice == deviceID
+}
+
 func (svc *userProfileService) ResetProfilePictureService(ctx context.Context, req *request.ResetProfilePictureRequest, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
 	var (
 		userDt *entity.UserDetail
@@ -989,7 +1067,7 @@ func (svc *userProfileService) ResetProfilePictureService(ctx context.Context, r
 			Message:    pkgErr.USER_NOT_FOUND_MSG,
 		}
 	}
-	if req.DeviceID != user.DeviceID {
+	if !svc.deviceService.IsTrusted(ctx, user, req.DeviceID) {
 		logData.Error = "invalid device id"
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.PROFILE_DEFERENCE_DEVICE_CODE,
@@ -1008,18 +1086,17 @@ func (svc *userProfileService) ResetProfilePictureService(ctx context.Context, r
 			Error:      err.Error(),
 		}
 	}
-	txUserDetail := svc.userDetailRepository.Tx(ctx)
-	err = svc.userDetailRepository.UpdateUserDetail(ctx, txUserDetail, userDt, &entity.UserDetail{ProfilePicture: *objectName})
+	err = svc.unitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		return svc.userDetailRepository.UpdateUserDetail(ctx, tx, userDt, &entity.UserDetail{ProfilePicture: *objectName})
+	})
 	if err != nil {
 		logData.Error = err.Error()
-		txUserDetail.Rollback()
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
 			Message:    pkgErr.SERVER_BUSY,
 			Error:      err.Error(),
 		}
 	}
-	txUserDetail.Commit()
 	logData.Success = true
 	return &dto.BaseResponse{
 		StatusCode: pkgErr.SUCCESS_CODE,
@@ -1084,7 +1161,7 @@ func (svc *userProfileService) GetProfilePictureController(ctx context.Context,
 	}
 	logData.UserUUID = user.UUID
 	logData.Email = user.Email
-	if customResource.HeaderXDeviceID != user.DeviceID {
+	if !svc.deviceService.IsTrusted(ctx, user, customResource.HeaderXDeviceID) {
 		logData.Error = "invalid device id"
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.PROFILE_DEFERENCE_DEVICE_CODE,
@@ -1122,3 +1199,59 @@ func (svc *userProfileService) GetProfilePictureController(ctx context.Context,
 		},
 	}
 }
+
+// ExportDataService hands the user every personal record the service holds in
+// one machine-readable document. It is only served to a trusted device.
+func (svc *userProfileService) ExportDataService(ctx context.Context, userUUID *string, logData *dto.CustomLoggerRequest) *dto.BaseResponse {
+	customResource, ok := ctx.Value(enum.CUSTOM_CONTEXT_VALUE).(*dto.ContextValue)
+	if !ok {
+		err := errors.New("failed to get custom resource")
+		svc.clogger.ErrorLogger(ctx, "ExportDataService", err)
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      "failed to get custom resource",
+		}
+	}
+	user, err := svc.userRepository.SelectUserByUUID(ctx, *userUUID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logData.Error = "user not found"
+			return &dto.BaseResponse{
+				StatusCode: pkgErr.PROFILE_USER_NOT_FOUND_CODE,
+				Message:    pkgErr.USER_NOT_FOUND_MSG,
+			}
+		}
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	logData.UserUUID = user.UUID
+	logData.Email = user.Email
+	if !svc.deviceService.IsTrusted(ctx, user, customResource.HeaderXDeviceID) {
+		logData.Error = "invalid device id"
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.PROFILE_DEFERENCE_DEVICE_CODE,
+			Message:    pkgErr.DEFERENCE_DEVICE_MSG,
+		}
+	}
+	export, err := svc.accountService.Export(ctx, user)
+	if err != nil {
+		logData.Error = err.Error()
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.UNDEFINED_ERROR_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	logData.Success = true
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+		Data:       export,
+	}
+}
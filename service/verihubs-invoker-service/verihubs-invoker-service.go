@@ -1,72 +1,181 @@
 package verihubsInvokerService
 
 import (
+	"backend-mobile-api/app/config"
 	"backend-mobile-api/helpers"
 	"backend-mobile-api/internal/repository/postgres"
 	"backend-mobile-api/model/dto"
 	"backend-mobile-api/model/dto/request"
+	"backend-mobile-api/model/dto/response"
 	"backend-mobile-api/model/entity"
 	"backend-mobile-api/model/enum"
 	"backend-mobile-api/model/enum/pkgErr"
+	"backend-mobile-api/service/otp"
 	"context"
+	"crypto/hmac"
 	"errors"
 	"fmt"
 	"gorm.io/gorm"
 	"strconv"
+	"time"
 )
 
 type verihubsInvokerService struct {
-	OTPRepository postgres.OtpRepository
-	Clog          *helpers.CustomLogger
+	OTPRepository  postgres.OtpRepository
+	OtpService     otp.OtpService
+	VerihubsConfig *config.Verihubs
+	Clog           *helpers.CustomLogger
+	UnitOfWork     postgres.UnitOfWork
 }
 type VerihubsInvokerService interface {
 	OtpInvokerService(ctx context.Context, req *request.VerihubsOtpInvoker) *dto.BaseResponse
+	OtpMetricsService(ctx context.Context, req *request.OtpMetricsRequest) *dto.BaseResponse
+	OtpTimelineService(ctx context.Context, req *request.OtpTimelineRequest) *dto.BaseResponse
 }
 
-func NewVerihubsInvokerService(OTPRepository postgres.OtpRepository, Clog *helpers.CustomLogger) VerihubsInvokerService {
-	return &verihubsInvokerService{OTPRepository: OTPRepository, Clog: Clog}
+func NewVerihubsInvokerService(OTPRepository postgres.OtpRepository, OtpService otp.OtpService, VerihubsConfig *config.Verihubs, Clog *helpers.CustomLogger, UnitOfWork postgres.UnitOfWork) VerihubsInvokerService {
+	return &verihubsInvokerService{OTPRepository: OTPRepository, OtpService: OtpService, VerihubsConfig: VerihubsConfig, Clog: Clog, UnitOfWork: UnitOfWork}
 }
+
+// OtpInvokerService applies a delivery report of verihubs. The callback carries the
+// token of the otp's verify key, the status is read with the table of the channel
+// the otp was sent on and every report is added to the otp's delivery timeline.
 func (svc *verihubsInvokerService) OtpInvokerService(ctx context.Context, req *request.VerihubsOtpInvoker) *dto.BaseResponse {
+	// an unknown session answers like a wrong token, the callback can not be used to probe session ids
+	unauthorized := &dto.BaseResponse{
+		StatusCode: pkgErr.OTP_CALLBACK_UNAUTHORIZED_CODE,
+		Message:    pkgErr.UNAUTHORIZED_MSG,
+	}
+	if svc.VerihubsConfig.OTPCallbackSecret == "" {
+		svc.Clog.WarnLogger(ctx, "OtpInvoker: callback secret is not configured")
+		return unauthorized
+	}
 	otpData, err := svc.OTPRepository.SelectOtpBySessionId(ctx, req.SessionId)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return &dto.BaseResponse{
-				StatusCode: pkgErr.OUTBOUND_RECORD_NOT_FOUND_CODE,
-				Message:    pkgErr.RECORD_NOT_FOUND_MSG,
-			}
-		}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.OUTBOUND_UNDIFINED_CODE,
 			Message:    pkgErr.SERVER_BUSY,
 			Error:      err.Error(),
 		}
 	}
-	tx := svc.OTPRepository.Tx(ctx)
+	if err != nil || !hmac.Equal([]byte(req.Token), []byte(otp.CallbackToken(svc.VerihubsConfig.OTPCallbackSecret, otpData.VerifyKey))) {
+		svc.Clog.WarnLogger(ctx, fmt.Sprintf("OtpInvoker: callback token rejected for session %s", req.SessionId))
+		return unauthorized
+	}
 	statusNum, _ := strconv.Atoi(req.Status)
-	status := enum.OtpSMSMapStatusVerihubs[statusNum]
+	status := enum.OtpMapStatusVerihubs(otpData.OtpMethod, statusNum)
 	if status == "" {
-		err = fmt.Errorf("out of map status on status num: %s", req.Status)
-		svc.Clog.ErrorLogger(ctx, "OtpInvoker.enum.OtpSMSMapStatusVerihubs", err)
+		err = fmt.Errorf("out of map status on status num: %s for method %s", req.Status, otpData.OtpMethod)
+		svc.Clog.ErrorLogger(ctx, "OtpInvoker.enum.OtpMapStatusVerihubs", err)
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.OUTBOUND_INVALID_PAYLOAD,
 			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
 			Error:      "invalid status",
 		}
 	}
-	err = svc.OTPRepository.UpdateOtpDataRepository(ctx, tx, otpData, &entity.OTP{
-		Status: status,
+	// the report always goes on the timeline, the otp itself only takes it while still open
+	var updated int64
+	err = svc.UnitOfWork.Do(ctx, func(ctx context.Context, tx *gorm.DB) error {
+		var err error
+		if updated, err = svc.OTPRepository.UpdateOpenOtpStatus(ctx, tx, otpData, status); err != nil {
+			return err
+		}
+		return svc.OTPRepository.InsertOtpDeliveryEvent(ctx, tx, &entity.OtpDeliveryEvent{
+			OtpID:          otpData.ID,
+			VerifyKey:      otpData.VerifyKey,
+			SessionId:      otpData.SessionId,
+			OtpMethod:      otpData.OtpMethod,
+			Status:         status,
+			ProviderStatus: req.Status,
+		})
 	})
 	if err != nil {
-		tx.Rollback()
 		return &dto.BaseResponse{
 			StatusCode: pkgErr.OUTBOUND_UNDIFINED_CODE,
 			Message:    pkgErr.SERVER_BUSY,
 			Error:      err.Error(),
 		}
 	}
-	tx.Commit()
+	if updated == 0 {
+		svc.Clog.WarnLogger(ctx, fmt.Sprintf("OtpInvoker: late %s report for closed session %s", status, req.SessionId))
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.SUCCESS_CODE,
+			Message:    pkgErr.SUCCES_MSG,
+		}
+	}
+	// verihubs only needs to know the callback arrived, a failed fallback is logged
+	if err = svc.OtpService.HandleDeliveryStatus(ctx, otpData, status); err != nil {
+		svc.Clog.ErrorLogger(ctx, "OtpInvoker.OtpService.HandleDeliveryStatus", err)
+	}
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+	}
+}
+
+// OtpMetricsService counts the otps sent per channel between two days, both included,
+// and how many of them verihubs bills for.
+func (svc *verihubsInvokerService) OtpMetricsService(ctx context.Context, req *request.OtpMetricsRequest) *dto.BaseResponse {
+	from, errFrom := time.ParseInLocation(time.DateOnly, req.From, time.Local)
+	to, errTo := time.ParseInLocation(time.DateOnly, req.To, time.Local)
+	if err := errors.Join(errFrom, errTo); err != nil || to.Before(from) {
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.OUTBOUND_INVALID_PAYLOAD,
+			Message:    pkgErr.INVALID_REQUEST_PAYLOAD_MSG,
+			Error:      "invalid date range",
+		}
+	}
+	metrics, err := svc.OTPRepository.SummarizeOtpDelivery(ctx, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.OUTBOUND_UNDIFINED_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	if metrics == nil {
+		metrics = []entity.OtpChannelMetric{}
+	}
+	return &dto.BaseResponse{
+		StatusCode: pkgErr.SUCCESS_CODE,
+		Message:    pkgErr.SUCCES_MSG,
+		Data: response.OtpMetricsResponse{
+			From:     req.From,
+			To:       req.To,
+			Channels: metrics,
+		},
+	}
+}
+
+// OtpTimelineService lists every send and report of a verify key, oldest first.
+func (svc *verihubsInvokerService) OtpTimelineService(ctx context.Context, req *request.OtpTimelineRequest) *dto.BaseResponse {
+	events, err := svc.OTPRepository.SelectOtpDeliveryEvents(ctx, req.VerifyID)
+	if err != nil {
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.OUTBOUND_UNDIFINED_CODE,
+			Message:    pkgErr.SERVER_BUSY,
+			Error:      err.Error(),
+		}
+	}
+	if len(events) == 0 {
+		return &dto.BaseResponse{
+			StatusCode: pkgErr.OUTBOUND_RECORD_NOT_FOUND_CODE,
+			Message:    pkgErr.RECORD_NOT_FOUND_MSG,
+		}
+	}
+	timeline := make([]response.OtpDeliveryEventResponse, 0, len(events))
+	for _, event := range events {
+		timeline = append(timeline, response.OtpDeliveryEventResponse{
+			OtpMethod:      event.OtpMethod,
+			SessionId:      event.SessionId,
+			Status:         event.Status,
+			ProviderStatus: event.ProviderStatus,
+			CreatedAt:      event.CreatedAt,
+		})
+	}
 	return &dto.BaseResponse{
 		StatusCode: pkgErr.SUCCESS_CODE,
 		Message:    pkgErr.SUCCES_MSG,
+		Data:       timeline,
 	}
 }